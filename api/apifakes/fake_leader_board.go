@@ -9,12 +9,149 @@ import (
 )
 
 type FakeLeaderBoard struct {
-	GetRanksStub        func(context.Context, int, int) ([]api.User, error)
+	AddReviewStub        func(context.Context, api.Review) (api.Review, error)
+	addReviewMutex       sync.RWMutex
+	addReviewArgsForCall []struct {
+		arg1 context.Context
+		arg2 api.Review
+	}
+	addReviewReturns struct {
+		result1 api.Review
+		result2 error
+	}
+	addReviewReturnsOnCall map[int]struct {
+		result1 api.Review
+		result2 error
+	}
+	AddWebhookStub        func(context.Context, api.Webhook) (api.Webhook, error)
+	addWebhookMutex       sync.RWMutex
+	addWebhookArgsForCall []struct {
+		arg1 context.Context
+		arg2 api.Webhook
+	}
+	addWebhookReturns struct {
+		result1 api.Webhook
+		result2 error
+	}
+	addWebhookReturnsOnCall map[int]struct {
+		result1 api.Webhook
+		result2 error
+	}
+	CreateSnapshotStub        func(context.Context, string) (api.Snapshot, error)
+	createSnapshotMutex       sync.RWMutex
+	createSnapshotArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	createSnapshotReturns struct {
+		result1 api.Snapshot
+		result2 error
+	}
+	createSnapshotReturnsOnCall map[int]struct {
+		result1 api.Snapshot
+		result2 error
+	}
+	DeleteSnapshotStub        func(context.Context, string) error
+	deleteSnapshotMutex       sync.RWMutex
+	deleteSnapshotArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	deleteSnapshotReturns struct {
+		result1 error
+	}
+	deleteSnapshotReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteWebhookStub        func(context.Context, string) error
+	deleteWebhookMutex       sync.RWMutex
+	deleteWebhookArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	deleteWebhookReturns struct {
+		result1 error
+	}
+	deleteWebhookReturnsOnCall map[int]struct {
+		result1 error
+	}
+	EraseUserStub        func(context.Context, string, api.Audit) (api.ErasureReport, error)
+	eraseUserMutex       sync.RWMutex
+	eraseUserArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 api.Audit
+	}
+	eraseUserReturns struct {
+		result1 api.ErasureReport
+		result2 error
+	}
+	eraseUserReturnsOnCall map[int]struct {
+		result1 api.ErasureReport
+		result2 error
+	}
+	ExpireInactiveStub        func(context.Context) (int, error)
+	expireInactiveMutex       sync.RWMutex
+	expireInactiveArgsForCall []struct {
+		arg1 context.Context
+	}
+	expireInactiveReturns struct {
+		result1 int
+		result2 error
+	}
+	expireInactiveReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	ExportUserStub        func(context.Context, string) (api.UserExport, error)
+	exportUserMutex       sync.RWMutex
+	exportUserArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	exportUserReturns struct {
+		result1 api.UserExport
+		result2 error
+	}
+	exportUserReturnsOnCall map[int]struct {
+		result1 api.UserExport
+		result2 error
+	}
+	GetDeadLettersStub        func(context.Context, string) ([]api.WebhookDelivery, error)
+	getDeadLettersMutex       sync.RWMutex
+	getDeadLettersArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getDeadLettersReturns struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}
+	getDeadLettersReturnsOnCall map[int]struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}
+	GetHistoryStub        func(context.Context, string) ([]api.HistoryEntry, error)
+	getHistoryMutex       sync.RWMutex
+	getHistoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getHistoryReturns struct {
+		result1 []api.HistoryEntry
+		result2 error
+	}
+	getHistoryReturnsOnCall map[int]struct {
+		result1 []api.HistoryEntry
+		result2 error
+	}
+	GetRanksStub        func(context.Context, int, int, ...api.Option) ([]api.User, error)
 	getRanksMutex       sync.RWMutex
 	getRanksArgsForCall []struct {
 		arg1 context.Context
 		arg2 int
 		arg3 int
+		arg4 []api.Option
 	}
 	getRanksReturns struct {
 		result1 []api.User
@@ -24,11 +161,51 @@ type FakeLeaderBoard struct {
 		result1 []api.User
 		result2 error
 	}
-	GetUserStub        func(context.Context, string) (api.User, error)
+	GetRejectionsStub        func(context.Context) ([]api.Rejection, error)
+	getRejectionsMutex       sync.RWMutex
+	getRejectionsArgsForCall []struct {
+		arg1 context.Context
+	}
+	getRejectionsReturns struct {
+		result1 []api.Rejection
+		result2 error
+	}
+	getRejectionsReturnsOnCall map[int]struct {
+		result1 []api.Rejection
+		result2 error
+	}
+	GetReviewsStub        func(context.Context) ([]api.Review, error)
+	getReviewsMutex       sync.RWMutex
+	getReviewsArgsForCall []struct {
+		arg1 context.Context
+	}
+	getReviewsReturns struct {
+		result1 []api.Review
+		result2 error
+	}
+	getReviewsReturnsOnCall map[int]struct {
+		result1 []api.Review
+		result2 error
+	}
+	GetSnapshotsStub        func(context.Context) ([]api.Snapshot, error)
+	getSnapshotsMutex       sync.RWMutex
+	getSnapshotsArgsForCall []struct {
+		arg1 context.Context
+	}
+	getSnapshotsReturns struct {
+		result1 []api.Snapshot
+		result2 error
+	}
+	getSnapshotsReturnsOnCall map[int]struct {
+		result1 []api.Snapshot
+		result2 error
+	}
+	GetUserStub        func(context.Context, string, ...api.Option) (api.User, error)
 	getUserMutex       sync.RWMutex
 	getUserArgsForCall []struct {
 		arg1 context.Context
 		arg2 string
+		arg3 []api.Option
 	}
 	getUserReturns struct {
 		result1 api.User
@@ -38,12 +215,94 @@ type FakeLeaderBoard struct {
 		result1 api.User
 		result2 error
 	}
-	SetUserStub        func(context.Context, string, int) error
+	GetVersionStub        func(context.Context) (api.BoardVersion, error)
+	getVersionMutex       sync.RWMutex
+	getVersionArgsForCall []struct {
+		arg1 context.Context
+	}
+	getVersionReturns struct {
+		result1 api.BoardVersion
+		result2 error
+	}
+	getVersionReturnsOnCall map[int]struct {
+		result1 api.BoardVersion
+		result2 error
+	}
+	GetWebhookDeliveriesStub        func(context.Context, string) ([]api.WebhookDelivery, error)
+	getWebhookDeliveriesMutex       sync.RWMutex
+	getWebhookDeliveriesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getWebhookDeliveriesReturns struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}
+	getWebhookDeliveriesReturnsOnCall map[int]struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}
+	GetWebhooksStub        func(context.Context) ([]api.Webhook, error)
+	getWebhooksMutex       sync.RWMutex
+	getWebhooksArgsForCall []struct {
+		arg1 context.Context
+	}
+	getWebhooksReturns struct {
+		result1 []api.Webhook
+		result2 error
+	}
+	getWebhooksReturnsOnCall map[int]struct {
+		result1 []api.Webhook
+		result2 error
+	}
+	OverrideScoreStub        func(context.Context, string, int, api.Audit) error
+	overrideScoreMutex       sync.RWMutex
+	overrideScoreArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+		arg4 api.Audit
+	}
+	overrideScoreReturns struct {
+		result1 error
+	}
+	overrideScoreReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ResolveReviewStub        func(context.Context, string, bool, api.Audit) error
+	resolveReviewMutex       sync.RWMutex
+	resolveReviewArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 bool
+		arg4 api.Audit
+	}
+	resolveReviewReturns struct {
+		result1 error
+	}
+	resolveReviewReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RevertScoreStub        func(context.Context, string, api.Audit) error
+	revertScoreMutex       sync.RWMutex
+	revertScoreArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 api.Audit
+	}
+	revertScoreReturns struct {
+		result1 error
+	}
+	revertScoreReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SetUserStub        func(context.Context, string, int, ...api.Option) error
 	setUserMutex       sync.RWMutex
 	setUserArgsForCall []struct {
 		arg1 context.Context
 		arg2 string
 		arg3 int
+		arg4 []api.Option
 	}
 	setUserReturns struct {
 		result1 error
@@ -51,10 +310,24 @@ type FakeLeaderBoard struct {
 	setUserReturnsOnCall map[int]struct {
 		result1 error
 	}
-	UserCountStub        func(context.Context) (int, error)
+	SetUserStateStub        func(context.Context, string, api.UserState) error
+	setUserStateMutex       sync.RWMutex
+	setUserStateArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 api.UserState
+	}
+	setUserStateReturns struct {
+		result1 error
+	}
+	setUserStateReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UserCountStub        func(context.Context, ...api.Option) (int, error)
 	userCountMutex       sync.RWMutex
 	userCountArgsForCall []struct {
 		arg1 context.Context
+		arg2 []api.Option
 	}
 	userCountReturns struct {
 		result1 int
@@ -68,20 +341,857 @@ type FakeLeaderBoard struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeLeaderBoard) GetRanks(arg1 context.Context, arg2 int, arg3 int) ([]api.User, error) {
-	fake.getRanksMutex.Lock()
-	ret, specificReturn := fake.getRanksReturnsOnCall[len(fake.getRanksArgsForCall)]
-	fake.getRanksArgsForCall = append(fake.getRanksArgsForCall, struct {
+func (fake *FakeLeaderBoard) AddReview(arg1 context.Context, arg2 api.Review) (api.Review, error) {
+	fake.addReviewMutex.Lock()
+	ret, specificReturn := fake.addReviewReturnsOnCall[len(fake.addReviewArgsForCall)]
+	fake.addReviewArgsForCall = append(fake.addReviewArgsForCall, struct {
+		arg1 context.Context
+		arg2 api.Review
+	}{arg1, arg2})
+	stub := fake.AddReviewStub
+	fakeReturns := fake.addReviewReturns
+	fake.recordInvocation("AddReview", []interface{}{arg1, arg2})
+	fake.addReviewMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) AddReviewCallCount() int {
+	fake.addReviewMutex.RLock()
+	defer fake.addReviewMutex.RUnlock()
+	return len(fake.addReviewArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) AddReviewCalls(stub func(context.Context, api.Review) (api.Review, error)) {
+	fake.addReviewMutex.Lock()
+	defer fake.addReviewMutex.Unlock()
+	fake.AddReviewStub = stub
+}
+
+func (fake *FakeLeaderBoard) AddReviewArgsForCall(i int) (context.Context, api.Review) {
+	fake.addReviewMutex.RLock()
+	defer fake.addReviewMutex.RUnlock()
+	argsForCall := fake.addReviewArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) AddReviewReturns(result1 api.Review, result2 error) {
+	fake.addReviewMutex.Lock()
+	defer fake.addReviewMutex.Unlock()
+	fake.AddReviewStub = nil
+	fake.addReviewReturns = struct {
+		result1 api.Review
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) AddReviewReturnsOnCall(i int, result1 api.Review, result2 error) {
+	fake.addReviewMutex.Lock()
+	defer fake.addReviewMutex.Unlock()
+	fake.AddReviewStub = nil
+	if fake.addReviewReturnsOnCall == nil {
+		fake.addReviewReturnsOnCall = make(map[int]struct {
+			result1 api.Review
+			result2 error
+		})
+	}
+	fake.addReviewReturnsOnCall[i] = struct {
+		result1 api.Review
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) AddWebhook(arg1 context.Context, arg2 api.Webhook) (api.Webhook, error) {
+	fake.addWebhookMutex.Lock()
+	ret, specificReturn := fake.addWebhookReturnsOnCall[len(fake.addWebhookArgsForCall)]
+	fake.addWebhookArgsForCall = append(fake.addWebhookArgsForCall, struct {
+		arg1 context.Context
+		arg2 api.Webhook
+	}{arg1, arg2})
+	stub := fake.AddWebhookStub
+	fakeReturns := fake.addWebhookReturns
+	fake.recordInvocation("AddWebhook", []interface{}{arg1, arg2})
+	fake.addWebhookMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) AddWebhookCallCount() int {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	return len(fake.addWebhookArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) AddWebhookCalls(stub func(context.Context, api.Webhook) (api.Webhook, error)) {
+	fake.addWebhookMutex.Lock()
+	defer fake.addWebhookMutex.Unlock()
+	fake.AddWebhookStub = stub
+}
+
+func (fake *FakeLeaderBoard) AddWebhookArgsForCall(i int) (context.Context, api.Webhook) {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	argsForCall := fake.addWebhookArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) AddWebhookReturns(result1 api.Webhook, result2 error) {
+	fake.addWebhookMutex.Lock()
+	defer fake.addWebhookMutex.Unlock()
+	fake.AddWebhookStub = nil
+	fake.addWebhookReturns = struct {
+		result1 api.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) AddWebhookReturnsOnCall(i int, result1 api.Webhook, result2 error) {
+	fake.addWebhookMutex.Lock()
+	defer fake.addWebhookMutex.Unlock()
+	fake.AddWebhookStub = nil
+	if fake.addWebhookReturnsOnCall == nil {
+		fake.addWebhookReturnsOnCall = make(map[int]struct {
+			result1 api.Webhook
+			result2 error
+		})
+	}
+	fake.addWebhookReturnsOnCall[i] = struct {
+		result1 api.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) CreateSnapshot(arg1 context.Context, arg2 string) (api.Snapshot, error) {
+	fake.createSnapshotMutex.Lock()
+	ret, specificReturn := fake.createSnapshotReturnsOnCall[len(fake.createSnapshotArgsForCall)]
+	fake.createSnapshotArgsForCall = append(fake.createSnapshotArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.CreateSnapshotStub
+	fakeReturns := fake.createSnapshotReturns
+	fake.recordInvocation("CreateSnapshot", []interface{}{arg1, arg2})
+	fake.createSnapshotMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) CreateSnapshotCallCount() int {
+	fake.createSnapshotMutex.RLock()
+	defer fake.createSnapshotMutex.RUnlock()
+	return len(fake.createSnapshotArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) CreateSnapshotCalls(stub func(context.Context, string) (api.Snapshot, error)) {
+	fake.createSnapshotMutex.Lock()
+	defer fake.createSnapshotMutex.Unlock()
+	fake.CreateSnapshotStub = stub
+}
+
+func (fake *FakeLeaderBoard) CreateSnapshotArgsForCall(i int) (context.Context, string) {
+	fake.createSnapshotMutex.RLock()
+	defer fake.createSnapshotMutex.RUnlock()
+	argsForCall := fake.createSnapshotArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) CreateSnapshotReturns(result1 api.Snapshot, result2 error) {
+	fake.createSnapshotMutex.Lock()
+	defer fake.createSnapshotMutex.Unlock()
+	fake.CreateSnapshotStub = nil
+	fake.createSnapshotReturns = struct {
+		result1 api.Snapshot
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) CreateSnapshotReturnsOnCall(i int, result1 api.Snapshot, result2 error) {
+	fake.createSnapshotMutex.Lock()
+	defer fake.createSnapshotMutex.Unlock()
+	fake.CreateSnapshotStub = nil
+	if fake.createSnapshotReturnsOnCall == nil {
+		fake.createSnapshotReturnsOnCall = make(map[int]struct {
+			result1 api.Snapshot
+			result2 error
+		})
+	}
+	fake.createSnapshotReturnsOnCall[i] = struct {
+		result1 api.Snapshot
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) DeleteSnapshot(arg1 context.Context, arg2 string) error {
+	fake.deleteSnapshotMutex.Lock()
+	ret, specificReturn := fake.deleteSnapshotReturnsOnCall[len(fake.deleteSnapshotArgsForCall)]
+	fake.deleteSnapshotArgsForCall = append(fake.deleteSnapshotArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.DeleteSnapshotStub
+	fakeReturns := fake.deleteSnapshotReturns
+	fake.recordInvocation("DeleteSnapshot", []interface{}{arg1, arg2})
+	fake.deleteSnapshotMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLeaderBoard) DeleteSnapshotCallCount() int {
+	fake.deleteSnapshotMutex.RLock()
+	defer fake.deleteSnapshotMutex.RUnlock()
+	return len(fake.deleteSnapshotArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) DeleteSnapshotCalls(stub func(context.Context, string) error) {
+	fake.deleteSnapshotMutex.Lock()
+	defer fake.deleteSnapshotMutex.Unlock()
+	fake.DeleteSnapshotStub = stub
+}
+
+func (fake *FakeLeaderBoard) DeleteSnapshotArgsForCall(i int) (context.Context, string) {
+	fake.deleteSnapshotMutex.RLock()
+	defer fake.deleteSnapshotMutex.RUnlock()
+	argsForCall := fake.deleteSnapshotArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) DeleteSnapshotReturns(result1 error) {
+	fake.deleteSnapshotMutex.Lock()
+	defer fake.deleteSnapshotMutex.Unlock()
+	fake.DeleteSnapshotStub = nil
+	fake.deleteSnapshotReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) DeleteSnapshotReturnsOnCall(i int, result1 error) {
+	fake.deleteSnapshotMutex.Lock()
+	defer fake.deleteSnapshotMutex.Unlock()
+	fake.DeleteSnapshotStub = nil
+	if fake.deleteSnapshotReturnsOnCall == nil {
+		fake.deleteSnapshotReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteSnapshotReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) DeleteWebhook(arg1 context.Context, arg2 string) error {
+	fake.deleteWebhookMutex.Lock()
+	ret, specificReturn := fake.deleteWebhookReturnsOnCall[len(fake.deleteWebhookArgsForCall)]
+	fake.deleteWebhookArgsForCall = append(fake.deleteWebhookArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.DeleteWebhookStub
+	fakeReturns := fake.deleteWebhookReturns
+	fake.recordInvocation("DeleteWebhook", []interface{}{arg1, arg2})
+	fake.deleteWebhookMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLeaderBoard) DeleteWebhookCallCount() int {
+	fake.deleteWebhookMutex.RLock()
+	defer fake.deleteWebhookMutex.RUnlock()
+	return len(fake.deleteWebhookArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) DeleteWebhookCalls(stub func(context.Context, string) error) {
+	fake.deleteWebhookMutex.Lock()
+	defer fake.deleteWebhookMutex.Unlock()
+	fake.DeleteWebhookStub = stub
+}
+
+func (fake *FakeLeaderBoard) DeleteWebhookArgsForCall(i int) (context.Context, string) {
+	fake.deleteWebhookMutex.RLock()
+	defer fake.deleteWebhookMutex.RUnlock()
+	argsForCall := fake.deleteWebhookArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) DeleteWebhookReturns(result1 error) {
+	fake.deleteWebhookMutex.Lock()
+	defer fake.deleteWebhookMutex.Unlock()
+	fake.DeleteWebhookStub = nil
+	fake.deleteWebhookReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) DeleteWebhookReturnsOnCall(i int, result1 error) {
+	fake.deleteWebhookMutex.Lock()
+	defer fake.deleteWebhookMutex.Unlock()
+	fake.DeleteWebhookStub = nil
+	if fake.deleteWebhookReturnsOnCall == nil {
+		fake.deleteWebhookReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteWebhookReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) EraseUser(arg1 context.Context, arg2 string, arg3 api.Audit) (api.ErasureReport, error) {
+	fake.eraseUserMutex.Lock()
+	ret, specificReturn := fake.eraseUserReturnsOnCall[len(fake.eraseUserArgsForCall)]
+	fake.eraseUserArgsForCall = append(fake.eraseUserArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 api.Audit
+	}{arg1, arg2, arg3})
+	stub := fake.EraseUserStub
+	fakeReturns := fake.eraseUserReturns
+	fake.recordInvocation("EraseUser", []interface{}{arg1, arg2, arg3})
+	fake.eraseUserMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) EraseUserCallCount() int {
+	fake.eraseUserMutex.RLock()
+	defer fake.eraseUserMutex.RUnlock()
+	return len(fake.eraseUserArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) EraseUserCalls(stub func(context.Context, string, api.Audit) (api.ErasureReport, error)) {
+	fake.eraseUserMutex.Lock()
+	defer fake.eraseUserMutex.Unlock()
+	fake.EraseUserStub = stub
+}
+
+func (fake *FakeLeaderBoard) EraseUserArgsForCall(i int) (context.Context, string, api.Audit) {
+	fake.eraseUserMutex.RLock()
+	defer fake.eraseUserMutex.RUnlock()
+	argsForCall := fake.eraseUserArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeLeaderBoard) EraseUserReturns(result1 api.ErasureReport, result2 error) {
+	fake.eraseUserMutex.Lock()
+	defer fake.eraseUserMutex.Unlock()
+	fake.EraseUserStub = nil
+	fake.eraseUserReturns = struct {
+		result1 api.ErasureReport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) EraseUserReturnsOnCall(i int, result1 api.ErasureReport, result2 error) {
+	fake.eraseUserMutex.Lock()
+	defer fake.eraseUserMutex.Unlock()
+	fake.EraseUserStub = nil
+	if fake.eraseUserReturnsOnCall == nil {
+		fake.eraseUserReturnsOnCall = make(map[int]struct {
+			result1 api.ErasureReport
+			result2 error
+		})
+	}
+	fake.eraseUserReturnsOnCall[i] = struct {
+		result1 api.ErasureReport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) ExpireInactive(arg1 context.Context) (int, error) {
+	fake.expireInactiveMutex.Lock()
+	ret, specificReturn := fake.expireInactiveReturnsOnCall[len(fake.expireInactiveArgsForCall)]
+	fake.expireInactiveArgsForCall = append(fake.expireInactiveArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.ExpireInactiveStub
+	fakeReturns := fake.expireInactiveReturns
+	fake.recordInvocation("ExpireInactive", []interface{}{arg1})
+	fake.expireInactiveMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) ExpireInactiveCallCount() int {
+	fake.expireInactiveMutex.RLock()
+	defer fake.expireInactiveMutex.RUnlock()
+	return len(fake.expireInactiveArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) ExpireInactiveCalls(stub func(context.Context) (int, error)) {
+	fake.expireInactiveMutex.Lock()
+	defer fake.expireInactiveMutex.Unlock()
+	fake.ExpireInactiveStub = stub
+}
+
+func (fake *FakeLeaderBoard) ExpireInactiveArgsForCall(i int) context.Context {
+	fake.expireInactiveMutex.RLock()
+	defer fake.expireInactiveMutex.RUnlock()
+	argsForCall := fake.expireInactiveArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLeaderBoard) ExpireInactiveReturns(result1 int, result2 error) {
+	fake.expireInactiveMutex.Lock()
+	defer fake.expireInactiveMutex.Unlock()
+	fake.ExpireInactiveStub = nil
+	fake.expireInactiveReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) ExpireInactiveReturnsOnCall(i int, result1 int, result2 error) {
+	fake.expireInactiveMutex.Lock()
+	defer fake.expireInactiveMutex.Unlock()
+	fake.ExpireInactiveStub = nil
+	if fake.expireInactiveReturnsOnCall == nil {
+		fake.expireInactiveReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.expireInactiveReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) ExportUser(arg1 context.Context, arg2 string) (api.UserExport, error) {
+	fake.exportUserMutex.Lock()
+	ret, specificReturn := fake.exportUserReturnsOnCall[len(fake.exportUserArgsForCall)]
+	fake.exportUserArgsForCall = append(fake.exportUserArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ExportUserStub
+	fakeReturns := fake.exportUserReturns
+	fake.recordInvocation("ExportUser", []interface{}{arg1, arg2})
+	fake.exportUserMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) ExportUserCallCount() int {
+	fake.exportUserMutex.RLock()
+	defer fake.exportUserMutex.RUnlock()
+	return len(fake.exportUserArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) ExportUserCalls(stub func(context.Context, string) (api.UserExport, error)) {
+	fake.exportUserMutex.Lock()
+	defer fake.exportUserMutex.Unlock()
+	fake.ExportUserStub = stub
+}
+
+func (fake *FakeLeaderBoard) ExportUserArgsForCall(i int) (context.Context, string) {
+	fake.exportUserMutex.RLock()
+	defer fake.exportUserMutex.RUnlock()
+	argsForCall := fake.exportUserArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) ExportUserReturns(result1 api.UserExport, result2 error) {
+	fake.exportUserMutex.Lock()
+	defer fake.exportUserMutex.Unlock()
+	fake.ExportUserStub = nil
+	fake.exportUserReturns = struct {
+		result1 api.UserExport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) ExportUserReturnsOnCall(i int, result1 api.UserExport, result2 error) {
+	fake.exportUserMutex.Lock()
+	defer fake.exportUserMutex.Unlock()
+	fake.ExportUserStub = nil
+	if fake.exportUserReturnsOnCall == nil {
+		fake.exportUserReturnsOnCall = make(map[int]struct {
+			result1 api.UserExport
+			result2 error
+		})
+	}
+	fake.exportUserReturnsOnCall[i] = struct {
+		result1 api.UserExport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetDeadLetters(arg1 context.Context, arg2 string) ([]api.WebhookDelivery, error) {
+	fake.getDeadLettersMutex.Lock()
+	ret, specificReturn := fake.getDeadLettersReturnsOnCall[len(fake.getDeadLettersArgsForCall)]
+	fake.getDeadLettersArgsForCall = append(fake.getDeadLettersArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetDeadLettersStub
+	fakeReturns := fake.getDeadLettersReturns
+	fake.recordInvocation("GetDeadLetters", []interface{}{arg1, arg2})
+	fake.getDeadLettersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetDeadLettersCallCount() int {
+	fake.getDeadLettersMutex.RLock()
+	defer fake.getDeadLettersMutex.RUnlock()
+	return len(fake.getDeadLettersArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetDeadLettersCalls(stub func(context.Context, string) ([]api.WebhookDelivery, error)) {
+	fake.getDeadLettersMutex.Lock()
+	defer fake.getDeadLettersMutex.Unlock()
+	fake.GetDeadLettersStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetDeadLettersArgsForCall(i int) (context.Context, string) {
+	fake.getDeadLettersMutex.RLock()
+	defer fake.getDeadLettersMutex.RUnlock()
+	argsForCall := fake.getDeadLettersArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) GetDeadLettersReturns(result1 []api.WebhookDelivery, result2 error) {
+	fake.getDeadLettersMutex.Lock()
+	defer fake.getDeadLettersMutex.Unlock()
+	fake.GetDeadLettersStub = nil
+	fake.getDeadLettersReturns = struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetDeadLettersReturnsOnCall(i int, result1 []api.WebhookDelivery, result2 error) {
+	fake.getDeadLettersMutex.Lock()
+	defer fake.getDeadLettersMutex.Unlock()
+	fake.GetDeadLettersStub = nil
+	if fake.getDeadLettersReturnsOnCall == nil {
+		fake.getDeadLettersReturnsOnCall = make(map[int]struct {
+			result1 []api.WebhookDelivery
+			result2 error
+		})
+	}
+	fake.getDeadLettersReturnsOnCall[i] = struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetHistory(arg1 context.Context, arg2 string) ([]api.HistoryEntry, error) {
+	fake.getHistoryMutex.Lock()
+	ret, specificReturn := fake.getHistoryReturnsOnCall[len(fake.getHistoryArgsForCall)]
+	fake.getHistoryArgsForCall = append(fake.getHistoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetHistoryStub
+	fakeReturns := fake.getHistoryReturns
+	fake.recordInvocation("GetHistory", []interface{}{arg1, arg2})
+	fake.getHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetHistoryCallCount() int {
+	fake.getHistoryMutex.RLock()
+	defer fake.getHistoryMutex.RUnlock()
+	return len(fake.getHistoryArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetHistoryCalls(stub func(context.Context, string) ([]api.HistoryEntry, error)) {
+	fake.getHistoryMutex.Lock()
+	defer fake.getHistoryMutex.Unlock()
+	fake.GetHistoryStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetHistoryArgsForCall(i int) (context.Context, string) {
+	fake.getHistoryMutex.RLock()
+	defer fake.getHistoryMutex.RUnlock()
+	argsForCall := fake.getHistoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) GetHistoryReturns(result1 []api.HistoryEntry, result2 error) {
+	fake.getHistoryMutex.Lock()
+	defer fake.getHistoryMutex.Unlock()
+	fake.GetHistoryStub = nil
+	fake.getHistoryReturns = struct {
+		result1 []api.HistoryEntry
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetHistoryReturnsOnCall(i int, result1 []api.HistoryEntry, result2 error) {
+	fake.getHistoryMutex.Lock()
+	defer fake.getHistoryMutex.Unlock()
+	fake.GetHistoryStub = nil
+	if fake.getHistoryReturnsOnCall == nil {
+		fake.getHistoryReturnsOnCall = make(map[int]struct {
+			result1 []api.HistoryEntry
+			result2 error
+		})
+	}
+	fake.getHistoryReturnsOnCall[i] = struct {
+		result1 []api.HistoryEntry
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetRanks(arg1 context.Context, arg2 int, arg3 int, arg4 ...api.Option) ([]api.User, error) {
+	fake.getRanksMutex.Lock()
+	ret, specificReturn := fake.getRanksReturnsOnCall[len(fake.getRanksArgsForCall)]
+	fake.getRanksArgsForCall = append(fake.getRanksArgsForCall, struct {
+		arg1 context.Context
+		arg2 int
+		arg3 int
+		arg4 []api.Option
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetRanksStub
+	fakeReturns := fake.getRanksReturns
+	fake.recordInvocation("GetRanks", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getRanksMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetRanksCallCount() int {
+	fake.getRanksMutex.RLock()
+	defer fake.getRanksMutex.RUnlock()
+	return len(fake.getRanksArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetRanksCalls(stub func(context.Context, int, int, ...api.Option) ([]api.User, error)) {
+	fake.getRanksMutex.Lock()
+	defer fake.getRanksMutex.Unlock()
+	fake.GetRanksStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetRanksArgsForCall(i int) (context.Context, int, int, []api.Option) {
+	fake.getRanksMutex.RLock()
+	defer fake.getRanksMutex.RUnlock()
+	argsForCall := fake.getRanksArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeLeaderBoard) GetRanksReturns(result1 []api.User, result2 error) {
+	fake.getRanksMutex.Lock()
+	defer fake.getRanksMutex.Unlock()
+	fake.GetRanksStub = nil
+	fake.getRanksReturns = struct {
+		result1 []api.User
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetRanksReturnsOnCall(i int, result1 []api.User, result2 error) {
+	fake.getRanksMutex.Lock()
+	defer fake.getRanksMutex.Unlock()
+	fake.GetRanksStub = nil
+	if fake.getRanksReturnsOnCall == nil {
+		fake.getRanksReturnsOnCall = make(map[int]struct {
+			result1 []api.User
+			result2 error
+		})
+	}
+	fake.getRanksReturnsOnCall[i] = struct {
+		result1 []api.User
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetRejections(arg1 context.Context) ([]api.Rejection, error) {
+	fake.getRejectionsMutex.Lock()
+	ret, specificReturn := fake.getRejectionsReturnsOnCall[len(fake.getRejectionsArgsForCall)]
+	fake.getRejectionsArgsForCall = append(fake.getRejectionsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetRejectionsStub
+	fakeReturns := fake.getRejectionsReturns
+	fake.recordInvocation("GetRejections", []interface{}{arg1})
+	fake.getRejectionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetRejectionsCallCount() int {
+	fake.getRejectionsMutex.RLock()
+	defer fake.getRejectionsMutex.RUnlock()
+	return len(fake.getRejectionsArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetRejectionsCalls(stub func(context.Context) ([]api.Rejection, error)) {
+	fake.getRejectionsMutex.Lock()
+	defer fake.getRejectionsMutex.Unlock()
+	fake.GetRejectionsStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetRejectionsArgsForCall(i int) context.Context {
+	fake.getRejectionsMutex.RLock()
+	defer fake.getRejectionsMutex.RUnlock()
+	argsForCall := fake.getRejectionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLeaderBoard) GetRejectionsReturns(result1 []api.Rejection, result2 error) {
+	fake.getRejectionsMutex.Lock()
+	defer fake.getRejectionsMutex.Unlock()
+	fake.GetRejectionsStub = nil
+	fake.getRejectionsReturns = struct {
+		result1 []api.Rejection
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetRejectionsReturnsOnCall(i int, result1 []api.Rejection, result2 error) {
+	fake.getRejectionsMutex.Lock()
+	defer fake.getRejectionsMutex.Unlock()
+	fake.GetRejectionsStub = nil
+	if fake.getRejectionsReturnsOnCall == nil {
+		fake.getRejectionsReturnsOnCall = make(map[int]struct {
+			result1 []api.Rejection
+			result2 error
+		})
+	}
+	fake.getRejectionsReturnsOnCall[i] = struct {
+		result1 []api.Rejection
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetReviews(arg1 context.Context) ([]api.Review, error) {
+	fake.getReviewsMutex.Lock()
+	ret, specificReturn := fake.getReviewsReturnsOnCall[len(fake.getReviewsArgsForCall)]
+	fake.getReviewsArgsForCall = append(fake.getReviewsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetReviewsStub
+	fakeReturns := fake.getReviewsReturns
+	fake.recordInvocation("GetReviews", []interface{}{arg1})
+	fake.getReviewsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetReviewsCallCount() int {
+	fake.getReviewsMutex.RLock()
+	defer fake.getReviewsMutex.RUnlock()
+	return len(fake.getReviewsArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetReviewsCalls(stub func(context.Context) ([]api.Review, error)) {
+	fake.getReviewsMutex.Lock()
+	defer fake.getReviewsMutex.Unlock()
+	fake.GetReviewsStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetReviewsArgsForCall(i int) context.Context {
+	fake.getReviewsMutex.RLock()
+	defer fake.getReviewsMutex.RUnlock()
+	argsForCall := fake.getReviewsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLeaderBoard) GetReviewsReturns(result1 []api.Review, result2 error) {
+	fake.getReviewsMutex.Lock()
+	defer fake.getReviewsMutex.Unlock()
+	fake.GetReviewsStub = nil
+	fake.getReviewsReturns = struct {
+		result1 []api.Review
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetReviewsReturnsOnCall(i int, result1 []api.Review, result2 error) {
+	fake.getReviewsMutex.Lock()
+	defer fake.getReviewsMutex.Unlock()
+	fake.GetReviewsStub = nil
+	if fake.getReviewsReturnsOnCall == nil {
+		fake.getReviewsReturnsOnCall = make(map[int]struct {
+			result1 []api.Review
+			result2 error
+		})
+	}
+	fake.getReviewsReturnsOnCall[i] = struct {
+		result1 []api.Review
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetSnapshots(arg1 context.Context) ([]api.Snapshot, error) {
+	fake.getSnapshotsMutex.Lock()
+	ret, specificReturn := fake.getSnapshotsReturnsOnCall[len(fake.getSnapshotsArgsForCall)]
+	fake.getSnapshotsArgsForCall = append(fake.getSnapshotsArgsForCall, struct {
 		arg1 context.Context
-		arg2 int
-		arg3 int
-	}{arg1, arg2, arg3})
-	stub := fake.GetRanksStub
-	fakeReturns := fake.getRanksReturns
-	fake.recordInvocation("GetRanks", []interface{}{arg1, arg2, arg3})
-	fake.getRanksMutex.Unlock()
+	}{arg1})
+	stub := fake.GetSnapshotsStub
+	fakeReturns := fake.getSnapshotsReturns
+	fake.recordInvocation("GetSnapshots", []interface{}{arg1})
+	fake.getSnapshotsMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -89,64 +1199,65 @@ func (fake *FakeLeaderBoard) GetRanks(arg1 context.Context, arg2 int, arg3 int)
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeLeaderBoard) GetRanksCallCount() int {
-	fake.getRanksMutex.RLock()
-	defer fake.getRanksMutex.RUnlock()
-	return len(fake.getRanksArgsForCall)
+func (fake *FakeLeaderBoard) GetSnapshotsCallCount() int {
+	fake.getSnapshotsMutex.RLock()
+	defer fake.getSnapshotsMutex.RUnlock()
+	return len(fake.getSnapshotsArgsForCall)
 }
 
-func (fake *FakeLeaderBoard) GetRanksCalls(stub func(context.Context, int, int) ([]api.User, error)) {
-	fake.getRanksMutex.Lock()
-	defer fake.getRanksMutex.Unlock()
-	fake.GetRanksStub = stub
+func (fake *FakeLeaderBoard) GetSnapshotsCalls(stub func(context.Context) ([]api.Snapshot, error)) {
+	fake.getSnapshotsMutex.Lock()
+	defer fake.getSnapshotsMutex.Unlock()
+	fake.GetSnapshotsStub = stub
 }
 
-func (fake *FakeLeaderBoard) GetRanksArgsForCall(i int) (context.Context, int, int) {
-	fake.getRanksMutex.RLock()
-	defer fake.getRanksMutex.RUnlock()
-	argsForCall := fake.getRanksArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+func (fake *FakeLeaderBoard) GetSnapshotsArgsForCall(i int) context.Context {
+	fake.getSnapshotsMutex.RLock()
+	defer fake.getSnapshotsMutex.RUnlock()
+	argsForCall := fake.getSnapshotsArgsForCall[i]
+	return argsForCall.arg1
 }
 
-func (fake *FakeLeaderBoard) GetRanksReturns(result1 []api.User, result2 error) {
-	fake.getRanksMutex.Lock()
-	defer fake.getRanksMutex.Unlock()
-	fake.GetRanksStub = nil
-	fake.getRanksReturns = struct {
-		result1 []api.User
+func (fake *FakeLeaderBoard) GetSnapshotsReturns(result1 []api.Snapshot, result2 error) {
+	fake.getSnapshotsMutex.Lock()
+	defer fake.getSnapshotsMutex.Unlock()
+	fake.GetSnapshotsStub = nil
+	fake.getSnapshotsReturns = struct {
+		result1 []api.Snapshot
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeLeaderBoard) GetRanksReturnsOnCall(i int, result1 []api.User, result2 error) {
-	fake.getRanksMutex.Lock()
-	defer fake.getRanksMutex.Unlock()
-	fake.GetRanksStub = nil
-	if fake.getRanksReturnsOnCall == nil {
-		fake.getRanksReturnsOnCall = make(map[int]struct {
-			result1 []api.User
+func (fake *FakeLeaderBoard) GetSnapshotsReturnsOnCall(i int, result1 []api.Snapshot, result2 error) {
+	fake.getSnapshotsMutex.Lock()
+	defer fake.getSnapshotsMutex.Unlock()
+	fake.GetSnapshotsStub = nil
+	if fake.getSnapshotsReturnsOnCall == nil {
+		fake.getSnapshotsReturnsOnCall = make(map[int]struct {
+			result1 []api.Snapshot
 			result2 error
 		})
 	}
-	fake.getRanksReturnsOnCall[i] = struct {
-		result1 []api.User
+	fake.getSnapshotsReturnsOnCall[i] = struct {
+		result1 []api.Snapshot
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeLeaderBoard) GetUser(arg1 context.Context, arg2 string) (api.User, error) {
+func (fake *FakeLeaderBoard) GetUser(arg1 context.Context, arg2 string, arg3 ...api.Option) (api.User, error) {
 	fake.getUserMutex.Lock()
 	ret, specificReturn := fake.getUserReturnsOnCall[len(fake.getUserArgsForCall)]
 	fake.getUserArgsForCall = append(fake.getUserArgsForCall, struct {
 		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
+		arg3 []api.Option
+	}{arg1, arg2, arg3})
 	stub := fake.GetUserStub
 	fakeReturns := fake.getUserReturns
-	fake.recordInvocation("GetUser", []interface{}{arg1, arg2})
+	fake.recordInvocation("GetUser", []interface{}{arg1, arg2, arg3})
 	fake.getUserMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -160,17 +1271,17 @@ func (fake *FakeLeaderBoard) GetUserCallCount() int {
 	return len(fake.getUserArgsForCall)
 }
 
-func (fake *FakeLeaderBoard) GetUserCalls(stub func(context.Context, string) (api.User, error)) {
+func (fake *FakeLeaderBoard) GetUserCalls(stub func(context.Context, string, ...api.Option) (api.User, error)) {
 	fake.getUserMutex.Lock()
 	defer fake.getUserMutex.Unlock()
 	fake.GetUserStub = stub
 }
 
-func (fake *FakeLeaderBoard) GetUserArgsForCall(i int) (context.Context, string) {
+func (fake *FakeLeaderBoard) GetUserArgsForCall(i int) (context.Context, string, []api.Option) {
 	fake.getUserMutex.RLock()
 	defer fake.getUserMutex.RUnlock()
 	argsForCall := fake.getUserArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeLeaderBoard) GetUserReturns(result1 api.User, result2 error) {
@@ -199,20 +1310,405 @@ func (fake *FakeLeaderBoard) GetUserReturnsOnCall(i int, result1 api.User, resul
 	}{result1, result2}
 }
 
-func (fake *FakeLeaderBoard) SetUser(arg1 context.Context, arg2 string, arg3 int) error {
+func (fake *FakeLeaderBoard) GetVersion(arg1 context.Context) (api.BoardVersion, error) {
+	fake.getVersionMutex.Lock()
+	ret, specificReturn := fake.getVersionReturnsOnCall[len(fake.getVersionArgsForCall)]
+	fake.getVersionArgsForCall = append(fake.getVersionArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetVersionStub
+	fakeReturns := fake.getVersionReturns
+	fake.recordInvocation("GetVersion", []interface{}{arg1})
+	fake.getVersionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetVersionCallCount() int {
+	fake.getVersionMutex.RLock()
+	defer fake.getVersionMutex.RUnlock()
+	return len(fake.getVersionArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetVersionCalls(stub func(context.Context) (api.BoardVersion, error)) {
+	fake.getVersionMutex.Lock()
+	defer fake.getVersionMutex.Unlock()
+	fake.GetVersionStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetVersionArgsForCall(i int) context.Context {
+	fake.getVersionMutex.RLock()
+	defer fake.getVersionMutex.RUnlock()
+	argsForCall := fake.getVersionArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLeaderBoard) GetVersionReturns(result1 api.BoardVersion, result2 error) {
+	fake.getVersionMutex.Lock()
+	defer fake.getVersionMutex.Unlock()
+	fake.GetVersionStub = nil
+	fake.getVersionReturns = struct {
+		result1 api.BoardVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetVersionReturnsOnCall(i int, result1 api.BoardVersion, result2 error) {
+	fake.getVersionMutex.Lock()
+	defer fake.getVersionMutex.Unlock()
+	fake.GetVersionStub = nil
+	if fake.getVersionReturnsOnCall == nil {
+		fake.getVersionReturnsOnCall = make(map[int]struct {
+			result1 api.BoardVersion
+			result2 error
+		})
+	}
+	fake.getVersionReturnsOnCall[i] = struct {
+		result1 api.BoardVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetWebhookDeliveries(arg1 context.Context, arg2 string) ([]api.WebhookDelivery, error) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	ret, specificReturn := fake.getWebhookDeliveriesReturnsOnCall[len(fake.getWebhookDeliveriesArgsForCall)]
+	fake.getWebhookDeliveriesArgsForCall = append(fake.getWebhookDeliveriesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetWebhookDeliveriesStub
+	fakeReturns := fake.getWebhookDeliveriesReturns
+	fake.recordInvocation("GetWebhookDeliveries", []interface{}{arg1, arg2})
+	fake.getWebhookDeliveriesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetWebhookDeliveriesCallCount() int {
+	fake.getWebhookDeliveriesMutex.RLock()
+	defer fake.getWebhookDeliveriesMutex.RUnlock()
+	return len(fake.getWebhookDeliveriesArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetWebhookDeliveriesCalls(stub func(context.Context, string) ([]api.WebhookDelivery, error)) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	defer fake.getWebhookDeliveriesMutex.Unlock()
+	fake.GetWebhookDeliveriesStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetWebhookDeliveriesArgsForCall(i int) (context.Context, string) {
+	fake.getWebhookDeliveriesMutex.RLock()
+	defer fake.getWebhookDeliveriesMutex.RUnlock()
+	argsForCall := fake.getWebhookDeliveriesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLeaderBoard) GetWebhookDeliveriesReturns(result1 []api.WebhookDelivery, result2 error) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	defer fake.getWebhookDeliveriesMutex.Unlock()
+	fake.GetWebhookDeliveriesStub = nil
+	fake.getWebhookDeliveriesReturns = struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetWebhookDeliveriesReturnsOnCall(i int, result1 []api.WebhookDelivery, result2 error) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	defer fake.getWebhookDeliveriesMutex.Unlock()
+	fake.GetWebhookDeliveriesStub = nil
+	if fake.getWebhookDeliveriesReturnsOnCall == nil {
+		fake.getWebhookDeliveriesReturnsOnCall = make(map[int]struct {
+			result1 []api.WebhookDelivery
+			result2 error
+		})
+	}
+	fake.getWebhookDeliveriesReturnsOnCall[i] = struct {
+		result1 []api.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetWebhooks(arg1 context.Context) ([]api.Webhook, error) {
+	fake.getWebhooksMutex.Lock()
+	ret, specificReturn := fake.getWebhooksReturnsOnCall[len(fake.getWebhooksArgsForCall)]
+	fake.getWebhooksArgsForCall = append(fake.getWebhooksArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetWebhooksStub
+	fakeReturns := fake.getWebhooksReturns
+	fake.recordInvocation("GetWebhooks", []interface{}{arg1})
+	fake.getWebhooksMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLeaderBoard) GetWebhooksCallCount() int {
+	fake.getWebhooksMutex.RLock()
+	defer fake.getWebhooksMutex.RUnlock()
+	return len(fake.getWebhooksArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) GetWebhooksCalls(stub func(context.Context) ([]api.Webhook, error)) {
+	fake.getWebhooksMutex.Lock()
+	defer fake.getWebhooksMutex.Unlock()
+	fake.GetWebhooksStub = stub
+}
+
+func (fake *FakeLeaderBoard) GetWebhooksArgsForCall(i int) context.Context {
+	fake.getWebhooksMutex.RLock()
+	defer fake.getWebhooksMutex.RUnlock()
+	argsForCall := fake.getWebhooksArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLeaderBoard) GetWebhooksReturns(result1 []api.Webhook, result2 error) {
+	fake.getWebhooksMutex.Lock()
+	defer fake.getWebhooksMutex.Unlock()
+	fake.GetWebhooksStub = nil
+	fake.getWebhooksReturns = struct {
+		result1 []api.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) GetWebhooksReturnsOnCall(i int, result1 []api.Webhook, result2 error) {
+	fake.getWebhooksMutex.Lock()
+	defer fake.getWebhooksMutex.Unlock()
+	fake.GetWebhooksStub = nil
+	if fake.getWebhooksReturnsOnCall == nil {
+		fake.getWebhooksReturnsOnCall = make(map[int]struct {
+			result1 []api.Webhook
+			result2 error
+		})
+	}
+	fake.getWebhooksReturnsOnCall[i] = struct {
+		result1 []api.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLeaderBoard) OverrideScore(arg1 context.Context, arg2 string, arg3 int, arg4 api.Audit) error {
+	fake.overrideScoreMutex.Lock()
+	ret, specificReturn := fake.overrideScoreReturnsOnCall[len(fake.overrideScoreArgsForCall)]
+	fake.overrideScoreArgsForCall = append(fake.overrideScoreArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+		arg4 api.Audit
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.OverrideScoreStub
+	fakeReturns := fake.overrideScoreReturns
+	fake.recordInvocation("OverrideScore", []interface{}{arg1, arg2, arg3, arg4})
+	fake.overrideScoreMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLeaderBoard) OverrideScoreCallCount() int {
+	fake.overrideScoreMutex.RLock()
+	defer fake.overrideScoreMutex.RUnlock()
+	return len(fake.overrideScoreArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) OverrideScoreCalls(stub func(context.Context, string, int, api.Audit) error) {
+	fake.overrideScoreMutex.Lock()
+	defer fake.overrideScoreMutex.Unlock()
+	fake.OverrideScoreStub = stub
+}
+
+func (fake *FakeLeaderBoard) OverrideScoreArgsForCall(i int) (context.Context, string, int, api.Audit) {
+	fake.overrideScoreMutex.RLock()
+	defer fake.overrideScoreMutex.RUnlock()
+	argsForCall := fake.overrideScoreArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeLeaderBoard) OverrideScoreReturns(result1 error) {
+	fake.overrideScoreMutex.Lock()
+	defer fake.overrideScoreMutex.Unlock()
+	fake.OverrideScoreStub = nil
+	fake.overrideScoreReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) OverrideScoreReturnsOnCall(i int, result1 error) {
+	fake.overrideScoreMutex.Lock()
+	defer fake.overrideScoreMutex.Unlock()
+	fake.OverrideScoreStub = nil
+	if fake.overrideScoreReturnsOnCall == nil {
+		fake.overrideScoreReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.overrideScoreReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) ResolveReview(arg1 context.Context, arg2 string, arg3 bool, arg4 api.Audit) error {
+	fake.resolveReviewMutex.Lock()
+	ret, specificReturn := fake.resolveReviewReturnsOnCall[len(fake.resolveReviewArgsForCall)]
+	fake.resolveReviewArgsForCall = append(fake.resolveReviewArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 bool
+		arg4 api.Audit
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ResolveReviewStub
+	fakeReturns := fake.resolveReviewReturns
+	fake.recordInvocation("ResolveReview", []interface{}{arg1, arg2, arg3, arg4})
+	fake.resolveReviewMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLeaderBoard) ResolveReviewCallCount() int {
+	fake.resolveReviewMutex.RLock()
+	defer fake.resolveReviewMutex.RUnlock()
+	return len(fake.resolveReviewArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) ResolveReviewCalls(stub func(context.Context, string, bool, api.Audit) error) {
+	fake.resolveReviewMutex.Lock()
+	defer fake.resolveReviewMutex.Unlock()
+	fake.ResolveReviewStub = stub
+}
+
+func (fake *FakeLeaderBoard) ResolveReviewArgsForCall(i int) (context.Context, string, bool, api.Audit) {
+	fake.resolveReviewMutex.RLock()
+	defer fake.resolveReviewMutex.RUnlock()
+	argsForCall := fake.resolveReviewArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeLeaderBoard) ResolveReviewReturns(result1 error) {
+	fake.resolveReviewMutex.Lock()
+	defer fake.resolveReviewMutex.Unlock()
+	fake.ResolveReviewStub = nil
+	fake.resolveReviewReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) ResolveReviewReturnsOnCall(i int, result1 error) {
+	fake.resolveReviewMutex.Lock()
+	defer fake.resolveReviewMutex.Unlock()
+	fake.ResolveReviewStub = nil
+	if fake.resolveReviewReturnsOnCall == nil {
+		fake.resolveReviewReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.resolveReviewReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) RevertScore(arg1 context.Context, arg2 string, arg3 api.Audit) error {
+	fake.revertScoreMutex.Lock()
+	ret, specificReturn := fake.revertScoreReturnsOnCall[len(fake.revertScoreArgsForCall)]
+	fake.revertScoreArgsForCall = append(fake.revertScoreArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 api.Audit
+	}{arg1, arg2, arg3})
+	stub := fake.RevertScoreStub
+	fakeReturns := fake.revertScoreReturns
+	fake.recordInvocation("RevertScore", []interface{}{arg1, arg2, arg3})
+	fake.revertScoreMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLeaderBoard) RevertScoreCallCount() int {
+	fake.revertScoreMutex.RLock()
+	defer fake.revertScoreMutex.RUnlock()
+	return len(fake.revertScoreArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) RevertScoreCalls(stub func(context.Context, string, api.Audit) error) {
+	fake.revertScoreMutex.Lock()
+	defer fake.revertScoreMutex.Unlock()
+	fake.RevertScoreStub = stub
+}
+
+func (fake *FakeLeaderBoard) RevertScoreArgsForCall(i int) (context.Context, string, api.Audit) {
+	fake.revertScoreMutex.RLock()
+	defer fake.revertScoreMutex.RUnlock()
+	argsForCall := fake.revertScoreArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeLeaderBoard) RevertScoreReturns(result1 error) {
+	fake.revertScoreMutex.Lock()
+	defer fake.revertScoreMutex.Unlock()
+	fake.RevertScoreStub = nil
+	fake.revertScoreReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) RevertScoreReturnsOnCall(i int, result1 error) {
+	fake.revertScoreMutex.Lock()
+	defer fake.revertScoreMutex.Unlock()
+	fake.RevertScoreStub = nil
+	if fake.revertScoreReturnsOnCall == nil {
+		fake.revertScoreReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.revertScoreReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) SetUser(arg1 context.Context, arg2 string, arg3 int, arg4 ...api.Option) error {
 	fake.setUserMutex.Lock()
 	ret, specificReturn := fake.setUserReturnsOnCall[len(fake.setUserArgsForCall)]
 	fake.setUserArgsForCall = append(fake.setUserArgsForCall, struct {
 		arg1 context.Context
 		arg2 string
 		arg3 int
-	}{arg1, arg2, arg3})
+		arg4 []api.Option
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.SetUserStub
 	fakeReturns := fake.setUserReturns
-	fake.recordInvocation("SetUser", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("SetUser", []interface{}{arg1, arg2, arg3, arg4})
 	fake.setUserMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4...)
 	}
 	if specificReturn {
 		return ret.result1
@@ -226,17 +1722,17 @@ func (fake *FakeLeaderBoard) SetUserCallCount() int {
 	return len(fake.setUserArgsForCall)
 }
 
-func (fake *FakeLeaderBoard) SetUserCalls(stub func(context.Context, string, int) error) {
+func (fake *FakeLeaderBoard) SetUserCalls(stub func(context.Context, string, int, ...api.Option) error) {
 	fake.setUserMutex.Lock()
 	defer fake.setUserMutex.Unlock()
 	fake.SetUserStub = stub
 }
 
-func (fake *FakeLeaderBoard) SetUserArgsForCall(i int) (context.Context, string, int) {
+func (fake *FakeLeaderBoard) SetUserArgsForCall(i int) (context.Context, string, int, []api.Option) {
 	fake.setUserMutex.RLock()
 	defer fake.setUserMutex.RUnlock()
 	argsForCall := fake.setUserArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeLeaderBoard) SetUserReturns(result1 error) {
@@ -262,18 +1758,82 @@ func (fake *FakeLeaderBoard) SetUserReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeLeaderBoard) UserCount(arg1 context.Context) (int, error) {
+func (fake *FakeLeaderBoard) SetUserState(arg1 context.Context, arg2 string, arg3 api.UserState) error {
+	fake.setUserStateMutex.Lock()
+	ret, specificReturn := fake.setUserStateReturnsOnCall[len(fake.setUserStateArgsForCall)]
+	fake.setUserStateArgsForCall = append(fake.setUserStateArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 api.UserState
+	}{arg1, arg2, arg3})
+	stub := fake.SetUserStateStub
+	fakeReturns := fake.setUserStateReturns
+	fake.recordInvocation("SetUserState", []interface{}{arg1, arg2, arg3})
+	fake.setUserStateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLeaderBoard) SetUserStateCallCount() int {
+	fake.setUserStateMutex.RLock()
+	defer fake.setUserStateMutex.RUnlock()
+	return len(fake.setUserStateArgsForCall)
+}
+
+func (fake *FakeLeaderBoard) SetUserStateCalls(stub func(context.Context, string, api.UserState) error) {
+	fake.setUserStateMutex.Lock()
+	defer fake.setUserStateMutex.Unlock()
+	fake.SetUserStateStub = stub
+}
+
+func (fake *FakeLeaderBoard) SetUserStateArgsForCall(i int) (context.Context, string, api.UserState) {
+	fake.setUserStateMutex.RLock()
+	defer fake.setUserStateMutex.RUnlock()
+	argsForCall := fake.setUserStateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeLeaderBoard) SetUserStateReturns(result1 error) {
+	fake.setUserStateMutex.Lock()
+	defer fake.setUserStateMutex.Unlock()
+	fake.SetUserStateStub = nil
+	fake.setUserStateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) SetUserStateReturnsOnCall(i int, result1 error) {
+	fake.setUserStateMutex.Lock()
+	defer fake.setUserStateMutex.Unlock()
+	fake.SetUserStateStub = nil
+	if fake.setUserStateReturnsOnCall == nil {
+		fake.setUserStateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setUserStateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLeaderBoard) UserCount(arg1 context.Context, arg2 ...api.Option) (int, error) {
 	fake.userCountMutex.Lock()
 	ret, specificReturn := fake.userCountReturnsOnCall[len(fake.userCountArgsForCall)]
 	fake.userCountArgsForCall = append(fake.userCountArgsForCall, struct {
 		arg1 context.Context
-	}{arg1})
+		arg2 []api.Option
+	}{arg1, arg2})
 	stub := fake.UserCountStub
 	fakeReturns := fake.userCountReturns
-	fake.recordInvocation("UserCount", []interface{}{arg1})
+	fake.recordInvocation("UserCount", []interface{}{arg1, arg2})
 	fake.userCountMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -287,17 +1847,17 @@ func (fake *FakeLeaderBoard) UserCountCallCount() int {
 	return len(fake.userCountArgsForCall)
 }
 
-func (fake *FakeLeaderBoard) UserCountCalls(stub func(context.Context) (int, error)) {
+func (fake *FakeLeaderBoard) UserCountCalls(stub func(context.Context, ...api.Option) (int, error)) {
 	fake.userCountMutex.Lock()
 	defer fake.userCountMutex.Unlock()
 	fake.UserCountStub = stub
 }
 
-func (fake *FakeLeaderBoard) UserCountArgsForCall(i int) context.Context {
+func (fake *FakeLeaderBoard) UserCountArgsForCall(i int) (context.Context, []api.Option) {
 	fake.userCountMutex.RLock()
 	defer fake.userCountMutex.RUnlock()
 	argsForCall := fake.userCountArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeLeaderBoard) UserCountReturns(result1 int, result2 error) {
@@ -329,12 +1889,52 @@ func (fake *FakeLeaderBoard) UserCountReturnsOnCall(i int, result1 int, result2
 func (fake *FakeLeaderBoard) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.addReviewMutex.RLock()
+	defer fake.addReviewMutex.RUnlock()
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	fake.createSnapshotMutex.RLock()
+	defer fake.createSnapshotMutex.RUnlock()
+	fake.deleteSnapshotMutex.RLock()
+	defer fake.deleteSnapshotMutex.RUnlock()
+	fake.deleteWebhookMutex.RLock()
+	defer fake.deleteWebhookMutex.RUnlock()
+	fake.eraseUserMutex.RLock()
+	defer fake.eraseUserMutex.RUnlock()
+	fake.expireInactiveMutex.RLock()
+	defer fake.expireInactiveMutex.RUnlock()
+	fake.exportUserMutex.RLock()
+	defer fake.exportUserMutex.RUnlock()
+	fake.getDeadLettersMutex.RLock()
+	defer fake.getDeadLettersMutex.RUnlock()
+	fake.getHistoryMutex.RLock()
+	defer fake.getHistoryMutex.RUnlock()
 	fake.getRanksMutex.RLock()
 	defer fake.getRanksMutex.RUnlock()
+	fake.getRejectionsMutex.RLock()
+	defer fake.getRejectionsMutex.RUnlock()
+	fake.getReviewsMutex.RLock()
+	defer fake.getReviewsMutex.RUnlock()
+	fake.getSnapshotsMutex.RLock()
+	defer fake.getSnapshotsMutex.RUnlock()
 	fake.getUserMutex.RLock()
 	defer fake.getUserMutex.RUnlock()
+	fake.getVersionMutex.RLock()
+	defer fake.getVersionMutex.RUnlock()
+	fake.getWebhookDeliveriesMutex.RLock()
+	defer fake.getWebhookDeliveriesMutex.RUnlock()
+	fake.getWebhooksMutex.RLock()
+	defer fake.getWebhooksMutex.RUnlock()
+	fake.overrideScoreMutex.RLock()
+	defer fake.overrideScoreMutex.RUnlock()
+	fake.resolveReviewMutex.RLock()
+	defer fake.resolveReviewMutex.RUnlock()
+	fake.revertScoreMutex.RLock()
+	defer fake.revertScoreMutex.RUnlock()
 	fake.setUserMutex.RLock()
 	defer fake.setUserMutex.RUnlock()
+	fake.setUserStateMutex.RLock()
+	defer fake.setUserStateMutex.RUnlock()
 	fake.userCountMutex.RLock()
 	defer fake.userCountMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -2,14 +2,165 @@ package api
 
 import (
 	"context"
+	"net/http"
 	"time"
 )
 
+// IdempotencyKeyHeader는 SetUser 요청의 idempotency key를 담는 HTTP header
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 type LeaderBoard interface {
-	UserCount(ctx context.Context) (int, error)
-	GetUser(ctx context.Context, userId string) (User, error)
-	SetUser(ctx context.Context, userId string, score int) error
-	GetRanks(ctx context.Context, rank, count int) ([]User, error)
+	UserCount(ctx context.Context, opts ...Option) (int, error)
+	GetUser(ctx context.Context, userId string, opts ...Option) (User, error)
+	SetUser(ctx context.Context, userId string, score int, opts ...Option) error
+	GetRanks(ctx context.Context, rank, count int, opts ...Option) ([]User, error)
+
+	// snapshotId가 비어있으면 생성 시각으로 id를 정한다
+	CreateSnapshot(ctx context.Context, snapshotId string) (Snapshot, error)
+	GetSnapshots(ctx context.Context) ([]Snapshot, error)
+	DeleteSnapshot(ctx context.Context, snapshotId string) error
+
+	SetUserState(ctx context.Context, userId string, state UserState) error
+
+	// OverrideScore는 관리자가 점수를 고친다. 변경 내역은 history에 남는다.
+	OverrideScore(ctx context.Context, userId string, score int, audit Audit) error
+	// RevertScore는 아직 되돌리지 않은 가장 최근의 OverrideScore를 되돌린다
+	RevertScore(ctx context.Context, userId string, audit Audit) error
+	// GetHistory는 사용자의 점수 변경 내역을 오래된 순서로 반환한다
+	GetHistory(ctx context.Context, userId string) ([]HistoryEntry, error)
+
+	// GetRejections는 검증 규칙에 맞지 않아 거부된 SetUser 요청들을 오래된 순서로 반환한다
+	GetRejections(ctx context.Context) ([]Rejection, error)
+
+	// AddReview는 검토할 점수 제출을 검토 대기열에 추가한다. Id, CreatedAt은 새로 정한다.
+	AddReview(ctx context.Context, review Review) (Review, error)
+	// GetReviews는 검토 대기 중인 제출들을 오래된 순서로 반환한다
+	GetReviews(ctx context.Context) ([]Review, error)
+	// ResolveReview는 검토를 끝내고 대기열에서 뺀다.
+	// 보류된 제출은 승인하면 반영하고, 이미 반영된 제출은 거절하면 이전 점수로 되돌린다.
+	ResolveReview(ctx context.Context, reviewId string, approve bool, audit Audit) error
+
+	// ExportUser는 사용자에 대해 보관하고 있는 모든 데이터를 모아서 반환한다
+	ExportUser(ctx context.Context, userId string) (UserExport, error)
+	// EraseUser는 사용자의 데이터를 현재 순위, 세그먼트, 체크포인트, 스냅샷, 기록들과 idempotency 기록, 제출 횟수에서 모두 지운다
+	EraseUser(ctx context.Context, userId string, audit Audit) (ErasureReport, error)
+
+	// ExpireInactive는 오랫동안 점수가 바뀌지 않은 사용자들을 삭제하고 삭제한 사용자 수를 반환한다
+	ExpireInactive(ctx context.Context) (int, error)
+
+	// AddWebhook은 변경 이벤트를 받을 webhook을 등록한다. Id, CreatedAt은 새로 정하고, Secret이 비어있으면 새로 만든다.
+	AddWebhook(ctx context.Context, webhook Webhook) (Webhook, error)
+	// GetWebhooks는 등록된 webhook들을 반환한다. Secret은 비운다.
+	GetWebhooks(ctx context.Context) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookId string) error
+	// GetWebhookDeliveries는 webhook으로 이벤트를 보낸 최근 결과들을 오래된 순서로 반환한다
+	GetWebhookDeliveries(ctx context.Context, webhookId string) ([]WebhookDelivery, error)
+	// GetDeadLetters는 webhook으로 끝내 보내지 못한 이벤트들을 오래된 순서로 반환한다
+	GetDeadLetters(ctx context.Context, webhookId string) ([]WebhookDelivery, error)
+
+	// GetVersion은 순위표가 바뀔 때마다 늘어나는 버전을 반환한다. 순위를 조회하지 않고 캐시가 유효한지 확인할 때 사용한다.
+	GetVersion(ctx context.Context) (BoardVersion, error)
+}
+
+// UserState는 사용자의 공개 순위 노출 상태
+type UserState string
+
+const (
+	UserStateActive UserState = "active"
+	// 공개 순위, 순위 계산, 사용자 수에서 제외한다
+	UserStateHidden UserState = "hidden"
+	// hidden과 같고, 점수 변경도 거부한다
+	UserStateBanned UserState = "banned"
+	// hidden과 같지만 본인에게는 제외되지 않은 것처럼 보인다
+	UserStateShadowBanned UserState = "shadow_banned"
+)
+
+func (s UserState) IsValid() bool {
+	switch s {
+	case UserStateActive, UserStateHidden, UserStateBanned, UserStateShadowBanned:
+		return true
+	}
+	return false
+}
+
+// Option은 조회 조건을 지정한다
+type Option func(*Options)
+
+type Options struct {
+	// 비어있지 않으면 현재 순위 대신 해당 스냅샷에서 조회한다
+	SnapshotId string
+	// 비어있지 않으면 해당 세그먼트 안에서 조회한다
+	Segment Segment
+	// SetUser에서 변경할 사용자 속성. 값이 ""인 속성은 제거한다.
+	Attributes map[string]string
+	// 조회하는 사용자 id. shadow ban된 사용자 본인에게는 자신이 순위에 있는 것처럼 보여준다.
+	Viewer string
+	// true이면 공개 순위에서 제외된 사용자도 조회한다 (관리자용)
+	IncludeHidden bool
+	// 비어있지 않으면 SetUser를 같은 키로 다시 요청해도 한 번만 반영하고 처음 결과를 반환한다
+	IdempotencyKey string
+	// nil이 아니면 SetUser가 성공한 뒤의 사용자를 담는다. 같은 idempotency key로 다시 요청하면 처음 요청의 결과를 담는다.
+	Result *User
+}
+
+// Segment는 사용자 속성 값이 같은 사용자들의 순위 (예: country=KR)
+type Segment struct {
+	Attribute string `json:"attribute"`
+	// 비어있으면 GetUser에서는 조회하는 사용자의 속성 값을 사용한다
+	Value string `json:"value"`
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+func WithSnapshot(snapshotId string) Option {
+	return func(options *Options) {
+		options.SnapshotId = snapshotId
+	}
+}
+
+func WithSegment(attribute, value string) Option {
+	return func(options *Options) {
+		options.Segment = Segment{Attribute: attribute, Value: value}
+	}
+}
+
+func WithViewer(userId string) Option {
+	return func(options *Options) {
+		options.Viewer = userId
+	}
+}
+
+func WithHidden() Option {
+	return func(options *Options) {
+		options.IncludeHidden = true
+	}
+}
+
+func WithIdempotencyKey(key string) Option {
+	return func(options *Options) {
+		options.IdempotencyKey = key
+	}
+}
+
+func WithResult(user *User) Option {
+	return func(options *Options) {
+		options.Result = user
+	}
+}
+
+func WithAttribute(attribute, value string) Option {
+	return func(options *Options) {
+		if options.Attributes == nil {
+			options.Attributes = map[string]string{}
+		}
+		options.Attributes[attribute] = value
+	}
 }
 
 type User struct {
@@ -18,6 +169,234 @@ type User struct {
 	// 1부터 시작하는 순위
 	Rank      int       `json:"rank"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// 직전 체크포인트 시점의 순위 (체크포인트에 없던 사용자는 0)
+	PreviousRank int `json:"previous_rank,omitempty"`
+	// PreviousRank - Rank, 순위가 오르면 양수
+	RankDelta int `json:"rank_delta,omitempty"`
+	// 세그먼트로 조회한 경우 세그먼트 안에서의 순위
+	SegmentRank int               `json:"segment_rank,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	// 비어있으면 active
+	State UserState `json:"state,omitempty"`
+}
+
+// Snapshot은 특정 시점에 고정된 순위표
+// 스냅샷에서 조회한 User에는 UpdatedAt, PreviousRank, RankDelta가 없다
+type Snapshot struct {
+	Id        string    `json:"id"`
+	UserCount int       `json:"user_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BoardVersion은 순위표의 버전. 한 번도 바뀌지 않았으면 Version은 0이고 UpdatedAt은 zero value이다.
+// Epoch는 저장소마다 다르므로, 저장소가 비워져서 Version이 다시 시작해도 Epoch와 Version은 겹치지 않는다.
+type BoardVersion struct {
+	Epoch     string    `json:"epoch"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Audit은 관리자 작업을 누가, 왜 했는지 기록한다
+type Audit struct {
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
+}
+
+type HistoryKind string
+
+const (
+	HistoryKindSet      HistoryKind = "set"
+	HistoryKindOverride HistoryKind = "override"
+	HistoryKindRevert   HistoryKind = "revert"
+)
+
+type HistoryEntry struct {
+	Kind          HistoryKind `json:"kind"`
+	Score         int         `json:"score"`
+	PreviousScore int         `json:"previous_score"`
+	// 관리자 작업(override, revert)인 경우에만 있다
+	Actor     string    `json:"actor,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RejectionReason string
+
+const (
+	RejectionScoreTooLow        RejectionReason = "score_too_low"
+	RejectionScoreTooHigh       RejectionReason = "score_too_high"
+	RejectionIncreaseTooLarge   RejectionReason = "increase_too_large"
+	RejectionScoreDecreased     RejectionReason = "score_decreased"
+	RejectionTooManySubmissions RejectionReason = "too_many_submissions"
+)
+
+// RejectionError는 점수 검증 규칙에 맞지 않는 요청의 오류.
+// NewRejectionError로 만든 오류에서 errors.As로 꺼낼 수 있다.
+type RejectionError struct {
+	Reason  RejectionReason
+	Message string
+}
+
+func (e RejectionError) Error() string {
+	return e.Message
+}
+
+func NewRejectionError(reason RejectionReason, message string) error {
+	return ErrorWithStatusCode(RejectionError{Reason: reason, Message: message}, http.StatusUnprocessableEntity)
+}
+
+// Rejection은 거부된 SetUser 요청의 기록
+type Rejection struct {
+	UserId        string          `json:"user_id"`
+	Score         int             `json:"score"`
+	PreviousScore int             `json:"previous_score"`
+	Reason        RejectionReason `json:"reason"`
+	Message       string          `json:"message"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// ReviewAction은 의심스러운 점수 제출을 처리한 방법
+type ReviewAction string
+
+const (
+	// 점수를 반영하고 검토 대기열에 넣는다
+	ReviewActionFlag ReviewAction = "flag"
+	// 점수를 반영하지 않고 검토 대기열에 넣는다
+	ReviewActionQuarantine ReviewAction = "quarantine"
+)
+
+// Review는 검토 대기열의 의심스러운 점수 제출
+type Review struct {
+	Id            string       `json:"id"`
+	UserId        string       `json:"user_id"`
+	Score         int          `json:"score"`
+	PreviousScore int          `json:"previous_score"`
+	Action        ReviewAction `json:"action"`
+	// 의심스러운 이유들 (예: "rank jumped from 50000 to 1 in 1m0s")
+	Reasons   []string  `json:"reasons"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership은 사용자가 들어있는 순위표
+type Membership struct {
+	// "ranks", "checkpoint", "updated" (마지막으로 점수가 바뀐 시각), "segment:<attribute>=<value>", "snapshot:<id>"
+	Board string `json:"board"`
+	Score int    `json:"score"`
+	Rank  int    `json:"rank"`
+}
+
+// UserExport는 한 사용자에 대해 보관하고 있는 데이터
+type UserExport struct {
+	UserId     string    `json:"user_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	// 현재 점수와 속성. 점수를 제출한 적이 없거나 지워졌으면 nil.
+	User        *User          `json:"user"`
+	Memberships []Membership   `json:"memberships"`
+	History     []HistoryEntry `json:"history"`
+	Rejections  []Rejection    `json:"rejections"`
+	Reviews     []Review       `json:"reviews"`
+	// 사용자가 들어있는 변경 이벤트와 webhook 전송 기록
+	Events            []Event           `json:"events"`
+	WebhookDeliveries []WebhookDelivery `json:"webhook_deliveries"`
+}
+
+// ErasureReport는 EraseUser가 지운 항목들과, 지운 다음 다시 확인한 결과
+type ErasureReport struct {
+	UserId   string    `json:"user_id"`
+	Actor    string    `json:"actor"`
+	Reason   string    `json:"reason,omitempty"`
+	ErasedAt time.Time `json:"erased_at"`
+	// 지운 항목들 (예: "user", "snapshot:20210501T000000Z", "history: 3 entries")
+	Erased []string `json:"erased"`
+	// 지운 다음 저장소에서 다시 찾은 항목들 (ExportUser가 모으는 데이터와 idempotency 기록, 제출 횟수). 비어있어야 한다.
+	Remaining []string `json:"remaining"`
+	Verified  bool     `json:"verified"`
+}
+
+// EventType은 변경 이벤트의 종류
+type EventType string
+
+const (
+	EventUserAdded    EventType = "user_added"
+	EventScoreChanged EventType = "score_changed"
+	EventUserDeleted  EventType = "user_deleted"
+	// 사용자가 상위 N위 안으로 들어왔다
+	EventEnteredTop EventType = "entered_top"
+	// 상위 N위 안의 사용자가 다른 사용자에게 추월당했다
+	EventOvertaken EventType = "overtaken"
+)
+
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventUserAdded, EventScoreChanged, EventUserDeleted, EventEnteredTop, EventOvertaken:
+		return true
+	}
+	return false
+}
+
+// Event는 순위표의 변경 이벤트. Seq는 1부터 1씩 늘어나므로 마지막으로 받은 Seq부터 이어서 받을 수 있다.
+type Event struct {
+	Seq  int64     `json:"seq"`
+	Type EventType `json:"type"`
+	// EraseUser로 지웠거나 공개 순위에서 숨긴 사용자의 user_deleted 이벤트에는 없다
+	UserId        string `json:"user_id"`
+	Score         int    `json:"score"`
+	PreviousScore int    `json:"previous_score,omitempty"`
+	// 공개 순위에 없으면 0. 동점인 사용자들이 있으면 그 사용자들보다 앞 순위로 센다.
+	Rank         int `json:"rank,omitempty"`
+	PreviousRank int `json:"previous_rank,omitempty"`
+	// overtaken 이벤트에서 추월한 사용자
+	OvertakenBy string    `json:"overtaken_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Webhook은 변경 이벤트를 받을 HTTP callback
+type Webhook struct {
+	Id  string `json:"id"`
+	URL string `json:"url"`
+	// 받을 이벤트 종류들. 비어있으면 모든 이벤트를 받는다.
+	Events []EventType `json:"events"`
+	// payload 서명에 쓰는 secret key. AddWebhook의 반환값에만 있다.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	// 재시도를 모두 실패해서 dead letter 목록으로 옮겼다
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery는 webhook으로 이벤트 하나를 보낸 결과
+type WebhookDelivery struct {
+	WebhookId string         `json:"webhook_id"`
+	Event     Event          `json:"event"`
+	Status    DeliveryStatus `json:"status"`
+	Attempts  int            `json:"attempts"`
+	// 마지막 시도의 응답 status code. 응답을 받지 못했으면 0.
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+type RankUpdateType string
+
+const (
+	// 구독한 순위 구간 전체
+	RankUpdateSnapshot RankUpdateType = "snapshot"
+	// 직전 메시지 이후 바뀐 부분
+	RankUpdateDiff RankUpdateType = "diff"
+)
+
+// RankUpdate는 순위 구간 구독(websocket)으로 받는 메시지
+type RankUpdate struct {
+	Type RankUpdateType `json:"type"`
+	// snapshot이면 구간의 모든 사용자, diff면 새로 들어왔거나 순위나 점수가 바뀐 사용자들
+	Users []User `json:"users"`
+	// diff에서 구간을 벗어난 사용자 id들
+	Removed []string `json:"removed,omitempty"`
 }
 
 func ErrorWithStatusCode(err error, statusCode int) error {
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 정규화한 사용자 id의 최대 글자 수
+const MaxUserIdLength = 64
+
+// NormalizeUserId는 사용자 id를 NFC로 정규화하고 정책에 맞는지 검사한다.
+// 사용자 id에는 유니코드 문자, 숫자, 결합 문자와 '-', '_', '.', '@'만 쓸 수 있다.
+func NormalizeUserId(userId string) (string, error) {
+	if !utf8.ValidString(userId) {
+		return "", invalidUserId("user id is not valid utf-8")
+	}
+
+	userId = norm.NFC.String(userId)
+
+	if userId == "" {
+		return "", invalidUserId("user id is empty")
+	}
+
+	if utf8.RuneCountInString(userId) > MaxUserIdLength {
+		return "", invalidUserId("user id is too long")
+	}
+
+	for _, r := range userId {
+		if !isUserIdRune(r) {
+			return "", invalidUserId("user id has invalid character: " + string(r))
+		}
+	}
+
+	return userId, nil
+}
+
+func isUserIdRune(r rune) bool {
+	switch r {
+	case '-', '_', '.', '@':
+		return true
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func invalidUserId(msg string) error {
+	return ErrorWithStatusCode(errors.New(msg), http.StatusBadRequest)
+}
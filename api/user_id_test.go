@@ -0,0 +1,46 @@
+package api_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestNormalizeUserId(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, testData := range []struct {
+		userId   string
+		expected string
+	}{
+		{"user1", "user1"},
+		{"a.b@c-d_e", "a.b@c-d_e"},
+		{"사용자", "사용자"},
+		// NFD로 입력해도 NFC로 저장한다
+		{"cafe\u0301", "caf\u00e9"},
+		{strings.Repeat("가", api.MaxUserIdLength), strings.Repeat("가", api.MaxUserIdLength)},
+	} {
+		userId, err := api.NormalizeUserId(testData.userId)
+		g.Expect(err).NotTo(HaveOccurred(), testData.userId)
+		g.Expect(userId).To(Equal(testData.expected))
+	}
+
+	for _, userId := range []string{
+		"",
+		"a/b",
+		"a?b",
+		"a#b",
+		"a b",
+		"a%2Fb",
+		"a:b",
+		"a\x00b",
+		"\xff",
+		strings.Repeat("a", api.MaxUserIdLength+1),
+	} {
+		_, err := api.NormalizeUserId(userId)
+		g.Expect(err).To(HaveOccurred(), userId)
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(400))
+	}
+}
@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
 	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/signature"
 	"github.com/spf13/cobra"
 )
 
@@ -16,15 +21,148 @@ func newClient(cmd *cobra.Command) (*http_client.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return http_client.New(endpoint), nil
+
+	apiKey, err := cmd.Flags().GetString("api-key")
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := cmd.Flags().GetString("token")
+	if err != nil {
+		return nil, err
+	}
+
+	client := http_client.New(endpoint)
+	client.APIKey = apiKey
+	client.BearerToken = token
+	return client, nil
+}
+
+// newSigner는 --game-id가 있으면 점수 제출에 서명할 Signer를 만든다
+func newSigner(cmd *cobra.Command) (*signature.Signer, error) {
+	gameId, err := cmd.Flags().GetString("game-id")
+	if err != nil || gameId == "" {
+		return nil, err
+	}
+
+	secret, err := cmd.Flags().GetString("secret")
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		return nil, errors.New("secret is empty")
+	}
+
+	board, err := cmd.Flags().GetString("board")
+	if err != nil {
+		return nil, err
+	}
+
+	return &signature.Signer{
+		Board:  board,
+		GameId: gameId,
+		Secret: []byte(secret),
+	}, nil
+}
+
+func queryOptions(cmd *cobra.Command) ([]api.Option, error) {
+	snapshotId, err := cmd.Flags().GetString("snapshot")
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := cmd.Flags().GetString("segment")
+	if err != nil {
+		return nil, err
+	}
+
+	viewer, err := cmd.Flags().GetString("viewer")
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []api.Option
+	if snapshotId != "" {
+		opts = append(opts, api.WithSnapshot(snapshotId))
+	}
+	if viewer != "" {
+		opts = append(opts, api.WithViewer(viewer))
+	}
+	if segment != "" {
+		attribute, value, ok := splitAttribute(segment)
+		if !ok {
+			return nil, errors.New("segment is invalid format")
+		}
+		opts = append(opts, api.WithSegment(attribute, value))
+	}
+	return opts, nil
+}
+
+// splitAttribute는 "name:value" 형식의 문자열을 나눈다
+func splitAttribute(s string) (string, string, bool) {
+	i := strings.Index(s, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringP("endpoint", "e", "http://localhost:8080", "endpoint (required)")
+	rootCmd.PersistentFlags().String("api-key", os.Getenv("LEADERBOARD_API_KEY"), "api key sent with every request (default $LEADERBOARD_API_KEY)")
+	rootCmd.PersistentFlags().String("token", os.Getenv("LEADERBOARD_TOKEN"), "JWT bearer token sent with every request (default $LEADERBOARD_TOKEN)")
 	rootCmd.AddCommand(userCountCmd)
 	rootCmd.AddCommand(setUserCmd)
 	rootCmd.AddCommand(getUserCmd)
 	rootCmd.AddCommand(getRanksCmd)
+	rootCmd.AddCommand(watchRanksCmd)
+	rootCmd.AddCommand(createSnapshotCmd)
+	rootCmd.AddCommand(getSnapshotsCmd)
+	rootCmd.AddCommand(deleteSnapshotCmd)
+	rootCmd.AddCommand(setStateCmd)
+	rootCmd.AddCommand(overrideCmd)
+	rootCmd.AddCommand(revertCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(rejectionsCmd)
+	rootCmd.AddCommand(reviewsCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(eraseCmd)
+	rootCmd.AddCommand(expireCmd)
+	rootCmd.AddCommand(webhooksCmd)
+	rootCmd.AddCommand(addWebhookCmd)
+	rootCmd.AddCommand(deleteWebhookCmd)
+	rootCmd.AddCommand(deliveriesCmd)
+	rootCmd.AddCommand(approveCmd)
+	rootCmd.AddCommand(rejectCmd)
+	rootCmd.AddCommand(keysCmd)
+	rootCmd.AddCommand(issueKeyCmd)
+	rootCmd.AddCommand(revokeKeyCmd)
+
+	for _, cmd := range []*cobra.Command{userCountCmd, getUserCmd, getRanksCmd} {
+		cmd.Flags().String("snapshot", "", "read from the snapshot instead of the current ranks")
+		cmd.Flags().String("segment", "", "read ranks within the segment (attribute:value)")
+		cmd.Flags().String("viewer", "", "user id of the viewer (admin only)")
+	}
+
+	getUserCmd.Flags().Bool("include-hidden", false, "also read users hidden from public ranks (admin)")
+
+	setUserCmd.Flags().StringArray("attribute", nil, "user attribute to set (attribute:value), repeatable")
+	setUserCmd.Flags().String("game-id", "", "sign the submission with the secret key of the game")
+	setUserCmd.Flags().String("secret", os.Getenv("LEADERBOARD_SECRET"), "secret key of the game (default $LEADERBOARD_SECRET)")
+	setUserCmd.Flags().String("board", "", "board name included in the signature")
+
+	addWebhookCmd.Flags().StringArray("event", nil, "event type to receive (default all), repeatable")
+	addWebhookCmd.Flags().String("secret", "", "secret key to sign payloads (generated if empty)")
+	deliveriesCmd.Flags().Bool("dead-letters", false, "print only the events that could not be delivered")
+
+	issueKeyCmd.Flags().StringArray("scope", nil, "scope of the key (read, write or admin), repeatable")
+	issueKeyCmd.Flags().StringArray("board", nil, "board the key can be used for (default all), repeatable")
+
+	for _, cmd := range []*cobra.Command{overrideCmd, revertCmd, approveCmd, rejectCmd, eraseCmd} {
+		cmd.Flags().String("actor", os.Getenv("USER"), "who makes the change")
+		cmd.Flags().String("reason", "", "why the change is made")
+	}
 }
 
 var rootCmd = &cobra.Command{
@@ -47,7 +185,12 @@ var userCountCmd = &cobra.Command{
 			return err
 		}
 
-		count, err := client.UserCount(ctx)
+		opts, err := queryOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		count, err := client.UserCount(ctx, opts...)
 		if err != nil {
 			return err
 		}
@@ -77,7 +220,25 @@ var setUserCmd = &cobra.Command{
 			return err
 		}
 
-		if err := client.SetUser(ctx, userId, score); err != nil {
+		if client.Signer, err = newSigner(cmd); err != nil {
+			return err
+		}
+
+		attributes, err := cmd.Flags().GetStringArray("attribute")
+		if err != nil {
+			return err
+		}
+
+		var opts []api.Option
+		for _, attribute := range attributes {
+			name, value, ok := splitAttribute(attribute)
+			if !ok {
+				return errors.New("attribute is invalid format")
+			}
+			opts = append(opts, api.WithAttribute(name, value))
+		}
+
+		if err := client.SetUser(ctx, userId, score, opts...); err != nil {
 			return err
 		}
 
@@ -101,7 +262,20 @@ var getUserCmd = &cobra.Command{
 			return err
 		}
 
-		user, err := client.GetUser(ctx, userId)
+		opts, err := queryOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		includeHidden, err := cmd.Flags().GetBool("include-hidden")
+		if err != nil {
+			return err
+		}
+		if includeHidden {
+			opts = append(opts, api.WithHidden())
+		}
+
+		user, err := client.GetUser(ctx, userId, opts...)
 		if err != nil {
 			return err
 		}
@@ -135,7 +309,12 @@ var getRanksCmd = &cobra.Command{
 			return err
 		}
 
-		users, err := client.GetRanks(ctx, rank, count)
+		opts, err := queryOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		users, err := client.GetRanks(ctx, rank, count, opts...)
 		if err != nil {
 			return err
 		}
@@ -147,9 +326,617 @@ var getRanksCmd = &cobra.Command{
 	},
 }
 
-func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+var watchRanksCmd = &cobra.Command{
+	Use:   "watchranks rank count",
+	Short: "print the rank window whenever it changes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("invalid number of arguments")
+		}
+
+		rank, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+
+		count, err := strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return client.SubscribeRanks(ctx, rank, count, func(users []api.User) error {
+			fmt.Println("---")
+			for _, user := range users {
+				fmt.Printf("%+v\n", user)
+			}
+			return nil
+		})
+	},
+}
+
+var createSnapshotCmd = &cobra.Command{
+	Use: "createsnapshot [flags] [snapshotId]",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		snapshotId := ""
+		if len(args) == 1 {
+			snapshotId = args[0]
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := client.CreateSnapshot(ctx, snapshotId)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%+v\n", snapshot)
+		return nil
+	},
+}
+
+var getSnapshotsCmd = &cobra.Command{
+	Use: "getsnapshots",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		snapshots, err := client.GetSnapshots(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, snapshot := range snapshots {
+			fmt.Printf("%+v\n", snapshot)
+		}
+		return nil
+	},
+}
+
+var deleteSnapshotCmd = &cobra.Command{
+	Use: "deletesnapshot [flags] snapshotId",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		snapshotId := args[0]
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return client.DeleteSnapshot(ctx, snapshotId)
+	},
+}
+
+var setStateCmd = &cobra.Command{
+	Use:   "setstate [flags] userId state",
+	Short: "set moderation state (active, hidden, banned, shadow_banned)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("invalid number of arguments")
+		}
+
+		userId := args[0]
+		state := api.UserState(args[1])
+		if !state.IsValid() {
+			return errors.New("invalid state")
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return client.SetUserState(ctx, userId, state)
+	},
+}
+
+func auditFlags(cmd *cobra.Command) (api.Audit, error) {
+	actor, err := cmd.Flags().GetString("actor")
+	if err != nil {
+		return api.Audit{}, err
+	}
+
+	reason, err := cmd.Flags().GetString("reason")
+	if err != nil {
+		return api.Audit{}, err
 	}
+
+	if actor == "" {
+		return api.Audit{}, errors.New("actor is empty")
+	}
+
+	return api.Audit{Actor: actor, Reason: reason}, nil
+}
+
+var overrideCmd = &cobra.Command{
+	Use:   "override [flags] userId score",
+	Short: "correct the score of the user (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("invalid number of arguments")
+		}
+
+		userId := args[0]
+		score, err := strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+
+		audit, err := auditFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return client.OverrideScore(ctx, userId, score, audit)
+	},
+}
+
+var revertCmd = &cobra.Command{
+	Use:   "revert [flags] userId",
+	Short: "revert the latest score correction of the user (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		userId := args[0]
+
+		audit, err := auditFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return client.RevertScore(ctx, userId, audit)
+	},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [flags] userId",
+	Short: "print the score history of the user (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		userId := args[0]
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		entries, err := client.GetHistory(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			fmt.Printf("%+v\n", entry)
+		}
+		return nil
+	},
+}
+
+var rejectionsCmd = &cobra.Command{
+	Use:   "rejections",
+	Short: "print score submissions rejected by the validation rules (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		rejections, err := client.GetRejections(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, rejection := range rejections {
+			fmt.Printf("%+v\n", rejection)
+		}
+		return nil
+	},
+}
+
+var reviewsCmd = &cobra.Command{
+	Use:   "reviews",
+	Short: "print suspicious score submissions waiting for review (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		reviews, err := client.GetReviews(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, review := range reviews {
+			fmt.Printf("%+v\n", review)
+		}
+		return nil
+	},
+}
+
+var approveCmd = &cobra.Command{
+	Use:   "approve [flags] reviewId",
+	Short: "approve the submission under review (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return resolveReview(cmd, args, true)
+	},
+}
+
+var rejectCmd = &cobra.Command{
+	Use:   "reject [flags] reviewId",
+	Short: "reject the submission under review (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return resolveReview(cmd, args, false)
+	},
+}
+
+func resolveReview(cmd *cobra.Command, args []string, approve bool) error {
+	if len(args) != 1 {
+		return errors.New("invalid number of arguments")
+	}
+
+	reviewId := args[0]
+
+	audit, err := auditFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	return client.ResolveReview(ctx, reviewId, approve, audit)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [flags] userId",
+	Short: "print all data held about the user as json (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		userId := args[0]
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		export, err := client.ExportUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		return printJson(export)
+	},
+}
+
+var eraseCmd = &cobra.Command{
+	Use:   "erase [flags] userId",
+	Short: "erase all data held about the user and print the report as json (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		userId := args[0]
+
+		audit, err := auditFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		report, err := client.EraseUser(ctx, userId, audit)
+		if err != nil {
+			return err
+		}
+
+		if err := printJson(report); err != nil {
+			return err
+		}
+
+		if !report.Verified {
+			return errors.New("erasure is not verified")
+		}
+		return nil
+	},
+}
+
+var expireCmd = &cobra.Command{
+	Use:   "expire",
+	Short: "delete users whose score has not changed within the inactivity ttl of the board (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		expired, err := client.ExpireInactive(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(expired)
+		return nil
+	},
+}
+
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "print registered webhooks (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		webhooks, err := client.GetWebhooks(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, webhook := range webhooks {
+			fmt.Printf("%+v\n", webhook)
+		}
+		return nil
+	},
+}
+
+var addWebhookCmd = &cobra.Command{
+	Use:   "add-webhook [flags] url",
+	Short: "register a webhook to receive leaderboard events and print it with the secret (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		webhook := api.Webhook{URL: args[0]}
+
+		events, err := cmd.Flags().GetStringArray("event")
+		if err != nil {
+			return err
+		}
+
+		for _, eventType := range events {
+			webhook.Events = append(webhook.Events, api.EventType(eventType))
+		}
+
+		if webhook.Secret, err = cmd.Flags().GetString("secret"); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		webhook, err = client.AddWebhook(ctx, webhook)
+		if err != nil {
+			return err
+		}
+
+		return printJson(webhook)
+	},
+}
+
+var deleteWebhookCmd = &cobra.Command{
+	Use:   "delete-webhook webhookId",
+	Short: "delete the webhook (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return client.DeleteWebhook(ctx, args[0])
+	},
+}
+
+var deliveriesCmd = &cobra.Command{
+	Use:   "deliveries [flags] webhookId",
+	Short: "print recent delivery results of the webhook (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		deadLetters, err := cmd.Flags().GetBool("dead-letters")
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		var deliveries []api.WebhookDelivery
+		if deadLetters {
+			deliveries, err = client.GetDeadLetters(ctx, args[0])
+		} else {
+			deliveries, err = client.GetWebhookDeliveries(ctx, args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			fmt.Printf("%+v\n", delivery)
+		}
+		return nil
+	},
+}
+
+func printJson(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "print issued api keys without their secrets (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		keys, err := client.GetAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			fmt.Printf("%+v\n", key)
+		}
+		return nil
+	},
+}
+
+var issueKeyCmd = &cobra.Command{
+	Use:   "issue-key [flags] name",
+	Short: "issue an api key and print it with the secret, which cannot be read again (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		key := apikey.Key{Name: args[0]}
+
+		scopes, err := cmd.Flags().GetStringArray("scope")
+		if err != nil {
+			return err
+		}
+
+		for _, scope := range scopes {
+			key.Scopes = append(key.Scopes, apikey.Scope(scope))
+		}
+
+		if key.Boards, err = cmd.Flags().GetStringArray("board"); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		key, err = client.IssueAPIKey(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		return printJson(key)
+	},
+}
+
+var revokeKeyCmd = &cobra.Command{
+	Use:   "revoke-key keyId",
+	Short: "revoke the api key (admin)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("invalid number of arguments")
+		}
+
+		ctx := context.Background()
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return client.RevokeAPIKey(ctx, args[0])
+	},
 }
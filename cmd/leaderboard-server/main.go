@@ -2,29 +2,184 @@ package main
 
 import (
 	"context"
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/anomaly"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
 	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
 	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/metrics_mw"
+	"github.com/bigflood/leaderboard/pkg/ratelimit"
+	"github.com/bigflood/leaderboard/pkg/signature"
 	"github.com/bigflood/leaderboard/pkg/storage"
 	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
 	const addr = ":8080"
 
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	metrics := metrics_mw.NewMetrics(registry)
+
+	store := createStorage()
 	lb := &leaderboard.LeaderBoard{
-		Storage: createStorage(),
+		Storage: &metrics_mw.StorageMiddleware{Metrics: metrics, Receiver: store},
+	}
+	registry.MustRegister(&metrics_mw.BoardCollector{LeaderBoard: lb})
+
+	lookupEnvInt("MAX_SNAPSHOTS", func(n int) { lb.MaxSnapshots = n })
+	lookupEnvDuration("SNAPSHOT_RETENTION", func(d time.Duration) { lb.SnapshotRetention = d })
+	lookupEnvInt("MAX_HISTORY", func(n int) { lb.MaxHistory = n })
+	lookupEnvDuration("IDEMPOTENCY_TTL", func(d time.Duration) { lb.IdempotencyTTL = d })
+
+	if attributes := os.Getenv("SEGMENT_ATTRIBUTES"); attributes != "" {
+		lb.SegmentAttributes = strings.Split(attributes, ",")
+	}
+
+	lookupEnvInt("MIN_SCORE", func(n int) { lb.Rules.MinScore = &n })
+	lookupEnvInt("MAX_SCORE", func(n int) { lb.Rules.MaxScore = &n })
+	lookupEnvInt("MAX_SCORE_INCREASE", func(n int) { lb.Rules.MaxIncrease = n })
+	lookupEnvInt("MAX_SUBMISSIONS", func(n int) { lb.Rules.MaxSubmissions = n })
+	lookupEnvDuration("SUBMISSION_WINDOW", func(d time.Duration) { lb.Rules.SubmissionWindow = d })
+	lb.Rules.Monotonic = os.Getenv("MONOTONIC_SCORE") == "true"
+	lookupEnvInt("MAX_REJECTIONS", func(n int) { lb.MaxRejections = n })
+	lookupEnvInt("MAX_REVIEWS", func(n int) { lb.MaxReviews = n })
+	if screener := createAnomalyScreener(); screener != nil {
+		lb.Screener = screener
 	}
+	lookupEnvDuration("INACTIVITY_TTL", func(d time.Duration) { lb.InactivityTTL = d })
+	lookupEnvInt("MAX_EVENTS", func(n int) { lb.MaxEvents = n })
+	lookupEnvInt("EVENT_TOP_N", func(n int) { lb.EventTopN = n })
+	lookupEnvInt("MAX_WEBHOOK_DELIVERIES", func(n int) { lb.MaxWebhookDeliveries = n })
 
-	server := http_server.New(lb, log.Default())
+	var serverOpts []http_server.Option
+
+	if secrets := os.Getenv("SIGNATURE_SECRETS"); secrets != "" {
+		verifier := &signature.Verifier{
+			Board:   os.Getenv("BOARD_NAME"),
+			Secrets: parseSecrets(secrets),
+			Nonces:  lb.Storage,
+		}
+		lookupEnvDuration("SIGNATURE_MAX_SKEW", func(d time.Duration) { verifier.MaxSkew = d })
+		serverOpts = append(serverOpts, http_server.WithVerifier(verifier))
+	}
+
+	// 관리자 key를 설정하면 API key 인증을 사용한다. 나머지 key들은 이 key로 발급한다.
+	if adminKey := os.Getenv("ADMIN_API_KEY"); adminKey != "" {
+		keys := &apikey.Store{
+			Storage: lb.Storage,
+			Board:   os.Getenv("BOARD_NAME"),
+			Static: map[string]apikey.Key{
+				apikey.Hash(adminKey): {Id: "admin", Name: "ADMIN_API_KEY", Scopes: []apikey.Scope{apikey.ScopeAdmin}},
+			},
+		}
+		serverOpts = append(serverOpts, http_server.WithAPIKeys(keys))
+	}
+
+	// JWT_HS256_SECRET이나 JWT_JWKS_FILE을 설정하면 플레이어가 bearer 토큰으로 자신의 점수를 조회, 제출할 수 있다
+	if tokens := createTokenVerifier(); tokens != nil {
+		serverOpts = append(serverOpts, http_server.WithTokens(tokens))
+	}
+
+	// RATE_LIMIT_*을 "100/1m"처럼 설정하면 요청 수를 제한한다. redis를 사용하면 서버들이 제한을 공유한다.
+	if limits := parseRateLimits(); limits != (http_server.RateLimits{}) {
+		serverOpts = append(serverOpts, http_server.WithRateLimit(createRateLimiter(store), limits))
+	}
+	if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		serverOpts = append(serverOpts, http_server.WithIPExtractor(echo.ExtractIPFromXFFHeader()))
+	}
+
+	requestTimeout, operationTimeouts := http_handler.DefaultRequestTimeout, map[string]time.Duration(nil)
+	lookupEnvDuration("REQUEST_TIMEOUT", func(d time.Duration) { requestTimeout = d })
+	if timeouts := os.Getenv("OPERATION_TIMEOUTS"); timeouts != "" {
+		operationTimeouts = parseOperationTimeouts(timeouts)
+	}
+	serverOpts = append(serverOpts, http_server.WithTimeouts(requestTimeout, operationTimeouts))
+
+	// Cache-Control 값에 ,가 들어가므로 route들은 ;로 구분한다 (예: "GET /ranks=public, max-age=10;GET /users/:id=no-cache")
+	if policies := os.Getenv("CACHE_CONTROL"); policies != "" {
+		serverOpts = append(serverOpts, http_server.WithCacheControl(parseCacheControl(policies)))
+	}
+
+	streamInterval, maxStreamConnections := http_server.DefaultRankStreamInterval, http_server.DefaultMaxStreamConnections
+	lookupEnvDuration("RANK_STREAM_INTERVAL", func(d time.Duration) { streamInterval = d })
+	lookupEnvInt("MAX_STREAM_CONNECTIONS", func(n int) { maxStreamConnections = n })
+	serverOpts = append(serverOpts, http_server.WithRankStream(streamInterval, maxStreamConnections))
+
+	// 순위 구간 구독에 연결할 수 있는 다른 사이트의 Origin 목록 (예: "https://game.example.com,https://admin.example.com")
+	if origins := os.Getenv("RANK_STREAM_ORIGINS"); origins != "" {
+		serverOpts = append(serverOpts, http_server.WithAllowedOrigins(strings.Split(origins, ",")))
+	}
+
+	if lb.MaxEvents > 0 {
+		heartbeatInterval := http_handler.DefaultHeartbeatInterval
+		lookupEnvDuration("SSE_HEARTBEAT_INTERVAL", func(d time.Duration) { heartbeatInterval = d })
+		maxEventStreams := http_handler.DefaultMaxEventStreams
+		lookupEnvInt("MAX_SSE_CONNECTIONS", func(n int) { maxEventStreams = n })
+		serverOpts = append(serverOpts, http_server.WithUserEvents(lb, heartbeatInterval, maxEventStreams))
+	}
+
+	serverOpts = append(serverOpts, http_server.WithHealthChecks(map[string]http_handler.HealthCheck{"storage": lb.Storage}))
+	lookupEnvDuration("SHUTDOWN_DELAY", func(d time.Duration) {
+		serverOpts = append(serverOpts, http_server.WithShutdownDelay(d))
+	})
+
+	serverOpts = append(serverOpts, http_server.WithMetrics(registry))
+
+	receiver := &metrics_mw.MetricsMiddleware{Metrics: metrics, Receiver: lb}
+	server := http_server.New(receiver, log.Default(), serverOpts...)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if interval := os.Getenv("CHECKPOINT_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		scheduler := &leaderboard.CheckpointScheduler{
+			LeaderBoard: lb,
+			Interval:    d,
+			Logger:      log.Default(),
+		}
+		go scheduler.Run(ctx)
+	}
+
+	if lb.InactivityTTL > 0 {
+		sweeper := &leaderboard.ExpirySweeper{
+			LeaderBoard: receiver,
+			Interval:    time.Hour,
+			Lease:       &leaderboard.Lease{Storage: lb.Storage, Name: "expiry"},
+			Logger:      log.Default(),
+		}
+		lookupEnvDuration("EXPIRY_INTERVAL", func(d time.Duration) { sweeper.Interval = d })
+		go sweeper.Run(ctx)
+	}
+
+	if lb.MaxEvents > 0 {
+		dispatcher := &leaderboard.WebhookDispatcher{
+			LeaderBoard: lb,
+			Lease:       &leaderboard.Lease{Storage: lb.Storage, Name: "webhooks"},
+			Logger:      log.Default(),
+		}
+		lookupEnvInt("WEBHOOK_MAX_ATTEMPTS", func(n int) { dispatcher.MaxAttempts = n })
+		lookupEnvDuration("WEBHOOK_BACKOFF", func(d time.Duration) { dispatcher.Backoff = d })
+		go dispatcher.Run(ctx)
+	}
+
 	go func() {
 		if waitSignal(ctx) {
 			server.Shutdown(context.Background())
@@ -51,6 +206,157 @@ func waitSignal(ctx context.Context) bool {
 	}
 }
 
+// createAnomalyScreener는 ANOMALY_* 환경변수로 설정한 규칙이 있으면 점수 제출을 검사할 Screener를 만든다
+func createAnomalyScreener() *anomaly.Screener {
+	action := api.ReviewAction(os.Getenv("ANOMALY_ACTION"))
+	if action == "" {
+		action = api.ReviewActionFlag
+	}
+
+	screener := &anomaly.Screener{}
+
+	lookupEnvInt("ANOMALY_RANK_JUMP", func(n int) {
+		detector := anomaly.RankJump{MinJump: n}
+		lookupEnvDuration("ANOMALY_RANK_JUMP_WINDOW", func(d time.Duration) { detector.Window = d })
+		screener.Rules = append(screener.Rules, anomaly.Rule{Detector: detector, Action: action})
+	})
+
+	lookupEnvFloat("ANOMALY_MAX_ZSCORE", func(f float64) {
+		detector := anomaly.ZScore{MaxZScore: f, MinSamples: 100}
+		lookupEnvInt("ANOMALY_MIN_SAMPLES", func(n int) { detector.MinSamples = n })
+		screener.Rules = append(screener.Rules, anomaly.Rule{Detector: detector, Action: action})
+	})
+
+	if len(screener.Rules) == 0 {
+		return nil
+	}
+
+	return screener
+}
+
+// parseSecrets는 "gameId:secret,gameId:secret" 형식의 game별 secret key 목록을 읽는다
+func parseSecrets(s string) map[string][]byte {
+	secrets := map[string][]byte{}
+	for _, item := range strings.Split(s, ",") {
+		i := strings.Index(item, ":")
+		if i <= 0 {
+			log.Fatal("SIGNATURE_SECRETS: invalid format")
+		}
+		secrets[item[:i]] = []byte(item[i+1:])
+	}
+	return secrets
+}
+
+// parseOperationTimeouts는 "POST /admin/expire=5m,GET /ranks=2s" 형식의 작업별 시간 제한을 읽는다
+func parseOperationTimeouts(s string) map[string]time.Duration {
+	timeouts := map[string]time.Duration{}
+	for _, item := range strings.Split(s, ",") {
+		i := strings.LastIndex(item, "=")
+		if i <= 0 {
+			log.Fatal("OPERATION_TIMEOUTS: invalid format")
+		}
+		d, err := time.ParseDuration(item[i+1:])
+		if err != nil {
+			log.Fatal("OPERATION_TIMEOUTS: ", err)
+		}
+		timeouts[strings.TrimSpace(item[:i])] = d
+	}
+	return timeouts
+}
+
+func parseCacheControl(s string) map[string]string {
+	policies := map[string]string{}
+	for _, item := range strings.Split(s, ";") {
+		i := strings.Index(item, "=")
+		if i <= 0 {
+			log.Fatal("CACHE_CONTROL: invalid format")
+		}
+		policies[strings.TrimSpace(item[:i])] = strings.TrimSpace(item[i+1:])
+	}
+	return policies
+}
+
+// lookupEnvInt는 환경변수 name이 있으면 정수로 읽어서 f를 호출한다
+func lookupEnvInt(name string, f func(n int)) {
+	if value := os.Getenv(name); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatal(name, ": ", err)
+		}
+		f(n)
+	}
+}
+
+func lookupEnvFloat(name string, f func(f float64)) {
+	if value := os.Getenv(name); value != "" {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Fatal(name, ": ", err)
+		}
+		f(n)
+	}
+}
+
+func lookupEnvDuration(name string, f func(d time.Duration)) {
+	if value := os.Getenv(name); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatal(name, ": ", err)
+		}
+		f(d)
+	}
+}
+
+func createTokenVerifier() *jwtauth.Verifier {
+	secret, jwksFile := os.Getenv("JWT_HS256_SECRET"), os.Getenv("JWT_JWKS_FILE")
+	if secret == "" && jwksFile == "" {
+		return nil
+	}
+
+	verifier := &jwtauth.Verifier{
+		HMACSecret:      []byte(secret),
+		Issuer:          os.Getenv("JWT_ISSUER"),
+		Audience:        os.Getenv("JWT_AUDIENCE"),
+		PrivilegedClaim: os.Getenv("JWT_PRIVILEGED_CLAIM"),
+	}
+	lookupEnvDuration("JWT_LEEWAY", func(d time.Duration) { verifier.Leeway = d })
+
+	if jwksFile != "" {
+		keys, err := jwtauth.LoadJWKSFile(jwksFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		verifier.RSAKeys = keys
+	}
+
+	return verifier
+}
+
+func parseRateLimits() http_server.RateLimits {
+	limits := http_server.RateLimits{}
+	for name, limit := range map[string]*ratelimit.Limit{
+		"RATE_LIMIT_CLIENT_READ":  &limits.ClientRead,
+		"RATE_LIMIT_CLIENT_WRITE": &limits.ClientWrite,
+		"RATE_LIMIT_USER_READ":    &limits.UserRead,
+		"RATE_LIMIT_USER_WRITE":   &limits.UserWrite,
+		"RATE_LIMIT_IP":           &limits.IP,
+	} {
+		l, err := ratelimit.ParseLimit(os.Getenv(name))
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		*limit = l
+	}
+	return limits
+}
+
+func createRateLimiter(store leaderboard.Storage) ratelimit.Limiter {
+	if redisStorage, ok := store.(*storage.RedisStorage); ok {
+		return &ratelimit.RedisLimiter{KeyPrefix: redisStorage.KeyPrefix, Client: redisStorage.Client}
+	}
+	return &ratelimit.MemLimiter{}
+}
+
 func createStorage() leaderboard.Storage {
 	if redisAddr := os.Getenv("REDIS_ADDR"); redisAddr != "" {
 		redisClient := redis.NewClient(&redis.Options{Addr: redisAddr})
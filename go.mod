@@ -9,7 +9,8 @@ require (
 	github.com/labstack/echo/v4 v4.2.2
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.4.1
 	github.com/onsi/gomega v1.11.0
+	github.com/prometheus/client_golang v1.11.0
 	github.com/spf13/cobra v1.1.3
-	golang.org/x/sys v0.0.0-20210503173754-0981d6026fa6 // indirect
-	golang.org/x/text v0.3.6 // indirect
+	golang.org/x/net v0.0.0-20210331212208-0fccb6fa2b5c
+	golang.org/x/text v0.3.6
 )
@@ -0,0 +1,51 @@
+package anomaly
+
+import (
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+)
+
+// Screener는 SetUser로 제출된 점수를 규칙들로 검사해서 의심스러운 제출을 검토 대기열로 보낸다.
+// leaderboard.LeaderBoard.Screener로 설정하면 점수 규칙, ban, idempotency 검사를 통과한 제출만 검사한다.
+type Screener struct {
+	Rules []Rule
+}
+
+var _ leaderboard.Screener = (*Screener)(nil)
+
+// Rule은 Detector가 의심스럽다고 판단한 제출을 Action으로 처리한다
+type Rule struct {
+	Detector Detector
+	Action   api.ReviewAction
+}
+
+type Detector interface {
+	// Detect는 submission이 의심스러우면 그 이유를 반환한다
+	Detect(submission Submission, stats Stats) (reason string, detected bool)
+}
+
+// Submission은 검사할 점수 제출
+type Submission = leaderboard.Submission
+
+// Stats는 지금 전체 순위에 있는 사용자들의 점수 통계. 저장소에 있으므로 서버들이 함께 쓴다.
+type Stats = leaderboard.ScoreStats
+
+// Screen은 submission에 해당하는 규칙들의 이유와, 그 중 가장 강한 처리 방법을 반환한다
+func (s *Screener) Screen(submission Submission, stats Stats) (api.ReviewAction, []string) {
+	var action api.ReviewAction
+	var reasons []string
+
+	for _, rule := range s.Rules {
+		reason, detected := rule.Detector.Detect(submission, stats)
+		if !detected {
+			continue
+		}
+
+		reasons = append(reasons, reason)
+		if action != api.ReviewActionQuarantine {
+			action = rule.Action
+		}
+	}
+
+	return action, reasons
+}
@@ -0,0 +1,148 @@
+package anomaly_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/anomaly"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+)
+
+func TestScreener_RankJump(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	maxScore := 5000
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+		NowFunc: func() time.Time { return now },
+		Rules:   leaderboard.ScoreRules{MaxScore: &maxScore},
+		Screener: &Screener{
+			Rules: []Rule{
+				{Detector: RankJump{MinJump: 5, Window: time.Minute}, Action: api.ReviewActionQuarantine},
+			},
+		},
+	}
+
+	for i := 1; i <= 10; i++ {
+		g.Expect(lb.SetUser(ctx, fmt.Sprint("user", i), 100*i)).To(Succeed())
+	}
+
+	// 10위에서 1위로: 보류하고 반영하지 않지만, 제출한 쪽에는 반영된 것처럼 응답한다
+	now = now.Add(30 * time.Second)
+	result := api.User{}
+	g.Expect(lb.SetUser(ctx, "user1", 2000, api.WithIdempotencyKey("k1"), api.WithResult(&result))).To(Succeed())
+	g.Expect(result.Score).To(Equal(2000))
+	g.Expect(result.Rank).To(Equal(1))
+	g.Expect(result.UpdatedAt).To(Equal(now))
+
+	user, err := lb.GetUser(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(100))
+
+	// 같은 idempotency key로 다시 보내도 검토 대기열에는 한 번만 넣는다
+	replayed := api.User{}
+	g.Expect(lb.SetUser(ctx, "user1", 2000, api.WithIdempotencyKey("k1"), api.WithResult(&replayed))).To(Succeed())
+	g.Expect(replayed).To(Equal(result))
+
+	reviews, err := lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(HaveLen(1))
+	g.Expect(reviews[0].UserId).To(Equal("user1"))
+	g.Expect(reviews[0].Score).To(Equal(2000))
+	g.Expect(reviews[0].PreviousScore).To(Equal(100))
+	g.Expect(reviews[0].Action).To(Equal(api.ReviewActionQuarantine))
+	g.Expect(reviews[0].Reasons).To(Equal([]string{"rank jumped from 10 to 1 in 30s"}))
+
+	// 점수 규칙과 ban 검사는 보류하기 전에 적용한다
+	g.Expect(lb.SetUser(ctx, "user2", 9999)).To(MatchError("score is greater than 5000"))
+	g.Expect(lb.SetUserState(ctx, "user3", api.UserStateBanned)).To(Succeed())
+	err = lb.SetUser(ctx, "user3", 3000)
+	g.Expect(err).To(MatchError("user is banned"))
+	g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusForbidden))
+
+	// 조금씩 오르거나 Window가 지난 경우는 의심하지 않는다
+	g.Expect(lb.SetUser(ctx, "user2", 450)).To(Succeed())
+	now = now.Add(2 * time.Minute)
+	g.Expect(lb.SetUser(ctx, "user4", 2000)).To(Succeed())
+
+	reviews, err = lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(HaveLen(1))
+
+	// 승인할 때는 지금의 규칙을 다시 적용한다
+	maxScore = 1000
+	g.Expect(lb.ResolveReview(ctx, reviews[0].Id, true, api.Audit{Actor: "admin"})).To(MatchError("score is greater than 1000"))
+
+	// 승인하면 반영한다
+	maxScore = 5000
+	g.Expect(lb.ResolveReview(ctx, reviews[0].Id, true, api.Audit{Actor: "admin"})).To(Succeed())
+
+	user, err = lb.GetUser(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(2000))
+
+	reviews, err = lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(BeEmpty())
+}
+
+func TestScreener_ZScore(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	s := &storage.MemStorage{}
+
+	newLeaderBoard := func() *leaderboard.LeaderBoard {
+		return &leaderboard.LeaderBoard{
+			Storage: s,
+			Screener: &Screener{
+				Rules: []Rule{
+					{Detector: ZScore{MaxZScore: 3, MinSamples: 10}, Action: api.ReviewActionFlag},
+				},
+			},
+		}
+	}
+
+	// 통계는 저장소에 있으므로 다른 서버에서 반영된 제출도 함께 센다
+	lb1, lb2 := newLeaderBoard(), newLeaderBoard()
+	for i := 1; i <= 20; i++ {
+		lb := lb1
+		if i%2 == 0 {
+			lb = lb2
+		}
+		g.Expect(lb.SetUser(ctx, fmt.Sprint("user", i), 1000+i%5)).To(Succeed())
+	}
+
+	// 분포에서 크게 벗어난 점수: 반영하고 검토 대기열에 넣는다
+	g.Expect(lb1.SetUser(ctx, "cheater", 999999)).To(Succeed())
+
+	user, err := lb1.GetUser(ctx, "cheater")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(999999))
+
+	reviews, err := lb1.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(HaveLen(1))
+	g.Expect(reviews[0].Action).To(Equal(api.ReviewActionFlag))
+	g.Expect(reviews[0].Reasons[0]).To(HavePrefix("score 999999 is "))
+
+	// 거절하면 이전 점수로 되돌린다
+	g.Expect(lb2.ResolveReview(ctx, reviews[0].Id, false, api.Audit{Actor: "admin", Reason: "cheating"})).To(Succeed())
+
+	user, err = lb2.GetUser(ctx, "cheater")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(0))
+
+	history, err := lb2.GetHistory(ctx, "cheater")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(history[len(history)-1].Kind).To(Equal(api.HistoryKindOverride))
+	g.Expect(history[len(history)-1].Reason).To(Equal("cheating"))
+}
@@ -0,0 +1,55 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RankJump는 짧은 시간에 순위가 크게 오른 제출을 찾는다 (예: 1분 만에 50000위에서 1위)
+type RankJump struct {
+	// 순위가 MinJump 이상 오르면 의심한다
+	MinJump int
+	// 직전 제출로부터 Window 안에 오른 경우만 의심한다 (0이면 시간과 상관 없이)
+	Window time.Duration
+}
+
+func (d RankJump) Detect(submission Submission, stats Stats) (string, bool) {
+	if !submission.Exists || submission.Previous.Rank == 0 {
+		return "", false
+	}
+
+	elapsed := submission.Now.Sub(submission.Previous.UpdatedAt)
+	if d.Window > 0 && elapsed > d.Window {
+		return "", false
+	}
+
+	if submission.Previous.Rank-submission.Rank < d.MinJump {
+		return "", false
+	}
+
+	return fmt.Sprintf("rank jumped from %d to %d in %v",
+		submission.Previous.Rank, submission.Rank, elapsed.Truncate(time.Second)), true
+}
+
+// ZScore는 지금까지 제출된 점수들의 분포에서 크게 벗어난 점수를 찾는다
+type ZScore struct {
+	// 평균에서 표준편차의 MaxZScore배보다 멀면 의심한다
+	MaxZScore float64
+	// 통계에 쓰인 제출이 MinSamples개가 되기 전에는 검사하지 않는다
+	MinSamples int
+}
+
+func (d ZScore) Detect(submission Submission, stats Stats) (string, bool) {
+	if stats.Count < d.MinSamples || stats.Count < 2 || stats.StdDev == 0 {
+		return "", false
+	}
+
+	z := (float64(submission.Score) - stats.Mean) / stats.StdDev
+	if math.Abs(z) <= d.MaxZScore {
+		return "", false
+	}
+
+	return fmt.Sprintf("score %d is %.1f standard deviations from the mean %.1f",
+		submission.Score, z, stats.Mean), true
+}
@@ -0,0 +1,284 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+// Header는 API key를 담는 HTTP header
+const Header = "X-API-Key"
+
+const (
+	keyListKey = "api_keys"
+	// 발급한 key의 secret 앞에 붙는 문자열
+	secretPrefix = "lbk_"
+)
+
+func keyValueKey(hash string) string {
+	return "api_key_" + hash
+}
+
+// Scope는 API key로 할 수 있는 작업의 범위
+type Scope string
+
+const (
+	// 조회 요청
+	ScopeRead Scope = "read"
+	// 점수 제출 등 변경 요청. read도 포함한다.
+	ScopeWrite Scope = "write"
+	// /admin route들. 모든 요청을 할 수 있다.
+	ScopeAdmin Scope = "admin"
+)
+
+func (s Scope) IsValid() bool {
+	switch s {
+	case ScopeRead, ScopeWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// includes는 s scope가 있으면 required scope의 요청도 할 수 있는지 반환한다
+func (s Scope) includes(required Scope) bool {
+	switch s {
+	case ScopeAdmin:
+		return true
+	case ScopeWrite:
+		return required == ScopeWrite || required == ScopeRead
+	}
+	return s == required
+}
+
+// Key는 발급한 API key
+type Key struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// 비어있을 수 없다
+	Scopes []Scope `json:"scopes"`
+	// 사용할 수 있는 board들. 비어있으면 모든 board에 사용할 수 있다.
+	Boards []string `json:"boards"`
+	// Issue의 반환값에만 있다. 저장소에는 hash만 저장한다.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Allows는 key로 board에서 scope의 요청을 할 수 있는지 반환한다
+func (key Key) Allows(scope Scope, board string) bool {
+	if len(key.Boards) > 0 {
+		found := false
+		for _, b := range key.Boards {
+			if b == board {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return key.HasScope(scope)
+}
+
+// HasScope는 key가 board와 관계없이 scope의 요청을 할 수 있는지 반환한다
+func (key Key) HasScope(scope Scope) bool {
+	for _, s := range key.Scopes {
+		if s.includes(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// storedKey는 저장소에 저장하는 key. Secret 대신 hash를 저장한다.
+type storedKey struct {
+	Key
+	Hash string `json:"hash"`
+}
+
+// KeyStorage는 key들을 저장한다. leaderboard.Storage 구현들이 이 interface를 만족한다.
+type KeyStorage interface {
+	AppendList(ctx context.Context, key string, data []byte, maxLen int) error
+	GetList(ctx context.Context, key string) ([][]byte, error)
+	RemoveListItem(ctx context.Context, key string, data []byte) error
+	GetValue(ctx context.Context, key string) ([]byte, error)
+	SetValue(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	DeleteValue(ctx context.Context, key string) error
+}
+
+// Hash는 secret을 저장소에 저장하는 형태로 바꾼다
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store는 API key를 발급, 폐기하고 요청의 key를 확인한다
+type Store struct {
+	Storage KeyStorage
+	// 이 서버의 board 이름. Boards가 있는 key는 이 board가 들어있어야 사용할 수 있다.
+	Board string
+	// 저장소 밖에서 설정한 key들 (예: 환경 변수로 받은 관리자 key). map key는 secret의 Hash. 폐기할 수 없다.
+	Static map[string]Key
+
+	NowFunc func() time.Time
+}
+
+func (store *Store) now() time.Time {
+	if store.NowFunc != nil {
+		return store.NowFunc()
+	}
+	return time.Now()
+}
+
+// Issue는 key.Name, key.Scopes, key.Boards로 새 key를 발급한다. Id, Secret, CreatedAt은 새로 정한다.
+func (store *Store) Issue(ctx context.Context, key Key) (Key, error) {
+	if len(key.Scopes) == 0 {
+		return Key{}, api.ErrorWithStatusCode(errors.New("scopes is empty"), http.StatusBadRequest)
+	}
+
+	for _, scope := range key.Scopes {
+		if !scope.IsValid() {
+			return Key{}, api.ErrorWithStatusCode(fmt.Errorf("invalid scope: %s", scope), http.StatusBadRequest)
+		}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+
+	key.Id = hex.EncodeToString(id)
+	key.Secret = secretPrefix + hex.EncodeToString(secret)
+	key.CreatedAt = store.now()
+
+	stored := storedKey{Key: key, Hash: Hash(key.Secret)}
+	stored.Secret = ""
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return Key{}, err
+	}
+
+	// 목록에 먼저 넣어서, 중간에 실패해도 폐기할 수 없는 key가 남지 않게 한다
+	if err := store.Storage.AppendList(ctx, keyListKey, data, 0); err != nil {
+		return Key{}, err
+	}
+
+	// 확인할 때는 hash로 바로 찾는다
+	if err := store.Storage.SetValue(ctx, keyValueKey(stored.Hash), data, 0); err != nil {
+		return Key{}, err
+	}
+
+	return key, nil
+}
+
+// List는 발급한 key들을 발급한 순서로 반환한다. Secret은 없다.
+func (store *Store) List(ctx context.Context) ([]Key, error) {
+	storedKeys, _, err := store.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]Key, len(storedKeys))
+	for i, stored := range storedKeys {
+		keys[i] = stored.Key
+	}
+
+	return keys, nil
+}
+
+func (store *Store) list(ctx context.Context) ([]storedKey, [][]byte, error) {
+	rawList, err := store.Storage.GetList(ctx, keyListKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]storedKey, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &keys[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return keys, rawList, nil
+}
+
+// Revoke는 key를 폐기한다. 폐기한 key로 보낸 요청은 바로 거부된다.
+func (store *Store) Revoke(ctx context.Context, keyId string) error {
+	keys, rawList, err := store.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys {
+		if key.Id != keyId {
+			continue
+		}
+
+		if err := store.Storage.DeleteValue(ctx, keyValueKey(key.Hash)); err != nil {
+			return err
+		}
+
+		return store.Storage.RemoveListItem(ctx, keyListKey, rawList[i])
+	}
+
+	return api.ErrorWithStatusCode(errors.New("api key not found"), http.StatusNotFound)
+}
+
+// Authenticate는 secret에 해당하는 key를 찾는다. 없거나 폐기된 key이면 401 오류를 반환한다.
+func (store *Store) Authenticate(ctx context.Context, secret string) (Key, error) {
+	if secret == "" {
+		return Key{}, api.ErrorWithStatusCode(errors.New("api key is required"), http.StatusUnauthorized)
+	}
+
+	hash := Hash(secret)
+
+	if key, ok := store.Static[hash]; ok {
+		return key, nil
+	}
+
+	data, err := store.Storage.GetValue(ctx, keyValueKey(hash))
+	if err != nil {
+		return Key{}, err
+	}
+
+	if data == nil {
+		return Key{}, api.ErrorWithStatusCode(errors.New("invalid api key"), http.StatusUnauthorized)
+	}
+
+	stored := storedKey{}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return Key{}, err
+	}
+
+	return stored.Key, nil
+}
+
+// Authorize는 secret의 key로 이 서버의 board에서 scope의 요청을 할 수 있는지 확인한다.
+// key가 없거나 잘못되었으면 401, 권한이 없으면 403 오류를 반환한다.
+func (store *Store) Authorize(ctx context.Context, secret string, scope Scope) (Key, error) {
+	key, err := store.Authenticate(ctx, secret)
+	if err != nil {
+		return Key{}, err
+	}
+
+	if !key.Allows(scope, store.Board) {
+		return Key{}, api.ErrorWithStatusCode(
+			fmt.Errorf("api key is not allowed to %s on board %q", scope, store.Board), http.StatusForbidden)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,130 @@
+package apikey_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testStore(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testStore(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testStore(t *testing.T, s KeyStorage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	store := &Store{
+		Storage: s,
+		Board:   "main",
+		NowFunc: func() time.Time { return now },
+	}
+
+	expectStatus := func(err error, msg string, statusCode int) {
+		g.Expect(err).To(MatchError(msg))
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(statusCode))
+	}
+
+	_, err := store.Issue(ctx, Key{Name: "empty"})
+	expectStatus(err, "scopes is empty", http.StatusBadRequest)
+
+	_, err = store.Issue(ctx, Key{Name: "unknown", Scopes: []Scope{"root"}})
+	expectStatus(err, "invalid scope: root", http.StatusBadRequest)
+
+	reader, err := store.Issue(ctx, Key{Name: "reader", Scopes: []Scope{ScopeRead}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reader.Id).NotTo(BeEmpty())
+	g.Expect(reader.Secret).To(HavePrefix("lbk_"))
+	g.Expect(reader.CreatedAt).To(Equal(now))
+
+	writer, err := store.Issue(ctx, Key{Name: "game server", Scopes: []Scope{ScopeWrite}, Boards: []string{"main"}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	other, err := store.Issue(ctx, Key{Name: "other board", Scopes: []Scope{ScopeAdmin}, Boards: []string{"other"}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys, err := store.List(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keys).To(HaveLen(3))
+	g.Expect(keys[0].Name).To(Equal("reader"))
+	g.Expect(keys[1].Boards).To(Equal([]string{"main"}))
+	for _, key := range keys {
+		g.Expect(key.Secret).To(BeEmpty())
+	}
+
+	key, err := store.Authorize(ctx, reader.Secret, ScopeRead)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key.Id).To(Equal(reader.Id))
+	g.Expect(key.Secret).To(BeEmpty())
+
+	_, err = store.Authorize(ctx, reader.Secret, ScopeWrite)
+	expectStatus(err, `api key is not allowed to write on board "main"`, http.StatusForbidden)
+
+	// write는 read도 포함하지만 admin은 아니다
+	_, err = store.Authorize(ctx, writer.Secret, ScopeRead)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = store.Authorize(ctx, writer.Secret, ScopeWrite)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = store.Authorize(ctx, writer.Secret, ScopeAdmin)
+	expectStatus(err, `api key is not allowed to admin on board "main"`, http.StatusForbidden)
+
+	// 다른 board용 key는 admin이라도 사용할 수 없다
+	_, err = store.Authorize(ctx, other.Secret, ScopeRead)
+	expectStatus(err, `api key is not allowed to read on board "main"`, http.StatusForbidden)
+
+	_, err = store.Authorize(ctx, "", ScopeRead)
+	expectStatus(err, "api key is required", http.StatusUnauthorized)
+
+	_, err = store.Authorize(ctx, reader.Secret+"x", ScopeRead)
+	expectStatus(err, "invalid api key", http.StatusUnauthorized)
+
+	// 저장소에는 secret을 저장하지 않는다
+	rawList, err := s.GetList(ctx, "api_keys")
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, data := range rawList {
+		g.Expect(string(data)).NotTo(ContainSubstring(strings.TrimPrefix(reader.Secret, "lbk_")))
+		g.Expect(string(data)).NotTo(ContainSubstring(strings.TrimPrefix(writer.Secret, "lbk_")))
+	}
+
+	g.Expect(store.Revoke(ctx, reader.Id)).To(Succeed())
+	expectStatus(store.Revoke(ctx, reader.Id), "api key not found", http.StatusNotFound)
+
+	_, err = store.Authorize(ctx, reader.Secret, ScopeRead)
+	expectStatus(err, "invalid api key", http.StatusUnauthorized)
+
+	keys, err = store.List(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keys).To(HaveLen(2))
+	g.Expect(keys[0].Id).To(Equal(writer.Id))
+
+	// 환경 변수 등으로 설정한 key
+	store.Static = map[string]Key{Hash("bootstrap"): {Id: "bootstrap", Scopes: []Scope{ScopeAdmin}}}
+	key, err = store.Authorize(ctx, "bootstrap", ScopeAdmin)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key.Id).To(Equal("bootstrap"))
+}
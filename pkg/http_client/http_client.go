@@ -1,21 +1,56 @@
 package http_client
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/signature"
 )
 
 type Client struct {
 	endpoint   string
 	httpClient *http.Client
+
+	// SetUser가 네트워크 오류나 서버 오류로 실패했을 때 다시 시도할 최대 횟수.
+	// 다시 시도할 때는 같은 idempotency key를 사용하므로 점수가 중복 반영되지 않는다.
+	MaxRetries    int
+	RetryInterval time.Duration
+
+	// nil이 아니면 점수 제출 요청에 서명한다
+	Signer *signature.Signer
+
+	// 비어있지 않으면 모든 요청에 API key로 보낸다
+	APIKey string
+	// 비어있지 않으면 모든 요청에 Authorization bearer 토큰으로 보낸다
+	BearerToken string
+
+	// ETag가 있는 조회 응답을 기억할 최대 수. 같은 조회를 다시 하면 If-None-Match를 보내고
+	// 304를 받으면 기억한 응답을 사용한다. 0이면 기억하지 않는다.
+	MaxCachedResponses int
+
+	cacheMutex sync.Mutex
+	cache      map[string]cachedResponse
+}
+
+// DefaultMaxCachedResponses는 New로 만든 클라이언트가 기억하는 조회 응답 수
+const DefaultMaxCachedResponses = 256
+
+type cachedResponse struct {
+	etag string
+	body []byte
 }
 
 func New(endpoint string) *Client {
@@ -41,19 +76,54 @@ func New(endpoint string) *Client {
 	}
 
 	return &Client{
-		endpoint:   endpoint,
-		httpClient: httpClient,
+		endpoint:           endpoint,
+		httpClient:         httpClient,
+		MaxRetries:         2,
+		RetryInterval:      500 * time.Millisecond,
+		MaxCachedResponses: DefaultMaxCachedResponses,
 	}
 }
 
-func (client *Client) doReq(ctx context.Context, method, path string, data interface{}) error {
-	req, err := http.NewRequest(method, client.endpoint+path, nil)
+// apiPrefix는 클라이언트가 사용하는 서버 API 버전
+const apiPrefix = "/v1"
+
+// doReq는 body가 nil이 아니면 JSON으로 보내고, 응답 envelope의 data를 data로 읽는다
+func (client *Client) doReq(ctx context.Context, method, path string, body, data interface{}) error {
+	return client.doReqWithHeader(ctx, method, path, nil, body, data)
+}
+
+func (client *Client) doReqWithHeader(ctx context.Context, method, path string, header http.Header, body, data interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, client.endpoint+apiPrefix+path, reqBody)
 	if err != nil {
 		return err
 	}
 
 	req = req.WithContext(ctx)
 
+	req.Header.Set("Accept", "application/json")
+	client.setAuthHeader(req.Header)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	cached, hasCached := client.cachedResponse(method, path)
+	if hasCached {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
 		return err
@@ -62,30 +132,130 @@ func (client *Client) doReq(ctx context.Context, method, path string, data inter
 	defer resp.Body.Close()
 	defer io.Copy(io.Discard, resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		type MessageData struct {
-			Message string
+	var respBody io.Reader = resp.Body
+
+	switch etag := resp.Header.Get("ETag"); {
+	case resp.StatusCode == http.StatusNotModified && hasCached:
+		respBody = bytes.NewReader(cached.body)
+	case resp.StatusCode == http.StatusOK && etag != "" && method == http.MethodGet:
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
 		}
-		msgData := MessageData{}
-		json.NewDecoder(resp.Body).Decode(&msgData)
+		client.storeResponse(path, cachedResponse{etag: etag, body: b})
+		respBody = bytes.NewReader(b)
+	case resp.StatusCode != http.StatusOK:
+		type ErrorData struct {
+			Error struct {
+				Message string
+				Reason  api.RejectionReason
+			}
+		}
+		errData := ErrorData{}
+		json.NewDecoder(resp.Body).Decode(&errData)
+		msgData := errData.Error
 		msg := msgData.Message
 		if msg == "" {
 			msg = resp.Status
 		}
+		if msgData.Reason != "" && resp.StatusCode == http.StatusUnprocessableEntity {
+			return api.NewRejectionError(msgData.Reason, msg)
+		}
 		return api.ErrorWithStatusCode(errors.New(msg), resp.StatusCode)
 	}
 
-	return json.NewDecoder(resp.Body).Decode(data)
+	type Envelope struct {
+		Data json.RawMessage
+	}
+	envelope := Envelope{}
+	if err := json.NewDecoder(respBody).Decode(&envelope); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(envelope.Data, data)
+}
+
+func (client *Client) cachedResponse(method, path string) (cachedResponse, bool) {
+	if method != http.MethodGet {
+		return cachedResponse{}, false
+	}
+
+	client.cacheMutex.Lock()
+	defer client.cacheMutex.Unlock()
+
+	cached, ok := client.cache[path]
+	return cached, ok
+}
+
+// storeResponse는 path의 조회 응답을 기억한다. MaxCachedResponses만큼 기억하고 있으면 아무거나 하나 잊는다.
+func (client *Client) storeResponse(path string, cached cachedResponse) {
+	if client.MaxCachedResponses <= 0 {
+		return
+	}
+
+	client.cacheMutex.Lock()
+	defer client.cacheMutex.Unlock()
+
+	if client.cache == nil {
+		client.cache = map[string]cachedResponse{}
+	}
+
+	if _, ok := client.cache[path]; !ok && len(client.cache) >= client.MaxCachedResponses {
+		for key := range client.cache {
+			delete(client.cache, key)
+			break
+		}
+	}
+
+	client.cache[path] = cached
+}
+
+// setAuthHeader는 API key와 bearer 토큰을 header에 넣는다
+func (client *Client) setAuthHeader(header http.Header) {
+	if client.APIKey != "" {
+		header.Set(apikey.Header, client.APIKey)
+	}
+	if client.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+client.BearerToken)
+	}
+}
+
+// optionsQuery는 api.Option을 서버의 query parameter로 변환한다
+func optionsQuery(opts []api.Option) url.Values {
+	options := api.NewOptions(opts...)
+	query := url.Values{}
+
+	if options.SnapshotId != "" {
+		query.Set("snapshot", options.SnapshotId)
+	}
+
+	if options.Viewer != "" {
+		query.Set("viewer", options.Viewer)
+	}
+
+	if options.Segment.Attribute != "" {
+		query.Set("segment", options.Segment.Attribute+":"+options.Segment.Value)
+	}
+
+	return query
 }
 
-func (client *Client) UserCount(ctx context.Context) (int, error) {
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+func (client *Client) UserCount(ctx context.Context, opts ...api.Option) (int, error) {
 	type UserCountData struct {
 		Count int
 	}
 
 	data := &UserCountData{}
 
-	err := client.doReq(ctx, http.MethodGet, "/usercount", data)
+	path := withQuery("/usercount", optionsQuery(opts))
+	err := client.doReq(ctx, http.MethodGet, path, nil, data)
 	if err != nil {
 		return 0, err
 	}
@@ -93,28 +263,356 @@ func (client *Client) UserCount(ctx context.Context) (int, error) {
 	return data.Count, nil
 }
 
-func (client *Client) GetUser(ctx context.Context, userId string) (api.User, error) {
+func (client *Client) GetUser(ctx context.Context, userId string, opts ...api.Option) (api.User, error) {
 	data := api.User{}
 
-	path := fmt.Sprintf("/users/%s", userId)
-	err := client.doReq(ctx, http.MethodGet, path, &data)
+	path := "/users/" + url.PathEscape(userId)
+	if api.NewOptions(opts...).IncludeHidden {
+		path = "/admin" + path
+	}
+
+	path = withQuery(path, optionsQuery(opts))
+	err := client.doReq(ctx, http.MethodGet, path, nil, &data)
 	return data, err
 }
 
-func (client *Client) SetUser(ctx context.Context, userId string, score int) error {
+func (client *Client) SetUser(ctx context.Context, userId string, score int, opts ...api.Option) error {
+	data := api.User{}
+
+	type Body struct {
+		Score      int               `json:"score"`
+		Attributes map[string]string `json:"attributes,omitempty"`
+	}
+
+	options := api.NewOptions(opts...)
+	body := Body{Score: score, Attributes: options.Attributes}
+
+	key := options.IdempotencyKey
+	if key == "" {
+		var err error
+		if key, err = newIdempotencyKey(); err != nil {
+			return err
+		}
+	}
+
+	header := http.Header{}
+	header.Set(api.IdempotencyKeyHeader, key)
+
+	path := "/users/" + url.PathEscape(userId)
+	err := client.retryIf(ctx, isRetryableSetUser, func() error {
+		if client.Signer != nil {
+			// 다시 시도할 때도 새 nonce로 서명해야 한다
+			signatureHeader, err := client.Signer.Header(userId, score, options.Attributes, key)
+			if err != nil {
+				return err
+			}
+			for k, v := range signatureHeader {
+				header[k] = v
+			}
+		}
+
+		return client.doReqWithHeader(ctx, http.MethodPut, path, header, body, &data)
+	})
+	if err != nil {
+		return err
+	}
+
+	if options.Result != nil {
+		*options.Result = data
+	}
+	return nil
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// retry는 f가 다시 시도할 수 있는 오류로 실패하면 MaxRetries번까지 다시 호출한다
+func (client *Client) retry(ctx context.Context, f func() error) error {
+	return client.retryIf(ctx, isRetryable, f)
+}
+
+// retryIf는 f가 retryable이 true인 오류로 실패하면 MaxRetries번까지 다시 호출한다
+func (client *Client) retryIf(ctx context.Context, retryable func(error) bool, f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= client.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(client.RetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func isRetryable(err error) bool {
+	var apiErr api.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode() >= http.StatusInternalServerError
+	}
+	// 응답을 받지 못한 경우
+	return true
+}
+
+// isRetryableSetUser는 같은 idempotency key의 요청이 아직 처리 중이거나
+// 다른 요청과 동시에 변경해서 생긴 409도 잠시 후 다시 시도한다
+func isRetryableSetUser(err error) bool {
+	var apiErr api.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode() == http.StatusConflict {
+		return true
+	}
+	return isRetryable(err)
+}
+
+func (client *Client) GetRanks(ctx context.Context, rank, count int, opts ...api.Option) ([]api.User, error) {
+	data := []api.User{}
+
+	query := optionsQuery(opts)
+	query.Set("rank", fmt.Sprint(rank))
+	query.Set("count", fmt.Sprint(count))
+
+	path := withQuery("/ranks", query)
+	err := client.doReq(ctx, http.MethodGet, path, nil, &data)
+	return data, err
+}
+
+func (client *Client) CreateSnapshot(ctx context.Context, snapshotId string) (api.Snapshot, error) {
+	data := api.Snapshot{}
+
+	type Body struct {
+		Id string `json:"id,omitempty"`
+	}
+
+	err := client.doReq(ctx, http.MethodPost, "/snapshots", Body{Id: snapshotId}, &data)
+	return data, err
+}
+
+func (client *Client) GetSnapshots(ctx context.Context) ([]api.Snapshot, error) {
+	data := []api.Snapshot{}
+
+	err := client.doReq(ctx, http.MethodGet, "/snapshots", nil, &data)
+	return data, err
+}
+
+func (client *Client) DeleteSnapshot(ctx context.Context, snapshotId string) error {
 	type Data struct {
 	}
 	data := Data{}
 
-	path := fmt.Sprintf("/users/%s?score=%v", userId, score)
-	err := client.doReq(ctx, http.MethodPut, path, &data)
+	path := "/snapshots/" + url.PathEscape(snapshotId)
+	err := client.doReq(ctx, http.MethodDelete, path, nil, &data)
 	return err
 }
 
-func (client *Client) GetRanks(ctx context.Context, rank, count int) ([]api.User, error) {
-	data := []api.User{}
+func (client *Client) SetUserState(ctx context.Context, userId string, state api.UserState) error {
+	data := api.User{}
+
+	type Body struct {
+		State api.UserState `json:"state"`
+	}
+
+	path := "/admin/users/" + url.PathEscape(userId) + "/state"
+	err := client.doReq(ctx, http.MethodPut, path, Body{State: state}, &data)
+	return err
+}
+
+func (client *Client) OverrideScore(ctx context.Context, userId string, score int, audit api.Audit) error {
+	data := api.User{}
+
+	type Body struct {
+		Score  int    `json:"score"`
+		Actor  string `json:"actor"`
+		Reason string `json:"reason,omitempty"`
+	}
+
+	path := "/admin/users/" + url.PathEscape(userId) + "/score"
+	err := client.doReq(ctx, http.MethodPut, path, Body{Score: score, Actor: audit.Actor, Reason: audit.Reason}, &data)
+	return err
+}
+
+func (client *Client) RevertScore(ctx context.Context, userId string, audit api.Audit) error {
+	data := api.User{}
+
+	path := "/admin/users/" + url.PathEscape(userId) + "/revert"
+	err := client.doReq(ctx, http.MethodPost, path, audit, &data)
+	return err
+}
+
+func (client *Client) GetHistory(ctx context.Context, userId string) ([]api.HistoryEntry, error) {
+	data := []api.HistoryEntry{}
 
-	path := fmt.Sprintf("/ranks?rank=%v&count=%v", rank, count)
-	err := client.doReq(ctx, http.MethodGet, path, &data)
+	err := client.doReq(ctx, http.MethodGet, "/admin/users/"+url.PathEscape(userId)+"/history", nil, &data)
 	return data, err
 }
+
+func (client *Client) GetRejections(ctx context.Context) ([]api.Rejection, error) {
+	data := []api.Rejection{}
+
+	err := client.doReq(ctx, http.MethodGet, "/admin/rejections", nil, &data)
+	return data, err
+}
+
+func (client *Client) AddReview(ctx context.Context, review api.Review) (api.Review, error) {
+	data := api.Review{}
+
+	type Body struct {
+		UserId        string           `json:"user_id"`
+		Score         int              `json:"score"`
+		PreviousScore int              `json:"previous_score"`
+		Action        api.ReviewAction `json:"action"`
+		Reasons       []string         `json:"reasons,omitempty"`
+	}
+
+	body := Body{
+		UserId:        review.UserId,
+		Score:         review.Score,
+		PreviousScore: review.PreviousScore,
+		Action:        review.Action,
+		Reasons:       review.Reasons,
+	}
+
+	err := client.doReq(ctx, http.MethodPost, "/admin/reviews", body, &data)
+	return data, err
+}
+
+func (client *Client) GetReviews(ctx context.Context) ([]api.Review, error) {
+	data := []api.Review{}
+
+	err := client.doReq(ctx, http.MethodGet, "/admin/reviews", nil, &data)
+	return data, err
+}
+
+func (client *Client) ResolveReview(ctx context.Context, reviewId string, approve bool, audit api.Audit) error {
+	type Data struct {
+	}
+	data := Data{}
+
+	action := "reject"
+	if approve {
+		action = "approve"
+	}
+
+	path := fmt.Sprintf("/admin/reviews/%s/%s", url.PathEscape(reviewId), action)
+	err := client.doReq(ctx, http.MethodPost, path, audit, &data)
+	return err
+}
+
+func (client *Client) ExportUser(ctx context.Context, userId string) (api.UserExport, error) {
+	data := api.UserExport{}
+
+	err := client.doReq(ctx, http.MethodGet, "/admin/users/"+url.PathEscape(userId)+"/export", nil, &data)
+	return data, err
+}
+
+func (client *Client) EraseUser(ctx context.Context, userId string, audit api.Audit) (api.ErasureReport, error) {
+	data := api.ErasureReport{}
+
+	err := client.doReq(ctx, http.MethodDelete, "/admin/users/"+url.PathEscape(userId), audit, &data)
+	return data, err
+}
+
+func (client *Client) ExpireInactive(ctx context.Context) (int, error) {
+	type ExpiredData struct {
+		Expired int
+	}
+
+	data := &ExpiredData{}
+
+	err := client.doReq(ctx, http.MethodPost, "/admin/expire", nil, data)
+	if err != nil {
+		return 0, err
+	}
+
+	return data.Expired, nil
+}
+
+func (client *Client) AddWebhook(ctx context.Context, webhook api.Webhook) (api.Webhook, error) {
+	data := api.Webhook{}
+
+	type Body struct {
+		URL    string          `json:"url"`
+		Events []api.EventType `json:"events,omitempty"`
+		Secret string          `json:"secret,omitempty"`
+	}
+
+	body := Body{URL: webhook.URL, Events: webhook.Events, Secret: webhook.Secret}
+	err := client.doReq(ctx, http.MethodPost, "/admin/webhooks", body, &data)
+	return data, err
+}
+
+func (client *Client) GetWebhooks(ctx context.Context) ([]api.Webhook, error) {
+	var data []api.Webhook
+
+	err := client.doReq(ctx, http.MethodGet, "/admin/webhooks", nil, &data)
+	return data, err
+}
+
+func (client *Client) DeleteWebhook(ctx context.Context, webhookId string) error {
+	type Data struct {
+	}
+	data := Data{}
+
+	err := client.doReq(ctx, http.MethodDelete, "/admin/webhooks/"+url.PathEscape(webhookId), nil, &data)
+	return err
+}
+
+func (client *Client) GetWebhookDeliveries(ctx context.Context, webhookId string) ([]api.WebhookDelivery, error) {
+	var data []api.WebhookDelivery
+
+	err := client.doReq(ctx, http.MethodGet, "/admin/webhooks/"+url.PathEscape(webhookId)+"/deliveries", nil, &data)
+	return data, err
+}
+
+func (client *Client) GetDeadLetters(ctx context.Context, webhookId string) ([]api.WebhookDelivery, error) {
+	var data []api.WebhookDelivery
+
+	err := client.doReq(ctx, http.MethodGet, "/admin/webhooks/"+url.PathEscape(webhookId)+"/deadletters", nil, &data)
+	return data, err
+}
+
+func (client *Client) GetVersion(ctx context.Context) (api.BoardVersion, error) {
+	data := api.BoardVersion{}
+
+	err := client.doReq(ctx, http.MethodGet, "/version", nil, &data)
+	return data, err
+}
+
+// IssueAPIKey는 key.Name, key.Scopes, key.Boards로 API key를 발급한다. 반환값의 Secret은 다시 조회할 수 없다.
+func (client *Client) IssueAPIKey(ctx context.Context, key apikey.Key) (apikey.Key, error) {
+	data := apikey.Key{}
+
+	type Body struct {
+		Name   string         `json:"name"`
+		Scopes []apikey.Scope `json:"scopes"`
+		Boards []string       `json:"boards,omitempty"`
+	}
+
+	body := Body{Name: key.Name, Scopes: key.Scopes, Boards: key.Boards}
+	err := client.doReq(ctx, http.MethodPost, "/admin/keys", body, &data)
+	return data, err
+}
+
+func (client *Client) GetAPIKeys(ctx context.Context) ([]apikey.Key, error) {
+	var data []apikey.Key
+
+	err := client.doReq(ctx, http.MethodGet, "/admin/keys", nil, &data)
+	return data, err
+}
+
+func (client *Client) RevokeAPIKey(ctx context.Context, keyId string) error {
+	type Data struct {
+	}
+	data := Data{}
+
+	err := client.doReq(ctx, http.MethodDelete, "/admin/keys/"+url.PathEscape(keyId), nil, &data)
+	return err
+}
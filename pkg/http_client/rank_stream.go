@@ -0,0 +1,115 @@
+package http_client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"golang.org/x/net/websocket"
+)
+
+// SubscribeRanks는 rank부터 count명의 순위 구간을 구독한다.
+// 처음에 구간 전체를 받고, 그 뒤로는 서버가 보내는 diff를 반영한 구간 전체를 바뀔 때마다 handler로 넘긴다.
+// ctx가 끝나거나, 연결이 끊기거나, handler가 오류를 반환하면 끝난다.
+func (client *Client) SubscribeRanks(ctx context.Context, rank, count int, handler func(users []api.User) error) error {
+	query := url.Values{}
+	query.Set("rank", strconv.Itoa(rank))
+	query.Set("count", strconv.Itoa(count))
+
+	location := withQuery(strings.Replace(client.endpoint, "http", "ws", 1)+apiPrefix+"/ranks/stream", query)
+
+	config, err := websocket.NewConfig(location, client.endpoint)
+	if err != nil {
+		return err
+	}
+
+	client.setAuthHeader(config.Header)
+
+	conn, err := dialContext(ctx, config.Location)
+	if err != nil {
+		return err
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer ws.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			ws.Close()
+		case <-done:
+		}
+	}()
+
+	users := map[string]api.User{}
+
+	for {
+		var update api.RankUpdate
+		if err := websocket.JSON.Receive(ws, &update); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		if update.Type == api.RankUpdateSnapshot {
+			users = map[string]api.User{}
+		}
+
+		for _, id := range update.Removed {
+			delete(users, id)
+		}
+
+		for _, user := range update.Users {
+			users[user.Id] = user
+		}
+
+		if err := handler(sortedWindow(users)); err != nil {
+			return err
+		}
+	}
+}
+
+func dialContext(ctx context.Context, location *url.URL) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	port := location.Port()
+	if location.Scheme == "wss" {
+		if port == "" {
+			port = "443"
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer}
+		return tlsDialer.DialContext(ctx, "tcp", net.JoinHostPort(location.Hostname(), port))
+	}
+
+	if port == "" {
+		port = "80"
+	}
+	return dialer.DialContext(ctx, "tcp", net.JoinHostPort(location.Hostname(), port))
+}
+
+func sortedWindow(users map[string]api.User) []api.User {
+	window := make([]api.User, 0, len(users))
+	for _, user := range users {
+		window = append(window, user)
+	}
+
+	sort.Slice(window, func(i, j int) bool {
+		return window[i].Rank < window[j].Rank
+	})
+
+	return window
+}
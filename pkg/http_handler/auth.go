@@ -0,0 +1,269 @@
+package http_handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// 인증한 API key를 담는 echo.Context key
+	apiKeyKey = "api_key"
+	// 검증한 bearer 토큰의 claim들을 담는 echo.Context key
+	claimsKey = "token_claims"
+)
+
+// requiredScope는 route에 필요한 API key scope를 정한다.
+// /admin route와 스냅샷을 만들거나 지우는 요청은 admin, 조회 요청은 read, 나머지는 write가 필요하다.
+func requiredScope(c echo.Context) apikey.Scope {
+	path := strings.TrimPrefix(c.Path(), "/v1")
+	if strings.HasPrefix(path, "/admin/") {
+		return apikey.ScopeAdmin
+	}
+
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead:
+		return apikey.ScopeRead
+	}
+
+	if path == "/snapshots" || strings.HasPrefix(path, "/snapshots/") {
+		return apikey.ScopeAdmin
+	}
+	return apikey.ScopeWrite
+}
+
+// bearerToken은 Authorization header의 bearer 토큰을 반환한다
+func bearerToken(req *http.Request) (string, bool) {
+	auth := req.Header.Get(echo.HeaderAuthorization)
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return auth[len("Bearer "):], true
+	}
+	return "", false
+}
+
+func isUnauthorized(err error) bool {
+	s, ok := err.(interface{ StatusCode() int })
+	return ok && s.StatusCode() == http.StatusUnauthorized
+}
+
+// Authorize는 Keys나 Tokens가 있으면 요청을 인증한다.
+// bearer 토큰이 있으면 Tokens로 검증하고 checkClaims로 권한을 확인한다.
+// 그렇지 않으면 요청의 API key가 route에 필요한 scope를 가졌는지 확인한다.
+// 인증 정보가 없거나 잘못되었으면 401, 권한이 없으면 403으로 응답한다.
+// 인증한 요청은 RateLimit이 있으면 그것을 거쳐 next로 넘긴다.
+// PreAuthRateLimit이 있으면 인증하기 전에 먼저 거친다.
+func (handler *HttpHandler) Authorize(next echo.HandlerFunc) echo.HandlerFunc {
+	if handler.RateLimit != nil {
+		next = handler.RateLimit(next)
+	}
+
+	authorize := handler.authorize(next)
+	if handler.PreAuthRateLimit != nil {
+		return handler.PreAuthRateLimit(authorize)
+	}
+	return authorize
+}
+
+func (handler *HttpHandler) authorize(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if handler.Keys == nil && handler.Tokens == nil {
+			return next(c)
+		}
+
+		if token, ok := bearerToken(c.Request()); ok && handler.Tokens != nil {
+			claims, err := handler.Tokens.Verify(token)
+			if err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return ErrorJson(c, err)
+			}
+
+			if err := checkClaims(c, claims); err != nil {
+				return ErrorJson(c, err)
+			}
+
+			c.Set(claimsKey, claims)
+			return next(c)
+		}
+
+		if handler.Keys == nil {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+			return ErrorJson(c, api.ErrorWithStatusCode(errors.New("bearer token is required"), http.StatusUnauthorized))
+		}
+
+		key, err := handler.Keys.Authorize(c.Request().Context(), c.Request().Header.Get(apikey.Header), requiredScope(c))
+		if err != nil {
+			if isUnauthorized(err) {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, apikey.Header)
+			}
+			return ErrorJson(c, err)
+		}
+
+		c.Set(apiKeyKey, key)
+		return next(c)
+	}
+}
+
+func forbidden(msg string) error {
+	return api.ErrorWithStatusCode(errors.New(msg), http.StatusForbidden)
+}
+
+// checkClaims는 토큰으로 요청한 사용자가 route를 사용할 수 있는지 확인한다.
+// 관리자 토큰은 모든 route를 사용할 수 있다. 그 외의 토큰은 조회 요청과
+// 자신의 id에 대한 사용자 route(/users/:id...)만 사용할 수 있고, 다른 사용자로 조회(viewer)할 수 없다.
+func checkClaims(c echo.Context, claims jwtauth.Claims) error {
+	if claims.Privileged {
+		return nil
+	}
+
+	path := strings.TrimPrefix(c.Path(), "/v1")
+	isUserRoute := strings.HasPrefix(path, "/users/:id")
+
+	scope := requiredScope(c)
+	if scope == apikey.ScopeAdmin || (scope == apikey.ScopeWrite && !isUserRoute) {
+		return forbidden("token is not allowed to " + string(scope) + " " + path)
+	}
+
+	subject, err := api.NormalizeUserId(claims.Subject)
+	if err != nil {
+		return forbidden("token subject is not a valid user id")
+	}
+
+	// 잘못된 id는 handler가 400으로 응답한다
+	if userId, ok := UserIdParam(c); ok && userId != subject {
+		return forbidden("token is not allowed to access another user")
+	}
+
+	if viewer := c.QueryParam("viewer"); viewer != "" {
+		if viewer, err := api.NormalizeUserId(viewer); err != nil || viewer != subject {
+			return forbidden("token is not allowed to view as another user")
+		}
+	}
+
+	return nil
+}
+
+// isAdmin은 요청한 쪽이 관리자인지 반환한다. 인증을 사용하지 않으면 모든 요청을 관리자의 요청으로 본다.
+func (handler *HttpHandler) isAdmin(c echo.Context) bool {
+	if handler.Keys == nil && handler.Tokens == nil {
+		return true
+	}
+
+	if claims, ok := TokenClaims(c); ok {
+		return claims.Privileged
+	}
+
+	key, ok := AuthenticatedKey(c)
+	return ok && key.HasScope(apikey.ScopeAdmin)
+}
+
+// viewer는 shadow ban된 사용자에게 자신이 보이도록 조회하는 사용자를 정한다.
+// 토큰으로 요청하면 토큰의 사용자이고, viewer query parameter로 다른 사용자가 보는 순위를 확인하는 것은 관리자만 할 수 있다.
+func (handler *HttpHandler) viewer(c echo.Context) string {
+	if claims, ok := TokenClaims(c); ok && !claims.Privileged {
+		return claims.Subject
+	}
+
+	if handler.isAdmin(c) {
+		return c.QueryParam("viewer")
+	}
+
+	// shadow ban을 확인하는 데 쓰이지 않도록 무시한다
+	return ""
+}
+
+// TokenClaims는 Authorize가 검증한 요청의 bearer 토큰 claim들을 반환한다
+func TokenClaims(c echo.Context) (jwtauth.Claims, bool) {
+	claims, ok := c.Get(claimsKey).(jwtauth.Claims)
+	return claims, ok
+}
+
+// UserIdParam은 /users/:id route가 대상으로 하는 사용자의 정규화한 id를 반환한다.
+// 다른 route이거나 id가 잘못되었으면 false를 반환한다.
+func UserIdParam(c echo.Context) (string, bool) {
+	if !strings.HasPrefix(strings.TrimPrefix(c.Path(), "/v1"), "/users/:id") {
+		return "", false
+	}
+
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return "", false
+	}
+
+	userId, err = api.NormalizeUserId(userId)
+	return userId, err == nil
+}
+
+// AuthenticatedKey는 Authorize가 확인한 요청의 API key를 반환한다
+func AuthenticatedKey(c echo.Context) (apikey.Key, bool) {
+	key, ok := c.Get(apiKeyKey).(apikey.Key)
+	return key, ok
+}
+
+func (handler *HttpHandler) keys() (*apikey.Store, error) {
+	if handler.Keys == nil {
+		return nil, api.ErrorWithStatusCode(errors.New("api key authentication is disabled"), http.StatusConflict)
+	}
+	return handler.Keys, nil
+}
+
+// issueKeyRequest는 POST /v1/admin/keys의 body
+type issueKeyRequest struct {
+	Name   string         `json:"name"`
+	Scopes []apikey.Scope `json:"scopes"`
+	Boards []string       `json:"boards,omitempty"`
+}
+
+func (handler *HttpHandler) HandleGetAdminKeys(c echo.Context) error {
+	keys, err := handler.keys()
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	list, err := keys.List(c.Request().Context())
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, list)
+}
+
+func (handler *HttpHandler) HandleV1PostAdminKeys(c echo.Context) error {
+	keys, err := handler.keys()
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	body := issueKeyRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	key, err := keys.Issue(c.Request().Context(), apikey.Key{
+		Name:   body.Name,
+		Scopes: body.Scopes,
+		Boards: body.Boards,
+	})
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, key)
+}
+
+func (handler *HttpHandler) HandleDeleteAdminKeys(c echo.Context) error {
+	keys, err := handler.keys()
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	if err := keys.Revoke(c.Request().Context(), c.Param("id")); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, struct{}{})
+}
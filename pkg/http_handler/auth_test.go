@@ -0,0 +1,352 @@
+package http_handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/api/apifakes"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpHandler_Authorize(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	keys := &apikey.Store{
+		Storage: &storage.MemStorage{},
+		Board:   "main",
+		Static:  map[string]apikey.Key{apikey.Hash("admin"): {Id: "admin", Scopes: []apikey.Scope{apikey.ScopeAdmin}}},
+	}
+
+	reader, err := keys.Issue(ctx, apikey.Key{Name: "reader", Scopes: []apikey.Scope{apikey.ScopeRead}})
+	g.Expect(err).NotTo(HaveOccurred())
+	writer, err := keys.Issue(ctx, apikey.Key{Name: "writer", Scopes: []apikey.Scope{apikey.ScopeWrite}})
+	g.Expect(err).NotTo(HaveOccurred())
+	other, err := keys.Issue(ctx, apikey.Key{Name: "other", Scopes: []apikey.Scope{apikey.ScopeWrite}, Boards: []string{"other"}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	handler := http_handler.New(&apifakes.FakeLeaderBoard{})
+	handler.Keys = keys
+
+	e := echo.New()
+	handler.Setup(e)
+
+	testDataList := []struct {
+		httpMethod, path, key, body string
+		expectedStatusCode          int
+		expectedBody                string
+	}{
+		{http.MethodGet, "/v1/usercount", "", "", http.StatusUnauthorized, `{"error":{"message":"api key is required"}}`},
+		{http.MethodGet, "/v1/usercount", "unknown", "", http.StatusUnauthorized, `{"error":{"message":"invalid api key"}}`},
+		{http.MethodGet, "/usercount", "", "", http.StatusUnauthorized, `{"message":"api key is required"}`},
+		{http.MethodGet, "/v1/usercount", reader.Secret, "", http.StatusOK, ""},
+		{http.MethodGet, "/usercount", reader.Secret, "", http.StatusOK, ""},
+		{http.MethodPut, "/v1/users/abc", reader.Secret, `{"score": 1}`, http.StatusForbidden,
+			`{"error":{"message":"api key is not allowed to write on board \"main\""}}`},
+		{http.MethodPut, "/v1/users/abc", writer.Secret, `{"score": 1}`, http.StatusOK, ""},
+		{http.MethodPut, "/v1/users/abc", other.Secret, `{"score": 1}`, http.StatusForbidden,
+			`{"error":{"message":"api key is not allowed to write on board \"main\""}}`},
+		{http.MethodGet, "/v1/admin/rejections", writer.Secret, "", http.StatusForbidden,
+			`{"error":{"message":"api key is not allowed to admin on board \"main\""}}`},
+		{http.MethodGet, "/admin/rejections", writer.Secret, "", http.StatusForbidden,
+			`{"message":"api key is not allowed to admin on board \"main\""}`},
+		{http.MethodGet, "/v1/admin/rejections", "admin", "", http.StatusOK, ""},
+		// 스냅샷을 만들거나 지우는 것은 관리자 작업이다
+		{http.MethodGet, "/v1/snapshots", reader.Secret, "", http.StatusOK, ""},
+		{http.MethodPost, "/v1/snapshots", writer.Secret, "", http.StatusForbidden,
+			`{"error":{"message":"api key is not allowed to admin on board \"main\""}}`},
+		{http.MethodDelete, "/v1/snapshots/s1", writer.Secret, "", http.StatusForbidden,
+			`{"error":{"message":"api key is not allowed to admin on board \"main\""}}`},
+		{http.MethodDelete, "/snapshots/s1", writer.Secret, "", http.StatusForbidden,
+			`{"message":"api key is not allowed to admin on board \"main\""}`},
+		{http.MethodPost, "/v1/snapshots", "admin", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/admin/keys", writer.Secret, "", http.StatusForbidden,
+			`{"error":{"message":"api key is not allowed to admin on board \"main\""}}`},
+		{http.MethodDelete, "/v1/admin/keys/" + reader.Id, "admin", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/usercount", reader.Secret, "", http.StatusUnauthorized, `{"error":{"message":"invalid api key"}}`},
+		// 인증 없이 사용할 수 있는 route
+		{http.MethodGet, "/healthz", "", "", http.StatusOK, ""},
+		{http.MethodGet, "/openapi.json", "", "", http.StatusOK, ""},
+	}
+
+	for _, testData := range testDataList {
+		req := httptest.NewRequest(testData.httpMethod, testData.path, strings.NewReader(testData.body))
+		if testData.key != "" {
+			req.Header.Set(apikey.Header, testData.key)
+		}
+		if testData.body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+
+		g.Expect(rw.Code).To(Equal(testData.expectedStatusCode), "%s %s: %s", testData.httpMethod, testData.path, rw.Body.String())
+		if testData.expectedBody != "" {
+			g.Expect(rw.Body.String()).To(MatchJSON(testData.expectedBody), "%s %s", testData.httpMethod, testData.path)
+		}
+
+		if testData.expectedStatusCode == http.StatusUnauthorized {
+			g.Expect(rw.Header().Get(echo.HeaderWWWAuthenticate)).To(Equal(apikey.Header))
+		}
+	}
+}
+
+func TestHttpHandler_AdminKeysDisabled(t *testing.T) {
+	g := NewWithT(t)
+
+	e := echo.New()
+	http_handler.New(&apifakes.FakeLeaderBoard{}).Setup(e)
+
+	// Keys가 없으면 API key를 확인하지 않고, key를 발급할 수도 없다
+	rw := httptest.NewRecorder()
+	e.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/usercount", nil))
+	g.Expect(rw.Code).To(Equal(http.StatusOK))
+
+	rw = httptest.NewRecorder()
+	e.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/admin/keys", nil))
+	g.Expect(rw.Code).To(Equal(http.StatusConflict))
+	g.Expect(rw.Body.String()).To(MatchJSON(`{"error":{"message":"api key authentication is disabled"}}`))
+}
+
+func TestHttpHandler_AuthorizeTokens(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	keys := &apikey.Store{
+		Storage: &storage.MemStorage{},
+		Board:   "main",
+		Static:  map[string]apikey.Key{apikey.Hash("reader"): {Id: "reader", Scopes: []apikey.Scope{apikey.ScopeRead}}},
+	}
+
+	handler := http_handler.New(&apifakes.FakeLeaderBoard{})
+	handler.Keys = keys
+	handler.Tokens = &jwtauth.Verifier{
+		HMACSecret: []byte("secret1"),
+		NowFunc:    func() time.Time { return now },
+	}
+
+	e := echo.New()
+	handler.Setup(e)
+
+	token := func(claims map[string]interface{}) string {
+		if _, ok := claims["exp"]; !ok {
+			claims["exp"] = now.Add(time.Hour).Unix()
+		}
+		token, err := jwtauth.SignHS256([]byte("secret1"), claims)
+		g.Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	user1 := token(map[string]interface{}{"sub": "user1"})
+	admin := token(map[string]interface{}{"sub": "ops", "leaderboard_admin": true})
+	expired := token(map[string]interface{}{"sub": "user1", "exp": now.Add(-time.Hour).Unix()})
+	invalidSubject := token(map[string]interface{}{"sub": "user 1!"})
+
+	testDataList := []struct {
+		httpMethod, path, token, key, body string
+		expectedStatusCode                 int
+		expectedBody                       string
+	}{
+		{http.MethodPut, "/v1/users/user1", user1, "", `{"score": 1}`, http.StatusOK, ""},
+		{http.MethodPut, "/v1/users/USER1", user1, "", `{"score": 1}`, http.StatusForbidden,
+			`{"error":{"message":"token is not allowed to access another user"}}`},
+		{http.MethodGet, "/v1/users/user1", user1, "", "", http.StatusOK, ""},
+		{http.MethodPut, "/v1/users/user2", user1, "", `{"score": 1}`, http.StatusForbidden,
+			`{"error":{"message":"token is not allowed to access another user"}}`},
+		{http.MethodGet, "/v1/users/user2", user1, "", "", http.StatusForbidden,
+			`{"error":{"message":"token is not allowed to access another user"}}`},
+		{http.MethodGet, "/v1/ranks?rank=1&count=1&viewer=user2", user1, "", "", http.StatusForbidden,
+			`{"error":{"message":"token is not allowed to view as another user"}}`},
+		{http.MethodGet, "/v1/ranks?rank=1&count=1&viewer=user1", user1, "", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/usercount", user1, "", "", http.StatusOK, ""},
+		{http.MethodPost, "/v1/snapshots", user1, "", "", http.StatusForbidden,
+			`{"error":{"message":"token is not allowed to admin /snapshots"}}`},
+		{http.MethodGet, "/v1/admin/rejections", user1, "", "", http.StatusForbidden,
+			`{"error":{"message":"token is not allowed to admin /admin/rejections"}}`},
+		{http.MethodGet, "/v1/usercount", invalidSubject, "", "", http.StatusForbidden,
+			`{"error":{"message":"token subject is not a valid user id"}}`},
+		// 관리자 토큰은 모든 route를 사용할 수 있다
+		{http.MethodPut, "/v1/users/user2", admin, "", `{"score": 1}`, http.StatusOK, ""},
+		{http.MethodGet, "/v1/admin/rejections", admin, "", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/usercount", expired, "", "", http.StatusUnauthorized,
+			`{"error":{"message":"token is expired"}}`},
+		{http.MethodGet, "/v1/usercount", "abc", "", "", http.StatusUnauthorized,
+			`{"error":{"message":"token is invalid format"}}`},
+		// 토큰이 없으면 API key를 확인한다
+		{http.MethodGet, "/v1/usercount", "", "reader", "", http.StatusOK, ""},
+		{http.MethodGet, "/v1/usercount", "", "", "", http.StatusUnauthorized,
+			`{"error":{"message":"api key is required"}}`},
+	}
+
+	for _, testData := range testDataList {
+		req := httptest.NewRequest(testData.httpMethod, testData.path, strings.NewReader(testData.body))
+		if testData.token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+testData.token)
+		}
+		if testData.key != "" {
+			req.Header.Set(apikey.Header, testData.key)
+		}
+		if testData.body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+
+		g.Expect(rw.Code).To(Equal(testData.expectedStatusCode), "%s %s: %s", testData.httpMethod, testData.path, rw.Body.String())
+		if testData.expectedBody != "" {
+			g.Expect(rw.Body.String()).To(MatchJSON(testData.expectedBody), "%s %s", testData.httpMethod, testData.path)
+		}
+
+		if testData.expectedStatusCode == http.StatusUnauthorized && testData.token != "" {
+			g.Expect(rw.Header().Get(echo.HeaderWWWAuthenticate)).To(Equal("Bearer"))
+		}
+	}
+
+	// API key 없이 토큰만 설정하면 토큰이 필요하다
+	handler.Keys = nil
+
+	rw := httptest.NewRecorder()
+	e.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/usercount", nil))
+	g.Expect(rw.Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(rw.Body.String()).To(MatchJSON(`{"error":{"message":"bearer token is required"}}`))
+	g.Expect(rw.Header().Get(echo.HeaderWWWAuthenticate)).To(Equal("Bearer"))
+}
+
+func TestHttpHandler_Viewer(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	fakeLb := &apifakes.FakeLeaderBoard{}
+
+	handler := http_handler.New(fakeLb)
+	handler.Keys = &apikey.Store{
+		Storage: &storage.MemStorage{},
+		Board:   "main",
+		Static: map[string]apikey.Key{
+			apikey.Hash("reader"): {Id: "reader", Scopes: []apikey.Scope{apikey.ScopeRead}},
+			apikey.Hash("admin"):  {Id: "admin", Scopes: []apikey.Scope{apikey.ScopeAdmin}},
+		},
+	}
+	handler.Tokens = &jwtauth.Verifier{
+		HMACSecret: []byte("secret1"),
+		NowFunc:    func() time.Time { return now },
+	}
+
+	e := echo.New()
+	handler.Setup(e)
+
+	token := func(claims map[string]interface{}) string {
+		claims["exp"] = now.Add(time.Hour).Unix()
+		token, err := jwtauth.SignHS256([]byte("secret1"), claims)
+		g.Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	testDataList := []struct {
+		description    string
+		path           string
+		token, key     string
+		expectedViewer string
+	}{
+		// 다른 사용자의 viewer로 shadow ban을 확인할 수 없다
+		{"read key", "/v1/users/abc?viewer=abc", "", "reader", ""},
+		{"admin key", "/v1/users/abc?viewer=abc", "", "admin", "abc"},
+		// 토큰의 사용자는 viewer 없이도 자신을 본다
+		{"user token", "/v1/users/abc", token(map[string]interface{}{"sub": "abc"}), "", "abc"},
+		{"admin token", "/v1/users/abc?viewer=abc", token(map[string]interface{}{"sub": "ops", "leaderboard_admin": true}), "", "abc"},
+		{"legacy route", "/users/abc?viewer=abc", "", "reader", ""},
+	}
+
+	for i, testData := range testDataList {
+		req := httptest.NewRequest(http.MethodGet, testData.path, nil)
+		if testData.token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+testData.token)
+		}
+		if testData.key != "" {
+			req.Header.Set(apikey.Header, testData.key)
+		}
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+
+		g.Expect(rw.Code).To(Equal(http.StatusOK), "%s: %s", testData.description, rw.Body.String())
+		g.Expect(fakeLb.GetUserCallCount()).To(Equal(i + 1))
+
+		_, _, opts := fakeLb.GetUserArgsForCall(i)
+		g.Expect(api.NewOptions(opts...).Viewer).To(Equal(testData.expectedViewer), testData.description)
+	}
+}
+
+func TestHttpHandler_AuthenticatedActor(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	fakeLb := &apifakes.FakeLeaderBoard{}
+
+	handler := http_handler.New(fakeLb)
+	handler.Keys = &apikey.Store{
+		Storage: &storage.MemStorage{},
+		Board:   "main",
+		Static:  map[string]apikey.Key{apikey.Hash("secret1"): {Id: "ops1", Scopes: []apikey.Scope{apikey.ScopeAdmin}}},
+	}
+	handler.Tokens = &jwtauth.Verifier{
+		HMACSecret: []byte("secret1"),
+		NowFunc:    func() time.Time { return now },
+	}
+
+	e := echo.New()
+	handler.Setup(e)
+
+	token, err := jwtauth.SignHS256([]byte("secret1"), map[string]interface{}{
+		"sub": "ops2", "leaderboard_admin": true, "exp": now.Add(time.Hour).Unix(),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// 요청에 있는 actor 대신 인증한 key나 토큰의 사용자를 기록한다
+	testDataList := []struct {
+		httpMethod, path, body string
+		token, key             string
+		expectedActor          string
+	}{
+		{http.MethodPut, "/v1/admin/users/abc/score", `{"score": 1, "actor": "mallory"}`, "", "secret1", "key:ops1"},
+		{http.MethodPut, "/v1/admin/users/abc/score", `{"score": 1}`, token, "", "token:ops2"},
+		{http.MethodPut, "/admin/users/abc/score?score=1&actor=mallory", "", "", "secret1", "key:ops1"},
+	}
+
+	for i, testData := range testDataList {
+		req := httptest.NewRequest(testData.httpMethod, testData.path, strings.NewReader(testData.body))
+		if testData.body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		if testData.token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+testData.token)
+		}
+		if testData.key != "" {
+			req.Header.Set(apikey.Header, testData.key)
+		}
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+
+		g.Expect(rw.Code).To(Equal(http.StatusOK), "%s %s: %s", testData.httpMethod, testData.path, rw.Body.String())
+
+		_, _, _, audit := fakeLb.OverrideScoreArgsForCall(i)
+		g.Expect(audit.Actor).To(Equal(testData.expectedActor), testData.path)
+	}
+}
@@ -0,0 +1,128 @@
+package http_handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultCacheControl은 조회 route별 기본 Cache-Control.
+// 순위는 자주 조회되지만 조금 늦게 보여도 되므로 잠깐 캐시하고, 사용자 조회는 매번 ETag로 확인하게 한다.
+func DefaultCacheControl() map[string]string {
+	return map[string]string{
+		"GET /ranks":     "private, max-age=5",
+		"GET /usercount": "private, max-age=30",
+		"GET /users/:id": "private, no-cache",
+	}
+}
+
+// versionETag는 저장소의 epoch와 순위표 버전으로 ETag를 만든다. 같은 버전이면 같은 URL의 응답도 같다.
+// 저장소가 비워져서 버전이 다시 시작해도 epoch가 다르므로 이전 ETag와 겹치지 않는다.
+// shadow ban된 사용자에게는 다른 응답을 보내므로 viewer가 있으면 viewer의 hash를 붙인다.
+func versionETag(version api.BoardVersion, viewer string) string {
+	tag := "v" + version.Epoch + "-" + strconv.FormatInt(version.Version, 10)
+	if viewer != "" {
+		sum := sha256.Sum256([]byte(viewer))
+		tag += "-" + hex.EncodeToString(sum[:8])
+	}
+	return `"` + tag + `"`
+}
+
+// etagMatches는 If-None-Match header에 etag가 있는지 반환한다. weak 비교를 한다.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// exists는 요청한 자원이 있는지 반환한다. 사용자 조회만 없을 수 있다.
+func (handler *HttpHandler) exists(c echo.Context) (bool, error) {
+	if strings.TrimPrefix(c.Path(), "/v1") != "/users/:id" {
+		return true, nil
+	}
+
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return false, nil
+	}
+	opts, err := handler.queryOptions(c)
+	if err != nil {
+		return false, nil
+	}
+
+	_, err = handler.lb.GetUser(c.Request().Context(), userId, opts...)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// notModified는 If-None-Match로 304로 응답할지 정한다. *는 자원이 있을 때만 같다.
+func (handler *HttpHandler) notModified(c echo.Context, version api.BoardVersion) (bool, error) {
+	ifNoneMatch := strings.TrimSpace(c.Request().Header.Get("If-None-Match"))
+	if ifNoneMatch == "*" {
+		return handler.exists(c)
+	}
+	return etagMatches(ifNoneMatch, versionETag(version, handler.viewer(c))), nil
+}
+
+func (handler *HttpHandler) setValidators(c echo.Context, version api.BoardVersion) {
+	header := c.Response().Header()
+	header.Set("ETag", versionETag(version, handler.viewer(c)))
+	// 응답이 인증한 사용자에 따라 다르므로 공유 캐시가 다른 사용자의 응답을 쓰지 않게 한다
+	header.Add(echo.HeaderVary, echo.HeaderAuthorization+", "+apikey.Header)
+	if !version.UpdatedAt.IsZero() {
+		header.Set(echo.HeaderLastModified, version.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	if cacheControl, ok := handler.CacheControl[c.Request().Method+" "+strings.TrimPrefix(c.Path(), "/v1")]; ok {
+		header.Set("Cache-Control", cacheControl)
+	}
+}
+
+// conditional은 조회 응답에 순위표 버전으로 ETag, Last-Modified와 route의 Cache-Control을 붙인다.
+// If-None-Match가 현재 ETag와 같거나, *이고 자원이 있으면 순위를 조회하지 않고 304로 응답한다.
+// 잘못된 요청이나 없는 자원은 next가 오류로 응답한다.
+func (handler *HttpHandler) conditional(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// 버전을 먼저 읽으므로 응답이 ETag보다 새로울 수는 있어도 오래될 수는 없다
+		version, err := handler.lb.GetVersion(c.Request().Context())
+		if err != nil {
+			return ErrorJson(c, err)
+		}
+
+		notModified, err := handler.notModified(c, version)
+		if err != nil {
+			return ErrorJson(c, err)
+		}
+
+		if notModified {
+			handler.setValidators(c, version)
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		c.Response().Before(func() {
+			if c.Response().Status == http.StatusOK {
+				handler.setValidators(c, version)
+			}
+		})
+		return next(c)
+	}
+}
+
+func (handler *HttpHandler) HandleGetVersion(c echo.Context) error {
+	version, err := handler.lb.GetVersion(c.Request().Context())
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, version)
+}
@@ -0,0 +1,154 @@
+package http_handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/api/apifakes"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpHandler_ConditionalGet(t *testing.T) {
+	g := NewWithT(t)
+
+	updatedAt := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	fake := &apifakes.FakeLeaderBoard{}
+	fake.GetVersionReturns(api.BoardVersion{Epoch: "e1", Version: 7, UpdatedAt: updatedAt}, nil)
+	fake.UserCountReturns(10, nil)
+	fake.GetUserReturns(api.User{Id: "abc", Score: 100, Rank: 1}, nil)
+	fake.GetRanksReturns([]api.User{{Id: "abc", Score: 100, Rank: 1}}, nil)
+
+	e := echo.New()
+	http_handler.New(fake).Setup(e)
+
+	testDataList := []struct {
+		path, ifNoneMatch  string
+		expectedStatusCode int
+		expectedCache      string
+		expectedCalls      int
+	}{
+		{"/v1/ranks?rank=1&count=10", "", http.StatusOK, "private, max-age=5", 1},
+		{"/v1/ranks?rank=1&count=10", `"ve1-7"`, http.StatusNotModified, "private, max-age=5", 0},
+		{"/v1/ranks?rank=1&count=10", `W/"ve1-7"`, http.StatusNotModified, "private, max-age=5", 0},
+		{"/v1/ranks?rank=1&count=10", `"ve1-6", "ve1-7"`, http.StatusNotModified, "private, max-age=5", 0},
+		{"/v1/ranks?rank=1&count=10", `"ve1-6"`, http.StatusOK, "private, max-age=5", 1},
+		// 저장소가 다르면 버전이 같아도 다르다
+		{"/v1/ranks?rank=1&count=10", `"ve0-7"`, http.StatusOK, "private, max-age=5", 1},
+		{"/v1/ranks?rank=1&count=10", "*", http.StatusNotModified, "private, max-age=5", 0},
+		{"/v1/usercount", `"ve1-7"`, http.StatusNotModified, "private, max-age=30", 0},
+		{"/v1/usercount", "", http.StatusOK, "private, max-age=30", 1},
+		// *는 사용자가 있는지 확인한다
+		{"/v1/users/abc", "*", http.StatusNotModified, "private, no-cache", 1},
+		{"/v1/users/abc", "", http.StatusOK, "private, no-cache", 1},
+		// /v1 이전의 route도 같다
+		{"/ranks?rank=1&count=10", `"ve1-7"`, http.StatusNotModified, "private, max-age=5", 0},
+		{"/users/abc", "", http.StatusOK, "private, no-cache", 1},
+	}
+
+	for _, testData := range testDataList {
+		callCount := fake.GetRanksCallCount() + fake.UserCountCallCount() + fake.GetUserCallCount()
+
+		req := httptest.NewRequest(http.MethodGet, testData.path, nil)
+		if testData.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", testData.ifNoneMatch)
+		}
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+
+		g.Expect(rw.Code).To(Equal(testData.expectedStatusCode), "%s %s: %s", testData.path, testData.ifNoneMatch, rw.Body.String())
+		g.Expect(rw.Header().Get("ETag")).To(Equal(`"ve1-7"`), testData.path)
+		g.Expect(rw.Header().Get("Last-Modified")).To(Equal("Sat, 01 May 2021 12:00:00 GMT"), testData.path)
+		g.Expect(rw.Header().Get("Cache-Control")).To(Equal(testData.expectedCache), testData.path)
+		g.Expect(rw.Header().Get("Vary")).To(Equal("Authorization, X-API-Key"), testData.path)
+
+		newCallCount := fake.GetRanksCallCount() + fake.UserCountCallCount() + fake.GetUserCallCount()
+		g.Expect(newCallCount).To(Equal(callCount+testData.expectedCalls), testData.path)
+		if testData.expectedStatusCode == http.StatusNotModified {
+			g.Expect(rw.Body.Len()).To(BeZero())
+		}
+	}
+
+	// 오류 응답에는 캐시 header를 붙이지 않는다
+	fake.GetUserReturns(api.User{}, api.ErrorWithStatusCode(errors.New("user not found"), http.StatusNotFound))
+
+	rw := httptest.NewRecorder()
+	e.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/users/unknown", nil))
+	g.Expect(rw.Code).To(Equal(http.StatusNotFound))
+	g.Expect(rw.Header().Get("ETag")).To(BeEmpty())
+	g.Expect(rw.Header().Get("Cache-Control")).To(BeEmpty())
+
+	// 없는 사용자는 *와 같지 않다
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/unknown", nil)
+	req.Header.Set("If-None-Match", "*")
+	rw = httptest.NewRecorder()
+	e.ServeHTTP(rw, req)
+	g.Expect(rw.Code).To(Equal(http.StatusNotFound))
+	g.Expect(rw.Header().Get("ETag")).To(BeEmpty())
+
+	// 다른 조회 route는 ETag가 없다
+	rw = httptest.NewRecorder()
+	e.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/snapshots", nil))
+	g.Expect(rw.Code).To(Equal(http.StatusOK))
+	g.Expect(rw.Header().Get("ETag")).To(BeEmpty())
+
+	rw = httptest.NewRecorder()
+	e.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/version", nil))
+	g.Expect(rw.Code).To(Equal(http.StatusOK))
+	g.Expect(rw.Body.String()).To(MatchJSON(`{"data":{"epoch":"e1","version":7,"updated_at":"2021-05-01T12:00:00Z"}}`))
+}
+
+func TestHttpHandler_ConditionalGetViewer(t *testing.T) {
+	g := NewWithT(t)
+
+	fake := &apifakes.FakeLeaderBoard{}
+	fake.GetVersionReturns(api.BoardVersion{Epoch: "e1", Version: 7}, nil)
+	fake.GetRanksReturns([]api.User{{Id: "abc", Score: 100, Rank: 1}}, nil)
+
+	handler := http_handler.New(fake)
+	handler.Tokens = &jwtauth.Verifier{HMACSecret: []byte("secret1")}
+
+	e := echo.New()
+	handler.Setup(e)
+
+	tokens := map[string]string{}
+	for _, userId := range []string{"a", "b"} {
+		token, err := jwtauth.SignHS256([]byte("secret1"), map[string]interface{}{
+			"sub": userId, "exp": time.Now().Add(time.Hour).Unix(),
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		tokens[userId] = token
+	}
+
+	get := func(userId, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/ranks?rank=1&count=10", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens[userId])
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+		return rw
+	}
+
+	rw := get("a", "")
+	g.Expect(rw.Code).To(Equal(http.StatusOK), rw.Body.String())
+	etagA := rw.Header().Get("ETag")
+	g.Expect(etagA).NotTo(Equal(`"ve1-7"`))
+
+	g.Expect(get("a", etagA).Code).To(Equal(http.StatusNotModified))
+
+	// shadow ban된 사용자는 다른 순위를 보므로 다른 사용자의 ETag로는 304를 받지 않는다
+	rw = get("b", etagA)
+	g.Expect(rw.Code).To(Equal(http.StatusOK))
+	g.Expect(rw.Header().Get("ETag")).NotTo(Equal(etagA))
+	g.Expect(fake.GetRanksCallCount()).To(Equal(2))
+}
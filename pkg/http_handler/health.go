@@ -0,0 +1,102 @@
+package http_handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultHealthCheckTimeout은 readiness에서 의존성 하나의 응답을 기다리는 시간
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// HealthCheck는 readiness에서 확인하는 의존성. leaderboard.Storage가 구현한다.
+type HealthCheck interface {
+	Ping(ctx context.Context) error
+}
+
+const (
+	healthStatusOk          = "ok"
+	healthStatusUnavailable = "unavailable"
+)
+
+type checkStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthData struct {
+	Status string `json:"status"`
+	// graceful shutdown 중이면 true
+	ShuttingDown bool                   `json:"shutting_down,omitempty"`
+	Checks       map[string]checkStatus `json:"checks,omitempty"`
+}
+
+// SetShuttingDown은 graceful shutdown을 시작했다고 표시한다. 이후 readiness는 실패한다.
+func (handler *HttpHandler) SetShuttingDown() {
+	atomic.StoreInt32(&handler.shuttingDown, 1)
+}
+
+func (handler *HttpHandler) isShuttingDown() bool {
+	return atomic.LoadInt32(&handler.shuttingDown) != 0
+}
+
+// HandleGetHealthz는 프로세스가 요청을 처리할 수 있으면 항상 성공한다 (liveness)
+func (handler *HttpHandler) HandleGetHealthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, healthData{Status: healthStatusOk})
+}
+
+// HandleGetReadyz는 모든 의존성이 응답하고 shutdown 중이 아니면 성공하고, 아니면 503으로 응답한다 (readiness)
+func (handler *HttpHandler) HandleGetReadyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), handler.HealthCheckTimeout)
+	defer cancel()
+
+	data := healthData{
+		Status:       healthStatusOk,
+		ShuttingDown: handler.isShuttingDown(),
+		Checks:       handler.checkHealth(ctx),
+	}
+
+	if data.ShuttingDown {
+		data.Status = healthStatusUnavailable
+	}
+	for _, check := range data.Checks {
+		if check.Status != healthStatusOk {
+			data.Status = healthStatusUnavailable
+		}
+	}
+
+	if data.Status != healthStatusOk {
+		return c.JSON(http.StatusServiceUnavailable, data)
+	}
+	return c.JSON(http.StatusOK, data)
+}
+
+// checkHealth는 의존성들을 동시에 확인한다
+func (handler *HttpHandler) checkHealth(ctx context.Context) map[string]checkStatus {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	checks := make(map[string]checkStatus, len(handler.HealthChecks))
+	for name, check := range handler.HealthChecks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+
+			status := checkStatus{Status: healthStatusOk}
+			if err := check.Ping(ctx); err != nil {
+				status = checkStatus{Status: healthStatusUnavailable, Error: err.Error()}
+			}
+
+			mutex.Lock()
+			checks[name] = status
+			mutex.Unlock()
+		}(name, check)
+	}
+
+	wg.Wait()
+	return checks
+}
@@ -0,0 +1,105 @@
+package http_handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api/apifakes"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/gomega"
+)
+
+type pingFunc func(ctx context.Context) error
+
+func (f pingFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestHttpHandler_Health(t *testing.T) {
+	g := NewWithT(t)
+
+	type CheckData struct {
+		Status string
+		Error  string
+	}
+
+	type HealthData struct {
+		Status       string
+		ShuttingDown bool `json:"shutting_down"`
+		Checks       map[string]CheckData
+	}
+
+	var storageErr error
+
+	handler := http_handler.New(&apifakes.FakeLeaderBoard{})
+	handler.HealthCheckTimeout = 10 * time.Millisecond
+	handler.HealthChecks = map[string]http_handler.HealthCheck{
+		"storage": pingFunc(func(ctx context.Context) error { return storageErr }),
+		"cache":   pingFunc(func(ctx context.Context) error { return nil }),
+	}
+
+	e := echo.New()
+	handler.Setup(e)
+
+	get := func(path string) (int, HealthData) {
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+
+		data := HealthData{}
+		g.Expect(json.Unmarshal(rw.Body.Bytes(), &data)).To(Succeed())
+		return rw.Code, data
+	}
+
+	statusCode, data := get("/healthz")
+	g.Expect(statusCode).To(Equal(http.StatusOK))
+	g.Expect(data).To(Equal(HealthData{Status: "ok"}))
+
+	statusCode, data = get("/readyz")
+	g.Expect(statusCode).To(Equal(http.StatusOK))
+	g.Expect(data).To(Equal(HealthData{
+		Status: "ok",
+		Checks: map[string]CheckData{"storage": {Status: "ok"}, "cache": {Status: "ok"}},
+	}))
+
+	// 의존성 하나라도 응답하지 않으면 준비되지 않았다
+	storageErr = errors.New("connection refused")
+
+	statusCode, data = get("/readyz")
+	g.Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(data).To(Equal(HealthData{
+		Status: "unavailable",
+		Checks: map[string]CheckData{
+			"storage": {Status: "unavailable", Error: "connection refused"},
+			"cache":   {Status: "ok"},
+		},
+	}))
+
+	// 응답이 늦으면 HealthCheckTimeout까지만 기다린다
+	handler.HealthChecks["storage"] = pingFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	statusCode, data = get("/readyz")
+	g.Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(data.Checks["storage"]).To(Equal(CheckData{Status: "unavailable", Error: "context deadline exceeded"}))
+
+	// shutdown 중에는 의존성과 관계없이 준비되지 않았다
+	handler.HealthChecks["storage"] = pingFunc(func(ctx context.Context) error { return nil })
+	handler.SetShuttingDown()
+
+	statusCode, data = get("/readyz")
+	g.Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(data.Status).To(Equal("unavailable"))
+	g.Expect(data.ShuttingDown).To(BeTrue())
+
+	// liveness는 shutdown 중에도 성공한다
+	statusCode, _ = get("/healthz")
+	g.Expect(statusCode).To(Equal(http.StatusOK))
+}
@@ -2,28 +2,107 @@ package http_handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
+	"github.com/bigflood/leaderboard/pkg/signature"
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	DefaultRequestTimeout = 30 * time.Second
+
+	// 클라이언트가 응답을 받기 전에 요청을 취소했다 (nginx와 같은 코드)
+	StatusClientClosedRequest = 499
+)
+
 type HttpHandler struct {
 	lb api.LeaderBoard
+
+	// nil이 아니면 점수 제출 요청의 서명을 검증한다
+	Verifier *signature.Verifier
+
+	// nil이 아니면 요청마다 API key를 확인하고, /v1/admin/keys로 key를 발급, 폐기한다
+	Keys *apikey.Store
+	// nil이 아니면 Authorization header의 bearer 토큰을 검증하고, 토큰의 사용자는 자신의 데이터만 변경할 수 있다
+	Tokens *jwtauth.Verifier
+	// nil이 아니면 Authorize가 인증한 다음 이 middleware로 요청 수를 제한한다
+	RateLimit echo.MiddlewareFunc
+	// nil이 아니면 Authorize가 인증하기 전에 이 middleware로 요청 수를 제한한다.
+	// 잘못된 인증 정보로 보내는 요청도 인증 저장소에 닿기 전에 막는다.
+	PreAuthRateLimit echo.MiddlewareFunc
+
+	// nil이 아니면 GET /v1/users/:id/events로 사용자의 순위 변경을 SSE로 보낸다
+	Events EventSource
+	// SSE 연결이 끊기지 않게 heartbeat comment를 보내는 주기
+	HeartbeatInterval time.Duration
+	// SSE 연결 하나를 유지하는 최대 시간. 끊기면 클라이언트가 Last-Event-ID로 이어서 받는다.
+	MaxStreamDuration time.Duration
+	// 동시에 유지하는 SSE 연결의 최대 수
+	MaxEventStreams int
+	// SSE 연결들이 함께 사용하는 변경 이벤트 구독
+	userEvents eventHub
+
+	// 요청 하나를 처리하는 시간 제한. 0이면 제한하지 않는다.
+	RequestTimeout time.Duration
+	// 작업별 시간 제한. key는 "POST /admin/expire"처럼 method와 /v1을 뺀 route path이고, 없는 작업은 RequestTimeout을 사용한다.
+	OperationTimeouts map[string]time.Duration
+
+	// 조회 route별 Cache-Control. key는 OperationTimeouts처럼 method와 /v1을 뺀 route path이고, 없는 route는 Cache-Control을 보내지 않는다.
+	CacheControl map[string]string
+
+	// GET /readyz에서 확인하는 의존성들. key는 응답에 표시하는 이름이다.
+	HealthChecks map[string]HealthCheck
+	// 의존성 확인을 기다리는 시간
+	HealthCheckTimeout time.Duration
+	// SetShuttingDown을 호출하면 1
+	shuttingDown int32
 }
 
 func New(lb api.LeaderBoard) *HttpHandler {
-	return &HttpHandler{lb: lb}
+	return &HttpHandler{
+		lb:                 lb,
+		HeartbeatInterval:  DefaultHeartbeatInterval,
+		MaxStreamDuration:  DefaultMaxStreamDuration,
+		MaxEventStreams:    DefaultMaxEventStreams,
+		RequestTimeout:     DefaultRequestTimeout,
+		CacheControl:       DefaultCacheControl(),
+		HealthCheckTimeout: DefaultHealthCheckTimeout,
+	}
 }
 
-func errorJson(c echo.Context, err error) error {
-	if err == nil {
-		return nil
+// deadline은 요청의 context에 작업별 시간 제한을 건다
+func (handler *HttpHandler) deadline(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// /v1 route도 같은 작업으로 본다
+		timeout, ok := handler.OperationTimeouts[c.Request().Method+" "+strings.TrimPrefix(c.Path(), "/v1")]
+		if !ok {
+			timeout = handler.RequestTimeout
+		}
+
+		if timeout <= 0 {
+			return next(c)
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+		defer cancel()
+
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
 	}
+}
 
-	type messageData struct {
-		Message string `json:"message"`
+// ErrorJson은 err을 status code와 함께 JSON으로 응답한다. /v1 요청이면 error envelope에 담는다.
+func ErrorJson(c echo.Context, err error) error {
+	if err == nil {
+		return nil
 	}
 
 	statusCode := http.StatusInternalServerError
@@ -31,81 +110,641 @@ func errorJson(c echo.Context, err error) error {
 		statusCode = s.StatusCode()
 	}
 
-	return c.JSON(statusCode, messageData{err.Error()})
+	// redis client는 context가 끝나면 i/o timeout 같은 다른 오류를 반환하기도 하므로 요청의 context도 확인한다
+	ctxErr := c.Request().Context().Err()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || ctxErr == context.DeadlineExceeded:
+		statusCode = http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled) || ctxErr == context.Canceled:
+		statusCode = StatusClientClosedRequest
+	}
+
+	data := errorData{Message: err.Error()}
+
+	var rejectionErr api.RejectionError
+	if errors.As(err, &rejectionErr) {
+		data.Reason = rejectionErr.Reason
+	}
+
+	if isV1(c) {
+		return c.JSON(statusCode, errorEnvelope{Error: data})
+	}
+	return c.JSON(statusCode, data)
+}
+
+func badRequest(c echo.Context, message string) error {
+	return ErrorJson(c, api.ErrorWithStatusCode(errors.New(message), http.StatusBadRequest))
+}
+
+// respond는 data를 200으로 응답한다. /v1 요청이면 data envelope에 담는다.
+func respond(c echo.Context, data interface{}) error {
+	if isV1(c) {
+		return c.JSON(http.StatusOK, dataEnvelope{Data: data})
+	}
+	return c.JSON(http.StatusOK, data)
 }
 
 func (handler *HttpHandler) Setup(e *echo.Echo) {
-	e.GET("/usercount", handler.HandleGetUserCount)
-	e.GET("/users/:id", handler.HandleGetUsers)
-	e.PUT("/users/:id", handler.HandlePutUsers)
-	e.GET("/ranks", handler.HandleGetRanks)
+	deadline := handler.deadline
+	authorize := handler.Authorize
+	conditional := handler.conditional
+	handler.setupV1(e.Group("/v1", handler.V1, authorize, Validate), deadline)
+
+	e.GET("/openapi.json", handler.HandleGetOpenAPI)
+	e.GET("/docs", handler.HandleGetDocs)
+	e.GET("/healthz", handler.HandleGetHealthz)
+	e.GET("/readyz", handler.HandleGetReadyz)
+
+	// /v1 이전의 route들. 점수 등을 query string으로 받는다.
+	e.GET("/usercount", handler.HandleGetUserCount, Deprecated, authorize, deadline, conditional)
+	e.GET("/users/:id", handler.HandleGetUsers, Deprecated, authorize, deadline, conditional)
+	e.PUT("/users/:id", handler.HandlePutUsers, Deprecated, authorize, deadline)
+	// 스트림은 MaxStreamDuration까지 유지한다
+	e.GET("/users/:id/events", handler.HandleGetUserEvents, Deprecated, authorize)
+	e.GET("/ranks", handler.HandleGetRanks, Deprecated, authorize, deadline, conditional)
+	e.GET("/snapshots", handler.HandleGetSnapshots, Deprecated, authorize, deadline)
+	e.POST("/snapshots", handler.HandlePostSnapshots, Deprecated, authorize, deadline)
+	e.DELETE("/snapshots/:id", handler.HandleDeleteSnapshots, Deprecated, authorize, deadline)
+
+	e.GET("/admin/users/:id", handler.HandleGetAdminUsers, Deprecated, authorize, deadline)
+	e.DELETE("/admin/users/:id", handler.HandleDeleteAdminUsers, Deprecated, authorize, deadline)
+	e.GET("/admin/users/:id/export", handler.HandleGetAdminUserExport, Deprecated, authorize, deadline)
+	e.PUT("/admin/users/:id/state", handler.HandlePutAdminUserState, Deprecated, authorize, deadline)
+	e.PUT("/admin/users/:id/score", handler.HandlePutAdminUserScore, Deprecated, authorize, deadline)
+	e.POST("/admin/users/:id/revert", handler.HandlePostAdminUserRevert, Deprecated, authorize, deadline)
+	e.GET("/admin/users/:id/history", handler.HandleGetAdminUserHistory, Deprecated, authorize, deadline)
+	e.GET("/admin/rejections", handler.HandleGetAdminRejections, Deprecated, authorize, deadline)
+	e.GET("/admin/reviews", handler.HandleGetAdminReviews, Deprecated, authorize, deadline)
+	e.POST("/admin/reviews", handler.HandlePostAdminReviews, Deprecated, authorize, deadline)
+	e.POST("/admin/reviews/:id/approve", handler.HandlePostAdminReviewApprove, Deprecated, authorize, deadline)
+	e.POST("/admin/reviews/:id/reject", handler.HandlePostAdminReviewReject, Deprecated, authorize, deadline)
+	e.POST("/admin/expire", handler.HandlePostAdminExpire, Deprecated, authorize, deadline)
+	e.GET("/admin/webhooks", handler.HandleGetAdminWebhooks, Deprecated, authorize, deadline)
+	e.POST("/admin/webhooks", handler.HandlePostAdminWebhooks, Deprecated, authorize, deadline)
+	e.DELETE("/admin/webhooks/:id", handler.HandleDeleteAdminWebhooks, Deprecated, authorize, deadline)
+	e.GET("/admin/webhooks/:id/deliveries", handler.HandleGetAdminWebhookDeliveries, Deprecated, authorize, deadline)
+	e.GET("/admin/webhooks/:id/deadletters", handler.HandleGetAdminWebhookDeadLetters, Deprecated, authorize, deadline)
+}
+
+// queryOptions는 조회 요청의 공통 query parameter를 api.Option으로 변환한다
+func (handler *HttpHandler) queryOptions(c echo.Context) ([]api.Option, error) {
+	var opts []api.Option
+
+	if snapshotId := c.QueryParam("snapshot"); snapshotId != "" {
+		opts = append(opts, api.WithSnapshot(snapshotId))
+	}
+
+	if viewer := handler.viewer(c); viewer != "" {
+		opts = append(opts, api.WithViewer(viewer))
+	}
+
+	if segment := c.QueryParam("segment"); segment != "" {
+		attribute, value, ok := splitAttribute(segment)
+		if !ok {
+			return nil, errors.New("segment is invalid format")
+		}
+		opts = append(opts, api.WithSegment(attribute, value))
+	}
+
+	return opts, nil
+}
+
+// pathParam은 path parameter를 읽는다. '/'처럼 escape된 문자가 있으면 echo가 escape된 그대로 넘겨주므로 unescape한다.
+func pathParam(c echo.Context, name string) (string, error) {
+	value := c.Param(name)
+	if c.Request().URL.RawPath == "" {
+		return value, nil
+	}
+
+	value, err := url.PathUnescape(value)
+	if err != nil {
+		return "", errors.New(name + " is invalid format")
+	}
+	return value, nil
+}
+
+// splitAttribute는 "name:value" 형식의 문자열을 나눈다
+func splitAttribute(s string) (string, string, bool) {
+	i := strings.Index(s, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
 }
 
 func (handler *HttpHandler) HandleGetUserCount(c echo.Context) error {
-	ctx := context.Background()
-	count, err := handler.lb.UserCount(ctx)
+	ctx := c.Request().Context()
+	opts, err := handler.queryOptions(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	count, err := handler.lb.UserCount(ctx, opts...)
 	if err != nil {
-		return errorJson(c, err)
+		return ErrorJson(c, err)
 	}
 
 	type UserCountData struct {
 		Count int `json:"count"`
 	}
 
-	return c.JSON(http.StatusOK, UserCountData{Count: count})
+	return respond(c, UserCountData{Count: count})
 }
 
 func (handler *HttpHandler) HandleGetUsers(c echo.Context) error {
-	ctx := context.Background()
-	userId := c.Param("id")
-	user, err := handler.lb.GetUser(ctx, userId)
+	ctx := c.Request().Context()
+	userId, err := pathParam(c, "id")
 	if err != nil {
-		return errorJson(c, err)
+		return badRequest(c, err.Error())
+	}
+	opts, err := handler.queryOptions(c)
+	if err != nil {
+		return badRequest(c, err.Error())
 	}
 
-	return c.JSON(http.StatusOK, user)
+	user, err := handler.lb.GetUser(ctx, userId, opts...)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, user)
 }
 
 func (handler *HttpHandler) HandlePutUsers(c echo.Context) error {
-	ctx := context.Background()
-	userId := c.Param("id")
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
 	score, err := strconv.Atoi(c.QueryParam("score"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, messageData{"score is empty or invalid format"})
+		return badRequest(c, "score is empty or invalid format")
 	}
 
-	if err := handler.lb.SetUser(ctx, userId, score); err != nil {
-		return errorJson(c, err)
+	var opts []api.Option
+	for _, attribute := range c.QueryParams()["attribute"] {
+		name, value, ok := splitAttribute(attribute)
+		if !ok {
+			return badRequest(c, "attribute is invalid format")
+		}
+		opts = append(opts, api.WithAttribute(name, value))
 	}
 
-	user, err := handler.lb.GetUser(ctx, userId)
-	if err != nil {
-		return errorJson(c, err)
+	return handler.putUser(c, userId, score, opts)
+}
+
+func (handler *HttpHandler) putUser(c echo.Context, userId string, score int, opts []api.Option) error {
+	ctx := c.Request().Context()
+
+	if handler.Verifier != nil {
+		if err := handler.Verifier.Verify(ctx, c.Request().Header, userId, score, api.NewOptions(opts...).Attributes); err != nil {
+			return ErrorJson(c, err)
+		}
 	}
 
-	return c.JSON(http.StatusOK, user)
+	if key := c.Request().Header.Get(api.IdempotencyKeyHeader); key != "" {
+		opts = append(opts, api.WithIdempotencyKey(key))
+	}
+
+	// 같은 idempotency key로 다시 받은 요청에는 처음 요청의 응답을 그대로 돌려준다
+	user := api.User{}
+	opts = append(opts, api.WithResult(&user))
+
+	if err := handler.lb.SetUser(ctx, userId, score, opts...); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, user)
 }
 
 func (handler *HttpHandler) HandleGetRanks(c echo.Context) error {
-	ctx := context.Background()
+	ctx := c.Request().Context()
 	rank, err := strconv.Atoi(c.QueryParam("rank"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, messageData{"rank is empty or invalid format"})
+		return badRequest(c, "rank is empty or invalid format")
 	}
 
 	count, err := strconv.Atoi(c.QueryParam("count"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, messageData{"count is empty or invalid format"})
+		return badRequest(c, "count is empty or invalid format")
+	}
+
+	opts, err := handler.queryOptions(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	users, err := handler.lb.GetRanks(ctx, rank, count, opts...)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, users)
+}
+
+func (handler *HttpHandler) HandleGetSnapshots(c echo.Context) error {
+	ctx := c.Request().Context()
+	snapshots, err := handler.lb.GetSnapshots(ctx)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, snapshots)
+}
+
+func (handler *HttpHandler) HandlePostSnapshots(c echo.Context) error {
+	return handler.createSnapshot(c, c.QueryParam("id"))
+}
+
+func (handler *HttpHandler) createSnapshot(c echo.Context, snapshotId string) error {
+	ctx := c.Request().Context()
+	snapshot, err := handler.lb.CreateSnapshot(ctx, snapshotId)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, snapshot)
+}
+
+func (handler *HttpHandler) HandleDeleteSnapshots(c echo.Context) error {
+	ctx := c.Request().Context()
+	snapshotId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	if err := handler.lb.DeleteSnapshot(ctx, snapshotId); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, struct{}{})
+}
+
+func (handler *HttpHandler) HandleGetAdminUsers(c echo.Context) error {
+	ctx := c.Request().Context()
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	user, err := handler.lb.GetUser(ctx, userId, api.WithHidden())
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, user)
+}
+
+func (handler *HttpHandler) HandleDeleteAdminUsers(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	audit, err := handler.queryAudit(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	return handler.eraseUser(c, userId, audit)
+}
+
+func (handler *HttpHandler) eraseUser(c echo.Context, userId string, audit api.Audit) error {
+	ctx := c.Request().Context()
+	report, err := handler.lb.EraseUser(ctx, userId, audit)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, report)
+}
+
+func (handler *HttpHandler) HandleGetAdminUserExport(c echo.Context) error {
+	ctx := c.Request().Context()
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	export, err := handler.lb.ExportUser(ctx, userId)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, export)
+}
+
+func (handler *HttpHandler) HandlePutAdminUserState(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	return handler.setUserState(c, userId, api.UserState(c.QueryParam("state")))
+}
+
+func (handler *HttpHandler) setUserState(c echo.Context, userId string, state api.UserState) error {
+	ctx := c.Request().Context()
+	if !state.IsValid() {
+		return badRequest(c, "state is empty or invalid")
+	}
+
+	if err := handler.lb.SetUserState(ctx, userId, state); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	user, err := handler.lb.GetUser(ctx, userId, api.WithHidden())
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, user)
+}
+
+func (handler *HttpHandler) queryAudit(c echo.Context) (api.Audit, error) {
+	audit := api.Audit{
+		Actor:  c.QueryParam("actor"),
+		Reason: c.QueryParam("reason"),
+	}
+	return audit, setActor(c, &audit)
+}
+
+// setActor는 인증한 요청이면 관리자 작업을 한 사람을 인증한 API key나 토큰의 사용자로 기록한다.
+// 요청에 있는 actor는 인증을 사용하지 않을 때만 사용한다.
+func setActor(c echo.Context, audit *api.Audit) error {
+	if claims, ok := TokenClaims(c); ok {
+		audit.Actor = "token:" + claims.Subject
+	} else if key, ok := AuthenticatedKey(c); ok {
+		audit.Actor = "key:" + key.Id
+	}
+
+	if audit.Actor == "" {
+		return errors.New("actor is empty")
+	}
+	return nil
+}
+
+func (handler *HttpHandler) HandlePutAdminUserScore(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	score, err := strconv.Atoi(c.QueryParam("score"))
+	if err != nil {
+		return badRequest(c, "score is empty or invalid format")
+	}
+
+	audit, err := handler.queryAudit(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	return handler.overrideScore(c, userId, score, audit)
+}
+
+func (handler *HttpHandler) overrideScore(c echo.Context, userId string, score int, audit api.Audit) error {
+	ctx := c.Request().Context()
+	if err := handler.lb.OverrideScore(ctx, userId, score, audit); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	user, err := handler.lb.GetUser(ctx, userId, api.WithHidden())
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, user)
+}
+
+func (handler *HttpHandler) HandlePostAdminUserRevert(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	audit, err := handler.queryAudit(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	return handler.revertScore(c, userId, audit)
+}
+
+func (handler *HttpHandler) revertScore(c echo.Context, userId string, audit api.Audit) error {
+	ctx := c.Request().Context()
+	if err := handler.lb.RevertScore(ctx, userId, audit); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	user, err := handler.lb.GetUser(ctx, userId, api.WithHidden())
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, user)
+}
+
+func (handler *HttpHandler) HandleGetAdminUserHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	entries, err := handler.lb.GetHistory(ctx, userId)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, entries)
+}
+
+func (handler *HttpHandler) HandleGetAdminRejections(c echo.Context) error {
+	ctx := c.Request().Context()
+	rejections, err := handler.lb.GetRejections(ctx)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, rejections)
+}
+
+func (handler *HttpHandler) HandleGetAdminReviews(c echo.Context) error {
+	ctx := c.Request().Context()
+	reviews, err := handler.lb.GetReviews(ctx)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, reviews)
+}
+
+func (handler *HttpHandler) HandlePostAdminReviews(c echo.Context) error {
+	review := api.Review{
+		UserId:  c.QueryParam("user"),
+		Action:  api.ReviewAction(c.QueryParam("action")),
+		Reasons: c.QueryParams()["reason"],
+	}
+
+	var err error
+	if review.Score, err = strconv.Atoi(c.QueryParam("score")); err != nil {
+		return badRequest(c, "score is empty or invalid format")
+	}
+
+	if review.PreviousScore, err = strconv.Atoi(c.QueryParam("previous_score")); err != nil {
+		return badRequest(c, "previous_score is empty or invalid format")
+	}
+
+	return handler.addReview(c, review)
+}
+
+func (handler *HttpHandler) addReview(c echo.Context, review api.Review) error {
+	ctx := c.Request().Context()
+	if review.UserId == "" {
+		return badRequest(c, "user is empty")
 	}
 
-	users, err := handler.lb.GetRanks(ctx, rank, count)
+	if review.Action != api.ReviewActionFlag && review.Action != api.ReviewActionQuarantine {
+		return badRequest(c, "action is empty or invalid")
+	}
+
+	review, err := handler.lb.AddReview(ctx, review)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, review)
+}
+
+func (handler *HttpHandler) HandlePostAdminReviewApprove(c echo.Context) error {
+	audit, err := handler.queryAudit(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	return handler.resolveReview(c, true, audit)
+}
+
+func (handler *HttpHandler) HandlePostAdminReviewReject(c echo.Context) error {
+	audit, err := handler.queryAudit(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	return handler.resolveReview(c, false, audit)
+}
+
+func (handler *HttpHandler) resolveReview(c echo.Context, approve bool, audit api.Audit) error {
+	ctx := c.Request().Context()
+	reviewId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	if err := handler.lb.ResolveReview(ctx, reviewId, approve, audit); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, struct{}{})
+}
+
+func (handler *HttpHandler) HandlePostAdminExpire(c echo.Context) error {
+	type ExpiredData struct {
+		Expired int `json:"expired"`
+	}
+
+	ctx := c.Request().Context()
+
+	expired, err := handler.lb.ExpireInactive(ctx)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, ExpiredData{Expired: expired})
+}
+
+func (handler *HttpHandler) HandleGetAdminWebhooks(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	webhooks, err := handler.lb.GetWebhooks(ctx)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, webhooks)
+}
+
+// webhookSecretRequest는 POST /admin/webhooks의 body
+type webhookSecretRequest struct {
+	Secret string `json:"secret,omitempty"`
+}
+
+// HandlePostAdminWebhooks는 url, event는 query string으로, secret은 body로 받는다
+func (handler *HttpHandler) HandlePostAdminWebhooks(c echo.Context) error {
+	if err := rejectQuerySecret(c); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	body := webhookSecretRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	webhook := api.Webhook{
+		URL:    c.QueryParam("url"),
+		Secret: body.Secret,
+	}
+
+	for _, eventType := range c.QueryParams()["event"] {
+		webhook.Events = append(webhook.Events, api.EventType(eventType))
+	}
+
+	return handler.addWebhook(c, webhook)
+}
+
+// rejectQuerySecret는 query string으로 보낸 webhook secret을 거부한다. query string은 접근 로그와 프록시에 남는다.
+func rejectQuerySecret(c echo.Context) error {
+	if _, ok := c.QueryParams()["secret"]; ok {
+		return api.ErrorWithStatusCode(errors.New("secret must be sent in the request body"), http.StatusBadRequest)
+	}
+	return nil
+}
+
+func (handler *HttpHandler) addWebhook(c echo.Context, webhook api.Webhook) error {
+	ctx := c.Request().Context()
+	webhook, err := handler.lb.AddWebhook(ctx, webhook)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, webhook)
+}
+
+func (handler *HttpHandler) HandleDeleteAdminWebhooks(c echo.Context) error {
+	ctx := c.Request().Context()
+	if err := handler.lb.DeleteWebhook(ctx, c.Param("id")); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, struct{}{})
+}
+
+func (handler *HttpHandler) HandleGetAdminWebhookDeliveries(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	deliveries, err := handler.lb.GetWebhookDeliveries(ctx, c.Param("id"))
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return respond(c, deliveries)
+}
+
+func (handler *HttpHandler) HandleGetAdminWebhookDeadLetters(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	deliveries, err := handler.lb.GetDeadLetters(ctx, c.Param("id"))
 	if err != nil {
-		return errorJson(c, err)
+		return ErrorJson(c, err)
 	}
 
-	return c.JSON(http.StatusOK, users)
+	return respond(c, deliveries)
 }
 
-type messageData struct {
+type errorData struct {
 	Message string `json:"message"`
+	// 점수 검증 규칙으로 거부된 경우 거부 사유
+	Reason api.RejectionReason `json:"reason,omitempty"`
 }
@@ -1,11 +1,13 @@
 package http_handler_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +25,8 @@ func TestHttpHandler(t *testing.T) {
 		description        string
 		httpMethod         string
 		path               string
+		header             http.Header
+		body               string
 		setup, after       func(*apifakes.FakeLeaderBoard)
 		expectedStatusCode int
 		data               interface{}
@@ -37,6 +41,15 @@ func TestHttpHandler(t *testing.T) {
 		Message string
 	}
 
+	type ExpiredData struct {
+		Expired int
+	}
+
+	type RejectionData struct {
+		Message string
+		Reason  string
+	}
+
 	now := time.Now().UTC()
 
 	testDataList := []TestData{
@@ -113,11 +126,47 @@ func TestHttpHandler(t *testing.T) {
 			httpMethod:  http.MethodPut,
 			path:        "/users/abc?score=300",
 			after: func(fake *apifakes.FakeLeaderBoard) {
-				_, userId, score := fake.SetUserArgsForCall(0)
+				_, userId, score, opts := fake.SetUserArgsForCall(0)
 				g.Expect(userId).To(Equal("abc"))
 				g.Expect(score).To(Equal(300))
+				options := api.NewOptions(opts...)
+				g.Expect(options.Attributes).To(BeEmpty())
+				g.Expect(options.IdempotencyKey).To(BeEmpty())
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "set users: responds with the result of SetUser",
+			httpMethod:  http.MethodPut,
+			path:        "/users/abc?score=300",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.SetUserStub = func(ctx context.Context, userId string, score int, opts ...api.Option) error {
+					*api.NewOptions(opts...).Result = api.User{Id: userId, Score: 100, Rank: 2, UpdatedAt: now}
+					return nil
+				}
 			},
 			expectedStatusCode: http.StatusOK,
+			data:               &api.User{},
+			expectedData:       &api.User{Id: "abc", Score: 100, Rank: 2, UpdatedAt: now},
+		},
+		{
+			description: "set users with attributes",
+			httpMethod:  http.MethodPut,
+			path:        "/users/abc?score=300&attribute=country:KR&attribute=platform:ios",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, _, _, opts := fake.SetUserArgsForCall(0)
+				g.Expect(api.NewOptions(opts...).Attributes).To(Equal(map[string]string{
+					"country":  "KR",
+					"platform": "ios",
+				}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "set users: invalid attribute",
+			httpMethod:         http.MethodPut,
+			path:               "/users/abc?score=300&attribute=KR",
+			expectedStatusCode: http.StatusBadRequest,
 		},
 		{
 			description:        "set users: empty user id",
@@ -157,9 +206,10 @@ func TestHttpHandler(t *testing.T) {
 					nil)
 			},
 			after: func(fake *apifakes.FakeLeaderBoard) {
-				_, rank, count := fake.GetRanksArgsForCall(0)
+				_, rank, count, opts := fake.GetRanksArgsForCall(0)
 				g.Expect(rank).To(Equal(10))
 				g.Expect(count).To(Equal(20))
+				g.Expect(opts).To(BeEmpty())
 			},
 			expectedStatusCode: http.StatusOK,
 			data:               &[]api.User{},
@@ -169,6 +219,32 @@ func TestHttpHandler(t *testing.T) {
 				{Id: "a12", Score: 107, Rank: 12},
 			},
 		},
+		{
+			description: "get ranks from snapshot",
+			httpMethod:  http.MethodGet,
+			path:        "/ranks?rank=1&count=10&snapshot=s1",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, _, _, opts := fake.GetRanksArgsForCall(0)
+				g.Expect(api.NewOptions(opts...)).To(Equal(api.Options{SnapshotId: "s1"}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "get ranks in segment",
+			httpMethod:  http.MethodGet,
+			path:        "/ranks?rank=1&count=10&segment=country:KR",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, _, _, opts := fake.GetRanksArgsForCall(0)
+				g.Expect(api.NewOptions(opts...).Segment).To(Equal(api.Segment{Attribute: "country", Value: "KR"}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "get ranks: invalid segment",
+			httpMethod:         http.MethodGet,
+			path:               "/ranks?rank=1&count=10&segment=KR",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			description:        "get ranks: empty rank",
 			httpMethod:         http.MethodGet,
@@ -194,6 +270,315 @@ func TestHttpHandler(t *testing.T) {
 			data:               &MessageData{},
 			expectedData:       &MessageData{"xyz message"},
 		},
+
+		{
+			description: "create snapshot",
+			httpMethod:  http.MethodPost,
+			path:        "/snapshots?id=friday",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.CreateSnapshotReturns(api.Snapshot{Id: "friday", UserCount: 3, CreatedAt: now}, nil)
+			},
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, snapshotId := fake.CreateSnapshotArgsForCall(0)
+				g.Expect(snapshotId).To(Equal("friday"))
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &api.Snapshot{},
+			expectedData:       &api.Snapshot{Id: "friday", UserCount: 3, CreatedAt: now},
+		},
+		{
+			description: "create snapshot: conflict",
+			httpMethod:  http.MethodPost,
+			path:        "/snapshots?id=friday",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.CreateSnapshotReturns(
+					api.Snapshot{},
+					api.ErrorWithStatusCode(errors.New("snapshot already exists"), http.StatusConflict))
+			},
+			expectedStatusCode: http.StatusConflict,
+			data:               &MessageData{},
+			expectedData:       &MessageData{"snapshot already exists"},
+		},
+		{
+			description: "delete snapshot",
+			httpMethod:  http.MethodDelete,
+			path:        "/snapshots/friday",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, snapshotId := fake.DeleteSnapshotArgsForCall(0)
+				g.Expect(snapshotId).To(Equal("friday"))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "admin get users",
+			httpMethod:  http.MethodGet,
+			path:        "/admin/users/abc",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.GetUserReturns(api.User{Id: "abc", Score: 100, Rank: 5, State: api.UserStateHidden}, nil)
+			},
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId, opts := fake.GetUserArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+				g.Expect(api.NewOptions(opts...).IncludeHidden).To(BeTrue())
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &api.User{},
+			expectedData:       &api.User{Id: "abc", Score: 100, Rank: 5, State: api.UserStateHidden},
+		},
+		{
+			description: "admin set user state",
+			httpMethod:  http.MethodPut,
+			path:        "/admin/users/abc/state?state=shadow_banned",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId, state := fake.SetUserStateArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+				g.Expect(state).To(Equal(api.UserStateShadowBanned))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "admin set user state: invalid state",
+			httpMethod:         http.MethodPut,
+			path:               "/admin/users/abc/state?state=deleted",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description: "get users with viewer",
+			httpMethod:  http.MethodGet,
+			path:        "/users/abc?viewer=abc",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, _, opts := fake.GetUserArgsForCall(0)
+				g.Expect(api.NewOptions(opts...).Viewer).To(Equal("abc"))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "put users: rejected by validation rules",
+			httpMethod:  http.MethodPut,
+			path:        "/users/abc?score=999999999",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.SetUserReturns(api.NewRejectionError(api.RejectionScoreTooHigh, "score is greater than 1000"))
+			},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			data:               &RejectionData{},
+			expectedData: &RejectionData{
+				Message: "score is greater than 1000",
+				Reason:  "score_too_high",
+			},
+		},
+		{
+			description: "admin get rejections",
+			httpMethod:  http.MethodGet,
+			path:        "/admin/rejections",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.GetRejectionsReturns([]api.Rejection{{UserId: "abc", Score: -1, Reason: api.RejectionScoreTooLow}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &[]api.Rejection{},
+			expectedData:       &[]api.Rejection{{UserId: "abc", Score: -1, Reason: api.RejectionScoreTooLow}},
+		},
+		{
+			description: "admin add review",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/reviews?user=abc&score=100&previous_score=10&action=quarantine&reason=r1&reason=r2",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, review := fake.AddReviewArgsForCall(0)
+				g.Expect(review).To(Equal(api.Review{
+					UserId:        "abc",
+					Score:         100,
+					PreviousScore: 10,
+					Action:        api.ReviewActionQuarantine,
+					Reasons:       []string{"r1", "r2"},
+				}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "admin add review: invalid action",
+			httpMethod:         http.MethodPost,
+			path:               "/admin/reviews?user=abc&score=100&previous_score=10&action=ban",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description: "admin approve review",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/reviews/r1/approve?actor=admin",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, reviewId, approve, audit := fake.ResolveReviewArgsForCall(0)
+				g.Expect(reviewId).To(Equal("r1"))
+				g.Expect(approve).To(BeTrue())
+				g.Expect(audit.Actor).To(Equal("admin"))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "admin reject review",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/reviews/r1/reject?actor=admin",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, _, approve, _ := fake.ResolveReviewArgsForCall(0)
+				g.Expect(approve).To(BeFalse())
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "put users with idempotency key",
+			httpMethod:  http.MethodPut,
+			path:        "/users/abc?score=10",
+			header:      http.Header{api.IdempotencyKeyHeader: []string{"key1"}},
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, _, _, opts := fake.SetUserArgsForCall(0)
+				g.Expect(api.NewOptions(opts...).IdempotencyKey).To(Equal("key1"))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "admin override score",
+			httpMethod:  http.MethodPut,
+			path:        "/admin/users/abc/score?score=10&actor=admin&reason=cheating",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId, score, audit := fake.OverrideScoreArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+				g.Expect(score).To(Equal(10))
+				g.Expect(audit).To(Equal(api.Audit{Actor: "admin", Reason: "cheating"}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "admin override score: no actor",
+			httpMethod:         http.MethodPut,
+			path:               "/admin/users/abc/score?score=10",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description: "admin revert score",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/users/abc/revert?actor=admin",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId, audit := fake.RevertScoreArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+				g.Expect(audit.Actor).To(Equal("admin"))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "admin get history",
+			httpMethod:  http.MethodGet,
+			path:        "/admin/users/abc/history",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId := fake.GetHistoryArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "admin export user",
+			httpMethod:  http.MethodGet,
+			path:        "/admin/users/abc/export",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.ExportUserReturns(api.UserExport{UserId: "abc", ExportedAt: now}, nil)
+			},
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId := fake.ExportUserArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &api.UserExport{},
+			expectedData:       &api.UserExport{UserId: "abc", ExportedAt: now},
+		},
+		{
+			description: "admin erase user",
+			httpMethod:  http.MethodDelete,
+			path:        "/admin/users/abc?actor=admin&reason=request",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.EraseUserReturns(api.ErasureReport{UserId: "abc", Erased: []string{"user"}, Verified: true}, nil)
+			},
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId, audit := fake.EraseUserArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+				g.Expect(audit).To(Equal(api.Audit{Actor: "admin", Reason: "request"}))
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &api.ErasureReport{},
+			expectedData:       &api.ErasureReport{UserId: "abc", Erased: []string{"user"}, Verified: true},
+		},
+		{
+			description:        "admin erase user: no actor",
+			httpMethod:         http.MethodDelete,
+			path:               "/admin/users/abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description: "admin add webhook",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/webhooks?url=http%3A%2F%2Fpartner.xx%2Fhook&event=entered_top&event=overtaken",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.AddWebhookReturns(api.Webhook{Id: "w1", URL: "http://partner.xx/hook", Secret: "secret"}, nil)
+			},
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, webhook := fake.AddWebhookArgsForCall(0)
+				g.Expect(webhook).To(Equal(api.Webhook{
+					URL:    "http://partner.xx/hook",
+					Events: []api.EventType{api.EventEnteredTop, api.EventOvertaken},
+				}))
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &api.Webhook{},
+			expectedData:       &api.Webhook{Id: "w1", URL: "http://partner.xx/hook", Secret: "secret"},
+		},
+		{
+			description: "admin add webhook: secret in body",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/webhooks?url=http%3A%2F%2Fpartner.xx%2Fhook",
+			header:      http.Header{"Content-Type": {"application/json"}},
+			body:        `{"secret":"s1"}`,
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, webhook := fake.AddWebhookArgsForCall(0)
+				g.Expect(webhook).To(Equal(api.Webhook{URL: "http://partner.xx/hook", Secret: "s1"}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "admin add webhook: secret in query string",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/webhooks?url=http%3A%2F%2Fpartner.xx%2Fhook&secret=s1",
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				g.Expect(fake.AddWebhookCallCount()).To(Equal(0))
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			data:               &MessageData{},
+			expectedData:       &MessageData{Message: "secret must be sent in the request body"},
+		},
+		{
+			description: "admin get webhook dead letters: not found",
+			httpMethod:  http.MethodGet,
+			path:        "/admin/webhooks/w1/deadletters",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.GetDeadLettersReturns(nil, api.ErrorWithStatusCode(errors.New("webhook not found"), http.StatusNotFound))
+			},
+			expectedStatusCode: http.StatusNotFound,
+			data:               &MessageData{},
+			expectedData:       &MessageData{Message: "webhook not found"},
+		},
+		{
+			description: "admin expire inactive users",
+			httpMethod:  http.MethodPost,
+			path:        "/admin/expire",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.ExpireInactiveReturns(3, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &ExpiredData{},
+			expectedData:       &ExpiredData{Expired: 3},
+		},
+		{
+			description:        "user events: change feed is disabled",
+			httpMethod:         http.MethodGet,
+			path:               "/users/user1/events",
+			expectedStatusCode: http.StatusConflict,
+			data:               &MessageData{},
+			expectedData:       &MessageData{Message: "change feed is disabled"},
+		},
 	}
 
 	for _, testData := range testDataList {
@@ -208,9 +593,13 @@ func TestHttpHandler(t *testing.T) {
 		rw := httptest.NewRecorder()
 
 		urlPrefix := "http://leaderboard.xx"
-		req, err := http.NewRequest(testData.httpMethod, urlPrefix+testData.path, nil)
+		req, err := http.NewRequest(testData.httpMethod, urlPrefix+testData.path, strings.NewReader(testData.body))
 		g.Expect(err).NotTo(HaveOccurred())
 
+		for k, v := range testData.header {
+			req.Header[k] = v
+		}
+
 		e.ServeHTTP(rw, req)
 
 		resp := rw.Result()
@@ -233,3 +622,46 @@ func TestHttpHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestHttpHandler_Deadline(t *testing.T) {
+	g := NewWithT(t)
+
+	fake := &apifakes.FakeLeaderBoard{}
+	// 저장소 작업처럼 context가 끝날 때까지 기다린다
+	fake.GetRanksStub = func(ctx context.Context, rank, count int, opts ...api.Option) ([]api.User, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	fake.UserCountStub = func(ctx context.Context, opts ...api.Option) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+
+	handler := http_handler.New(fake)
+	handler.RequestTimeout = time.Hour
+	handler.OperationTimeouts = map[string]time.Duration{"GET /ranks": 10 * time.Millisecond}
+
+	e := echo.New()
+	handler.Setup(e)
+
+	// 작업별 시간 제한을 넘으면 504
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ranks?rank=1&count=10", nil)
+	e.ServeHTTP(rw, req)
+	g.Expect(rw.Code).To(Equal(http.StatusGatewayTimeout))
+
+	ctx, _, _, _ := fake.GetRanksArgsForCall(0)
+	g.Expect(ctx.Err()).To(Equal(context.DeadlineExceeded))
+
+	// 클라이언트가 요청을 취소하면 499
+	reqCtx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	rw = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/usercount", nil).WithContext(reqCtx)
+	e.ServeHTTP(rw, req)
+	g.Expect(rw.Code).To(Equal(http_handler.StatusClientClosedRequest))
+
+	ctx, _ = fake.UserCountArgsForCall(0)
+	g.Expect(ctx.Err()).To(Equal(context.Canceled))
+}
@@ -0,0 +1,132 @@
+package http_handler
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed openapi.json
+var openapiJson []byte
+
+//go:embed docs.html
+var docsHtml []byte
+
+var spec = mustLoadSpec()
+
+func mustLoadSpec() *openapi.Document {
+	doc, err := openapi.Load(openapiJson)
+	if err != nil {
+		panic("openapi.json: " + err.Error())
+	}
+	return doc
+}
+
+// Spec은 route들을 설명하는 OpenAPI 문서를 반환한다
+func Spec() *openapi.Document {
+	return spec
+}
+
+// SpecPath는 echo의 route path("/v1/users/:id")를 OpenAPI 문서의 path("/v1/users/{id}")로 바꾼다
+func SpecPath(routePath string) string {
+	segments := strings.Split(routePath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Validate는 요청이 OpenAPI 문서에 맞지 않으면 거절한다.
+// 응답할 수 있는 media type을 Accept가 허용하지 않으면 406, body가 JSON이 아니면 415, parameter나 body가 schema에 맞지 않으면 400으로 응답한다.
+// 문서에 없는 route는 그대로 처리한다.
+// /v1 route에만 사용하고, /v1 이전의 route들은 문서에 없으므로 검증하지 않는다.
+func Validate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+
+		operation, ok := spec.Operation(req.Method, SpecPath(c.Path()))
+		if !ok {
+			return next(c)
+		}
+
+		if mediaTypes := spec.ResponseTypes(operation); len(mediaTypes) > 0 && !accepts(req.Header.Get(echo.HeaderAccept), mediaTypes) {
+			message := "only " + strings.Join(mediaTypes, ", ") + " responses are available"
+			return ErrorJson(c, api.ErrorWithStatusCode(errors.New(message), http.StatusNotAcceptable))
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+		if err != nil {
+			return badRequest(c, "failed to read request body")
+		}
+		if len(body) > maxBodySize {
+			return ErrorJson(c, api.ErrorWithStatusCode(errors.New("request body is too large"), http.StatusRequestEntityTooLarge))
+		}
+		// handler가 다시 읽을 수 있게 한다
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		pathParams := map[string]string{}
+		for _, name := range c.ParamNames() {
+			value, err := pathParam(c, name)
+			if err != nil {
+				return badRequest(c, err.Error())
+			}
+			pathParams[name] = value
+		}
+
+		if err := spec.ValidateRequest(operation, req, pathParams, body); err != nil {
+			return ErrorJson(c, err)
+		}
+
+		return next(c)
+	}
+}
+
+// accepts는 Accept header가 mediaTypes 중 하나를 허용하는지 반환한다
+func accepts(accept string, mediaTypes []string) bool {
+	if accept == "" {
+		return true
+	}
+
+	for _, item := range strings.Split(accept, ",") {
+		accepted, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		if q, ok := params["q"]; ok {
+			if value, err := strconv.ParseFloat(q, 64); err != nil || value <= 0 {
+				continue
+			}
+		}
+
+		for _, mediaType := range mediaTypes {
+			switch {
+			case accepted == "*/*", accepted == mediaType:
+				return true
+			case strings.HasSuffix(accepted, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(accepted, "*")):
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (handler *HttpHandler) HandleGetOpenAPI(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, openapiJson)
+}
+
+func (handler *HttpHandler) HandleGetDocs(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, docsHtml)
+}
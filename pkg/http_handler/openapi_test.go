@@ -0,0 +1,304 @@
+package http_handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/api/apifakes"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/openapi"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/gomega"
+)
+
+// http_server가 등록하는 route
+var serverRoutes = map[string]bool{"GET /v1/ranks/stream": true, "GET /metrics": true}
+
+// /v1 이전의 route가 아닌, 버전이 없는 route
+var unversionedPaths = map[string]bool{"/openapi.json": true, "/docs": true, "/healthz": true, "/readyz": true}
+
+func TestSpec_Routes(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := http_handler.Spec()
+
+	e := echo.New()
+	http_handler.New(&apifakes.FakeLeaderBoard{}).Setup(e)
+
+	registered := map[string]bool{}
+	for _, route := range e.Routes() {
+		// echo가 group에 등록하는 not found route
+		if strings.HasSuffix(route.Path, "/*") || route.Path == "/v1" {
+			continue
+		}
+
+		path := http_handler.SpecPath(route.Path)
+		if !strings.HasPrefix(path, "/v1/") && !unversionedPaths[path] {
+			// /v1 이전의 route는 문서에 없고 검증하지도 않는다. 대신 사용할 /v1 route가 있어야 한다.
+			_, ok := spec.Operation(route.Method, path)
+			g.Expect(ok).To(BeFalse(), "%s %s is a route before /v1", route.Method, route.Path)
+
+			_, ok = spec.Operation(route.Method, "/v1"+path)
+			g.Expect(ok).To(BeTrue(), "%s %s has no /v1 route", route.Method, route.Path)
+			continue
+		}
+
+		registered[route.Method+" "+path] = true
+
+		_, ok := spec.Operation(route.Method, path)
+		g.Expect(ok).To(BeTrue(), "%s %s is missing from openapi.json", route.Method, route.Path)
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			key := strings.ToUpper(method) + " " + path
+			g.Expect(registered[key] || serverRoutes[key]).To(BeTrue(), "%s is not registered", key)
+		}
+	}
+}
+
+func TestSpec_Schemas(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := http_handler.Spec()
+
+	types := map[string]interface{}{
+		"User":            api.User{},
+		"Snapshot":        api.Snapshot{},
+		"HistoryEntry":    api.HistoryEntry{},
+		"Rejection":       api.Rejection{},
+		"Review":          api.Review{},
+		"Membership":      api.Membership{},
+		"UserExport":      api.UserExport{},
+		"ErasureReport":   api.ErasureReport{},
+		"Event":           api.Event{},
+		"Webhook":         api.Webhook{},
+		"WebhookDelivery": api.WebhookDelivery{},
+		"RankUpdate":      api.RankUpdate{},
+		"APIKey":          apikey.Key{},
+		"BoardVersion":    api.BoardVersion{},
+	}
+
+	for name, v := range types {
+		schema, ok := spec.Schema(name)
+		g.Expect(ok).To(BeTrue(), name)
+
+		var properties, required []string
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			tag := strings.Split(typ.Field(i).Tag.Get("json"), ",")
+			properties = append(properties, tag[0])
+			// omitempty가 아니면 항상 있다
+			if len(tag) == 1 {
+				required = append(required, tag[0])
+			}
+		}
+
+		var schemaProperties []string
+		for property := range schema.Properties {
+			schemaProperties = append(schemaProperties, property)
+		}
+
+		g.Expect(schemaProperties).To(ConsistOf(properties), "properties of %s", name)
+
+		schemaRequired := append([]string{}, schema.Required...)
+		sort.Strings(schemaRequired)
+		sort.Strings(required)
+		g.Expect(schemaRequired).To(Equal(required), "required properties of %s", name)
+	}
+}
+
+func TestSpec_Responses(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := http_handler.Spec()
+	now := time.Now().UTC()
+
+	user := api.User{
+		Id:           "abc",
+		Score:        100,
+		Rank:         5,
+		UpdatedAt:    now,
+		PreviousRank: 7,
+		RankDelta:    2,
+		SegmentRank:  1,
+		Attributes:   map[string]string{"country": "KR"},
+		State:        api.UserStateHidden,
+	}
+
+	fake := &apifakes.FakeLeaderBoard{}
+	fake.UserCountReturns(10, nil)
+	fake.GetUserReturns(user, nil)
+	fake.GetRanksReturns([]api.User{user, {Id: "def", Score: 90, Rank: 6, UpdatedAt: now}}, nil)
+	fake.GetSnapshotsReturns([]api.Snapshot{{Id: "s1", UserCount: 3, CreatedAt: now}}, nil)
+	fake.ExportUserReturns(api.UserExport{UserId: "abc", ExportedAt: now}, nil)
+	fake.EraseUserReturns(api.ErasureReport{UserId: "abc", Actor: "admin", ErasedAt: now, Erased: []string{"user"}, Verified: true}, nil)
+	fake.GetHistoryReturns([]api.HistoryEntry{{Kind: api.HistoryKindOverride, Score: 1, Actor: "admin", CreatedAt: now}}, nil)
+	fake.GetRejectionsReturns([]api.Rejection{{UserId: "abc", Reason: api.RejectionScoreTooHigh, CreatedAt: now}}, nil)
+	fake.GetReviewsReturns([]api.Review{{Id: "r1", UserId: "abc", Action: api.ReviewActionFlag, CreatedAt: now}}, nil)
+	fake.AddWebhookReturns(api.Webhook{Id: "w1", URL: "http://example.com", Secret: "secret", CreatedAt: now}, nil)
+	fake.GetWebhookDeliveriesReturns([]api.WebhookDelivery{{
+		WebhookId:   "w1",
+		Event:       api.Event{Seq: 1, Type: api.EventScoreChanged, UserId: "abc", Score: 1, PreviousScore: 2, CreatedAt: now},
+		Status:      api.DeliveryStatusFailed,
+		Attempts:    3,
+		StatusCode:  500,
+		Error:       "server error",
+		CompletedAt: now,
+	}}, nil)
+
+	handler := http_handler.New(fake)
+	handler.Keys = &apikey.Store{
+		Storage: &storage.MemStorage{},
+		Static:  map[string]apikey.Key{apikey.Hash("admin"): {Id: "admin", Scopes: []apikey.Scope{apikey.ScopeAdmin}}},
+	}
+
+	e := echo.New()
+	handler.Setup(e)
+
+	testDataList := []struct {
+		httpMethod, path, route, body string
+		expectedStatusCode            int
+	}{
+		{http.MethodGet, "/v1/usercount", "/v1/usercount", "", http.StatusOK},
+		{http.MethodGet, "/v1/users/abc", "/v1/users/{id}", "", http.StatusOK},
+		{http.MethodPut, "/v1/users/abc", "/v1/users/{id}", `{"score": 100}`, http.StatusOK},
+		{http.MethodPut, "/v1/users/abc", "/v1/users/{id}", `{"score": "100"}`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/ranks?rank=1&count=2", "/v1/ranks", "", http.StatusOK},
+		{http.MethodGet, "/v1/ranks?rank=x&count=2", "/v1/ranks", "", http.StatusBadRequest},
+		{http.MethodGet, "/v1/snapshots", "/v1/snapshots", "", http.StatusOK},
+		{http.MethodGet, "/v1/version", "/v1/version", "", http.StatusOK},
+		{http.MethodGet, "/v1/admin/users/abc/export", "/v1/admin/users/{id}/export", "", http.StatusOK},
+		{http.MethodDelete, "/v1/admin/users/abc", "/v1/admin/users/{id}", `{"actor": "admin"}`, http.StatusOK},
+		{http.MethodGet, "/v1/admin/users/abc/history", "/v1/admin/users/{id}/history", "", http.StatusOK},
+		{http.MethodGet, "/v1/admin/rejections", "/v1/admin/rejections", "", http.StatusOK},
+		{http.MethodGet, "/v1/admin/reviews", "/v1/admin/reviews", "", http.StatusOK},
+		{http.MethodPost, "/v1/admin/reviews/r1/approve", "/v1/admin/reviews/{id}/approve", `{"actor": "admin"}`, http.StatusOK},
+		{http.MethodPost, "/v1/admin/webhooks", "/v1/admin/webhooks", `{"url": "http://example.com"}`, http.StatusOK},
+		{http.MethodGet, "/v1/admin/webhooks/w1/deliveries", "/v1/admin/webhooks/{id}/deliveries", "", http.StatusOK},
+		{http.MethodPost, "/v1/admin/keys", "/v1/admin/keys", `{"name": "game server", "scopes": ["write"], "boards": ["main"]}`, http.StatusOK},
+		{http.MethodPost, "/v1/admin/keys", "/v1/admin/keys", `{"scopes": ["root"]}`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/admin/keys", "/v1/admin/keys", "", http.StatusOK},
+		{http.MethodDelete, "/v1/admin/keys/unknown", "/v1/admin/keys/{id}", "", http.StatusNotFound},
+		{http.MethodGet, "/openapi.json", "/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/docs", "/docs", "", http.StatusOK},
+	}
+
+	for _, testData := range testDataList {
+		req := httptest.NewRequest(testData.httpMethod, testData.path, strings.NewReader(testData.body))
+		req.Header.Set(apikey.Header, "admin")
+		if testData.body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+
+		resp := rw.Result()
+		body, err := io.ReadAll(resp.Body)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Expect(resp.StatusCode).To(Equal(testData.expectedStatusCode),
+			"%s %s: body=%s", testData.httpMethod, testData.path, string(body))
+
+		operation, ok := spec.Operation(testData.httpMethod, testData.route)
+		g.Expect(ok).To(BeTrue(), testData.route)
+
+		err = spec.ValidateResponse(operation, resp.StatusCode, resp.Header.Get(echo.HeaderContentType), body)
+		g.Expect(err).NotTo(HaveOccurred(), "%s %s: body=%s", testData.httpMethod, testData.path, string(body))
+	}
+
+	// 순위표가 바뀌지 않았으면 body 없이 304로 응답한다
+	for route, path := range map[string]string{
+		"/v1/usercount":  "/v1/usercount",
+		"/v1/users/{id}": "/v1/users/abc",
+		"/v1/ranks":      "/v1/ranks?rank=1&count=2",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(apikey.Header, "admin")
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+		g.Expect(rw.Code).To(Equal(http.StatusOK), path)
+
+		req.Header.Set("If-None-Match", rw.Header().Get("ETag"))
+
+		rw = httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+		g.Expect(rw.Code).To(Equal(http.StatusNotModified), path)
+
+		operation, ok := spec.Operation(http.MethodGet, route)
+		g.Expect(ok).To(BeTrue(), route)
+
+		err := spec.ValidateResponse(operation, rw.Code, rw.Header().Get(echo.HeaderContentType), rw.Body.Bytes())
+		g.Expect(err).NotTo(HaveOccurred(), path)
+	}
+}
+
+func TestSpec_ContentNegotiation(t *testing.T) {
+	g := NewWithT(t)
+
+	fake := &apifakes.FakeLeaderBoard{}
+
+	e := echo.New()
+	handler := http_handler.New(fake)
+	handler.Setup(e)
+
+	// SSE route는 text/event-stream을 요청할 수 있다
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/abc/events", nil)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	rw := httptest.NewRecorder()
+	e.ServeHTTP(rw, req)
+	g.Expect(rw.Code).To(Equal(http.StatusConflict))
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/users/abc", nil)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	rw = httptest.NewRecorder()
+	e.ServeHTTP(rw, req)
+	g.Expect(rw.Code).To(Equal(http.StatusNotAcceptable))
+	g.Expect(fake.GetUserCallCount()).To(Equal(0))
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/users/abc", nil)
+	req.Header.Set(echo.HeaderAccept, "application/*")
+	rw = httptest.NewRecorder()
+	e.ServeHTTP(rw, req)
+	g.Expect(rw.Code).To(Equal(http.StatusOK))
+}
+
+func TestSpec_Security(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := http_handler.Spec()
+
+	scheme, ok := spec.Components.SecuritySchemes["ApiKey"]
+	g.Expect(ok).To(BeTrue())
+	g.Expect(scheme.In).To(Equal("header"))
+	g.Expect(scheme.Name).To(Equal(apikey.Header))
+	bearer, ok := spec.Components.SecuritySchemes["BearerAuth"]
+	g.Expect(ok).To(BeTrue())
+	g.Expect(bearer.Scheme).To(Equal("bearer"))
+
+	// 둘 중 하나로 인증한다
+	g.Expect(spec.Security).To(Equal([]openapi.SecurityRequirement{{"ApiKey": {}}, {"BearerAuth": {}}}))
+
+	// 버전이 없는 route와 /metrics는 인증 없이 사용할 수 있고, 나머지는 모두 인증한다
+	for path, operations := range spec.Paths {
+		for method, operation := range operations {
+			if unversionedPaths[path] || path == "/metrics" {
+				g.Expect(operation.Security).NotTo(BeNil(), "%s %s", method, path)
+				g.Expect(operation.Security).To(BeEmpty(), "%s %s", method, path)
+			} else {
+				g.Expect(operation.Security).To(BeNil(), "%s %s", method, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package http_handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DefaultHeartbeatInterval = 15 * time.Second
+	// http_server의 WriteTimeout(120s)보다 짧아야 한다
+	DefaultMaxStreamDuration = 100 * time.Second
+	DefaultMaxEventStreams   = 1000
+
+	// 연결이 끊긴 뒤 클라이언트가 다시 연결할 때까지 기다리는 시간(ms)
+	streamRetryMillis = 1000
+	// 연결 하나에 쌓일 수 있는 이벤트 수. 넘치면 느린 연결로 보고 끊는다.
+	userEventBuffer = 100
+)
+
+// EventSource는 SetUser 등이 기록하는 변경 이벤트를 읽는다. leaderboard.LeaderBoard가 구현한다.
+type EventSource interface {
+	LastEventSeq(ctx context.Context) (int64, error)
+	Subscribe(ctx context.Context, afterSeq int64, handler func(api.Event) error) error
+}
+
+// eventFeed는 연결들이 함께 받는 Subscribe 하나
+type eventFeed struct {
+	cancel      context.CancelFunc
+	subscribers map[chan api.Event]struct{}
+}
+
+// eventHub는 변경 이벤트를 Subscribe 하나로 읽어서 모든 SSE 연결에 나눠준다.
+// 첫 연결이 구독을 시작하고, 마지막 연결이 끊기면 구독을 멈춘다.
+type eventHub struct {
+	mutex       sync.Mutex
+	feed        *eventFeed
+	connections int
+}
+
+type eventSubscription struct {
+	feed   *eventFeed
+	events chan api.Event
+}
+
+// subscribe는 연결을 구독자로 등록한다. 구독 중이 아니면 afterSeq 다음 이벤트부터 구독을 시작한다.
+// 구독이 오류로 멈추거나 연결이 이벤트를 제때 읽지 않으면 events가 닫힌다.
+func (hub *eventHub) subscribe(source EventSource, afterSeq int64, maxConnections int) (*eventSubscription, bool) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.connections >= maxConnections {
+		return nil, false
+	}
+	hub.connections++
+
+	if hub.feed == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		hub.feed = &eventFeed{
+			cancel:      cancel,
+			subscribers: map[chan api.Event]struct{}{},
+		}
+		go hub.run(ctx, source, afterSeq, hub.feed)
+	}
+
+	sub := &eventSubscription{
+		feed:   hub.feed,
+		events: make(chan api.Event, userEventBuffer),
+	}
+	sub.feed.subscribers[sub.events] = struct{}{}
+	return sub, true
+}
+
+func (hub *eventHub) unsubscribe(sub *eventSubscription) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.connections--
+	hub.remove(sub.feed, sub.events)
+
+	if len(sub.feed.subscribers) == 0 && hub.feed == sub.feed {
+		hub.feed = nil
+		sub.feed.cancel()
+	}
+}
+
+// remove는 구독자 목록에서 events를 빼고 닫는다. mutex를 잡고 호출해야 한다.
+func (hub *eventHub) remove(feed *eventFeed, events chan api.Event) {
+	if _, ok := feed.subscribers[events]; !ok {
+		return
+	}
+
+	delete(feed.subscribers, events)
+	close(events)
+}
+
+func (hub *eventHub) run(ctx context.Context, source EventSource, afterSeq int64, feed *eventFeed) {
+	source.Subscribe(ctx, afterSeq, func(event api.Event) error {
+		hub.mutex.Lock()
+		defer hub.mutex.Unlock()
+
+		for events := range feed.subscribers {
+			select {
+			case events <- event:
+			default:
+				hub.remove(feed, events)
+			}
+		}
+		return nil
+	})
+
+	// 구독이 멈추면 연결들을 끝내서 클라이언트가 Last-Event-ID로 다시 연결하게 한다
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.feed == feed {
+		hub.feed = nil
+	}
+	for events := range feed.subscribers {
+		hub.remove(feed, events)
+	}
+}
+
+// HandleGetUserEvents는 사용자의 점수나 순위가 바뀔 때마다 SSE로 보낸다.
+// 이벤트 id는 변경 이벤트의 순번이므로, 다시 연결할 때 Last-Event-ID로 놓친 변경부터 이어서 받는다.
+// 연결하면 먼저 현재 상태를 보내고, 사용자가 삭제되면 deleted 이벤트를 보내고 끝낸다.
+// 변경 이벤트를 읽지 못하게 되면 연결을 끝낸다.
+func (handler *HttpHandler) HandleGetUserEvents(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if handler.Events == nil {
+		return ErrorJson(c, api.ErrorWithStatusCode(errors.New("change feed is disabled"), http.StatusConflict))
+	}
+
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	lastEventId := c.Request().Header.Get("Last-Event-ID")
+
+	var seq int64
+	if lastEventId != "" {
+		seq, err = strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil || seq < 0 {
+			return badRequest(c, "Last-Event-ID is invalid format")
+		}
+	}
+
+	head, err := handler.Events.LastEventSeq(ctx)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+	if lastEventId == "" {
+		seq = head
+	}
+
+	// 사용자를 읽기 전에 구독해야 그 사이의 변경을 놓치지 않는다
+	sub, ok := handler.userEvents.subscribe(handler.Events, head, handler.MaxEventStreams)
+	if !ok {
+		return ErrorJson(c, api.ErrorWithStatusCode(errors.New("too many connections"), http.StatusServiceUnavailable))
+	}
+	defer handler.userEvents.unsubscribe(sub)
+
+	// GET /users/:id와 같이 shadow ban된 사용자 자신에게는 자신의 순위를 보낸다
+	viewer := api.WithViewer(handler.viewer(c))
+
+	user, err := handler.lb.GetUser(ctx, userId, viewer)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, handler.MaxStreamDuration)
+	defer cancel()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	// nginx가 응답을 모아서 보내지 않게 한다
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", streamRetryMillis); err != nil {
+		return nil
+	}
+	if err := writeUserEvent(res, seq, "rank", user); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(handler.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+
+		case event, ok := <-sub.events:
+			if !ok {
+				return nil
+			}
+
+			// 밀려 있는 이벤트들은 한 번에 반영한다
+			changed := false
+			for pending := true; pending; {
+				// 구독을 시작하기 전의 이벤트는 이미 현재 상태에 반영되어 있다
+				if event.Seq > seq {
+					changed = changed || event.Seq != seq+1 || affectsUser(event, user)
+					seq = event.Seq
+				}
+
+				select {
+				case event, ok = <-sub.events:
+					if !ok {
+						return nil
+					}
+				default:
+					pending = false
+				}
+			}
+
+			if !changed {
+				continue
+			}
+
+			current, err := handler.lb.GetUser(ctx, userId, viewer)
+			if isNotFound(err) {
+				writeUserEvent(res, seq, "deleted", api.User{Id: userId})
+				return nil
+			}
+			if err != nil {
+				return nil
+			}
+
+			if current.Score == user.Score && current.Rank == user.Rank {
+				continue
+			}
+			user = current
+
+			if err := writeUserEvent(res, seq, "rank", user); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func writeUserEvent(res *echo.Response, seq int64, eventName string, user api.User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", seq, eventName, data); err != nil {
+		return err
+	}
+
+	res.Flush()
+	return nil
+}
+
+// affectsUser는 event로 user의 점수나 순위가 바뀔 수 있는지 반환한다.
+// 동점이면 순위가 id 순서로 정해지므로 같은 점수도 포함한다.
+func affectsUser(event api.Event, user api.User) bool {
+	if event.UserId == user.Id {
+		return true
+	}
+
+	switch event.Type {
+	case api.EventUserAdded, api.EventUserDeleted:
+		return event.Score >= user.Score
+	case api.EventScoreChanged:
+		low, high := event.PreviousScore, event.Score
+		if low > high {
+			low, high = high, low
+		}
+		return low <= user.Score && user.Score <= high
+	}
+
+	return false
+}
+
+func isNotFound(err error) bool {
+	var statusErr interface{ StatusCode() int }
+	return errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusNotFound
+}
@@ -0,0 +1,312 @@
+package http_handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// v1 route인지 표시하는 echo.Context key
+	apiVersionKey = "api_version"
+
+	// 요청 body의 최대 크기
+	maxBodySize = 1 << 20
+)
+
+// v1 응답은 성공하면 data, 실패하면 error에 담는다
+type dataEnvelope struct {
+	Data interface{} `json:"data"`
+}
+
+type errorEnvelope struct {
+	Error errorData `json:"error"`
+}
+
+func isV1(c echo.Context) bool {
+	return c.Get(apiVersionKey) == "v1"
+}
+
+// V1은 /v1 route를 표시한다. /v1 route의 응답은 envelope에 담는다.
+func (handler *HttpHandler) V1(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(apiVersionKey, "v1")
+		return next(c)
+	}
+}
+
+// Deprecated는 /v1 이전의 route에 Deprecation header와 대신 사용할 /v1 route를 알려준다
+func Deprecated(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Response().Header()
+		header.Set("Deprecation", "true")
+		header.Set("Link", "</v1"+c.Request().URL.EscapedPath()+`>; rel="successor-version"`)
+		return next(c)
+	}
+}
+
+// bindJson은 요청 body의 JSON을 v로 읽는다. 모르는 필드가 있으면 거절하고, body가 비어있으면 v를 그대로 둔다.
+func bindJson(c echo.Context, v interface{}) error {
+	req := c.Request()
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+	if err != nil {
+		return api.ErrorWithStatusCode(errors.New("failed to read request body"), http.StatusBadRequest)
+	}
+	if len(body) > maxBodySize {
+		return api.ErrorWithStatusCode(errors.New("request body is too large"), http.StatusRequestEntityTooLarge)
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if err != nil || mediaType != echo.MIMEApplicationJSON {
+		return api.ErrorWithStatusCode(errors.New("request body must be application/json"), http.StatusUnsupportedMediaType)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return api.ErrorWithStatusCode(errors.New("invalid request body: "+err.Error()), http.StatusBadRequest)
+	}
+
+	if decoder.More() {
+		return api.ErrorWithStatusCode(errors.New("invalid request body: unexpected data after JSON value"), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (handler *HttpHandler) setupV1(g *echo.Group, deadline echo.MiddlewareFunc) {
+	g.GET("/usercount", handler.HandleGetUserCount, deadline, handler.conditional)
+	g.GET("/users/:id", handler.HandleGetUsers, deadline, handler.conditional)
+	g.PUT("/users/:id", handler.HandleV1PutUsers, deadline)
+	g.GET("/users/:id/events", handler.HandleGetUserEvents)
+	g.GET("/ranks", handler.HandleGetRanks, deadline, handler.conditional)
+	g.GET("/version", handler.HandleGetVersion, deadline)
+	g.GET("/snapshots", handler.HandleGetSnapshots, deadline)
+	g.POST("/snapshots", handler.HandleV1PostSnapshots, deadline)
+	g.DELETE("/snapshots/:id", handler.HandleDeleteSnapshots, deadline)
+
+	g.GET("/admin/users/:id", handler.HandleGetAdminUsers, deadline)
+	g.DELETE("/admin/users/:id", handler.HandleV1DeleteAdminUsers, deadline)
+	g.GET("/admin/users/:id/export", handler.HandleGetAdminUserExport, deadline)
+	g.PUT("/admin/users/:id/state", handler.HandleV1PutAdminUserState, deadline)
+	g.PUT("/admin/users/:id/score", handler.HandleV1PutAdminUserScore, deadline)
+	g.POST("/admin/users/:id/revert", handler.HandleV1PostAdminUserRevert, deadline)
+	g.GET("/admin/users/:id/history", handler.HandleGetAdminUserHistory, deadline)
+	g.GET("/admin/rejections", handler.HandleGetAdminRejections, deadline)
+	g.GET("/admin/reviews", handler.HandleGetAdminReviews, deadline)
+	g.POST("/admin/reviews", handler.HandleV1PostAdminReviews, deadline)
+	g.POST("/admin/reviews/:id/approve", handler.HandleV1PostAdminReviewApprove, deadline)
+	g.POST("/admin/reviews/:id/reject", handler.HandleV1PostAdminReviewReject, deadline)
+	g.POST("/admin/expire", handler.HandlePostAdminExpire, deadline)
+	g.GET("/admin/webhooks", handler.HandleGetAdminWebhooks, deadline)
+	g.POST("/admin/webhooks", handler.HandleV1PostAdminWebhooks, deadline)
+	g.DELETE("/admin/webhooks/:id", handler.HandleDeleteAdminWebhooks, deadline)
+	g.GET("/admin/webhooks/:id/deliveries", handler.HandleGetAdminWebhookDeliveries, deadline)
+	g.GET("/admin/webhooks/:id/deadletters", handler.HandleGetAdminWebhookDeadLetters, deadline)
+	g.GET("/admin/keys", handler.HandleGetAdminKeys, deadline)
+	g.POST("/admin/keys", handler.HandleV1PostAdminKeys, deadline)
+	g.DELETE("/admin/keys/:id", handler.HandleDeleteAdminKeys, deadline)
+}
+
+// setUserRequest는 PUT /v1/users/:id의 body
+type setUserRequest struct {
+	Score      *int              `json:"score"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (handler *HttpHandler) HandleV1PutUsers(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	body := setUserRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+	if body.Score == nil {
+		return badRequest(c, "score is empty")
+	}
+
+	var opts []api.Option
+	for name, value := range body.Attributes {
+		opts = append(opts, api.WithAttribute(name, value))
+	}
+
+	return handler.putUser(c, userId, *body.Score, opts)
+}
+
+// createSnapshotRequest는 POST /v1/snapshots의 body. body가 없으면 id를 만든다.
+type createSnapshotRequest struct {
+	Id string `json:"id,omitempty"`
+}
+
+func (handler *HttpHandler) HandleV1PostSnapshots(c echo.Context) error {
+	body := createSnapshotRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return handler.createSnapshot(c, body.Id)
+}
+
+// bindAudit은 body의 관리자 작업 기록을 읽는다
+func bindAudit(c echo.Context) (api.Audit, error) {
+	audit := api.Audit{}
+	if err := bindJson(c, &audit); err != nil {
+		return api.Audit{}, err
+	}
+
+	if err := setActor(c, &audit); err != nil {
+		return api.Audit{}, api.ErrorWithStatusCode(err, http.StatusBadRequest)
+	}
+
+	return audit, nil
+}
+
+func (handler *HttpHandler) HandleV1DeleteAdminUsers(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	audit, err := bindAudit(c)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return handler.eraseUser(c, userId, audit)
+}
+
+// setUserStateRequest는 PUT /v1/admin/users/:id/state의 body
+type setUserStateRequest struct {
+	State api.UserState `json:"state"`
+}
+
+func (handler *HttpHandler) HandleV1PutAdminUserState(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	body := setUserStateRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return handler.setUserState(c, userId, body.State)
+}
+
+// overrideScoreRequest는 PUT /v1/admin/users/:id/score의 body
+type overrideScoreRequest struct {
+	Score  *int   `json:"score"`
+	Actor  string `json:"actor"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (handler *HttpHandler) HandleV1PutAdminUserScore(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	body := overrideScoreRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+	if body.Score == nil {
+		return badRequest(c, "score is empty")
+	}
+
+	audit := api.Audit{Actor: body.Actor, Reason: body.Reason}
+	if err := setActor(c, &audit); err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	return handler.overrideScore(c, userId, *body.Score, audit)
+}
+
+func (handler *HttpHandler) HandleV1PostAdminUserRevert(c echo.Context) error {
+	userId, err := pathParam(c, "id")
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	audit, err := bindAudit(c)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return handler.revertScore(c, userId, audit)
+}
+
+// addReviewRequest는 POST /v1/admin/reviews의 body
+type addReviewRequest struct {
+	UserId        string           `json:"user_id"`
+	Score         int              `json:"score"`
+	PreviousScore int              `json:"previous_score"`
+	Action        api.ReviewAction `json:"action"`
+	Reasons       []string         `json:"reasons,omitempty"`
+}
+
+func (handler *HttpHandler) HandleV1PostAdminReviews(c echo.Context) error {
+	body := addReviewRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return handler.addReview(c, api.Review{
+		UserId:        body.UserId,
+		Score:         body.Score,
+		PreviousScore: body.PreviousScore,
+		Action:        body.Action,
+		Reasons:       body.Reasons,
+	})
+}
+
+func (handler *HttpHandler) HandleV1PostAdminReviewApprove(c echo.Context) error {
+	audit, err := bindAudit(c)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+	return handler.resolveReview(c, true, audit)
+}
+
+func (handler *HttpHandler) HandleV1PostAdminReviewReject(c echo.Context) error {
+	audit, err := bindAudit(c)
+	if err != nil {
+		return ErrorJson(c, err)
+	}
+	return handler.resolveReview(c, false, audit)
+}
+
+// addWebhookRequest는 POST /v1/admin/webhooks의 body
+type addWebhookRequest struct {
+	URL    string          `json:"url"`
+	Events []api.EventType `json:"events,omitempty"`
+	Secret string          `json:"secret,omitempty"`
+}
+
+func (handler *HttpHandler) HandleV1PostAdminWebhooks(c echo.Context) error {
+	body := addWebhookRequest{}
+	if err := bindJson(c, &body); err != nil {
+		return ErrorJson(c, err)
+	}
+
+	return handler.addWebhook(c, api.Webhook{
+		URL:    body.URL,
+		Events: body.Events,
+		Secret: body.Secret,
+	})
+}
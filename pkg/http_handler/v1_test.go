@@ -0,0 +1,283 @@
+package http_handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/api/apifakes"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpHandler_V1(t *testing.T) {
+	g := NewWithT(t)
+
+	type TestData struct {
+		description        string
+		httpMethod         string
+		path               string
+		header             http.Header
+		body               string
+		setup, after       func(*apifakes.FakeLeaderBoard)
+		expectedStatusCode int
+		expectedHeader     http.Header
+		data               interface{}
+		expectedData       interface{}
+	}
+
+	type UserCountData struct {
+		Data struct {
+			Count int
+		}
+	}
+
+	type ErrorData struct {
+		Error struct {
+			Message string
+			Reason  string
+		}
+	}
+
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+
+	testDataList := []TestData{
+		{
+			description: "usercount",
+			httpMethod:  http.MethodGet,
+			path:        "/v1/usercount",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.UserCountReturns(123, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			data:               &UserCountData{},
+			expectedData:       &UserCountData{Data: struct{ Count int }{Count: 123}},
+		},
+		{
+			description: "get users: not found",
+			httpMethod:  http.MethodGet,
+			path:        "/v1/users/unknown",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.GetUserReturns(
+					api.User{},
+					api.ErrorWithStatusCode(errors.New("unknown not found"), http.StatusNotFound))
+			},
+			expectedStatusCode: http.StatusNotFound,
+			data:               &ErrorData{},
+			expectedData: &ErrorData{Error: struct {
+				Message string
+				Reason  string
+			}{Message: "unknown not found"}},
+		},
+		{
+			description: "set users",
+			httpMethod:  http.MethodPut,
+			path:        "/v1/users/abc",
+			header:      jsonHeader,
+			body:        `{"score": 300, "attributes": {"country": "KR"}}`,
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId, score, opts := fake.SetUserArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+				g.Expect(score).To(Equal(300))
+				g.Expect(api.NewOptions(opts...).Attributes).To(Equal(map[string]string{"country": "KR"}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "set users: empty score",
+			httpMethod:         http.MethodPut,
+			path:               "/v1/users/abc",
+			header:             jsonHeader,
+			body:               `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+			data:               &ErrorData{},
+			expectedData: &ErrorData{Error: struct {
+				Message string
+				Reason  string
+			}{Message: "invalid request body: score is required"}},
+		},
+		{
+			description:        "set users: unknown field",
+			httpMethod:         http.MethodPut,
+			path:               "/v1/users/abc",
+			header:             jsonHeader,
+			body:               `{"score": 300, "rank": 1}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "set users: trailing data",
+			httpMethod:         http.MethodPut,
+			path:               "/v1/users/abc",
+			header:             jsonHeader,
+			body:               `{"score": 300} {"score": 400}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "set users: not json",
+			httpMethod:         http.MethodPut,
+			path:               "/v1/users/abc",
+			header:             http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+			body:               `score=300`,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			description:        "set users: query string is not accepted",
+			httpMethod:         http.MethodPut,
+			path:               "/v1/users/abc?score=300",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "not acceptable",
+			httpMethod:         http.MethodGet,
+			path:               "/v1/usercount",
+			header:             http.Header{"Accept": {"text/html"}},
+			expectedStatusCode: http.StatusNotAcceptable,
+		},
+		{
+			description:        "accept with quality",
+			httpMethod:         http.MethodGet,
+			path:               "/v1/usercount",
+			header:             http.Header{"Accept": {"text/html, application/json;q=0.5"}},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "accept json with zero quality",
+			httpMethod:         http.MethodGet,
+			path:               "/v1/usercount",
+			header:             http.Header{"Accept": {"application/json;q=0"}},
+			expectedStatusCode: http.StatusNotAcceptable,
+		},
+		{
+			description: "create snapshot without body",
+			httpMethod:  http.MethodPost,
+			path:        "/v1/snapshots",
+			setup: func(fake *apifakes.FakeLeaderBoard) {
+				fake.CreateSnapshotReturns(api.Snapshot{Id: "s1"}, nil)
+			},
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, id := fake.CreateSnapshotArgsForCall(0)
+				g.Expect(id).To(BeEmpty())
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "erase user",
+			httpMethod:  http.MethodDelete,
+			path:        "/v1/admin/users/abc",
+			header:      jsonHeader,
+			body:        `{"actor": "admin", "reason": "gdpr"}`,
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, userId, audit := fake.EraseUserArgsForCall(0)
+				g.Expect(userId).To(Equal("abc"))
+				g.Expect(audit).To(Equal(api.Audit{Actor: "admin", Reason: "gdpr"}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description:        "erase user: empty actor",
+			httpMethod:         http.MethodDelete,
+			path:               "/v1/admin/users/abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description: "approve review",
+			httpMethod:  http.MethodPost,
+			path:        "/v1/admin/reviews/r1/approve",
+			header:      jsonHeader,
+			body:        `{"actor": "admin"}`,
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, reviewId, approve, audit := fake.ResolveReviewArgsForCall(0)
+				g.Expect(reviewId).To(Equal("r1"))
+				g.Expect(approve).To(BeTrue())
+				g.Expect(audit.Actor).To(Equal("admin"))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "add webhook",
+			httpMethod:  http.MethodPost,
+			path:        "/v1/admin/webhooks",
+			header:      jsonHeader,
+			body:        `{"url":"http://partner.xx/hook","secret":"s1"}`,
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				_, webhook := fake.AddWebhookArgsForCall(0)
+				g.Expect(webhook).To(Equal(api.Webhook{URL: "http://partner.xx/hook", Secret: "s1"}))
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			description: "add webhook: secret in query string",
+			httpMethod:  http.MethodPost,
+			path:        "/v1/admin/webhooks?secret=s1",
+			header:      jsonHeader,
+			body:        `{"url":"http://partner.xx/hook"}`,
+			after: func(fake *apifakes.FakeLeaderBoard) {
+				g.Expect(fake.AddWebhookCallCount()).To(Equal(0))
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			data:               &ErrorData{},
+			expectedData: &ErrorData{Error: struct {
+				Message string
+				Reason  string
+			}{Message: "unknown query parameter: secret"}},
+		},
+		{
+			description:        "deprecated route",
+			httpMethod:         http.MethodGet,
+			path:               "/usercount",
+			expectedStatusCode: http.StatusOK,
+			expectedHeader: http.Header{
+				"Deprecation": {"true"},
+				"Link":        {`</v1/usercount>; rel="successor-version"`},
+			},
+		},
+	}
+
+	for _, testData := range testDataList {
+		fake := &apifakes.FakeLeaderBoard{}
+
+		if testData.setup != nil {
+			testData.setup(fake)
+		}
+
+		e := echo.New()
+		http_handler.New(fake).Setup(e)
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(testData.httpMethod, testData.path, strings.NewReader(testData.body))
+		for k, v := range testData.header {
+			req.Header[k] = v
+		}
+
+		e.ServeHTTP(rw, req)
+
+		resp := rw.Result()
+		body, err := io.ReadAll(resp.Body)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Expect(resp.StatusCode).To(Equal(testData.expectedStatusCode),
+			"%s: %s, body=%s", testData.description, resp.Status, string(body))
+
+		for k, v := range testData.expectedHeader {
+			g.Expect(resp.Header[k]).To(Equal(v), testData.description)
+		}
+
+		if testData.data != nil {
+			err = json.Unmarshal(body, testData.data)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(testData.data).To(Equal(testData.expectedData),
+				"%s: body=%s", testData.description, string(body))
+		}
+
+		if testData.after != nil {
+			testData.after(fake)
+		}
+	}
+}
@@ -5,20 +5,136 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
 	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
 	"github.com/bigflood/leaderboard/pkg/logging_mw"
+	"github.com/bigflood/leaderboard/pkg/signature"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Server struct {
 	httpServer *http.Server
 	e          *echo.Echo
+	handler    *http_handler.HttpHandler
+	rankStream *rankStream
+	cancel     context.CancelFunc
+
+	// Shutdown이 readiness를 실패로 바꾼 다음 연결을 닫기 전까지 기다리는 시간
+	shutdownDelay time.Duration
+	// nil이 아니면 GET /metrics로 지표들을 보여준다
+	metrics *prometheus.Registry
+	// 요청 수를 client IP별로 제한할 때 사용한다
+	ipExtractor echo.IPExtractor
 }
 
-func New(lb api.LeaderBoard, logger *log.Logger) *Server {
+// Option은 서버의 선택적인 기능을 설정한다
+type Option func(*Server)
+
+// WithVerifier는 점수 제출 요청의 서명을 verifier로 검증하게 한다
+func WithVerifier(verifier *signature.Verifier) Option {
+	return func(s *Server) {
+		s.handler.Verifier = verifier
+	}
+}
+
+// WithAPIKeys는 /healthz 등을 뺀 모든 요청에서 keys로 API key를 확인하게 한다
+func WithAPIKeys(keys *apikey.Store) Option {
+	return func(s *Server) {
+		s.handler.Keys = keys
+	}
+}
+
+// WithTokens는 Authorization header의 JWT bearer 토큰을 tokens로 검증하게 한다.
+// 토큰의 sub claim이 사용자 id이고, 관리자 claim이 없는 토큰은 자신의 데이터만 변경할 수 있다.
+func WithTokens(tokens *jwtauth.Verifier) Option {
+	return func(s *Server) {
+		s.handler.Tokens = tokens
+	}
+}
+
+// WithUserEvents는 GET /v1/users/:id/events로 사용자의 순위 변경을 events에서 읽어 SSE로 보내게 한다.
+// heartbeatInterval과 maxConnections가 0 이하이면 기본값(DefaultHeartbeatInterval, DefaultMaxEventStreams)을 사용한다.
+func WithUserEvents(events http_handler.EventSource, heartbeatInterval time.Duration, maxConnections int) Option {
+	return func(s *Server) {
+		s.handler.Events = events
+		if heartbeatInterval > 0 {
+			s.handler.HeartbeatInterval = heartbeatInterval
+		}
+		if maxConnections > 0 {
+			s.handler.MaxEventStreams = maxConnections
+		}
+	}
+}
+
+// WithTimeouts는 요청 처리 시간 제한을 설정한다.
+// operations는 "POST /admin/expire"처럼 method와 route path를 key로 하는 작업별 시간 제한이고, 없는 작업은 timeout을 사용한다.
+func WithTimeouts(timeout time.Duration, operations map[string]time.Duration) Option {
+	return func(s *Server) {
+		s.handler.RequestTimeout = timeout
+		s.handler.OperationTimeouts = operations
+	}
+}
+
+// WithCacheControl은 조회 route별 Cache-Control을 바꾼다. policies에 없는 route는 http_handler.DefaultCacheControl을 사용한다.
+// key는 "GET /ranks"처럼 method와 /v1을 뺀 route path이고, 값이 ""이면 Cache-Control을 보내지 않는다.
+func WithCacheControl(policies map[string]string) Option {
+	return func(s *Server) {
+		for route, policy := range policies {
+			if policy == "" {
+				delete(s.handler.CacheControl, route)
+			} else {
+				s.handler.CacheControl[route] = policy
+			}
+		}
+	}
+}
+
+// WithRankStream은 순위 구간 구독(GET /v1/ranks/stream)이 순위를 다시 조회하는 주기와 최대 연결 수를 설정한다.
+// 0 이하인 값은 무시하고 기본값(DefaultRankStreamInterval, DefaultMaxStreamConnections)을 사용한다.
+func WithRankStream(interval time.Duration, maxConnections int) Option {
+	return func(s *Server) {
+		if interval > 0 {
+			s.rankStream.interval = interval
+		}
+		if maxConnections > 0 {
+			s.rankStream.maxConnections = maxConnections
+		}
+	}
+}
+
+// WithAllowedOrigins는 순위 구간 구독(GET /v1/ranks/stream)에 연결할 수 있는 다른 사이트의 Origin들을 설정한다 (예: "https://game.example.com").
+// 설정하지 않으면 같은 host의 페이지와 Origin header를 보내지 않는 클라이언트만 연결할 수 있다.
+func WithAllowedOrigins(origins []string) Option {
+	return func(s *Server) {
+		s.rankStream.allowedOrigins = map[string]bool{}
+		for _, origin := range origins {
+			s.rankStream.allowedOrigins[strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))] = true
+		}
+	}
+}
+
+// WithHealthChecks는 GET /readyz에서 checks의 의존성들을 확인하게 한다. key는 응답에 표시하는 이름이다.
+func WithHealthChecks(checks map[string]http_handler.HealthCheck) Option {
+	return func(s *Server) {
+		s.handler.HealthChecks = checks
+	}
+}
+
+// WithShutdownDelay는 Shutdown이 readiness를 실패로 바꾼 다음 delay만큼 요청을 더 받고 나서 연결을 닫게 한다.
+// 그동안 load balancer가 readiness 실패를 보고 이 서버로 요청을 보내지 않게 된다.
+func WithShutdownDelay(delay time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownDelay = delay
+	}
+}
+
+func New(lb api.LeaderBoard, logger *log.Logger, opts ...Option) *Server {
 	if logger != nil {
 		lb = &logging_mw.LoggingMiddleware{
 			Receiver: lb,
@@ -26,13 +142,27 @@ func New(lb api.LeaderBoard, logger *log.Logger) *Server {
 		}
 	}
 
-	handler := http_handler.New(lb)
+	s := &Server{
+		handler:     http_handler.New(lb),
+		rankStream:  newRankStream(lb),
+		ipExtractor: echo.ExtractIPDirect(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
 
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.IPExtractor = s.ipExtractor
 
-	handler.Setup(e)
+	if s.metrics != nil {
+		setupMetrics(e, s.metrics)
+	}
+
+	s.handler.Setup(e)
+	e.GET("/v1/ranks/stream", s.rankStream.handle, s.handler.V1, s.handler.Authorize, http_handler.Validate)
+	e.GET("/ranks/stream", s.rankStream.handle, http_handler.Deprecated, s.handler.Authorize)
 
 	httpServer := &http.Server{
 		ReadHeaderTimeout: 30 * time.Second,
@@ -41,10 +171,14 @@ func New(lb api.LeaderBoard, logger *log.Logger) *Server {
 		IdleTimeout:       330 * time.Second,
 	}
 
-	return &Server{
-		httpServer: httpServer,
-		e:          e,
-	}
+	s.httpServer = httpServer
+	s.e = e
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.rankStream.run(ctx)
+
+	return s
 }
 
 func (s *Server) ListenAndServe(addr string) error {
@@ -58,5 +192,19 @@ func (s *Server) Serve(listener net.Listener) error {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	s.handler.SetShuttingDown()
+
+	if s.shutdownDelay > 0 {
+		timer := time.NewTimer(s.shutdownDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	err := s.httpServer.Shutdown(ctx)
+	// hijack한 websocket 연결은 http.Server가 닫지 않으므로 따로 끊는다
+	s.cancel()
+	return err
 }
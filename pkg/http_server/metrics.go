@@ -0,0 +1,77 @@
+package http_server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// unmatchedRoute는 등록된 route가 없거나 route에 없는 method로 받은 요청의 route label.
+	// echo는 이런 요청의 path를 요청 path 그대로 두므로 label 값이 끝없이 늘어나지 않게 한다.
+	unmatchedRoute = "unmatched"
+	// otherMethod는 표준이 아닌 method의 label. 클라이언트가 보낸 method를 그대로 label로 쓰지 않는다.
+	otherMethod = "other"
+)
+
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// WithMetrics는 route별 요청 처리 시간을 registry에 기록하고, registry의 지표들을 GET /metrics로 보여준다
+func WithMetrics(registry *prometheus.Registry) Option {
+	return func(s *Server) {
+		s.metrics = registry
+	}
+}
+
+func setupMetrics(e *echo.Echo, registry *prometheus.Registry) {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+	registry.MustRegister(duration)
+
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+			if err != nil {
+				// status code가 정해지도록 먼저 오류 응답을 보낸다
+				c.Error(err)
+			}
+
+			route := c.Path()
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) && (httpErr.Code == http.StatusNotFound || httpErr.Code == http.StatusMethodNotAllowed) {
+				route = unmatchedRoute
+			}
+
+			method := c.Request().Method
+			if !knownMethods[method] {
+				method = otherMethod
+			}
+
+			duration.WithLabelValues(method, route, strconv.Itoa(c.Response().Status)).
+				Observe(time.Since(start).Seconds())
+			return nil
+		}
+	})
+
+	e.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+}
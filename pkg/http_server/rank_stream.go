@@ -0,0 +1,307 @@
+package http_server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	DefaultRankStreamInterval   = time.Second
+	DefaultMaxStreamConnections = 1000
+
+	// 구독할 수 있는 구간의 최대 크기
+	maxStreamCount = 100
+	// 연결 하나에 쌓일 수 있는 메시지 수. 넘치면 느린 연결로 보고 끊는다.
+	streamSendBuffer   = 16
+	streamWriteTimeout = 10 * time.Second
+)
+
+type rankWindow struct {
+	rank  int
+	count int
+}
+
+type rankSubscriber struct {
+	send chan api.RankUpdate
+}
+
+type windowState struct {
+	users       []api.User
+	subscribers map[*rankSubscriber]struct{}
+}
+
+// rankStream은 순위 구간을 websocket으로 구독하게 한다.
+// 같은 구간을 구독하는 연결들은 tick마다 한 번 조회한 결과를 함께 받는다.
+type rankStream struct {
+	lb             api.LeaderBoard
+	interval       time.Duration
+	maxConnections int
+	// 다른 사이트의 페이지에서 연결할 수 있는 Origin들 (소문자, 예: "https://game.example.com")
+	allowedOrigins map[string]bool
+
+	mutex       sync.Mutex
+	windows     map[rankWindow]*windowState
+	connections int
+}
+
+func newRankStream(lb api.LeaderBoard) *rankStream {
+	return &rankStream{
+		lb:             lb,
+		interval:       DefaultRankStreamInterval,
+		maxConnections: DefaultMaxStreamConnections,
+		windows:        map[rankWindow]*windowState{},
+	}
+}
+
+func (s *rankStream) handle(c echo.Context) error {
+	rank, err := strconv.Atoi(c.QueryParam("rank"))
+	if err != nil || rank < 1 {
+		return http_handler.ErrorJson(c, api.ErrorWithStatusCode(errors.New("rank is empty or invalid format"), http.StatusBadRequest))
+	}
+
+	count, err := strconv.Atoi(c.QueryParam("count"))
+	if err != nil || count < 1 || count > maxStreamCount {
+		return http_handler.ErrorJson(c, api.ErrorWithStatusCode(errors.New("count is empty or invalid format"), http.StatusBadRequest))
+	}
+
+	if !s.acquire() {
+		return http_handler.ErrorJson(c, api.ErrorWithStatusCode(errors.New("too many connections"), http.StatusServiceUnavailable))
+	}
+	defer s.release()
+
+	window := rankWindow{rank: rank, count: count}
+	server := websocket.Server{
+		Handshake: s.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			s.serve(ws, window)
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// checkOrigin은 다른 사이트의 페이지가 브라우저에 있는 사용자의 인증 정보로 구독하지 못하게 한다.
+// 같은 host의 페이지와 allowedOrigins의 페이지에서 연 연결만 받는다. Origin header가 없으면 브라우저가 아니므로 받는다.
+func (s *rankStream) checkOrigin(config *websocket.Config, req *http.Request) error {
+	if req.Header.Get("Origin") == "" {
+		return nil
+	}
+
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	config.Origin = origin
+
+	if strings.EqualFold(origin.Host, req.Host) || s.allowedOrigins[strings.ToLower(origin.Scheme+"://"+origin.Host)] {
+		return nil
+	}
+
+	return fmt.Errorf("origin is not allowed: %s", origin)
+}
+
+func (s *rankStream) acquire() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.connections >= s.maxConnections {
+		return false
+	}
+	s.connections++
+	return true
+}
+
+func (s *rankStream) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.connections--
+}
+
+func (s *rankStream) serve(ws *websocket.Conn, window rankWindow) {
+	// http.Server의 ReadTimeout, WriteTimeout이 hijack한 연결에도 남아있으므로 지운다
+	ws.SetDeadline(time.Time{})
+
+	sub := &rankSubscriber{send: make(chan api.RankUpdate, streamSendBuffer)}
+	if err := s.subscribe(ws.Request().Context(), window, sub); err != nil {
+		return
+	}
+
+	// 클라이언트가 보내는 메시지는 없고, 연결이 끊긴 것을 알기 위해서만 읽는다
+	go func() {
+		var msg string
+		for websocket.Message.Receive(ws, &msg) == nil {
+		}
+		s.unsubscribe(window, sub)
+	}()
+
+	for update := range sub.send {
+		ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if err := websocket.JSON.Send(ws, update); err != nil {
+			s.unsubscribe(window, sub)
+			break
+		}
+	}
+}
+
+// subscribe는 sub에 구간의 현재 상태를 snapshot으로 넣고 구독자로 등록한다.
+// 처음 구독하는 구간은 mutex를 놓고 조회하므로 느린 조회가 다른 구독이나 tick을 막지 않는다.
+func (s *rankStream) subscribe(ctx context.Context, window rankWindow, sub *rankSubscriber) error {
+	if s.register(window, sub, nil) {
+		return nil
+	}
+
+	users, err := s.lb.GetRanks(ctx, window.rank, window.count)
+	if err != nil {
+		return err
+	}
+	if users == nil {
+		users = []api.User{}
+	}
+
+	s.register(window, sub, users)
+	return nil
+}
+
+// register는 구간이 이미 구독 중이거나 users가 있으면 sub를 구독자로 등록하고 true를 반환한다.
+// 조회하는 동안 다른 연결이 같은 구간을 구독했으면 그 상태를 사용한다.
+func (s *rankStream) register(window rankWindow, sub *rankSubscriber, users []api.User) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := s.windows[window]
+	if state == nil {
+		if users == nil {
+			return false
+		}
+
+		state = &windowState{
+			users:       users,
+			subscribers: map[*rankSubscriber]struct{}{},
+		}
+		s.windows[window] = state
+	}
+
+	sub.send <- api.RankUpdate{Type: api.RankUpdateSnapshot, Users: state.users}
+	state.subscribers[sub] = struct{}{}
+	return true
+}
+
+func (s *rankStream) unsubscribe(window rankWindow, sub *rankSubscriber) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if state := s.windows[window]; state != nil {
+		s.remove(window, state, sub)
+	}
+}
+
+// remove는 구독자 목록에서 sub를 빼고 send를 닫는다. mutex를 잡고 호출해야 한다.
+func (s *rankStream) remove(window rankWindow, state *windowState, sub *rankSubscriber) {
+	if _, ok := state.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(state.subscribers, sub)
+	close(sub.send)
+
+	if len(state.subscribers) == 0 {
+		delete(s.windows, window)
+	}
+}
+
+func (s *rankStream) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.closeAll()
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+// tick은 구독 중인 구간마다 순위를 한 번 조회해서 바뀐 부분을 구독자들에게 보낸다
+func (s *rankStream) tick(ctx context.Context) {
+	s.mutex.Lock()
+	windows := make([]rankWindow, 0, len(s.windows))
+	for window := range s.windows {
+		windows = append(windows, window)
+	}
+	s.mutex.Unlock()
+
+	for _, window := range windows {
+		users, err := s.lb.GetRanks(ctx, window.rank, window.count)
+		if err != nil {
+			continue
+		}
+
+		s.mutex.Lock()
+		if state := s.windows[window]; state != nil {
+			update, changed := diffRanks(state.users, users)
+			state.users = users
+
+			if changed {
+				for sub := range state.subscribers {
+					select {
+					case sub.send <- update:
+					default:
+						s.remove(window, state, sub)
+					}
+				}
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func (s *rankStream) closeAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for window, state := range s.windows {
+		for sub := range state.subscribers {
+			s.remove(window, state, sub)
+		}
+	}
+}
+
+// diffRanks는 prev에서 cur로 바뀐 부분을 diff로 만든다
+func diffRanks(prev, cur []api.User) (api.RankUpdate, bool) {
+	update := api.RankUpdate{Type: api.RankUpdateDiff, Users: []api.User{}}
+
+	prevUsers := make(map[string]api.User, len(prev))
+	for _, user := range prev {
+		prevUsers[user.Id] = user
+	}
+
+	for _, user := range cur {
+		old, ok := prevUsers[user.Id]
+		if !ok || old.Rank != user.Rank || old.Score != user.Score || old.RankDelta != user.RankDelta {
+			update.Users = append(update.Users, user)
+		}
+		delete(prevUsers, user.Id)
+	}
+
+	for _, user := range prev {
+		if _, ok := prevUsers[user.Id]; ok {
+			update.Removed = append(update.Removed, user.Id)
+		}
+	}
+
+	return update, len(update.Users) > 0 || len(update.Removed) > 0
+}
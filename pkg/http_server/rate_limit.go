@@ -0,0 +1,136 @@
+package http_server
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimits는 요청 수 제한들. 0인 Limit은 제한하지 않는다.
+type RateLimits struct {
+	// 클라이언트(API key, 없으면 client IP)별 조회, 변경 요청 수 제한
+	ClientRead, ClientWrite ratelimit.Limit
+	// 대상 사용자(/users/:id의 id, 없으면 토큰의 사용자)별 조회, 변경 요청 수 제한
+	UserRead, UserWrite ratelimit.Limit
+	// 인증하기 전에 확인하는 client IP별 요청 수 제한
+	IP ratelimit.Limit
+}
+
+// WithRateLimit은 요청의 수를 limiter로 제한한다. IP 제한은 인증하기 전에, 나머지는 인증한 다음에 확인한다.
+// 제한을 넘은 요청은 429와 Retry-After로 응답하고, 모든 응답에 RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset header를 넣는다.
+func WithRateLimit(limiter ratelimit.Limiter, limits RateLimits) Option {
+	return func(s *Server) {
+		s.handler.RateLimit = rateLimit(limiter, func(c echo.Context) []rateLimitBucket {
+			return rateLimitBuckets(c, limits)
+		})
+
+		if !limits.IP.Unlimited() {
+			s.handler.PreAuthRateLimit = rateLimit(limiter, func(c echo.Context) []rateLimitBucket {
+				return []rateLimitBucket{{"ip:" + c.RealIP(), limits.IP}}
+			})
+		}
+	}
+}
+
+// WithIPExtractor는 요청의 client IP를 읽는 방법을 정한다. 기본값은 연결의 주소를 그대로 사용한다.
+// proxy 뒤에서 실행하면 echo.ExtractIPFromXFFHeader 등을 사용한다.
+func WithIPExtractor(extractor echo.IPExtractor) Option {
+	return func(s *Server) {
+		s.ipExtractor = extractor
+	}
+}
+
+type rateLimitBucket struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+// rateLimitBuckets는 요청이 token을 꺼낼 bucket들을 정한다
+func rateLimitBuckets(c echo.Context, limits RateLimits) []rateLimitBucket {
+	clientLimit, userLimit := limits.ClientRead, limits.UserRead
+	kind := "read"
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead:
+	default:
+		clientLimit, userLimit = limits.ClientWrite, limits.UserWrite
+		kind = "write"
+	}
+
+	var buckets []rateLimitBucket
+
+	if key, ok := http_handler.AuthenticatedKey(c); ok {
+		buckets = append(buckets, rateLimitBucket{kind + ":key:" + key.Id, clientLimit})
+	} else {
+		buckets = append(buckets, rateLimitBucket{kind + ":ip:" + c.RealIP(), clientLimit})
+	}
+
+	// escape된 id도 handler와 같은 사용자로 센다
+	user, _ := http_handler.UserIdParam(c)
+	if claims, ok := http_handler.TokenClaims(c); ok && user == "" {
+		user = claims.Subject
+	}
+	if user != "" {
+		buckets = append(buckets, rateLimitBucket{kind + ":user:" + user, userLimit})
+	}
+
+	return buckets
+}
+
+// rateLimit은 buckets가 정한 bucket들에서 차례로 token을 꺼낸다.
+// 거절한 요청이 뒤의 bucket의 token을 쓰지 않도록 처음 거절한 bucket에서 멈춘다.
+func rateLimit(limiter ratelimit.Limiter, buckets func(c echo.Context) []rateLimitBucket) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var reported *ratelimit.Result
+			allowed, retryAfter := true, time.Duration(0)
+
+			for _, bucket := range buckets(c) {
+				if bucket.limit.Unlimited() {
+					continue
+				}
+
+				result, err := limiter.Allow(c.Request().Context(), bucket.key, bucket.limit)
+				if err != nil {
+					return http_handler.ErrorJson(c, err)
+				}
+
+				// 남은 요청 수가 가장 적은 제한을 알려준다
+				if reported == nil || result.Remaining < reported.Remaining {
+					reported = &result
+				}
+				if !result.Allowed {
+					allowed, retryAfter = false, result.RetryAfter
+					break
+				}
+			}
+
+			if reported == nil {
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(reported.Limit.Count))
+			header.Set("RateLimit-Remaining", strconv.Itoa(reported.Remaining))
+			header.Set("RateLimit-Reset", seconds(reported.Reset))
+
+			if !allowed {
+				header.Set("Retry-After", seconds(retryAfter))
+				return http_handler.ErrorJson(c, api.ErrorWithStatusCode(errors.New("rate limit exceeded"), http.StatusTooManyRequests))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// seconds는 d를 올림한 초 단위 문자열로 반환한다
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,383 @@
+package jwtauth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+const (
+	// DefaultPrivilegedClaim은 다른 사용자의 데이터에도 접근할 수 있는 토큰임을 표시하는 claim.
+	// 값이 true이면 관리자 토큰이다.
+	DefaultPrivilegedClaim = "leaderboard_admin"
+
+	// 서버와 토큰 발급자의 시각 차이로 exp, nbf를 잘못 판단하지 않도록 허용하는 오차
+	DefaultLeeway = time.Minute
+)
+
+// Claims는 검증한 토큰의 claim들
+type Claims struct {
+	// sub claim. 사용자 id로 사용한다.
+	Subject    string
+	Privileged bool
+	ExpiresAt  time.Time
+	// 토큰의 모든 claim. 숫자는 json.Number이다.
+	Raw map[string]interface{}
+}
+
+// Verifier는 HS256, RS256으로 서명된 JWT bearer 토큰을 검증한다
+type Verifier struct {
+	// HS256 토큰의 secret key. 비어있으면 HS256 토큰을 받지 않는다.
+	HMACSecret []byte
+	// RS256 토큰의 공개키들. key는 JWKS의 kid이다. 비어있으면 RS256 토큰을 받지 않는다.
+	RSAKeys map[string]*rsa.PublicKey
+
+	// 비어있지 않으면 iss claim이 같아야 한다
+	Issuer string
+	// 비어있지 않으면 aud claim에 들어있어야 한다
+	Audience string
+	// 관리자 토큰을 표시하는 claim (""이면 DefaultPrivilegedClaim)
+	PrivilegedClaim string
+	// exp, nbf의 허용 오차 (0이면 DefaultLeeway)
+	Leeway time.Duration
+
+	NowFunc func() time.Time
+}
+
+func (verifier *Verifier) now() time.Time {
+	if verifier.NowFunc != nil {
+		return verifier.NowFunc()
+	}
+	return time.Now()
+}
+
+func (verifier *Verifier) leeway() time.Duration {
+	if verifier.Leeway > 0 {
+		return verifier.Leeway
+	}
+	return DefaultLeeway
+}
+
+func (verifier *Verifier) privilegedClaim() string {
+	if verifier.PrivilegedClaim != "" {
+		return verifier.PrivilegedClaim
+	}
+	return DefaultPrivilegedClaim
+}
+
+func unauthorized(msg string) error {
+	return api.ErrorWithStatusCode(errors.New(msg), http.StatusUnauthorized)
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Verify는 token의 서명과 exp, nbf, iss, aud claim을 확인하고 claim들을 반환한다.
+// 토큰이 잘못되었거나 만료되었으면 401 오류를 반환한다.
+func (verifier *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, unauthorized("token is invalid format")
+	}
+
+	h := header{}
+	if err := decodePart(parts[0], &h); err != nil {
+		return Claims{}, unauthorized("token header is invalid format")
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, unauthorized("token signature is invalid format")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+
+	// alg마다 설정한 key만 사용하므로 RSA 공개키를 HMAC secret으로 쓰게 하는 토큰은 검증되지 않는다
+	switch h.Alg {
+	case "HS256":
+		if len(verifier.HMACSecret) == 0 {
+			return Claims{}, unauthorized("unsupported token algorithm: HS256")
+		}
+		if !hmac.Equal(signature, signHMAC(verifier.HMACSecret, signed)) {
+			return Claims{}, unauthorized("invalid token signature")
+		}
+	case "RS256":
+		key, err := verifier.rsaKey(h.Kid)
+		if err != nil {
+			return Claims{}, err
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return Claims{}, unauthorized("invalid token signature")
+		}
+	default:
+		return Claims{}, unauthorized(fmt.Sprintf("unsupported token algorithm: %s", h.Alg))
+	}
+
+	raw := map[string]interface{}{}
+	if err := decodePart(parts[1], &raw); err != nil {
+		return Claims{}, unauthorized("token claims are invalid format")
+	}
+
+	return verifier.checkClaims(raw)
+}
+
+func (verifier *Verifier) rsaKey(kid string) (*rsa.PublicKey, error) {
+	if kid == "" && len(verifier.RSAKeys) == 1 {
+		for _, key := range verifier.RSAKeys {
+			return key, nil
+		}
+	}
+
+	key, ok := verifier.RSAKeys[kid]
+	if !ok {
+		if len(verifier.RSAKeys) == 0 {
+			return nil, unauthorized("unsupported token algorithm: RS256")
+		}
+		return nil, unauthorized(fmt.Sprintf("unknown token key id: %q", kid))
+	}
+	return key, nil
+}
+
+func (verifier *Verifier) checkClaims(raw map[string]interface{}) (Claims, error) {
+	now := verifier.now()
+	leeway := verifier.leeway()
+
+	exp, ok := numericDate(raw["exp"])
+	if !ok {
+		return Claims{}, unauthorized("token has no valid exp claim")
+	}
+	if !now.Before(exp.Add(leeway)) {
+		return Claims{}, unauthorized("token is expired")
+	}
+
+	if _, found := raw["nbf"]; found {
+		nbf, ok := numericDate(raw["nbf"])
+		if !ok {
+			return Claims{}, unauthorized("token has invalid nbf claim")
+		}
+		if now.Add(leeway).Before(nbf) {
+			return Claims{}, unauthorized("token is not valid yet")
+		}
+	}
+
+	if verifier.Issuer != "" && raw["iss"] != verifier.Issuer {
+		return Claims{}, unauthorized("token issuer is not allowed")
+	}
+
+	if verifier.Audience != "" && !hasAudience(raw["aud"], verifier.Audience) {
+		return Claims{}, unauthorized("token audience is not allowed")
+	}
+
+	subject, _ := raw["sub"].(string)
+	if subject == "" {
+		return Claims{}, unauthorized("token has no sub claim")
+	}
+
+	return Claims{
+		Subject:    subject,
+		Privileged: raw[verifier.privilegedClaim()] == true,
+		ExpiresAt:  exp,
+		Raw:        raw,
+	}, nil
+}
+
+func decodePart(part string, v interface{}) error {
+	data, err := encoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// numericDate는 초 단위 unix 시각인 claim 값을 읽는다
+func numericDate(v interface{}) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(f*float64(time.Second))), true
+}
+
+// hasAudience는 aud claim(문자열이나 문자열 배열)에 audience가 있는지 반환한다
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func signHMAC(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func encodePart(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(data), nil
+}
+
+func sign(h header, claims map[string]interface{}, signFunc func(data []byte) ([]byte, error)) (string, error) {
+	h.Typ = "JWT"
+
+	encodedHeader, err := encodePart(h)
+	if err != nil {
+		return "", err
+	}
+
+	encodedClaims, err := encodePart(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := encodedHeader + "." + encodedClaims
+	signature, err := signFunc([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + encoding.EncodeToString(signature), nil
+}
+
+// SignHS256은 claims를 담은 HS256 토큰을 만든다. 테스트나 도구에서 토큰을 발급할 때 사용한다.
+func SignHS256(secret []byte, claims map[string]interface{}) (string, error) {
+	return sign(header{Alg: "HS256"}, claims, func(data []byte) ([]byte, error) {
+		return signHMAC(secret, data), nil
+	})
+}
+
+// SignRS256은 claims를 담은 RS256 토큰을 만든다. kid는 검증하는 쪽 JWKS의 key id이다.
+func SignRS256(key *rsa.PrivateKey, kid string, claims map[string]interface{}) (string, error) {
+	return sign(header{Alg: "RS256", Kid: kid}, claims, func(data []byte) ([]byte, error) {
+		digest := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	})
+}
+
+// jwk는 JWKS의 key 하나. RSA 공개키에 필요한 필드만 읽는다.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// ParseJWKS는 JWKS 문서에서 서명 검증에 쓸 RSA 공개키들을 kid별로 읽는다. 다른 종류의 key는 건너뛴다.
+func ParseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	set := jwks{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") || (key.Alg != "" && key.Alg != "RS256") {
+			continue
+		}
+
+		n, err := encoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid n: %w", key.Kid, err)
+		}
+
+		e, err := encoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid e: %w", key.Kid, err)
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 || exponent.Int64() < 3 {
+			return nil, fmt.Errorf("key %q: invalid e", key.Kid)
+		}
+
+		if _, ok := keys[key.Kid]; ok {
+			return nil, fmt.Errorf("duplicate key id: %q", key.Kid)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no RS256 signing key in jwks")
+	}
+
+	return keys, nil
+}
+
+// LoadJWKSFile은 path의 JWKS 파일을 ParseJWKS로 읽는다
+func LoadJWKSFile(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// MarshalJWKS는 RSA 공개키들을 kid별로 JWKS 문서로 만든다
+func MarshalJWKS(keys map[string]*rsa.PublicKey) ([]byte, error) {
+	kids := make([]string, 0, len(keys))
+	for kid := range keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := jwks{}
+	for _, kid := range kids {
+		key := keys[kid]
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   encoding.EncodeToString(key.N.Bytes()),
+			E:   encoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	return json.Marshal(set)
+}
@@ -0,0 +1,171 @@
+package jwtauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/jwtauth"
+	. "github.com/onsi/gomega"
+)
+
+func TestVerifier(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(HaveOccurred())
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	jwks, err := MarshalJWKS(map[string]*rsa.PublicKey{"key1": &rsaKey.PublicKey})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	g.Expect(os.WriteFile(path, jwks, 0600)).To(Succeed())
+
+	rsaKeys, err := LoadJWKSFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	verifier := &Verifier{
+		HMACSecret: []byte("secret1"),
+		RSAKeys:    rsaKeys,
+		Issuer:     "game",
+		Audience:   "leaderboard",
+		NowFunc:    func() time.Time { return now },
+	}
+
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub": "user1",
+			"iss": "game",
+			"aud": []string{"leaderboard", "chat"},
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for k, v := range extra {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	hs256 := func(secret string, c map[string]interface{}) string {
+		token, err := SignHS256([]byte(secret), c)
+		g.Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	rs256 := func(key *rsa.PrivateKey, kid string, c map[string]interface{}) string {
+		token, err := SignRS256(key, kid, c)
+		g.Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	// header만 바꾼 토큰
+	withHeader := func(token, header string) string {
+		parts := strings.Split(token, ".")
+		parts[0] = base64.RawURLEncoding.EncodeToString([]byte(header))
+		return strings.Join(parts, ".")
+	}
+
+	result, err := verifier.Verify(hs256("secret1", claims(nil)))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Subject).To(Equal("user1"))
+	g.Expect(result.Privileged).To(BeFalse())
+	g.Expect(result.ExpiresAt).To(BeTemporally("==", now.Add(time.Hour)))
+
+	result, err = verifier.Verify(rs256(rsaKey, "key1", claims(map[string]interface{}{"leaderboard_admin": true})))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Subject).To(Equal("user1"))
+	g.Expect(result.Privileged).To(BeTrue())
+
+	// key가 하나뿐이면 kid가 없어도 된다
+	_, err = verifier.Verify(rs256(rsaKey, "", claims(nil)))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	testDataList := []struct {
+		token       string
+		expectedErr string
+	}{
+		{"abc", "token is invalid format"},
+		{hs256("secret2", claims(nil)), "invalid token signature"},
+		{rs256(otherKey, "key1", claims(nil)), "invalid token signature"},
+		{rs256(rsaKey, "key2", claims(nil)), `unknown token key id: "key2"`},
+		{withHeader(hs256("secret1", claims(nil)), `{"alg":"none"}`), "unsupported token algorithm: none"},
+		{withHeader(hs256("secret1", claims(nil)), `{"alg":"RS256","kid":"key1"}`), "invalid token signature"},
+		{hs256("secret1", claims(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()})), "token is expired"},
+		{hs256("secret1", claims(map[string]interface{}{"exp": nil})), "token has no valid exp claim"},
+		{hs256("secret1", claims(map[string]interface{}{"exp": "tomorrow"})), "token has no valid exp claim"},
+		{hs256("secret1", claims(map[string]interface{}{"nbf": now.Add(2 * time.Minute).Unix()})), "token is not valid yet"},
+		{hs256("secret1", claims(map[string]interface{}{"iss": "other"})), "token issuer is not allowed"},
+		{hs256("secret1", claims(map[string]interface{}{"aud": "chat"})), "token audience is not allowed"},
+		{hs256("secret1", claims(map[string]interface{}{"sub": nil})), "token has no sub claim"},
+		{hs256("secret1", claims(map[string]interface{}{"sub": ""})), "token has no sub claim"},
+	}
+
+	for _, testData := range testDataList {
+		_, err := verifier.Verify(testData.token)
+		g.Expect(err).To(MatchError(testData.expectedErr), testData.token)
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusUnauthorized))
+	}
+
+	// 허용 오차 안에서는 만료되지 않았다
+	_, err = verifier.Verify(hs256("secret1", claims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// 관리자 claim은 true일 때만 인정한다
+	result, err = verifier.Verify(hs256("secret1", claims(map[string]interface{}{"leaderboard_admin": "true"})))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Privileged).To(BeFalse())
+
+	// RS256만 설정하면 HS256 토큰은 받지 않는다. 공개키를 HMAC secret으로 쓴 토큰도 마찬가지이다.
+	rsaOnly := &Verifier{RSAKeys: rsaKeys, NowFunc: verifier.NowFunc}
+	_, err = rsaOnly.Verify(hs256(string(jwks), claims(nil)))
+	g.Expect(err).To(MatchError("unsupported token algorithm: HS256"))
+
+	hmacOnly := &Verifier{HMACSecret: []byte("secret1"), NowFunc: verifier.NowFunc}
+	_, err = hmacOnly.Verify(rs256(rsaKey, "key1", claims(nil)))
+	g.Expect(err).To(MatchError("unsupported token algorithm: RS256"))
+}
+
+func TestParseJWKS(t *testing.T) {
+	g := NewWithT(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	jwks, err := MarshalJWKS(map[string]*rsa.PublicKey{"key1": &rsaKey.PublicKey})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys, err := ParseJWKS(jwks)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keys).To(HaveLen(1))
+	g.Expect(keys["key1"].Equal(&rsaKey.PublicKey)).To(BeTrue())
+
+	// 서명 검증에 쓰지 않는 key는 건너뛴다
+	keys, err = ParseJWKS([]byte(`{"keys": [
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": "AA", "y": "AA"},
+		{"kty": "RSA", "kid": "enc1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		{"kty": "RSA", "kid": "sig1", "use": "sig", "n": "AQAB", "e": "AQAB"}
+	]}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keys).To(HaveKey("sig1"))
+	g.Expect(keys).To(HaveLen(1))
+
+	_, err = ParseJWKS([]byte(`{"keys": [{"kty": "EC", "kid": "ec1"}]}`))
+	g.Expect(err).To(MatchError("no RS256 signing key in jwks"))
+
+	_, err = ParseJWKS([]byte(`{"keys": [{"kty": "RSA", "kid": "k", "n": "AQAB", "e": "AQ"}]}`))
+	g.Expect(err).To(MatchError(`key "k": invalid e`))
+}
@@ -0,0 +1,50 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// CheckpointScheduler는 Interval 단위로 맞춰진 시각마다 Checkpoint를 실행한다.
+// 예: Interval이 24시간이면 매일 00:00 UTC에 체크포인트를 남긴다.
+type CheckpointScheduler struct {
+	LeaderBoard *LeaderBoard
+	Interval    time.Duration
+
+	// nil이면 실제 시계를 사용한다
+	Clock  clock.Clock
+	Logger *log.Logger
+}
+
+func (s *CheckpointScheduler) Run(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return errors.New("invalid checkpoint interval")
+	}
+
+	c := s.Clock
+	if c == nil {
+		c = clock.New()
+	}
+
+	for {
+		now := c.Now()
+		next := now.Truncate(s.Interval).Add(s.Interval)
+		timer := c.Timer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		err := s.LeaderBoard.Checkpoint(ctx)
+		if s.Logger != nil {
+			s.Logger.Printf("LeaderBoard.Checkpoint() -> err=%v\n", err)
+		}
+	}
+}
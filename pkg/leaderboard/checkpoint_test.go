@@ -0,0 +1,124 @@
+package leaderboard_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/benbjohnson/clock"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Checkpoint(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testCheckpoint(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testCheckpoint(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testCheckpoint(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{Storage: s}
+
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 10)).To(Succeed())
+
+	// 체크포인트 전에는 이전 순위가 없다
+	user, err := lb.GetUser(ctx, "c")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.PreviousRank).To(Equal(0))
+	g.Expect(user.RankDelta).To(Equal(0))
+
+	g.Expect(lb.Checkpoint(ctx)).To(Succeed())
+
+	g.Expect(lb.SetUser(ctx, "c", 40)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "d", 25)).To(Succeed())
+
+	user, err = lb.GetUser(ctx, "c")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Rank).To(Equal(1))
+	g.Expect(user.PreviousRank).To(Equal(3))
+	g.Expect(user.RankDelta).To(Equal(2))
+
+	users, err := lb.GetRanks(ctx, 1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(4))
+
+	type rankData struct {
+		Id                        string
+		Rank, PrevRank, RankDelta int
+	}
+	ranks := make([]rankData, len(users))
+	for i, u := range users {
+		ranks[i] = rankData{u.Id, u.Rank, u.PreviousRank, u.RankDelta}
+	}
+
+	g.Expect(ranks).To(Equal([]rankData{
+		{"c", 1, 3, 2},
+		{"a", 2, 1, -1},
+		{"d", 3, 0, 0},
+		{"b", 4, 2, -2},
+	}))
+}
+
+func TestCheckpointScheduler(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeMock := clock.NewMock()
+	timeMock.Set(time.Date(2021, 5, 1, 23, 0, 0, 0, time.UTC))
+
+	lb := &LeaderBoard{Storage: &storage.MemStorage{}}
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	scheduler := &CheckpointScheduler{
+		LeaderBoard: lb,
+		Interval:    24 * time.Hour,
+		Clock:       timeMock,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- scheduler.Run(ctx)
+	}()
+
+	// 다음 자정 전까지는 체크포인트가 없다
+	timeMock.Add(59 * time.Minute)
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.PreviousRank).To(Equal(0))
+
+	// 자정에 체크포인트를 남긴다
+	timeMock.Add(time.Minute)
+
+	g.Eventually(func() int {
+		user, _ := lb.GetUser(ctx, "a")
+		return user.PreviousRank
+	}).Should(Equal(2))
+
+	cancel()
+	g.Eventually(done).Should(Receive(Equal(context.Canceled)))
+}
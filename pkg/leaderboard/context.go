@@ -0,0 +1,32 @@
+package leaderboard
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext는 parent의 값은 그대로 가지지만 parent가 취소되거나 시간 제한이 지나도 끝나지 않는다
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach는 시작한 변경을 요청이 끊겨도 끝까지 반영할 때 사용한다
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (ctx detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (ctx detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (ctx detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
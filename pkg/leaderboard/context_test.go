@@ -0,0 +1,123 @@
+package leaderboard_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_ContextCanceled(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testContextCanceled(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testContextCanceled(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testContextCanceled(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	lb := LeaderBoard{Storage: s}
+
+	ctx := context.Background()
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	// 취소된 요청은 저장소를 바꾸지 않는다
+	g.Expect(lb.SetUser(canceled, "a", 20)).To(MatchError(context.Canceled))
+	g.Expect(lb.SetUser(canceled, "b", 30)).To(MatchError(context.Canceled))
+
+	_, err := lb.GetRanks(canceled, 1, 10)
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	expired, cancel := context.WithDeadline(ctx, time.Now())
+	defer cancel()
+
+	_, err = lb.UserCount(expired)
+	g.Expect(err).To(MatchError(context.DeadlineExceeded))
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(10))
+
+	_, err = lb.GetUser(ctx, "b")
+	g.Expect(err).To(MatchError("not found"))
+}
+
+func TestLeaderBoard_ContextCanceledMidway(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testContextCanceledMidway(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testContextCanceledMidway(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testContextCanceledMidway(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	hook := &hookStorage{Storage: s}
+	lb := LeaderBoard{Storage: hook}
+
+	ctx := context.Background()
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+
+	version, err := lb.GetVersion(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// 처리 중으로 표시한 다음 사용자를 읽는 동안 요청이 취소된다
+	canceled, cancel := context.WithCancel(ctx)
+	hook.afterGetData = cancel
+
+	// idempotency key로 받은 요청은 취소되어도 끝까지 저장한다
+	g.Expect(lb.SetUser(canceled, "a", 20, api.WithIdempotencyKey("k1"))).To(Succeed())
+	g.Expect(canceled.Err()).To(MatchError(context.Canceled))
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(20))
+
+	history, err := lb.GetHistory(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(history).To(HaveLen(2))
+	g.Expect(history[1].Score).To(Equal(20))
+
+	current, err := lb.GetVersion(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(current.Version).To(Equal(version.Version + 1))
+
+	// 다시 요청하면 처리 중이 아니라 처음 결과를 받는다
+	result := api.User{}
+	g.Expect(lb.SetUser(ctx, "a", 20, api.WithIdempotencyKey("k1"), api.WithResult(&result))).To(Succeed())
+	g.Expect(result.Score).To(Equal(20))
+}
@@ -0,0 +1,261 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+type Event = api.Event
+
+const (
+	eventStreamKey = "events"
+	eventBatchSize = 100
+
+	DefaultEventPollInterval = time.Second
+)
+
+func (lb *LeaderBoard) eventPollInterval() time.Duration {
+	if lb.EventPollInterval > 0 {
+		return lb.EventPollInterval
+	}
+	return DefaultEventPollInterval
+}
+
+// eventItem은 event를 스트림에 추가하는 변경
+func (lb *LeaderBoard) eventItem(event Event) (storage.Append, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return storage.Append{}, err
+	}
+
+	return storage.Append{Key: eventStreamKey, Data: data, MaxLen: lb.MaxEvents}, nil
+}
+
+func (lb *LeaderBoard) eventItems(events []Event) ([]storage.Append, error) {
+	items := make([]storage.Append, len(events))
+	for i, event := range events {
+		item, err := lb.eventItem(event)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// eventRank는 이벤트에 기록할 user의 공개 순위를 반환한다. 이벤트를 기록하지 않거나 공개 순위에 없으면 0.
+func (lb *LeaderBoard) eventRank(ctx context.Context, user User) (int, error) {
+	if lb.MaxEvents <= 0 || user.Id == "" || !isPublic(user) {
+		return 0, nil
+	}
+
+	ranks, err := lb.Storage.GetRanks(ctx, user.Id)
+	if err != nil {
+		return 0, err
+	}
+
+	return ranks[0], nil
+}
+
+// changeEvents는 oldUser를 newUser로 저장할 때 기록할 점수 변경 이벤트들을 반환한다.
+// 저장과 함께 기록하도록 저장하기 전의 순위로 계산한다. 사용자가 없었으면 oldUser는 zero value.
+func (lb *LeaderBoard) changeEvents(ctx context.Context, oldUser, newUser User) ([]Event, error) {
+	if lb.MaxEvents <= 0 {
+		return nil, nil
+	}
+
+	exists := oldUser.Id != ""
+
+	// 공개 순위에서 빠지거나 돌아온 사용자는 점수가 같아도 다른 사용자들의 순위를 바꾼다
+	if exists && isPublic(oldUser) && !isPublic(newUser) {
+		// 숨겨진 것이 드러나지 않도록 삭제처럼 UserId 없이 알린다
+		return lb.deletedEvents(User{Score: oldUser.Score}), nil
+	}
+	if exists && !isPublic(oldUser) && isPublic(newUser) {
+		// 새로 추가된 사용자처럼 알린다
+		oldUser, exists = User{}, false
+	}
+
+	if exists && oldUser.Score == newUser.Score {
+		return nil, nil
+	}
+
+	previousRank, err := lb.eventRank(ctx, oldUser)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := 0
+	if isPublic(newUser) {
+		// 동점인 사용자들보다는 앞 순위로 센다
+		if rank, err = lb.virtualRank(ctx, "", newUser.Score); err != nil {
+			return nil, err
+		}
+
+		// 지금 순위에서 newUser보다 위에 있는 자신은 빼고 센다
+		if previousRank > 0 && oldUser.Score > newUser.Score {
+			rank--
+		}
+	}
+
+	event := Event{
+		Type:      api.EventUserAdded,
+		UserId:    newUser.Id,
+		Score:     newUser.Score,
+		Rank:      rank,
+		CreatedAt: lb.now(),
+	}
+
+	if exists {
+		event.Type = api.EventScoreChanged
+		event.PreviousScore = oldUser.Score
+		event.PreviousRank = previousRank
+	}
+
+	events := []Event{event}
+
+	if rank == 0 || rank > lb.EventTopN {
+		return events, nil
+	}
+
+	if previousRank == 0 || previousRank > lb.EventTopN {
+		event.Type = api.EventEnteredTop
+		event.PreviousScore = oldUser.Score
+		event.PreviousRank = previousRank
+		events = append(events, event)
+	}
+
+	overtaken, err := lb.overtakenEvents(ctx, newUser, rank, previousRank)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(events, overtaken...), nil
+}
+
+// overtakenEvents는 rank로 올라오는 사용자에게 한 순위씩 밀려나는 사용자들 중에서
+// 상위 EventTopN위 안에 있던 사용자들에 대한 overtaken 이벤트를 반환한다
+func (lb *LeaderBoard) overtakenEvents(ctx context.Context, user User, rank, previousRank int) ([]Event, error) {
+	// 추월당하는 사용자들은 지금 rank위부터 previousRank-1위까지 있다
+	last := lb.EventTopN + 1
+	if previousRank > 0 && previousRank < last {
+		last = previousRank
+	}
+
+	if last <= rank {
+		return nil, nil
+	}
+
+	userIds, err := lb.Storage.GetSortedRange(ctx, rank, last-rank)
+	if err != nil {
+		return nil, err
+	}
+
+	scores, err := lb.Storage.GetIndexScores(ctx, "", userIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for i, overtakenId := range userIds {
+		// 같은 점수는 추월한 것이 아니다
+		if scores[i] >= user.Score {
+			continue
+		}
+
+		events = append(events, Event{
+			Type:         api.EventOvertaken,
+			UserId:       overtakenId,
+			Score:        scores[i],
+			Rank:         rank + 1 + i,
+			PreviousRank: rank + i,
+			OvertakenBy:  user.Id,
+			CreatedAt:    lb.now(),
+		})
+	}
+
+	return events, nil
+}
+
+// deletedEvents는 user를 삭제할 때 기록할 이벤트들을 반환한다
+func (lb *LeaderBoard) deletedEvents(user User) []Event {
+	if lb.MaxEvents <= 0 {
+		return nil
+	}
+
+	return []Event{{
+		Type:      api.EventUserDeleted,
+		UserId:    user.Id,
+		Score:     user.Score,
+		CreatedAt: lb.now(),
+	}}
+}
+
+// GetEvents는 순번이 afterSeq보다 큰 변경 이벤트들을 오래된 순서로 최대 count개 반환한다.
+// 보관 개수를 넘었거나 EraseUser로 지워진 이벤트들은 건너뛰므로, 받은 Seq가 이어지지 않으면 그 사이의 이벤트를 놓친 것이다.
+func (lb *LeaderBoard) GetEvents(ctx context.Context, afterSeq int64, count int) ([]Event, error) {
+	if count <= 0 {
+		return nil, api.ErrorWithStatusCode(errors.New("invalid count"), http.StatusBadRequest)
+	}
+
+	seqs, rawList, err := lb.Storage.ReadStream(ctx, eventStreamKey, afterSeq, count)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &events[i]); err != nil {
+			return nil, err
+		}
+		events[i].Seq = seqs[i]
+	}
+
+	return events, nil
+}
+
+// Subscribe는 순번이 afterSeq보다 큰 변경 이벤트들을 차례로 handler에 넘긴다.
+// ctx가 끝나거나 handler가 오류를 반환할 때까지 새 이벤트를 기다린다.
+func (lb *LeaderBoard) Subscribe(ctx context.Context, afterSeq int64, handler func(Event) error) error {
+	ticker := time.NewTicker(lb.eventPollInterval())
+	defer ticker.Stop()
+
+	for {
+		events, err := lb.GetEvents(ctx, afterSeq, eventBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := handler(event); err != nil {
+				return err
+			}
+			afterSeq = event.Seq
+		}
+
+		// 아직 읽지 않은 이벤트가 남아있을 수 있다
+		if len(events) == eventBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// LastEventSeq는 마지막으로 기록한 변경 이벤트의 순번을 반환한다. 이후의 이벤트만 받으려면 이 순번부터 구독한다.
+func (lb *LeaderBoard) LastEventSeq(ctx context.Context) (int64, error) {
+	if lb.MaxEvents <= 0 {
+		return 0, api.ErrorWithStatusCode(errors.New("change feed is disabled"), http.StatusConflict)
+	}
+	return lb.Storage.LastStreamSeq(ctx, eventStreamKey)
+}
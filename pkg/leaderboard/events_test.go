@@ -0,0 +1,296 @@
+package leaderboard_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Events(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testEvents(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testEvents(t, miniredisStorage{
+			RedisStorage: &storage.RedisStorage{
+				KeyPrefix: "test",
+				Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+			},
+			server: s,
+		})
+	})
+}
+
+// miniredisStorage는 miniredis의 XDEL이 지울 항목을 제대로 찾지 못하는 문제를 피한다.
+// 지울 항목을 뺀 나머지 항목들을 miniredis에 직접 다시 쓴다.
+type miniredisStorage struct {
+	*storage.RedisStorage
+	server *miniredis.Miniredis
+}
+
+func (s miniredisStorage) DeleteStreamItems(ctx context.Context, key string, seqs ...int64) error {
+	streamKey := s.KeyPrefix + "_stream_" + key
+
+	entries, err := s.server.Stream(streamKey)
+	if err == miniredis.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	deleted := map[string]bool{}
+	for _, seq := range seqs {
+		deleted[fmt.Sprintf("%d-0", seq)] = true
+	}
+
+	s.server.Del(streamKey)
+	for _, entry := range entries {
+		if deleted[entry.ID] {
+			continue
+		}
+		if _, err := s.server.XAdd(streamKey, entry.ID, entry.Values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func testEvents(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage:   s,
+		NowFunc:   func() time.Time { return now },
+		MaxEvents: 6,
+		EventTopN: 1,
+	}
+
+	seq, err := lb.LastEventSeq(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(seq).To(Equal(int64(0)))
+
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 40)).To(Succeed())
+
+	seq, err = lb.LastEventSeq(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(seq).To(Equal(int64(6)))
+
+	events, err := lb.GetEvents(ctx, 0, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(Equal([]Event{
+		{Seq: 1, Type: api.EventUserAdded, UserId: "a", Score: 30, Rank: 1, CreatedAt: now},
+		{Seq: 2, Type: api.EventEnteredTop, UserId: "a", Score: 30, Rank: 1, CreatedAt: now},
+		{Seq: 3, Type: api.EventUserAdded, UserId: "b", Score: 20, Rank: 2, CreatedAt: now},
+		{Seq: 4, Type: api.EventScoreChanged, UserId: "b", Score: 40, PreviousScore: 20, Rank: 1, PreviousRank: 2, CreatedAt: now},
+		{Seq: 5, Type: api.EventEnteredTop, UserId: "b", Score: 40, PreviousScore: 20, Rank: 1, PreviousRank: 2, CreatedAt: now},
+		{Seq: 6, Type: api.EventOvertaken, UserId: "a", Score: 30, Rank: 2, PreviousRank: 1, OvertakenBy: "b", CreatedAt: now},
+	}))
+
+	// 점수가 바뀌지 않으면 이벤트가 없다
+	g.Expect(lb.SetUser(ctx, "b", 40)).To(Succeed())
+	_, err = lb.EraseUser(ctx, "a", api.Audit{Actor: "admin"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	events, err = lb.GetEvents(ctx, 6, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(Equal([]Event{
+		{Seq: 7, Type: api.EventUserDeleted, Score: 30, CreatedAt: now},
+	}))
+
+	// MaxEvents를 넘은 오래된 이벤트와 EraseUser로 지운 사용자의 이벤트는 남지 않는다
+	events, err = lb.GetEvents(ctx, 0, 2)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(HaveLen(2))
+	g.Expect(events[0].Seq).To(Equal(int64(3)))
+	g.Expect(events[1].Seq).To(Equal(int64(4)))
+
+	for i := 0; i < 20; i++ {
+		g.Expect(lb.SetUser(ctx, "c", i+1)).To(Succeed())
+	}
+
+	events, err = lb.GetEvents(ctx, 0, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(HaveLen(6))
+	g.Expect(events[0].Seq).To(Equal(int64(22)))
+	g.Expect(events[5].Seq).To(Equal(int64(27)))
+	g.Expect(events[5].Score).To(Equal(20))
+
+	seq, err = lb.LastEventSeq(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(seq).To(Equal(int64(27)))
+
+	_, err = lb.GetEvents(ctx, 0, 0)
+	g.Expect(err).To(MatchError("invalid count"))
+}
+
+func TestLeaderBoard_EventsConcurrentWrites(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{
+		Storage:   &storage.MemStorage{},
+		MaxEvents: 1000,
+	}
+
+	// 다른 요청과 겹칠 때 다시 시도하는 횟수를 넘지 않도록 5개만 동시에 보낸다
+	var wg sync.WaitGroup
+	for i := 1; i <= 5; i++ {
+		wg.Add(1)
+		go func(score int) {
+			defer wg.Done()
+			g.Expect(lb.SetUser(ctx, "a", score)).To(Succeed())
+		}(i)
+	}
+	wg.Wait()
+
+	// 이벤트는 저장과 함께 기록되므로 저장된 순서대로 이어진다
+	events, err := lb.GetEvents(ctx, 0, 100)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(HaveLen(5))
+	for i := 1; i < len(events); i++ {
+		g.Expect(events[i].PreviousScore).To(Equal(events[i-1].Score))
+	}
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(events[len(events)-1].Score))
+}
+
+func TestLeaderBoard_EventsDisabled(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{Storage: &storage.MemStorage{}}
+
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+
+	events, err := lb.GetEvents(ctx, 0, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(BeEmpty())
+
+	_, err = lb.LastEventSeq(ctx)
+	g.Expect(err).To(MatchError("change feed is disabled"))
+}
+
+func TestLeaderBoard_Subscribe(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		MaxEvents:         1000,
+		EventPollInterval: time.Millisecond,
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+
+	received := make(chan Event, 10)
+	done := make(chan error)
+	go func() {
+		done <- lb.Subscribe(ctx, 0, func(event Event) error {
+			received <- event
+			return nil
+		})
+	}()
+
+	var event Event
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.Seq).To(Equal(int64(1)))
+	g.Expect(event.UserId).To(Equal("a"))
+
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.Seq).To(Equal(int64(2)))
+
+	cancel()
+	g.Eventually(done).Should(Receive(Equal(context.Canceled)))
+
+	// 마지막으로 받은 순번부터 다시 구독한다
+	errStop := errors.New("stop")
+	err := lb.Subscribe(context.Background(), 1, func(event Event) error {
+		g.Expect(event.UserId).To(Equal("b"))
+		return errStop
+	})
+	g.Expect(err).To(Equal(errStop))
+}
+
+func TestLeaderBoard_StateEvents(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testStateEvents(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testStateEvents(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+// 공개 순위에서 숨기거나 되돌린 사용자는 다른 사용자들의 순위를 바꾸므로 이벤트로 알린다
+func testStateEvents(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage:   s,
+		NowFunc:   func() time.Time { return now },
+		MaxEvents: 100,
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	seq, err := lb.LastEventSeq(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// 숨겨진 것이 드러나지 않도록 UserId 없이 삭제로 알린다
+	g.Expect(lb.SetUserState(ctx, "a", api.UserStateShadowBanned)).To(Succeed())
+	g.Expect(lb.SetUserState(ctx, "a", api.UserStateHidden)).To(Succeed())
+
+	events, err := lb.GetEvents(ctx, seq, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(Equal([]Event{
+		{Seq: seq + 1, Type: api.EventUserDeleted, Score: 30, CreatedAt: now},
+	}))
+
+	g.Expect(lb.SetUserState(ctx, "a", api.UserStateActive)).To(Succeed())
+
+	events, err = lb.GetEvents(ctx, seq+1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(Equal([]Event{
+		{Seq: seq + 2, Type: api.EventUserAdded, UserId: "a", Score: 30, Rank: 1, CreatedAt: now},
+	}))
+}
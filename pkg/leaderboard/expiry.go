@@ -0,0 +1,247 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+const (
+	// updatedIndex는 점수가 마지막으로 바뀐 시각 순서로 사용자를 모아둔 인덱스
+	updatedIndex = "updated"
+
+	expireBatchSize = 100
+)
+
+// updatedScore는 오래 전에 갱신된 사용자일수록 updatedIndex에서 앞 순위가 되도록 시각을 점수로 바꾼다
+func updatedScore(updatedAt time.Time) int {
+	return -int(updatedAt.Unix())
+}
+
+// tracksUpdate는 user를 만료 대상으로 updatedIndex에 넣을지 반환한다.
+// ban된 사용자가 만료되어 다시 가입하는 일이 없도록 만료 대상에서 뺀다.
+func (lb *LeaderBoard) tracksUpdate(user User) bool {
+	return lb.InactivityTTL > 0 && user.State != api.UserStateBanned && user.State != api.UserStateShadowBanned
+}
+
+// ExpireInactive는 InactivityTTL 동안 점수가 바뀌지 않은 사용자를 삭제하고 삭제한 사용자 수를 반환한다.
+// 체크포인트와 스냅샷은 그 시점의 기록이므로 그대로 둔다.
+func (lb *LeaderBoard) ExpireInactive(ctx context.Context) (int, error) {
+	if lb.InactivityTTL <= 0 {
+		return 0, nil
+	}
+
+	if err := lb.backfillUpdatedIndex(ctx); err != nil {
+		return 0, err
+	}
+
+	cutoff := lb.now().Add(-lb.InactivityTTL)
+	expired := 0
+
+	for {
+		// cutoff 이전에 갱신된 사용자는 updatedIndex에서 cutoff보다 점수가 높다
+		count, err := lb.Storage.IndexCountAbove(ctx, updatedIndex, updatedScore(cutoff))
+		if err != nil {
+			return expired, err
+		}
+
+		if count == 0 {
+			return expired, nil
+		}
+
+		if count > expireBatchSize {
+			count = expireBatchSize
+		}
+
+		userIds, err := lb.Storage.GetIndexSortedRange(ctx, updatedIndex, 1, count)
+		if err != nil {
+			return expired, err
+		}
+
+		for _, userId := range userIds {
+			ok, err := lb.expireUser(ctx, userId, cutoff)
+			if err != nil {
+				return expired, err
+			}
+
+			if ok {
+				expired++
+			}
+		}
+	}
+}
+
+// expireUser는 cutoff 이전에 갱신된 사용자를 삭제하고, 삭제했는지 여부를 반환한다
+func (lb *LeaderBoard) expireUser(ctx context.Context, userId string, cutoff time.Time) (bool, error) {
+	user, userData, err := lb.loadUser(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	exists := userData != nil
+
+	// 인덱스를 읽은 다음에 점수가 바뀌었거나 만료 대상이 아니게 되었으면 인덱스만 고친다.
+	// 그 사이에 다시 바뀌었으면 다음 배치에서 다시 읽는다.
+	if exists && (!user.UpdatedAt.Before(cutoff) || !lb.tracksUpdate(user)) {
+		w := storage.Write{
+			Key:        userId,
+			Data:       userData,
+			CheckData:  true,
+			ExpectData: userData,
+		}
+		if lb.tracksUpdate(user) {
+			w.IndexScores = []storage.IndexScore{{Name: updatedIndex, Score: updatedScore(user.UpdatedAt)}}
+		} else {
+			w.RemoveIndexes = []string{updatedIndex}
+		}
+
+		_, err := lb.Storage.WriteData(ctx, w)
+		return false, err
+	}
+
+	addIndexes, _ := lb.segmentIndexChanges(nil, user.Attributes)
+
+	// 읽은 다음에 점수가 제출되었으면 지우지 않는다
+	w := storage.Write{
+		Key:           userId,
+		Delete:        true,
+		RemoveIndexes: append([]string{"", updatedIndex}, addIndexes...),
+		CheckData:     true,
+		ExpectData:    userData,
+		DeleteLists:   []string{historyListKey(userId)},
+	}
+
+	if exists {
+		if w.Streams, err = lb.eventItems(lb.deletedEvents(user)); err != nil {
+			return false, err
+		}
+		w.Incrs = statsIncrs(user, isRanked(user, exists), User{}, false)
+		lb.bumpVersion(&w)
+	}
+
+	applied, err := lb.Storage.WriteData(ctx, w)
+	if err != nil || !applied || !exists {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// updatedIndexBackfilledKey는 updatedIndex를 저장된 사용자들로 채웠는지 기록하는 값
+const updatedIndexBackfilledKey = "updated_index_backfilled"
+
+// backfillUpdatedIndex는 InactivityTTL 없이 저장된 사용자들을 updatedIndex에 넣는다.
+// 그렇지 않으면 그 사용자들은 다시 점수를 제출할 때까지 만료되지 않는다.
+// InactivityTTL 없이 사용자를 저장할 때마다 채웠다는 기록을 지우므로 그 다음 ExpireInactive가 다시 채운다.
+func (lb *LeaderBoard) backfillUpdatedIndex(ctx context.Context) error {
+	done, err := lb.Storage.GetValue(ctx, updatedIndexBackfilledKey)
+	if err != nil || done != nil {
+		return err
+	}
+
+	// 갱신 시각이 없는 사용자는 지금부터 InactivityTTL이 지나면 만료된다
+	now := lb.now()
+
+	err = lb.Storage.ScanDataKeys(ctx, expireBatchSize, func(userIds []string) error {
+		rawList, err := lb.Storage.GetData(ctx, userIds...)
+		if err != nil {
+			return err
+		}
+
+		for _, data := range rawList {
+			if len(data) == 0 {
+				continue
+			}
+
+			user := User{}
+			if err := json.Unmarshal(data, &user); err != nil {
+				return err
+			}
+
+			if !lb.tracksUpdate(user) {
+				continue
+			}
+
+			updatedAt := user.UpdatedAt
+			if updatedAt.IsZero() {
+				updatedAt = now
+			}
+
+			// 그 사이에 점수가 제출되었으면 이미 updatedIndex에 들어있다
+			_, err := lb.Storage.WriteData(ctx, storage.Write{
+				Key:         user.Id,
+				Data:        data,
+				IndexScores: []storage.IndexScore{{Name: updatedIndex, Score: updatedScore(updatedAt)}},
+				CheckData:   true,
+				ExpectData:  data,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return lb.Storage.SetValue(ctx, updatedIndexBackfilledKey, []byte("1"), 0)
+}
+
+// ExpirySweeper는 Interval마다 ExpireInactive를 실행한다
+type ExpirySweeper struct {
+	// metrics 등의 middleware를 거치도록 api.LeaderBoard로 호출한다
+	LeaderBoard api.LeaderBoard
+	Interval    time.Duration
+
+	// 설정하면 Interval마다 lease를 잡은 서버 하나만 실행한다
+	Lease *Lease
+
+	// nil이면 실제 시계를 사용한다
+	Clock  clock.Clock
+	Logger *log.Logger
+}
+
+func (s *ExpirySweeper) Run(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return errors.New("invalid expiry interval")
+	}
+
+	c := s.Clock
+	if c == nil {
+		c = clock.New()
+	}
+
+	ticker := c.Ticker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if s.Lease != nil {
+			acquired, err := s.Lease.TryAcquire(ctx, s.Interval)
+			if err != nil && s.Logger != nil {
+				s.Logger.Printf("ExpirySweeper: lease error: %v\n", err)
+			}
+			if !acquired {
+				continue
+			}
+		}
+
+		start := c.Now()
+		expired, err := s.LeaderBoard.ExpireInactive(ctx)
+		if s.Logger != nil {
+			s.Logger.Printf("LeaderBoard.ExpireInactive() -> %v, err=%v, elapsed=%v\n", expired, err, c.Since(start))
+		}
+	}
+}
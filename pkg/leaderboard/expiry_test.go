@@ -0,0 +1,333 @@
+package leaderboard_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/benbjohnson/clock"
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/api/apifakes"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_ExpireInactive(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testExpireInactive(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testExpireInactive(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testExpireInactive(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage:           s,
+		NowFunc:           func() time.Time { return now },
+		SegmentAttributes: []string{"country"},
+		InactivityTTL:     30 * 24 * time.Hour,
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 30, api.WithAttribute("country", "kr"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "banned", 10)).To(Succeed())
+	g.Expect(lb.SetUserState(ctx, "banned", api.UserStateBanned)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "shadow", 10)).To(Succeed())
+	g.Expect(lb.SetUserState(ctx, "shadow", api.UserStateShadowBanned)).To(Succeed())
+	_, err := lb.CreateSnapshot(ctx, "s1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	now = now.Add(20 * 24 * time.Hour)
+	g.Expect(lb.SetUser(ctx, "b", 25)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 5)).To(Succeed())
+
+	// 아직 만료된 사용자가 없다
+	expired, err := lb.ExpireInactive(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(Equal(0))
+
+	now = now.Add(15 * 24 * time.Hour)
+
+	expired, err = lb.ExpireInactive(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(Equal(1))
+
+	_, err = lb.GetUser(ctx, "a")
+	g.Expect(err).To(MatchError("not found"))
+
+	count, err := lb.UserCount(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(2))
+
+	count, err = lb.UserCount(ctx, api.WithSegment("country", "kr"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(0))
+
+	history, err := lb.GetHistory(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(history).To(BeEmpty())
+
+	// 스냅샷은 그대로 남는다
+	user, err := lb.GetUser(ctx, "a", api.WithSnapshot("s1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(30))
+
+	// ban된 사용자는 만료되지 않는다
+	user, err = lb.GetUser(ctx, "banned", api.WithHidden())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.State).To(Equal(api.UserStateBanned))
+
+	user, err = lb.GetUser(ctx, "shadow", api.WithHidden())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.State).To(Equal(api.UserStateShadowBanned))
+
+	now = now.Add(30 * 24 * time.Hour)
+
+	expired, err = lb.ExpireInactive(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(Equal(2))
+
+	count, err = lb.UserCount(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(0))
+}
+
+func TestLeaderBoard_ExpireInactiveBatches(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage:       &storage.MemStorage{},
+		NowFunc:       func() time.Time { return now },
+		InactivityTTL: time.Hour,
+	}
+
+	for i := 0; i < 250; i++ {
+		g.Expect(lb.SetUser(ctx, "user"+strconv.Itoa(i), i+1)).To(Succeed())
+		now = now.Add(time.Second)
+	}
+
+	now = now.Add(time.Hour)
+
+	expired, err := lb.ExpireInactive(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(Equal(250))
+}
+
+func TestLeaderBoard_ExpireInactiveBackfill(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	s := &storage.MemStorage{}
+
+	// InactivityTTL을 설정하기 전에 저장된 사용자들
+	before := LeaderBoard{
+		Storage: s,
+		NowFunc: func() time.Time { return now },
+	}
+	g.Expect(before.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(before.SetUser(ctx, "hidden", 20)).To(Succeed())
+	g.Expect(before.SetUserState(ctx, "hidden", api.UserStateHidden)).To(Succeed())
+	g.Expect(before.SetUser(ctx, "shadow", 30)).To(Succeed())
+	g.Expect(before.SetUserState(ctx, "shadow", api.UserStateShadowBanned)).To(Succeed())
+
+	lb := LeaderBoard{
+		Storage:       s,
+		NowFunc:       func() time.Time { return now },
+		InactivityTTL: time.Hour,
+	}
+
+	now = now.Add(2 * time.Hour)
+	g.Expect(lb.SetUser(ctx, "b", 40)).To(Succeed())
+
+	expired, err := lb.ExpireInactive(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(Equal(2))
+
+	for _, userId := range []string{"a", "hidden"} {
+		_, err = lb.GetUser(ctx, userId, api.WithHidden())
+		g.Expect(err).To(MatchError("not found"))
+	}
+
+	for _, userId := range []string{"b", "shadow"} {
+		_, err = lb.GetUser(ctx, userId, api.WithHidden())
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+
+	// 한 번 채운 다음에도 InactivityTTL 없이 저장된 사용자는 다시 채워서 만료한다
+	g.Expect(before.SetUser(ctx, "c", 50)).To(Succeed())
+
+	now = now.Add(2 * time.Hour)
+	expired, err = lb.ExpireInactive(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(Equal(2))
+
+	for _, userId := range []string{"b", "c"} {
+		_, err = lb.GetUser(ctx, userId, api.WithHidden())
+		g.Expect(err).To(MatchError("not found"))
+	}
+}
+
+func TestLeaderBoard_ExpireInactiveConcurrentSubmit(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	s := &hookStorage{Storage: &storage.MemStorage{}}
+	lb := LeaderBoard{
+		Storage:       s,
+		NowFunc:       func() time.Time { return now },
+		InactivityTTL: time.Hour,
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	now = now.Add(2 * time.Hour)
+
+	// 만료할 사용자를 읽은 직후에 점수가 제출된다
+	s.afterGetData = func() {
+		g.Expect(lb.SetUser(ctx, "a", 20)).To(Succeed())
+	}
+
+	expired, err := lb.ExpireInactive(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(Equal(0))
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(20))
+
+	history, err := lb.GetHistory(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(history).To(HaveLen(2))
+}
+
+// hookStorage는 처음으로 사용자 data를 읽은 직후에 afterGetData를 한 번 실행한다
+type hookStorage struct {
+	Storage
+	afterGetData func()
+}
+
+func (s *hookStorage) GetData(ctx context.Context, keys ...string) ([][]byte, error) {
+	data, err := s.Storage.GetData(ctx, keys...)
+	if hook := s.afterGetData; hook != nil {
+		s.afterGetData = nil
+		hook()
+	}
+	return data, err
+}
+
+func TestExpirySweeper(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeMock := clock.NewMock()
+	timeMock.Set(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	lb := &LeaderBoard{
+		Storage:       &storage.MemStorage{},
+		NowFunc:       timeMock.Now,
+		InactivityTTL: 24 * time.Hour,
+	}
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+
+	logs := &syncBuffer{}
+	sweeper := &ExpirySweeper{
+		LeaderBoard: lb,
+		Interval:    time.Hour,
+		Clock:       timeMock,
+		Logger:      log.New(logs, "", 0),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- sweeper.Run(ctx)
+	}()
+
+	g.Eventually(func() int {
+		timeMock.Add(time.Hour)
+		count, _ := lb.UserCount(ctx)
+		return count
+	}).Should(Equal(0))
+
+	g.Eventually(logs.String).Should(ContainSubstring("LeaderBoard.ExpireInactive() -> 1, err=<nil>"))
+
+	cancel()
+	g.Eventually(done).Should(Receive(Equal(context.Canceled)))
+}
+
+func TestExpirySweeper_Lease(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeMock := clock.NewMock()
+	s := &storage.MemStorage{NowFunc: timeMock.Now}
+
+	// 같은 저장소를 쓰는 두 서버 중 한 서버만 실행한다
+	fakes := []*apifakes.FakeLeaderBoard{{}, {}}
+	for _, fake := range fakes {
+		sweeper := &ExpirySweeper{
+			LeaderBoard: fake,
+			Interval:    time.Hour,
+			Lease:       &Lease{Storage: s, Name: "expiry"},
+			Clock:       timeMock,
+		}
+		go sweeper.Run(ctx)
+	}
+
+	calls := func() int {
+		return fakes[0].ExpireInactiveCallCount() + fakes[1].ExpireInactiveCallCount()
+	}
+
+	for i := 1; i <= 3; i++ {
+		// 두 sweeper가 ticker를 만들 때까지 기다린다
+		time.Sleep(10 * time.Millisecond)
+		timeMock.Add(time.Hour)
+		g.Eventually(calls).Should(Equal(i))
+		g.Consistently(calls, 50*time.Millisecond).Should(Equal(i))
+	}
+}
+
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
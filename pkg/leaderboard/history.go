@@ -0,0 +1,173 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+type HistoryEntry = api.HistoryEntry
+
+func historyListKey(userId string) string {
+	return "history_" + userId
+}
+
+// DefaultMaxHistory는 MaxHistory가 0일 때 사용자별로 보관하는 history 수
+const DefaultMaxHistory = 100
+
+func (lb *LeaderBoard) maxHistory() int {
+	switch {
+	case lb.MaxHistory > 0:
+		return lb.MaxHistory
+	case lb.MaxHistory < 0:
+		return 0
+	}
+	return DefaultMaxHistory
+}
+
+// historyItem은 사용자 데이터와 함께 저장할 history 항목을 만든다
+func (lb *LeaderBoard) historyItem(userId string, entry HistoryEntry) (storage.Append, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return storage.Append{}, err
+	}
+
+	return storage.Append{Key: historyListKey(userId), Data: data, MaxLen: lb.maxHistory()}, nil
+}
+
+func (lb *LeaderBoard) GetHistory(ctx context.Context, userId string) ([]HistoryEntry, error) {
+	userId, err := lb.storedUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return lb.getHistory(ctx, userId)
+}
+
+func (lb *LeaderBoard) getHistory(ctx context.Context, userId string) ([]HistoryEntry, error) {
+	rawList, err := lb.Storage.GetList(ctx, historyListKey(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &entries[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// OverrideScore는 ban된 사용자의 점수도 고칠 수 있다
+func (lb *LeaderBoard) OverrideScore(ctx context.Context, userId string, score int, audit api.Audit) error {
+	userId, err := lb.storedUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if audit.Actor == "" {
+		return api.ErrorWithStatusCode(errors.New("actor is empty"), http.StatusBadRequest)
+	}
+
+	return lb.retryOnConflict(func() error {
+		oldUser, oldData, err := lb.loadUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		if oldData == nil {
+			return api.ErrorWithStatusCode(errors.New("not found"), http.StatusNotFound)
+		}
+
+		return lb.changeScore(ctx, userId, oldUser, oldData, score, api.HistoryKindOverride, audit)
+	})
+}
+
+// RevertScore는 되돌릴 override 이후에 점수가 바뀌었으면 새 점수를 덮어쓰지 않고 409로 응답한다
+func (lb *LeaderBoard) RevertScore(ctx context.Context, userId string, audit api.Audit) error {
+	userId, err := lb.storedUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if audit.Actor == "" {
+		return api.ErrorWithStatusCode(errors.New("actor is empty"), http.StatusBadRequest)
+	}
+
+	return lb.retryOnConflict(func() error {
+		oldUser, oldData, err := lb.loadUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		if oldData == nil {
+			return api.ErrorWithStatusCode(errors.New("not found"), http.StatusNotFound)
+		}
+
+		entries, err := lb.getHistory(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		entry, ok := revertTarget(entries)
+		if !ok {
+			return api.ErrorWithStatusCode(errors.New("nothing to revert"), http.StatusConflict)
+		}
+
+		if entry.Score != oldUser.Score {
+			return api.ErrorWithStatusCode(errors.New("score was changed after the override"), http.StatusConflict)
+		}
+
+		return lb.changeScore(ctx, userId, oldUser, oldData, entry.PreviousScore, api.HistoryKindRevert, audit)
+	})
+}
+
+// revertTarget은 최근 내역부터 보면서 아직 되돌리지 않은 가장 최근의 override를 찾는다.
+// revert 하나가 override 하나를 상쇄한다. 그 override 이후에 제출된 점수가 있으면 되돌릴 수 없다.
+func revertTarget(entries []HistoryEntry) (HistoryEntry, bool) {
+	reverted := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		switch entries[i].Kind {
+		case api.HistoryKindSet:
+			if reverted == 0 {
+				return HistoryEntry{}, false
+			}
+		case api.HistoryKindRevert:
+			reverted++
+		case api.HistoryKindOverride:
+			if reverted > 0 {
+				reverted--
+				continue
+			}
+			return entries[i], true
+		}
+	}
+
+	return HistoryEntry{}, false
+}
+
+// changeScore는 oldData로 읽었던 사용자의 점수를 관리자 작업으로 바꾸고 history에 함께 기록한다.
+// 사용자가 없으면 oldUser는 zero value, oldData는 nil.
+func (lb *LeaderBoard) changeScore(ctx context.Context, userId string, oldUser User, oldData []byte, score int, kind api.HistoryKind, audit api.Audit) error {
+	newUser := oldUser
+	newUser.Id = userId
+	newUser.Score = score
+	newUser.UpdatedAt = lb.now()
+
+	return lb.saveUser(ctx, oldUser, oldData, newUser, userChange{
+		history: &HistoryEntry{
+			Kind:          kind,
+			Score:         score,
+			PreviousScore: oldUser.Score,
+			Actor:         audit.Actor,
+			Reason:        audit.Reason,
+			CreatedAt:     newUser.UpdatedAt,
+		},
+	})
+}
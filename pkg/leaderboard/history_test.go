@@ -0,0 +1,160 @@
+package leaderboard_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_History(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testHistory(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testHistory(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testHistory(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage: s,
+		NowFunc: func() time.Time { return now },
+	}
+
+	admin := api.Audit{Actor: "admin", Reason: "cheating"}
+
+	g.Expect(lb.OverrideScore(ctx, "a", 10, admin)).To(MatchError("not found"))
+
+	g.Expect(lb.SetUser(ctx, "a", 100)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 50)).To(Succeed())
+
+	g.Expect(lb.RevertScore(ctx, "a", admin)).To(MatchError("nothing to revert"))
+	g.Expect(lb.OverrideScore(ctx, "a", 10, api.Audit{})).To(MatchError("actor is empty"))
+
+	now = now.Add(time.Hour)
+	g.Expect(lb.OverrideScore(ctx, "a", 10, admin)).To(Succeed())
+	g.Expect(lb.OverrideScore(ctx, "a", 20, admin)).To(Succeed())
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(20))
+	g.Expect(user.Rank).To(Equal(2))
+	g.Expect(user.UpdatedAt).To(Equal(now))
+
+	// revert는 아직 되돌리지 않은 가장 최근 override부터 되돌린다
+	g.Expect(lb.RevertScore(ctx, "a", api.Audit{Actor: "admin"})).To(Succeed())
+	user, err = lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(10))
+
+	g.Expect(lb.RevertScore(ctx, "a", api.Audit{Actor: "admin"})).To(Succeed())
+	user, err = lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(100))
+	g.Expect(user.Rank).To(Equal(1))
+
+	g.Expect(lb.RevertScore(ctx, "a", admin)).To(MatchError("nothing to revert"))
+
+	entries, err := lb.GetHistory(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(Equal([]api.HistoryEntry{
+		{Kind: api.HistoryKindSet, Score: 100, PreviousScore: 0, CreatedAt: now.Add(-time.Hour)},
+		{Kind: api.HistoryKindOverride, Score: 10, PreviousScore: 100, Actor: "admin", Reason: "cheating", CreatedAt: now},
+		{Kind: api.HistoryKindOverride, Score: 20, PreviousScore: 10, Actor: "admin", Reason: "cheating", CreatedAt: now},
+		{Kind: api.HistoryKindRevert, Score: 10, PreviousScore: 20, Actor: "admin", CreatedAt: now},
+		{Kind: api.HistoryKindRevert, Score: 100, PreviousScore: 10, Actor: "admin", CreatedAt: now},
+	}))
+
+	// ban된 사용자도 점수를 고칠 수 있다
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateBanned)).To(Succeed())
+	g.Expect(lb.OverrideScore(ctx, "b", 0, admin)).To(Succeed())
+
+	user, err = lb.GetUser(ctx, "b", api.WithHidden())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(0))
+
+	// MaxHistory를 넘으면 오래된 내역부터 지운다
+	lb.MaxHistory = 2
+	g.Expect(lb.SetUser(ctx, "c", 1)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 2)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 3)).To(Succeed())
+
+	entries, err = lb.GetHistory(ctx, "c")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(2))
+	g.Expect(entries[0].Score).To(Equal(2))
+	g.Expect(entries[1].Score).To(Equal(3))
+
+	// 기본값으로도 오래된 내역은 지운다
+	lb.MaxHistory = 0
+	for i := 0; i < DefaultMaxHistory+1; i++ {
+		g.Expect(lb.SetUser(ctx, "d", i+1)).To(Succeed())
+	}
+
+	entries, err = lb.GetHistory(ctx, "d")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(DefaultMaxHistory))
+
+	// override 이후에 제출된 점수는 revert로 덮어쓰지 않는다
+	g.Expect(lb.OverrideScore(ctx, "c", 50, admin)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 60)).To(Succeed())
+	g.Expect(lb.RevertScore(ctx, "c", admin)).To(MatchError("nothing to revert"))
+
+	user, err = lb.GetUser(ctx, "c")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(60))
+}
+
+func TestLeaderBoard_HistoryConcurrent(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{Storage: &storage.MemStorage{}}
+
+	g.Expect(lb.SetUser(ctx, "a", 1)).To(Succeed())
+
+	// 동시에 바꿔도 history는 저장된 점수가 바뀐 순서와 같다
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(score int) {
+			defer wg.Done()
+			g.Expect(lb.SetUser(ctx, "a", score)).To(Succeed())
+		}(100 + i)
+	}
+	wg.Wait()
+
+	entries, err := lb.GetHistory(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(11))
+
+	for i := 1; i < len(entries); i++ {
+		g.Expect(entries[i].PreviousScore).To(Equal(entries[i-1].Score))
+	}
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(entries[len(entries)-1].Score))
+}
@@ -0,0 +1,171 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+const (
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// IdempotencyInProgressTTL은 처리 중 표시를 유지하는 기간.
+	// 처리하던 서버가 응답하지 못하고 죽어도 이 기간이 지나면 같은 키로 다시 요청할 수 있다.
+	IdempotencyInProgressTTL = time.Minute
+)
+
+// idempotencyRecord는 idempotency key로 받은 요청과 그 결과
+type idempotencyRecord struct {
+	Score      int               `json:"score"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// false이면 처리 중
+	Done       bool                `json:"done"`
+	StatusCode int                 `json:"status_code,omitempty"`
+	Message    string              `json:"message,omitempty"`
+	Reason     api.RejectionReason `json:"reason,omitempty"`
+	// 성공한 요청의 응답
+	User *api.User `json:"user,omitempty"`
+}
+
+func idempotencyValueKey(userId, key string) string {
+	return "idempotency_" + userId + ":" + key
+}
+
+func (lb *LeaderBoard) idempotencyTTL() time.Duration {
+	if lb.IdempotencyTTL > 0 {
+		return lb.IdempotencyTTL
+	}
+	return DefaultIdempotencyTTL
+}
+
+func (record idempotencyRecord) sameRequest(score int, attributes map[string]string) bool {
+	return record.Score == score && equalAttributes(record.Attributes, attributes)
+}
+
+func (record idempotencyRecord) err() error {
+	if record.StatusCode == 0 {
+		return nil
+	}
+	if record.Reason != "" {
+		return api.NewRejectionError(record.Reason, record.Message)
+	}
+	return api.ErrorWithStatusCode(errors.New(record.Message), record.StatusCode)
+}
+
+// setUserIdempotent는 같은 idempotency key로 처음 받은 요청만 반영하고, 다시 받은 요청에는 처음 결과를 반환한다
+func (lb *LeaderBoard) setUserIdempotent(ctx context.Context, userId string, score int, options api.Options) error {
+	key := idempotencyValueKey(userId, options.IdempotencyKey)
+
+	record := idempotencyRecord{
+		Score:      score,
+		Attributes: options.Attributes,
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	ok, err := lb.Storage.SetValueIfAbsent(ctx, key, data, IdempotencyInProgressTTL)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return lb.replayIdempotent(ctx, userId, score, options)
+	}
+
+	// 처리 중으로 표시한 다음에는 요청이 취소되어도 점수와 결과를 끝까지 저장한다.
+	// 중간에 멈추면 점수는 저장됐는데 같은 키로 다시 요청하면 처리 중이라고 응답하게 된다.
+	ctx = detach(ctx)
+
+	held, setErr := lb.setUser(ctx, userId, score, options)
+
+	var apiErr api.Error
+	if setErr != nil && (!errors.As(setErr, &apiErr) || apiErr.StatusCode() >= http.StatusInternalServerError) {
+		// 일시적인 오류일 수 있으므로 기억하지 않고 다시 시도할 수 있게 한다
+		if err := lb.Storage.DeleteValue(context.Background(), key); err != nil {
+			return err
+		}
+		return setErr
+	}
+
+	record.Done = true
+	if setErr != nil {
+		record.StatusCode = apiErr.StatusCode()
+		record.Message = apiErr.Error()
+
+		var rejectionErr api.RejectionError
+		if errors.As(setErr, &rejectionErr) {
+			record.Reason = rejectionErr.Reason
+		}
+	} else if held != nil {
+		record.User = held
+	} else if user, err := lb.GetUser(ctx, userId, api.WithViewer(userId)); err == nil {
+		record.User = &user
+	}
+
+	data, err = json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if err := lb.Storage.SetValue(ctx, key, data, lb.idempotencyTTL()); err != nil {
+		return err
+	}
+
+	if setErr != nil {
+		return setErr
+	}
+
+	return lb.replayResult(ctx, userId, record, options)
+}
+
+func (lb *LeaderBoard) replayIdempotent(ctx context.Context, userId string, score int, options api.Options) error {
+	data, err := lb.Storage.GetValue(ctx, idempotencyValueKey(userId, options.IdempotencyKey))
+	if err != nil {
+		return err
+	}
+
+	// 그 사이에 만료되었으면 처음 받은 요청으로 처리한다
+	if len(data) == 0 {
+		return lb.setUserIdempotent(ctx, userId, score, options)
+	}
+
+	record := idempotencyRecord{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	if !record.sameRequest(score, options.Attributes) {
+		return api.ErrorWithStatusCode(errors.New("idempotency key is already used for another request"), http.StatusUnprocessableEntity)
+	}
+
+	if !record.Done {
+		return api.ErrorWithStatusCode(errors.New("request with the same idempotency key is in progress"), http.StatusConflict)
+	}
+
+	if err := record.err(); err != nil {
+		return err
+	}
+
+	return lb.replayResult(ctx, userId, record, options)
+}
+
+// replayResult는 options.Result에 처음 요청의 응답을 담는다
+func (lb *LeaderBoard) replayResult(ctx context.Context, userId string, record idempotencyRecord, options api.Options) error {
+	if options.Result == nil {
+		return nil
+	}
+
+	if record.User == nil {
+		return lb.storeResult(ctx, userId, nil, options)
+	}
+
+	*options.Result = *record.User
+	return nil
+}
@@ -0,0 +1,112 @@
+package leaderboard_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Idempotency(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		now := time.Now()
+		s := &storage.MemStorage{
+			NowFunc: func() time.Time { return now },
+		}
+
+		testIdempotency(t, s, func(d time.Duration) {
+			now = now.Add(d)
+		})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testIdempotency(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		}, s.FastForward)
+	})
+}
+
+func testIdempotency(t *testing.T, s Storage, advance func(d time.Duration)) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{
+		Storage:        s,
+		IdempotencyTTL: time.Hour,
+	}
+
+	score := func(userId string) int {
+		user, err := lb.GetUser(ctx, userId, api.WithHidden())
+		g.Expect(err).NotTo(HaveOccurred())
+		return user.Score
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 10, api.WithIdempotencyKey("k1"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "a", 20)).To(Succeed())
+
+	// 같은 키로 다시 요청하면 반영하지 않고 처음 결과를 반환한다
+	g.Expect(lb.SetUser(ctx, "a", 10, api.WithIdempotencyKey("k1"))).To(Succeed())
+	g.Expect(score("a")).To(Equal(20))
+
+	err := lb.SetUser(ctx, "a", 30, api.WithIdempotencyKey("k1"))
+	g.Expect(err).To(MatchError("idempotency key is already used for another request"))
+	g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusUnprocessableEntity))
+
+	// 키는 사용자별로 구분한다
+	g.Expect(lb.SetUser(ctx, "b", 10, api.WithIdempotencyKey("k1"))).To(Succeed())
+	g.Expect(score("b")).To(Equal(10))
+
+	// 실패한 결과도 기억한다
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateBanned)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 50, api.WithIdempotencyKey("k2"))).To(MatchError("user is banned"))
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateActive)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 50, api.WithIdempotencyKey("k2"))).To(MatchError("user is banned"))
+	g.Expect(score("b")).To(Equal(10))
+
+	// 다시 받은 요청에는 처음 요청의 응답을 돌려준다
+	first := api.User{}
+	g.Expect(lb.SetUser(ctx, "c", 30, api.WithIdempotencyKey("k3"), api.WithResult(&first))).To(Succeed())
+	g.Expect(first.Score).To(Equal(30))
+	g.Expect(first.Rank).To(Equal(1))
+	g.Expect(lb.SetUser(ctx, "c", 40)).To(Succeed())
+
+	replayed := api.User{}
+	g.Expect(lb.SetUser(ctx, "c", 30, api.WithIdempotencyKey("k3"), api.WithResult(&replayed))).To(Succeed())
+	g.Expect(replayed).To(Equal(first))
+
+	// 처리하던 요청이 끝나지 못했으면 처리 중 표시가 만료된 뒤에 다시 처리한다
+	inProgress := []byte(`{"score":5}`)
+	g.Expect(s.SetValueIfAbsent(ctx, "idempotency_d:k4", inProgress, IdempotencyInProgressTTL)).To(BeTrue())
+
+	err = lb.SetUser(ctx, "d", 5, api.WithIdempotencyKey("k4"))
+	g.Expect(err).To(MatchError("request with the same idempotency key is in progress"))
+	g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusConflict))
+
+	advance(IdempotencyInProgressTTL)
+	g.Expect(lb.SetUser(ctx, "d", 5, api.WithIdempotencyKey("k4"))).To(Succeed())
+	g.Expect(score("d")).To(Equal(5))
+
+	// 끝난 요청은 처리 중 표시보다 오래 기억한다
+	advance(IdempotencyInProgressTTL)
+	g.Expect(lb.SetUser(ctx, "d", 5, api.WithIdempotencyKey("k4"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "d", 6, api.WithIdempotencyKey("k4"))).To(MatchError("idempotency key is already used for another request"))
+
+	// 기억하는 기간이 지나면 새 요청으로 처리한다
+	advance(time.Hour)
+	g.Expect(lb.SetUser(ctx, "a", 10, api.WithIdempotencyKey("k1"))).To(Succeed())
+	g.Expect(score("a")).To(Equal(10))
+}
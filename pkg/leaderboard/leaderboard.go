@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
 )
 
 type User = api.User
@@ -16,16 +17,107 @@ type LeaderBoard struct {
 	NowFunc func() time.Time
 
 	Storage Storage
+
+	// 보관할 최대 스냅샷 수 (0이면 제한 없음)
+	MaxSnapshots int
+	// 스냅샷 보관 기간 (0이면 제한 없음)
+	SnapshotRetention time.Duration
+
+	// 세그먼트별 순위를 유지할 사용자 속성 (예: "country", "platform")
+	SegmentAttributes []string
+
+	// 사용자별로 보관할 최대 history 수 (0이면 DefaultMaxHistory, 음수이면 제한 없음)
+	MaxHistory int
+
+	// idempotency key로 요청 결과를 기억하는 기간 (0이면 DefaultIdempotencyTTL)
+	IdempotencyTTL time.Duration
+
+	// SetUser로 제출하는 점수의 검증 규칙
+	Rules ScoreRules
+	// 보관할 최대 거부 기록 수 (0이면 DefaultMaxRejections, 음수이면 제한 없음)
+	MaxRejections int
+	// nil이 아니면 규칙을 통과한 제출을 검사해서 의심스러운 제출을 검토 대기열에 넣는다
+	Screener Screener
+	// 보관할 최대 검토 대기 수 (0이면 DefaultMaxReviews, 음수이면 제한 없음). 넘으면 오래된 것부터 지운다.
+	MaxReviews int
+
+	// 이 기간 동안 점수가 바뀌지 않은 사용자는 ExpireInactive로 삭제한다 (0이면 삭제하지 않음).
+	// InactivityTTL 없이 저장된 사용자는 다음 ExpireInactive가 만료 대상에 넣는다.
+	InactivityTTL time.Duration
+
+	// 보관할 최대 변경 이벤트 수 (0이면 이벤트를 기록하지 않음)
+	MaxEvents int
+	// 이 순위 안으로 들어온 사용자에 대해 entered_top 이벤트를 기록한다 (0이면 기록하지 않음)
+	EventTopN int
+	// Subscribe가 새 이벤트를 확인하는 간격 (0이면 DefaultEventPollInterval)
+	EventPollInterval time.Duration
+
+	// webhook마다 보관할 최근 전송 결과와 dead letter 수 (0이면 DefaultMaxWebhookDeliveries)
+	MaxWebhookDeliveries int
 }
 
 type Storage interface {
 	Count(ctx context.Context) (int, error)
 	GetData(ctx context.Context, keys ...string) ([][]byte, error)
-	SetData(ctx context.Context, key string, data []byte, score int) error
+	// WriteData는 w의 변경들을 원자적으로 적용한다. w.CheckData인데 저장된 data가 다르면 아무것도 바꾸지 않고 false를 반환한다.
+	WriteData(ctx context.Context, w storage.Write) (bool, error)
 	GetRanks(ctx context.Context, keys ...string) ([]int, error)
 	GetSortedRange(ctx context.Context, rank, count int) ([]string, error)
+	// ScanDataKeys는 data가 저장된 모든 key를 최대 batchSize개씩 나눠 handler에 넘긴다.
+	// 도중에 추가되거나 지워진 key는 넘기지 않을 수 있다.
+	ScanDataKeys(ctx context.Context, batchSize int, handler func(keys []string) error) error
+
+	// CopyIndex는 현재 순위 전체를 name 인덱스로 복사한다. 같은 이름의 인덱스는 교체된다.
+	CopyIndex(ctx context.Context, name string) error
+	// GetIndexRanks는 name 인덱스에서의 순위를 반환한다. 인덱스에 없는 key는 0.
+	GetIndexRanks(ctx context.Context, name string, keys ...string) ([]int, error)
+	GetIndexScores(ctx context.Context, name string, keys ...string) ([]int, error)
+	IndexCount(ctx context.Context, name string) (int, error)
+	// IndexCountAbove는 name 인덱스에서 score보다 점수가 높은 key의 수를 반환한다
+	IndexCountAbove(ctx context.Context, name string, score int) (int, error)
+	GetIndexSortedRange(ctx context.Context, name string, rank, count int) ([]string, error)
+	DeleteIndex(ctx context.Context, name string) error
+	// IndexNames는 key가 하나 이상 있는 인덱스들의 이름을 반환한다
+	IndexNames(ctx context.Context) ([]string, error)
+
+	// AppendList는 key 목록 끝에 data를 추가한다. maxLen이 0보다 크면 최근 maxLen개만 남긴다.
+	AppendList(ctx context.Context, key string, data []byte, maxLen int) error
+	GetList(ctx context.Context, key string) ([][]byte, error)
+	// RemoveListItem은 key 목록에서 data와 같은 항목을 모두 제거한다
+	RemoveListItem(ctx context.Context, key string, data []byte) error
+	DeleteList(ctx context.Context, key string) error
+
+	// GetValue는 key의 값을 반환한다. 없거나 만료되었으면 nil.
+	GetValue(ctx context.Context, key string) ([]byte, error)
+	// SetValue는 ttl이 지나면 만료되는 값을 저장한다
+	SetValue(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// SetValueIfAbsent는 key에 값이 없을 때만 저장하고, 저장했는지 여부를 반환한다
+	SetValueIfAbsent(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error)
+	// ExtendValue는 key의 값이 data일 때만 ttl이 지나면 만료되도록 다시 저장하고, 저장했는지 여부를 반환한다
+	ExtendValue(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error)
+	DeleteValue(ctx context.Context, key string) error
+	// ValueKeys는 prefix로 시작하는 값들의 key를 반환한다. 만료된 값은 포함하지 않는다.
+	ValueKeys(ctx context.Context, prefix string) ([]string, error)
+	// IncrValue는 key의 값을 1 늘리고 늘어난 값을 반환한다. 처음 만들어진 값은 ttl이 지나면 만료된다.
+	IncrValue(ctx context.Context, key string, ttl time.Duration) (int, error)
+
+	// AppendStream은 key 스트림 끝에 data를 추가하고 붙인 순번을 반환한다. 순번은 1부터 1씩 늘어난다.
+	// maxLen이 0보다 크면 최근 maxLen개만 남긴다.
+	AppendStream(ctx context.Context, key string, data []byte, maxLen int) (int64, error)
+	// ReadStream은 key 스트림에서 순번이 afterSeq보다 큰 항목들을 순번 순서로 최대 count개 반환한다.
+	// 이미 지워진 항목들은 건너뛴다.
+	ReadStream(ctx context.Context, key string, afterSeq int64, count int) (seqs []int64, data [][]byte, err error)
+	// DeleteStreamItems는 key 스트림에서 순번이 seqs인 항목들을 지운다. 다른 항목들의 순번은 바뀌지 않는다.
+	DeleteStreamItems(ctx context.Context, key string, seqs ...int64) error
+	// LastStreamSeq는 key 스트림에 마지막으로 붙인 순번을 반환한다. 비어있으면 0.
+	LastStreamSeq(ctx context.Context, key string) (int64, error)
+
+	// Ping은 저장소에 연결할 수 있는지 확인한다
+	Ping(ctx context.Context) error
 }
 
+const checkpointIndex = "checkpoint"
+
 func (lb *LeaderBoard) now() time.Time {
 	if lb.NowFunc != nil {
 		return lb.NowFunc()
@@ -33,11 +125,58 @@ func (lb *LeaderBoard) now() time.Time {
 	return time.Now()
 }
 
-func (lb *LeaderBoard) UserCount(ctx context.Context) (int, error) {
+func (lb *LeaderBoard) UserCount(ctx context.Context, opts ...api.Option) (int, error) {
+	options := api.NewOptions(opts...)
+	if err := lb.checkQueryOptions(&options); err != nil {
+		return 0, err
+	}
+
+	if options.SnapshotId != "" {
+		return lb.snapshotUserCount(ctx, options.SnapshotId)
+	}
+
+	if options.Segment.Attribute != "" {
+		return lb.segmentUserCount(ctx, options.Segment)
+	}
+
 	return lb.Storage.Count(ctx)
 }
 
-func (lb *LeaderBoard) GetUser(ctx context.Context, userId string) (User, error) {
+func (lb *LeaderBoard) GetUser(ctx context.Context, userId string, opts ...api.Option) (User, error) {
+	options := api.NewOptions(opts...)
+	if err := lb.checkQueryOptions(&options); err != nil {
+		return User{}, err
+	}
+
+	var err error
+	if options.IncludeHidden {
+		// 숨겨진 사용자까지 조회하는 관리자 요청은 정책 이전에 저장된 id도 찾을 수 있다
+		userId, err = lb.storedUserId(ctx, userId)
+	} else {
+		userId, err = api.NormalizeUserId(userId)
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	if options.SnapshotId != "" {
+		return lb.getSnapshotUser(ctx, options.SnapshotId, userId)
+	}
+
+	user, err := lb.getUser(ctx, userId, options)
+	if err != nil {
+		return User{}, err
+	}
+
+	if !options.IncludeHidden {
+		hideShadowBan(&user, options.Viewer)
+	}
+
+	return user, nil
+}
+
+// getUser는 options로 조회한 사용자를 반환한다. userId와 options는 검사한 것이어야 한다.
+func (lb *LeaderBoard) getUser(ctx context.Context, userId string, options api.Options) (User, error) {
 	users, err := lb.Storage.GetData(ctx, userId)
 	if err != nil {
 		return User{}, err
@@ -52,48 +191,310 @@ func (lb *LeaderBoard) GetUser(ctx context.Context, userId string) (User, error)
 		return User{}, err
 	}
 
-	ranks, err := lb.Storage.GetRanks(ctx, userId)
-	if err != nil {
+	if !isPublic(user) && !options.IncludeHidden && !isShadowBannedViewer(user, options.Viewer) {
+		return User{}, api.ErrorWithStatusCode(errors.New("not found"), http.StatusNotFound)
+	}
+
+	if user.Rank, err = lb.userRank(ctx, user); err != nil {
 		return User{}, err
 	}
 
-	user.Rank = ranks[0]
-	return user, nil
+	returnUsers := []User{user}
+	if err := lb.setPreviousRanks(ctx, returnUsers); err != nil {
+		return User{}, err
+	}
+
+	if options.Segment.Attribute != "" {
+		if err := lb.setSegmentRank(ctx, &returnUsers[0], options.Segment); err != nil {
+			return User{}, err
+		}
+	}
+
+	return returnUsers[0], nil
 }
 
-func (lb *LeaderBoard) SetUser(ctx context.Context, userId string, score int) error {
-	users, err := lb.Storage.GetData(ctx, userId)
+func (lb *LeaderBoard) SetUser(ctx context.Context, userId string, score int, opts ...api.Option) error {
+	userId, err := api.NormalizeUserId(userId)
 	if err != nil {
 		return err
 	}
 
-	oldData := users[0]
-	oldUser := User{}
-	if len(oldData) != 0 {
-		if err := json.Unmarshal(oldData, &oldUser); err != nil {
-			return err
-		}
+	options := api.NewOptions(opts...)
+	if options.IdempotencyKey != "" {
+		return lb.setUserIdempotent(ctx, userId, score, options)
 	}
 
-	if oldUser.Score == score {
+	held, err := lb.setUser(ctx, userId, score, options)
+	if err != nil {
+		return err
+	}
+
+	return lb.storeResult(ctx, userId, held, options)
+}
+
+// storeResult는 options.Result에 제출한 쪽에 보여줄 사용자를 담는다. held가 nil이면 저장된 사용자.
+// shadow ban된 사용자도 자신의 점수와 순위를 받도록 사용자 자신으로 조회한다.
+func (lb *LeaderBoard) storeResult(ctx context.Context, userId string, held *User, options api.Options) error {
+	if options.Result == nil {
+		return nil
+	}
+
+	if held != nil {
+		*options.Result = *held
 		return nil
 	}
 
+	user, err := lb.GetUser(ctx, userId, api.WithViewer(userId))
+	if err != nil {
+		return err
+	}
+
+	*options.Result = user
+	return nil
+}
+
+// setUser는 점수를 저장한다. 제출이 보류되었으면 반영된 것처럼 보이는 사용자를 반환한다.
+func (lb *LeaderBoard) setUser(ctx context.Context, userId string, score int, options api.Options) (*User, error) {
+	if err := lb.checkAttributes(options.Attributes); err != nil {
+		return nil, err
+	}
+
+	// 범위를 벗어난 점수는 제출 수에 넣지 않고, 제출 수는 다시 시도해도 한 번만 센다
+	err := lb.checkScoreRange(score)
+	if err == nil {
+		err = lb.countSubmission(ctx, userId)
+	}
+
+	var held *User
+	if err == nil {
+		err = lb.retryOnConflict(func() error {
+			var err error
+			held, err = lb.trySetUser(ctx, userId, score, options)
+			return err
+		})
+	}
+
+	if err := lb.recordRejection(ctx, userId, score, err); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+func (lb *LeaderBoard) trySetUser(ctx context.Context, userId string, score int, options api.Options) (*User, error) {
+	oldUser, oldData, err := lb.loadUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	exists := oldData != nil
+
+	if oldUser.State == api.UserStateBanned {
+		return nil, api.ErrorWithStatusCode(errors.New("user is banned"), http.StatusForbidden)
+	}
+
+	if err := lb.validateScore(oldUser, exists, score); err != nil {
+		return nil, err
+	}
+
+	attributes := mergeAttributes(oldUser.Attributes, options.Attributes)
+	attributesChanged := !equalAttributes(oldUser.Attributes, attributes)
+
+	if oldUser.Score == score && !attributesChanged {
+		return nil, nil
+	}
+
 	newUser := User{
-		Id:        userId,
-		Score:     score,
-		UpdatedAt: lb.now(),
+		Id:         userId,
+		Score:      score,
+		UpdatedAt:  lb.now(),
+		Attributes: attributes,
+		State:      oldUser.State,
 	}
 
+	// 속성만 바뀐 경우에는 updatedAt을 유지하고 history도 남기지 않는다
+	if oldUser.Score == score {
+		newUser.UpdatedAt = oldUser.UpdatedAt
+		return nil, lb.saveUser(ctx, oldUser, oldData, newUser, userChange{})
+	}
+
+	change := userChange{
+		history: &HistoryEntry{
+			Kind:          api.HistoryKindSet,
+			Score:         score,
+			PreviousScore: oldUser.Score,
+			CreatedAt:     newUser.UpdatedAt,
+		},
+	}
+
+	if lb.Screener != nil {
+		submission, action, reasons, err := lb.screen(ctx, oldUser, exists, newUser)
+		if err != nil {
+			return nil, err
+		}
+
+		switch action {
+		case api.ReviewActionQuarantine:
+			return lb.quarantine(ctx, submission, newUser, reasons)
+		case api.ReviewActionFlag:
+			change.review = &Review{
+				UserId:        userId,
+				Score:         score,
+				PreviousScore: oldUser.Score,
+				Action:        action,
+				Reasons:       reasons,
+			}
+		}
+	}
+
+	return nil, lb.saveUser(ctx, oldUser, oldData, newUser, change)
+}
+
+// userChange는 사용자를 저장할 때 함께 기록할 것들
+type userChange struct {
+	// nil이 아니면 history에 남긴다
+	history *HistoryEntry
+	// nil이 아니면 검토 대기열에 넣는다
+	review *Review
+}
+
+// errConflict는 사용자를 읽은 다음 저장하기 전에 다른 요청이 사용자를 바꿨다는 뜻
+var errConflict = errors.New("user was changed by another request")
+
+// 다른 요청과 겹쳤을 때 다시 시도하는 최대 횟수
+const maxWriteAttempts = 5
+
+// retryOnConflict는 fn이 errConflict를 반환하면 사용자를 다시 읽어서 처리하도록 fn을 다시 호출한다
+func (lb *LeaderBoard) retryOnConflict(fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err != errConflict {
+			return err
+		}
+
+		if attempt == maxWriteAttempts {
+			return api.ErrorWithStatusCode(err, http.StatusConflict)
+		}
+	}
+}
+
+// loadUser는 저장된 사용자 데이터를 읽는다. data는 저장된 그대로의 값으로, 사용자가 없으면 nil이다.
+func (lb *LeaderBoard) loadUser(ctx context.Context, userId string) (user User, data []byte, err error) {
+	users, err := lb.Storage.GetData(ctx, userId)
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	if len(users[0]) == 0 {
+		return User{}, nil, nil
+	}
+
+	if err := json.Unmarshal(users[0], &user); err != nil {
+		return User{}, nil, err
+	}
+
+	return user, users[0], nil
+}
+
+// storedUserId는 관리자와 개인정보 처리 요청이 대상으로 하는 사용자 id를 반환한다.
+// 사용자 id 정책이 생기기 전에 저장된 id는 정규화하면 찾을 수 없으므로, 그 id로 저장된 사용자나 history가 있으면 받은 그대로 사용한다.
+func (lb *LeaderBoard) storedUserId(ctx context.Context, userId string) (string, error) {
+	normalized, err := api.NormalizeUserId(userId)
+	if err == nil && normalized == userId {
+		return normalized, nil
+	}
+
+	if userId != "" {
+		_, data, loadErr := lb.loadUser(ctx, userId)
+		if loadErr != nil {
+			return "", loadErr
+		}
+
+		history := [][]byte{}
+		if data == nil {
+			if history, loadErr = lb.Storage.GetList(ctx, historyListKey(userId)); loadErr != nil {
+				return "", loadErr
+			}
+		}
+
+		if data != nil || len(history) > 0 {
+			return userId, nil
+		}
+	}
+
+	return normalized, err
+}
+
+// saveUser는 oldData로 읽었던 oldUser를 newUser로 저장하고 순위 인덱스들을 갱신한다.
+// change도 함께 기록한다. 그 사이에 사용자가 바뀌었으면 아무것도 저장하지 않고 errConflict를 반환한다.
+func (lb *LeaderBoard) saveUser(ctx context.Context, oldUser User, oldData []byte, newUser User, change userChange) error {
 	newData, err := json.Marshal(newUser)
 	if err != nil {
 		return err
 	}
 
-	return lb.Storage.SetData(ctx, userId, newData, score)
+	addIndexes, removeIndexes := lb.indexChanges(oldUser, newUser)
+
+	if lb.InactivityTTL > 0 && !lb.tracksUpdate(newUser) {
+		removeIndexes = append(removeIndexes, updatedIndex)
+	}
+
+	events, err := lb.changeEvents(ctx, oldUser, newUser)
+	if err != nil {
+		return err
+	}
+
+	w := storage.Write{
+		Key:           newUser.Id,
+		Data:          newData,
+		Score:         newUser.Score,
+		AddIndexes:    addIndexes,
+		RemoveIndexes: removeIndexes,
+		CheckData:     true,
+		ExpectData:    oldData,
+	}
+
+	if lb.tracksUpdate(newUser) {
+		w.IndexScores = []storage.IndexScore{{Name: updatedIndex, Score: updatedScore(newUser.UpdatedAt)}}
+	} else if lb.InactivityTTL <= 0 {
+		// updatedIndex에 넣지 않은 사용자가 생겼으므로 InactivityTTL을 설정한 서버가 updatedIndex를 다시 채우게 한다
+		w.DeleteValues = []string{updatedIndexBackfilledKey}
+	}
+
+	if change.history != nil {
+		item, err := lb.historyItem(newUser.Id, *change.history)
+		if err != nil {
+			return err
+		}
+		w.Lists = append(w.Lists, item)
+	}
+
+	w.Incrs = statsIncrs(oldUser, isRanked(oldUser, oldData != nil), newUser, isRanked(newUser, true))
+
+	if change.review != nil {
+		item, err := lb.reviewItem(change.review)
+		if err != nil {
+			return err
+		}
+		w.Lists = append(w.Lists, item)
+	}
+
+	if w.Streams, err = lb.eventItems(events); err != nil {
+		return err
+	}
+	lb.bumpVersion(&w)
+
+	applied, err := lb.Storage.WriteData(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	if !applied {
+		return errConflict
+	}
+
+	return nil
 }
 
-func (lb *LeaderBoard) GetRanks(ctx context.Context, rank, count int) ([]User, error) {
+func (lb *LeaderBoard) GetRanks(ctx context.Context, rank, count int, opts ...api.Option) ([]User, error) {
 	if rank < 1 {
 		return nil, api.ErrorWithStatusCode(errors.New("invalid rank"), http.StatusBadRequest)
 	}
@@ -102,6 +503,27 @@ func (lb *LeaderBoard) GetRanks(ctx context.Context, rank, count int) ([]User, e
 		return nil, api.ErrorWithStatusCode(errors.New("invalid count"), http.StatusBadRequest)
 	}
 
+	options := api.NewOptions(opts...)
+	if err := lb.checkQueryOptions(&options); err != nil {
+		return nil, err
+	}
+
+	if options.SnapshotId != "" {
+		return lb.getSnapshotRanks(ctx, options.SnapshotId, rank, count)
+	}
+
+	if options.Segment.Attribute != "" {
+		return lb.getSegmentRanks(ctx, options.Segment, rank, count)
+	}
+
+	if options.Viewer != "" {
+		return lb.getRanksForViewer(ctx, options.Viewer, rank, count)
+	}
+
+	return lb.getRanks(ctx, rank, count)
+}
+
+func (lb *LeaderBoard) getRanks(ctx context.Context, rank, count int) ([]User, error) {
 	userIds, err := lb.Storage.GetSortedRange(ctx, rank, count)
 	if err != nil {
 		return nil, err
@@ -120,5 +542,42 @@ func (lb *LeaderBoard) GetRanks(ctx context.Context, rank, count int) ([]User, e
 		returnUsers[i].Rank = rank + i
 	}
 
+	if err := lb.setPreviousRanks(ctx, returnUsers); err != nil {
+		return nil, err
+	}
+
 	return returnUsers, nil
 }
+
+// Checkpoint는 현재 모든 사용자의 순위를 기록한다.
+// 이후 조회되는 PreviousRank, RankDelta는 마지막 체크포인트를 기준으로 한다.
+func (lb *LeaderBoard) Checkpoint(ctx context.Context) error {
+	// PreviousRank, RankDelta가 바뀐다
+	w := storage.Write{CopyIndexes: []string{checkpointIndex}}
+	lb.bumpVersion(&w)
+
+	_, err := lb.Storage.WriteData(ctx, w)
+	return err
+}
+
+func (lb *LeaderBoard) setPreviousRanks(ctx context.Context, users []User) error {
+	userIds := make([]string, len(users))
+	for i, u := range users {
+		userIds[i] = u.Id
+	}
+
+	prevRanks, err := lb.Storage.GetIndexRanks(ctx, checkpointIndex, userIds...)
+	if err != nil {
+		return err
+	}
+
+	for i, prevRank := range prevRanks {
+		if prevRank == 0 {
+			continue
+		}
+		users[i].PreviousRank = prevRank
+		users[i].RankDelta = prevRank - users[i].Rank
+	}
+
+	return nil
+}
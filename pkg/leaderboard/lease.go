@@ -0,0 +1,40 @@
+package leaderboard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Lease는 여러 서버 중 한 서버만 작업을 실행하도록 저장소에 잡아두는 lease.
+// 서버마다 따로 만들어서 사용한다.
+type Lease struct {
+	Storage Storage
+	Name    string
+	// lease를 잡은 서버를 구분하는 id. 비어있으면 처음 잡을 때 임의로 만든다.
+	Owner string
+}
+
+func (l *Lease) key() string {
+	return "lease_" + l.Name
+}
+
+// TryAcquire는 ttl 동안 lease를 잡는다. 이미 잡고 있으면 ttl 동안 연장하고,
+// 다른 서버가 잡고 있으면 false를 반환한다.
+func (l *Lease) TryAcquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	if l.Owner == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return false, err
+		}
+		l.Owner = hex.EncodeToString(id)
+	}
+
+	acquired, err := l.Storage.SetValueIfAbsent(ctx, l.key(), []byte(l.Owner), ttl)
+	if err != nil || acquired {
+		return acquired, err
+	}
+
+	return l.Storage.ExtendValue(ctx, l.key(), []byte(l.Owner), ttl)
+}
@@ -0,0 +1,149 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+// isPublic은 user가 공개 순위에 포함되는지 여부를 반환한다
+func isPublic(user User) bool {
+	return user.State == "" || user.State == api.UserStateActive
+}
+
+func isShadowBannedViewer(user User, viewer string) bool {
+	return user.State == api.UserStateShadowBanned && user.Id == viewer
+}
+
+// hideShadowBan은 shadow ban된 사용자가 자신을 조회할 때 shadow ban된 것을 알 수 없도록 상태를 지운다
+func hideShadowBan(user *User, viewer string) {
+	if isShadowBannedViewer(*user, viewer) {
+		user.State = ""
+	}
+}
+
+// virtualRank는 공개 순위에서 제외된 사용자가 name 인덱스에 score로 있었다면 받았을 순위를 반환한다
+func (lb *LeaderBoard) virtualRank(ctx context.Context, name string, score int) (int, error) {
+	count, err := lb.Storage.IndexCountAbove(ctx, name, score)
+	if err != nil {
+		return 0, err
+	}
+	return count + 1, nil
+}
+
+// userRank는 user의 현재 순위를 반환한다. 공개 순위에서 제외된 사용자는 virtualRank.
+func (lb *LeaderBoard) userRank(ctx context.Context, user User) (int, error) {
+	if !isPublic(user) {
+		return lb.virtualRank(ctx, "", user.Score)
+	}
+
+	ranks, err := lb.Storage.GetRanks(ctx, user.Id)
+	if err != nil {
+		return 0, err
+	}
+	return ranks[0], nil
+}
+
+// indexChanges는 oldUser가 newUser로 바뀔 때 storage.Write에 넘길 인덱스 변경 목록을 반환한다
+func (lb *LeaderBoard) indexChanges(oldUser, newUser User) (addIndexes, removeIndexes []string) {
+	addIndexes, removeIndexes = lb.segmentIndexChanges(oldUser.Attributes, newUser.Attributes)
+
+	if isPublic(newUser) {
+		return append([]string{""}, addIndexes...), removeIndexes
+	}
+
+	// 공개 순위에서 제외된 사용자는 모든 순위에서 뺀다
+	return nil, append(append([]string{""}, addIndexes...), removeIndexes...)
+}
+
+func (lb *LeaderBoard) SetUserState(ctx context.Context, userId string, state api.UserState) error {
+	userId, err := lb.storedUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !state.IsValid() {
+		return api.ErrorWithStatusCode(errors.New("invalid state"), http.StatusBadRequest)
+	}
+
+	return lb.retryOnConflict(func() error {
+		oldUser, oldData, err := lb.loadUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		if oldData == nil {
+			return api.ErrorWithStatusCode(errors.New("not found"), http.StatusNotFound)
+		}
+
+		newUser := oldUser
+		newUser.State = state
+		if state == api.UserStateActive {
+			newUser.State = ""
+		}
+
+		if newUser.State == oldUser.State {
+			return nil
+		}
+
+		return lb.saveUser(ctx, oldUser, oldData, newUser, userChange{})
+	})
+}
+
+// getRanksForViewer는 viewer가 shadow ban된 경우 viewer가 공개 순위에 있는 것처럼 rank부터 count개의 순위를 반환한다
+func (lb *LeaderBoard) getRanksForViewer(ctx context.Context, viewer string, rank, count int) ([]User, error) {
+	viewerUser, err := lb.getUser(ctx, viewer, api.NewOptions(api.WithViewer(viewer)))
+
+	var apiErr api.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode() == http.StatusNotFound {
+		return lb.getRanks(ctx, rank, count)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isShadowBannedViewer(viewerUser, viewer) {
+		return lb.getRanks(ctx, rank, count)
+	}
+
+	// viewer보다 순위가 낮은 사용자들은 한 칸씩 밀려나므로, 한 칸 앞에서부터 하나 더 읽는다
+	publicRank := rank - 1
+	if publicRank < 1 {
+		publicRank = 1
+	}
+
+	publicUsers, err := lb.getRanks(ctx, publicRank, count+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hideShadowBan(&viewerUser, viewer)
+
+	users := []User{viewerUser}
+	for _, u := range publicUsers {
+		if u.Rank >= viewerUser.Rank {
+			u.Rank++
+			if u.PreviousRank != 0 {
+				u.RankDelta = u.PreviousRank - u.Rank
+			}
+		}
+		users = append(users, u)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Rank < users[j].Rank
+	})
+
+	returnUsers := make([]User, 0, count)
+	for _, u := range users {
+		if u.Rank >= rank && u.Rank < rank+count {
+			returnUsers = append(returnUsers, u)
+		}
+	}
+
+	return returnUsers, nil
+}
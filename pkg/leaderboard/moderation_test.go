@@ -0,0 +1,126 @@
+package leaderboard_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Moderation(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testModeration(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testModeration(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testModeration(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{
+		Storage:           s,
+		SegmentAttributes: []string{"country"},
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 40, api.WithAttribute("country", "KR"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 30, api.WithAttribute("country", "KR"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 20)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "d", 10)).To(Succeed())
+
+	rankedIds := func(opts ...api.Option) []string {
+		users, err := lb.GetRanks(ctx, 1, 10, opts...)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		ids := make([]string, len(users))
+		for i, u := range users {
+			ids[i] = u.Id
+			g.Expect(u.Rank).To(Equal(i + 1))
+		}
+		return ids
+	}
+
+	g.Expect(lb.SetUserState(ctx, "x", api.UserStateHidden)).To(MatchError("not found"))
+	g.Expect(lb.SetUserState(ctx, "a", "unknown")).To(MatchError("invalid state"))
+
+	// hidden: 공개 순위와 사용자 수에서 제외
+	g.Expect(lb.SetUserState(ctx, "c", api.UserStateHidden)).To(Succeed())
+
+	g.Expect(rankedIds()).To(Equal([]string{"a", "b", "d"}))
+
+	count, err := lb.UserCount(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(3))
+
+	_, err = lb.GetUser(ctx, "c")
+	g.Expect(err).To(MatchError("not found"))
+
+	user, err := lb.GetUser(ctx, "c", api.WithHidden())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.State).To(Equal(api.UserStateHidden))
+	g.Expect(user.Score).To(Equal(20))
+
+	// banned: 점수 변경도 거부
+	g.Expect(lb.SetUserState(ctx, "d", api.UserStateBanned)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "d", 100)).To(MatchError("user is banned"))
+	g.Expect(rankedIds()).To(Equal([]string{"a", "b"}))
+
+	// shadow_banned: 세그먼트에서도 제외되지만 본인에게는 그대로 보인다
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateShadowBanned)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 50)).To(Succeed())
+
+	g.Expect(rankedIds()).To(Equal([]string{"a"}))
+
+	count, err = lb.UserCount(ctx, api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(1))
+
+	_, err = lb.GetUser(ctx, "b")
+	g.Expect(err).To(MatchError("not found"))
+
+	_, err = lb.GetUser(ctx, "b", api.WithViewer("a"))
+	g.Expect(err).To(MatchError("not found"))
+
+	user, err = lb.GetUser(ctx, "b", api.WithViewer("b"), api.WithSegment("country", ""))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(50))
+	g.Expect(user.Rank).To(Equal(1))
+	g.Expect(user.SegmentRank).To(Equal(1))
+
+	g.Expect(rankedIds(api.WithViewer("b"))).To(Equal([]string{"b", "a"}))
+	g.Expect(rankedIds(api.WithViewer("a"))).To(Equal([]string{"a"}))
+
+	users, err := lb.GetRanks(ctx, 2, 1, api.WithViewer("b"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(1))
+	g.Expect(users[0].Id).To(Equal("a"))
+	g.Expect(users[0].Rank).To(Equal(2))
+
+	// 다시 active로 바꾸면 순위와 세그먼트에 돌아온다
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateActive)).To(Succeed())
+	g.Expect(lb.SetUserState(ctx, "c", api.UserStateActive)).To(Succeed())
+
+	g.Expect(rankedIds()).To(Equal([]string{"b", "a", "c"}))
+
+	user, err = lb.GetUser(ctx, "b", api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.State).To(BeEmpty())
+	g.Expect(user.SegmentRank).To(Equal(1))
+}
@@ -0,0 +1,433 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+// boardName은 인덱스 이름을 Membership.Board 형식으로 바꾼다
+func boardName(index string) string {
+	switch {
+	case index == "":
+		return "ranks"
+	case strings.HasPrefix(index, "snapshot_"):
+		return "snapshot:" + strings.TrimPrefix(index, "snapshot_")
+	}
+	return index
+}
+
+// userIndexes는 저장소에 있는 모든 인덱스 이름을 반환한다.
+// 설정에서 빠진 segment 속성의 인덱스처럼 지금 설정으로는 찾을 수 없는 인덱스에도 사용자가 남아있을 수 있다.
+func (lb *LeaderBoard) userIndexes(ctx context.Context) ([]string, error) {
+	return lb.Storage.IndexNames(ctx)
+}
+
+func (lb *LeaderBoard) memberships(ctx context.Context, userId string) ([]api.Membership, error) {
+	indexes, err := lb.userIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := []api.Membership{}
+
+	for _, index := range indexes {
+		ranks, err := lb.Storage.GetIndexRanks(ctx, index, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		if ranks[0] == 0 {
+			continue
+		}
+
+		scores, err := lb.Storage.GetIndexScores(ctx, index, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		memberships = append(memberships, api.Membership{
+			Board: boardName(index),
+			Score: scores[0],
+			Rank:  ranks[0],
+		})
+	}
+
+	return memberships, nil
+}
+
+// userListItems는 key 목록에서 user_id가 userId인 항목들의 원본 데이터를 반환한다
+func (lb *LeaderBoard) userListItems(ctx context.Context, key, userId string) ([][]byte, error) {
+	rawList, err := lb.Storage.GetList(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var items [][]byte
+	for _, data := range rawList {
+		item := struct {
+			UserId string `json:"user_id"`
+		}{}
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		if item.UserId == userId {
+			items = append(items, data)
+		}
+	}
+
+	return items, nil
+}
+
+func eventMentions(event Event, userId string) bool {
+	return event.UserId == userId || event.OvertakenBy == userId
+}
+
+// userEvents는 변경 이벤트 스트림에서 userId가 들어있는 이벤트들을 반환한다
+func (lb *LeaderBoard) userEvents(ctx context.Context, userId string) ([]Event, error) {
+	events := []Event{}
+
+	afterSeq := int64(0)
+	for {
+		batch, err := lb.GetEvents(ctx, afterSeq, eventBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(batch) == 0 {
+			return events, nil
+		}
+
+		for _, event := range batch {
+			if eventMentions(event, userId) {
+				events = append(events, event)
+			}
+		}
+
+		afterSeq = batch[len(batch)-1].Seq
+	}
+}
+
+// listItem은 목록 key에 저장된 항목의 원본 데이터
+type listItem struct {
+	key  string
+	data []byte
+}
+
+// userWebhookDeliveries는 webhook 전송 기록과 dead letter 목록에서 userId가 들어있는 이벤트의 항목들을 반환한다
+func (lb *LeaderBoard) userWebhookDeliveries(ctx context.Context, userId string) ([]listItem, error) {
+	webhooks, _, err := lb.getWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []listItem
+	for _, webhook := range webhooks {
+		for _, key := range []string{webhookDeliveryListKey(webhook.Id), webhookDeadLetterListKey(webhook.Id)} {
+			rawList, err := lb.Storage.GetList(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, data := range rawList {
+				delivery := WebhookDelivery{}
+				if err := json.Unmarshal(data, &delivery); err != nil {
+					return nil, err
+				}
+				if eventMentions(delivery.Event, userId) {
+					items = append(items, listItem{key: key, data: data})
+				}
+			}
+		}
+	}
+
+	return items, nil
+}
+
+func (lb *LeaderBoard) ExportUser(ctx context.Context, userId string) (api.UserExport, error) {
+	userId, err := lb.storedUserId(ctx, userId)
+	if err != nil {
+		return api.UserExport{}, err
+	}
+
+	return lb.exportUser(ctx, userId)
+}
+
+// exportUser는 storedUserId로 찾은 userId의 데이터를 모은다
+func (lb *LeaderBoard) exportUser(ctx context.Context, userId string) (api.UserExport, error) {
+	export := api.UserExport{
+		UserId:     userId,
+		ExportedAt: lb.now(),
+	}
+
+	_, userData, err := lb.loadUser(ctx, userId)
+	if err != nil {
+		return api.UserExport{}, err
+	}
+
+	if userData != nil {
+		user, err := lb.getUser(ctx, userId, api.NewOptions(api.WithHidden()))
+		if err != nil {
+			return api.UserExport{}, err
+		}
+		export.User = &user
+	}
+
+	if export.Memberships, err = lb.memberships(ctx, userId); err != nil {
+		return api.UserExport{}, err
+	}
+
+	if export.History, err = lb.getHistory(ctx, userId); err != nil {
+		return api.UserExport{}, err
+	}
+
+	rejections, err := lb.userListItems(ctx, rejectionListKey, userId)
+	if err != nil {
+		return api.UserExport{}, err
+	}
+
+	export.Rejections = make([]Rejection, len(rejections))
+	for i, data := range rejections {
+		if err := json.Unmarshal(data, &export.Rejections[i]); err != nil {
+			return api.UserExport{}, err
+		}
+	}
+
+	reviews, err := lb.userListItems(ctx, reviewListKey, userId)
+	if err != nil {
+		return api.UserExport{}, err
+	}
+
+	export.Reviews = make([]Review, len(reviews))
+	for i, data := range reviews {
+		if err := json.Unmarshal(data, &export.Reviews[i]); err != nil {
+			return api.UserExport{}, err
+		}
+	}
+
+	if export.Events, err = lb.userEvents(ctx, userId); err != nil {
+		return api.UserExport{}, err
+	}
+
+	deliveries, err := lb.userWebhookDeliveries(ctx, userId)
+	if err != nil {
+		return api.UserExport{}, err
+	}
+
+	// 같은 전송이 전송 기록과 dead letter 목록에 함께 있을 수 있다
+	seen := map[string]bool{}
+	export.WebhookDeliveries = []WebhookDelivery{}
+	for _, item := range deliveries {
+		if seen[string(item.data)] {
+			continue
+		}
+		seen[string(item.data)] = true
+
+		delivery := WebhookDelivery{}
+		if err := json.Unmarshal(item.data, &delivery); err != nil {
+			return api.UserExport{}, err
+		}
+		export.WebhookDeliveries = append(export.WebhookDeliveries, delivery)
+	}
+
+	return export, nil
+}
+
+// exportItems는 export에 남아있는 항목들을 ErasureReport 형식으로 나열한다
+func exportItems(export api.UserExport) []string {
+	items := []string{}
+
+	if export.User != nil {
+		items = append(items, "user")
+	}
+
+	for _, m := range export.Memberships {
+		items = append(items, m.Board)
+	}
+
+	if n := len(export.History); n > 0 {
+		items = append(items, fmt.Sprintf("history: %d entries", n))
+	}
+
+	if n := len(export.Rejections); n > 0 {
+		items = append(items, fmt.Sprintf("rejections: %d entries", n))
+	}
+
+	if n := len(export.Reviews); n > 0 {
+		items = append(items, fmt.Sprintf("reviews: %d entries", n))
+	}
+
+	if n := len(export.Events); n > 0 {
+		items = append(items, fmt.Sprintf("events: %d entries", n))
+	}
+
+	if n := len(export.WebhookDeliveries); n > 0 {
+		items = append(items, fmt.Sprintf("webhook deliveries: %d entries", n))
+	}
+
+	return items
+}
+
+// userValues는 userId로 만든 idempotency 기록과 제출 횟수 값의 key들을 반환한다.
+// 정책에 맞는 사용자 id에는 ':'가 없으므로 다른 사용자의 key와 겹치지 않는다.
+func (lb *LeaderBoard) userValues(ctx context.Context, userId string) (idempotencyKeys, submissionKeys []string, err error) {
+	if idempotencyKeys, err = lb.Storage.ValueKeys(ctx, idempotencyValueKey(userId, "")); err != nil {
+		return nil, nil, err
+	}
+
+	if submissionKeys, err = lb.Storage.ValueKeys(ctx, submissionCountPrefix(userId)); err != nil {
+		return nil, nil, err
+	}
+
+	return idempotencyKeys, submissionKeys, nil
+}
+
+// valueItems는 userValues가 찾은 값들을 ErasureReport 형식으로 나열한다
+func valueItems(idempotencyKeys, submissionKeys []string) []string {
+	items := []string{}
+
+	if n := len(idempotencyKeys); n > 0 {
+		items = append(items, fmt.Sprintf("idempotency records: %d entries", n))
+	}
+
+	if n := len(submissionKeys); n > 0 {
+		items = append(items, fmt.Sprintf("submission counts: %d entries", n))
+	}
+
+	return items
+}
+
+// eraseUserData는 사용자와 history, values 값들을 지우고 모든 인덱스에서 뺀다. 읽은 다음에 사용자가 바뀌었으면 errConflict를 반환한다.
+func (lb *LeaderBoard) eraseUserData(ctx context.Context, userId string, indexes, values []string) error {
+	user, userData, err := lb.loadUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	exists := userData != nil
+
+	w := storage.Write{
+		Key:           userId,
+		Delete:        true,
+		RemoveIndexes: indexes,
+		CheckData:     true,
+		ExpectData:    userData,
+		DeleteLists:   []string{historyListKey(userId)},
+		DeleteValues:  values,
+		Incrs:         statsIncrs(user, isRanked(user, exists), User{}, false),
+	}
+
+	if exists {
+		if w.Streams, err = lb.eventItems(lb.deletedEvents(User{Score: user.Score})); err != nil {
+			return err
+		}
+	}
+
+	lb.bumpVersion(&w)
+
+	applied, err := lb.Storage.WriteData(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	if !applied {
+		return errConflict
+	}
+
+	return nil
+}
+
+// EraseUser는 사용자의 idempotency 기록과 제출 횟수도 지운다. 서명한 요청의 nonce 값에는 사용자 id가 들어있지 않다.
+// 사용자의 id가 들어있는 이벤트들은 스트림에서 지우고, 구독자들에게는 UserId가 없는 user_deleted 이벤트로 알린다.
+func (lb *LeaderBoard) EraseUser(ctx context.Context, userId string, audit api.Audit) (api.ErasureReport, error) {
+	if audit.Actor == "" {
+		return api.ErasureReport{}, api.ErrorWithStatusCode(errors.New("actor is empty"), http.StatusBadRequest)
+	}
+
+	userId, err := lb.storedUserId(ctx, userId)
+	if err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	export, err := lb.exportUser(ctx, userId)
+	if err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	report := api.ErasureReport{
+		UserId:   userId,
+		Actor:    audit.Actor,
+		Reason:   audit.Reason,
+		ErasedAt: lb.now(),
+		Erased:   exportItems(export),
+	}
+
+	idempotencyKeys, submissionKeys, err := lb.userValues(ctx, userId)
+	if err != nil {
+		return api.ErasureReport{}, err
+	}
+	report.Erased = append(report.Erased, valueItems(idempotencyKeys, submissionKeys)...)
+
+	indexes, err := lb.userIndexes(ctx)
+	if err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	err = lb.retryOnConflict(func() error {
+		return lb.eraseUserData(ctx, userId, indexes, append(idempotencyKeys, submissionKeys...))
+	})
+	if err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	seqs := make([]int64, len(export.Events))
+	for i, event := range export.Events {
+		seqs[i] = event.Seq
+	}
+
+	if err := lb.Storage.DeleteStreamItems(ctx, eventStreamKey, seqs...); err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	items, err := lb.userWebhookDeliveries(ctx, userId)
+	if err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	for _, key := range []string{rejectionListKey, reviewListKey} {
+		rawList, err := lb.userListItems(ctx, key, userId)
+		if err != nil {
+			return api.ErasureReport{}, err
+		}
+
+		for _, data := range rawList {
+			items = append(items, listItem{key: key, data: data})
+		}
+	}
+
+	for _, item := range items {
+		if err := lb.Storage.RemoveListItem(ctx, item.key, item.data); err != nil {
+			return api.ErasureReport{}, err
+		}
+	}
+
+	// 지운 다음 저장소 전체에서 다시 찾아서 남은 항목이 없는지 확인한다
+	export, err = lb.exportUser(ctx, userId)
+	if err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	if idempotencyKeys, submissionKeys, err = lb.userValues(ctx, userId); err != nil {
+		return api.ErasureReport{}, err
+	}
+
+	report.Remaining = append(exportItems(export), valueItems(idempotencyKeys, submissionKeys)...)
+	report.Verified = len(report.Remaining) == 0
+
+	return report, nil
+}
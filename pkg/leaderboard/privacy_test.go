@@ -0,0 +1,241 @@
+package leaderboard_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Privacy(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testPrivacy(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testPrivacy(t, miniredisStorage{
+			RedisStorage: &storage.RedisStorage{
+				KeyPrefix: "test",
+				Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+			},
+			server: s,
+		})
+	})
+}
+
+func testPrivacy(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	maxScore := 1000
+	lb := LeaderBoard{
+		Storage:           s,
+		NowFunc:           func() time.Time { return now },
+		SegmentAttributes: []string{"country"},
+		Rules:             ScoreRules{MaxScore: &maxScore, MaxSubmissions: 100, SubmissionWindow: time.Hour},
+		MaxEvents:         100,
+		EventTopN:         10,
+	}
+	admin := api.Audit{Actor: "admin", Reason: "privacy request"}
+
+	// a가 b를 추월한 이벤트에도 a의 id가 들어있다
+	g.Expect(lb.SetUser(ctx, "b", 20, api.WithAttribute("country", "kr"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "a", 30, api.WithAttribute("country", "kr"), api.WithIdempotencyKey("k1"))).To(Succeed())
+	g.Expect(lb.Checkpoint(ctx)).To(Succeed())
+	_, err := lb.CreateSnapshot(ctx, "s1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(lb.OverrideScore(ctx, "a", 40, admin)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "a", 2000)).NotTo(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 2000)).NotTo(Succeed())
+	_, err = lb.AddReview(ctx, api.Review{UserId: "a", Score: 50, Action: api.ReviewActionQuarantine})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	export, err := lb.ExportUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(export.UserId).To(Equal("a"))
+	g.Expect(export.ExportedAt).To(Equal(now))
+	g.Expect(export.User).NotTo(BeNil())
+	g.Expect(export.User.Score).To(Equal(40))
+	g.Expect(export.Memberships).To(ConsistOf(
+		api.Membership{Board: "ranks", Score: 40, Rank: 1},
+		api.Membership{Board: "checkpoint", Score: 30, Rank: 1},
+		api.Membership{Board: "segment:country=kr", Score: 40, Rank: 1},
+		api.Membership{Board: "snapshot:s1", Score: 30, Rank: 1},
+	))
+	g.Expect(export.History).To(HaveLen(2))
+	g.Expect(export.Rejections).To(HaveLen(1))
+	g.Expect(export.Reviews).To(HaveLen(1))
+	g.Expect(export.Events).To(HaveLen(4))
+	for _, event := range export.Events {
+		g.Expect([]string{event.UserId, event.OvertakenBy}).To(ContainElement("a"))
+	}
+
+	_, err = lb.EraseUser(ctx, "a", api.Audit{})
+	g.Expect(err).To(MatchError("actor is empty"))
+
+	// 설정에서 segment 속성이 빠져도 저장소에 남은 segment 인덱스에서 지운다
+	erasingLb := LeaderBoard{
+		Storage:   s,
+		NowFunc:   func() time.Time { return now },
+		MaxEvents: 100,
+	}
+
+	report, err := erasingLb.EraseUser(ctx, "a", admin)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Actor).To(Equal("admin"))
+	g.Expect(report.Reason).To(Equal("privacy request"))
+	g.Expect(report.Erased).To(ContainElements("user", "ranks", "segment:country=kr", "snapshot:s1", "history: 2 entries", "events: 4 entries",
+		"idempotency records: 1 entries", "submission counts: 1 entries"))
+	g.Expect(report.Remaining).To(BeEmpty())
+	g.Expect(report.Verified).To(BeTrue())
+
+	// a의 id로 만든 값은 남지 않고 b의 값은 남는다
+	for _, prefix := range []string{"idempotency_a:", "submissions_a:"} {
+		keys, err := s.ValueKeys(ctx, prefix)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(keys).To(BeEmpty())
+	}
+	keys, err := s.ValueKeys(ctx, "submissions_b:")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keys).To(HaveLen(1))
+
+	// 스냅샷의 사용자 수는 남아있는 사용자만 센다
+	snapshots, err := lb.GetSnapshots(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].UserCount).To(Equal(1))
+
+	// 이벤트 스트림에는 a의 id가 남지 않고, 삭제는 id가 없는 이벤트로 알린다
+	events, err := lb.GetEvents(ctx, 0, 100)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(HaveLen(3))
+	for _, event := range events[:2] {
+		g.Expect(event.UserId).To(Equal("b"))
+		g.Expect(event.OvertakenBy).To(BeEmpty())
+	}
+	g.Expect(events[2].Type).To(Equal(api.EventUserDeleted))
+	g.Expect(events[2].UserId).To(BeEmpty())
+	g.Expect(events[2].Score).To(Equal(40))
+
+	_, err = lb.GetUser(ctx, "a")
+	g.Expect(err).To(MatchError("not found"))
+
+	// 다른 사용자의 데이터와 순위는 그대로 남는다
+	users, err := lb.GetRanks(ctx, 1, 10, api.WithSnapshot("s1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(1))
+	g.Expect(users[0].Id).To(Equal("b"))
+	g.Expect(users[0].Rank).To(Equal(1))
+
+	users, err = lb.GetRanks(ctx, 1, 10, api.WithSegment("country", "kr"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(1))
+	g.Expect(users[0].Id).To(Equal("b"))
+
+	rejections, err := lb.GetRejections(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rejections).To(HaveLen(1))
+	g.Expect(rejections[0].UserId).To(Equal("b"))
+
+	reviews, err := lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(BeEmpty())
+
+	// 지운 사용자를 다시 지워도 남은 데이터가 없으므로 성공한다
+	report, err = lb.EraseUser(ctx, "a", admin)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Erased).To(BeEmpty())
+	g.Expect(report.Verified).To(BeTrue())
+}
+
+func TestLeaderBoard_LegacyUserId(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testLegacyUserId(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testLegacyUserId(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+// 사용자 id 정책이 생기기 전에 저장된 id도 관리자와 개인정보 처리 요청으로 찾을 수 있다
+func testLegacyUserId(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage: s,
+		NowFunc: func() time.Time { return now },
+	}
+	admin := api.Audit{Actor: "admin", Reason: "privacy request"}
+
+	// 정책에 맞지 않는 글자가 있는 id와 NFC로 정규화하지 않은 id
+	for _, userId := range []string{"legacy id!", "café"} {
+		data, err := json.Marshal(api.User{Id: userId, Score: 10})
+		g.Expect(err).NotTo(HaveOccurred())
+		_, err = s.WriteData(ctx, storage.Write{Key: userId, Data: data, Score: 10, AddIndexes: []string{""}})
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+
+	_, err := lb.GetUser(ctx, "legacy id!")
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = lb.GetUser(ctx, "café")
+	g.Expect(err).To(MatchError("not found"))
+
+	for _, userId := range []string{"legacy id!", "café"} {
+		user, err := lb.GetUser(ctx, userId, api.WithHidden())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(user.Id).To(Equal(userId))
+		g.Expect(user.Score).To(Equal(10))
+
+		g.Expect(lb.SetUserState(ctx, userId, api.UserStateBanned)).To(Succeed())
+		g.Expect(lb.OverrideScore(ctx, userId, 20, admin)).To(Succeed())
+
+		history, err := lb.GetHistory(ctx, userId)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(history).To(HaveLen(1))
+
+		export, err := lb.ExportUser(ctx, userId)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(export.UserId).To(Equal(userId))
+		g.Expect(export.User).NotTo(BeNil())
+		g.Expect(export.User.Score).To(Equal(20))
+		g.Expect(export.User.State).To(Equal(api.UserStateBanned))
+
+		report, err := lb.EraseUser(ctx, userId, admin)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.UserId).To(Equal(userId))
+		g.Expect(report.Erased).To(ContainElements("user", "history: 1 entries"))
+		g.Expect(report.Verified).To(BeTrue())
+
+		users, err := s.GetData(ctx, userId)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(users[0]).To(BeEmpty())
+	}
+}
@@ -0,0 +1,156 @@
+package leaderboard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+type Review = api.Review
+
+const reviewListKey = "reviews"
+
+// DefaultMaxReviews는 MaxReviews가 0일 때 보관하는 검토 대기 수
+const DefaultMaxReviews = 10000
+
+func (lb *LeaderBoard) maxReviews() int {
+	switch {
+	case lb.MaxReviews > 0:
+		return lb.MaxReviews
+	case lb.MaxReviews < 0:
+		return 0
+	}
+	return DefaultMaxReviews
+}
+
+// reviewItem은 review에 id와 생성 시각을 붙여서 검토 대기열에 추가할 항목을 만든다
+func (lb *LeaderBoard) reviewItem(review *Review) (storage.Append, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return storage.Append{}, err
+	}
+
+	review.Id = hex.EncodeToString(id)
+	review.CreatedAt = lb.now()
+
+	data, err := json.Marshal(review)
+	if err != nil {
+		return storage.Append{}, err
+	}
+
+	return storage.Append{Key: reviewListKey, Data: data, MaxLen: lb.maxReviews()}, nil
+}
+
+func (lb *LeaderBoard) AddReview(ctx context.Context, review Review) (Review, error) {
+	userId, err := api.NormalizeUserId(review.UserId)
+	if err != nil {
+		return Review{}, err
+	}
+	review.UserId = userId
+
+	item, err := lb.reviewItem(&review)
+	if err != nil {
+		return Review{}, err
+	}
+
+	if err := lb.Storage.AppendList(ctx, item.Key, item.Data, item.MaxLen); err != nil {
+		return Review{}, err
+	}
+
+	return review, nil
+}
+
+func (lb *LeaderBoard) GetReviews(ctx context.Context) ([]Review, error) {
+	reviews, _, err := lb.getReviews(ctx)
+	return reviews, err
+}
+
+func (lb *LeaderBoard) getReviews(ctx context.Context) ([]Review, [][]byte, error) {
+	rawList, err := lb.Storage.GetList(ctx, reviewListKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reviews := make([]Review, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &reviews[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return reviews, rawList, nil
+}
+
+func (lb *LeaderBoard) ResolveReview(ctx context.Context, reviewId string, approve bool, audit api.Audit) error {
+	if audit.Actor == "" {
+		return api.ErrorWithStatusCode(errors.New("actor is empty"), http.StatusBadRequest)
+	}
+
+	reviews, rawList, err := lb.getReviews(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, review := range reviews {
+		if review.Id != reviewId {
+			continue
+		}
+
+		if err := lb.applyReview(ctx, review, approve, audit); err != nil {
+			return err
+		}
+
+		return lb.Storage.RemoveListItem(ctx, reviewListKey, rawList[i])
+	}
+
+	return api.ErrorWithStatusCode(errors.New("review not found"), http.StatusNotFound)
+}
+
+// applyReview는 검토 결과에 따라 점수를 바꾼다. 변경 내역은 override로 history에 남긴다.
+// 보류된 제출을 승인할 때는 지금의 사용자에 대해 ban과 점수 규칙을 다시 검사한다.
+// 표시된 제출을 거부할 때는 그 뒤에 점수가 바뀌지 않았어야 한다.
+func (lb *LeaderBoard) applyReview(ctx context.Context, review Review, approve bool, audit api.Audit) error {
+	var score int
+	switch {
+	case approve && review.Action == api.ReviewActionQuarantine:
+		score = review.Score
+	case !approve && review.Action == api.ReviewActionFlag:
+		score = review.PreviousScore
+	default:
+		return nil
+	}
+
+	return lb.retryOnConflict(func() error {
+		oldUser, oldData, err := lb.loadUser(ctx, review.UserId)
+		if err != nil {
+			return err
+		}
+
+		// 검토하는 동안 새 점수가 제출되었으면 그 점수를 예전 점수로 덮어쓰지 않는다
+		if !approve && oldUser.Score != review.Score {
+			return api.ErrorWithStatusCode(errors.New("score was changed after the review"), http.StatusConflict)
+		}
+
+		if approve {
+			if oldUser.State == api.UserStateBanned {
+				return api.ErrorWithStatusCode(errors.New("user is banned"), http.StatusForbidden)
+			}
+
+			if err := lb.validateScore(oldUser, oldData != nil, score); err != nil {
+				return err
+			}
+		}
+
+		if oldUser.Score == score {
+			return nil
+		}
+
+		return lb.changeScore(ctx, review.UserId, oldUser, oldData, score, api.HistoryKindOverride, audit)
+	})
+}
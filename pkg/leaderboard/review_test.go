@@ -0,0 +1,119 @@
+package leaderboard_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Reviews(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testReviews(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testReviews(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testReviews(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage: s,
+		NowFunc: func() time.Time { return now },
+	}
+	admin := api.Audit{Actor: "admin"}
+
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	quarantined, err := lb.AddReview(ctx, api.Review{UserId: "c", Score: 100, Action: api.ReviewActionQuarantine})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(quarantined.Id).NotTo(BeEmpty())
+
+	flagged, err := lb.AddReview(ctx, api.Review{UserId: "b", Score: 20, PreviousScore: 10, Action: api.ReviewActionFlag})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reviews, err := lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(Equal([]api.Review{quarantined, flagged}))
+
+	g.Expect(lb.ResolveReview(ctx, "unknown", true, admin)).To(MatchError("review not found"))
+	g.Expect(lb.ResolveReview(ctx, flagged.Id, false, api.Audit{})).To(MatchError("actor is empty"))
+
+	// 보류된 제출을 거절하면 반영하지 않는다
+	g.Expect(lb.ResolveReview(ctx, quarantined.Id, false, admin)).To(Succeed())
+	_, err = lb.GetUser(ctx, "c")
+	g.Expect(err).To(MatchError("not found"))
+
+	// 반영된 제출을 거절하면 이전 점수로 되돌린다
+	g.Expect(lb.ResolveReview(ctx, flagged.Id, false, admin)).To(Succeed())
+	user, err := lb.GetUser(ctx, "b")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(10))
+
+	reviews, err = lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(BeEmpty())
+
+	// 표시된 뒤에 새 점수가 제출되었으면 거절해도 그 점수를 덮어쓰지 않는다
+	now = now.Add(time.Minute)
+	g.Expect(lb.SetUser(ctx, "b", 15)).To(Succeed())
+	flagged, err = lb.AddReview(ctx, api.Review{UserId: "b", Score: 15, PreviousScore: 10, Action: api.ReviewActionFlag})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	now = now.Add(time.Minute)
+	g.Expect(lb.SetUser(ctx, "b", 25)).To(Succeed())
+
+	err = lb.ResolveReview(ctx, flagged.Id, false, admin)
+	g.Expect(err).To(MatchError("score was changed after the review"))
+	g.Expect(err.(api.Error).StatusCode()).To(Equal(409))
+
+	user, err = lb.GetUser(ctx, "b")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(25))
+
+	// 검토는 남아있으므로 승인해서 닫을 수 있다
+	g.Expect(lb.ResolveReview(ctx, flagged.Id, true, admin)).To(Succeed())
+
+	reviews, err = lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(BeEmpty())
+
+	// ban된 사용자의 보류된 제출은 승인할 수 없다
+	g.Expect(lb.SetUserState(ctx, "a", api.UserStateBanned)).To(Succeed())
+	banned, err := lb.AddReview(ctx, api.Review{UserId: "a", Score: 40, PreviousScore: 30, Action: api.ReviewActionQuarantine})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(lb.ResolveReview(ctx, banned.Id, true, admin)).To(MatchError("user is banned"))
+
+	// MaxReviews를 넘으면 오래된 검토부터 지운다
+	lb.MaxReviews = 2
+	for i := 0; i < 3; i++ {
+		_, err := lb.AddReview(ctx, api.Review{UserId: "b", Score: i, Action: api.ReviewActionFlag})
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+
+	reviews, err = lb.GetReviews(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviews).To(HaveLen(2))
+	g.Expect(reviews[0].Score).To(Equal(1))
+}
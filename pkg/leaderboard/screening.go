@@ -0,0 +1,156 @@
+package leaderboard
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+// Screener는 검증 규칙과 ban 검사를 통과한 점수 제출을 검사한다.
+// 검토가 필요하면 처리 방법과 이유를 반환하고, action이 ""이면 그대로 반영한다.
+type Screener interface {
+	Screen(submission Submission, stats ScoreStats) (action api.ReviewAction, reasons []string)
+}
+
+// Submission은 Screener가 검사하는 점수 제출
+type Submission struct {
+	UserId string
+	Score  int
+	// 제출한 점수로 받을 순위
+	Rank int
+	// 제출 전의 사용자. Exists가 false이면 처음 제출하는 사용자.
+	Previous User
+	Exists   bool
+	Now      time.Time
+}
+
+// ScoreStats는 지금 전체 순위에 있는 사용자들의 점수 통계
+type ScoreStats struct {
+	Count  int
+	Mean   float64
+	StdDev float64
+}
+
+// 통계는 서버들이 함께 쓰도록 저장소에 정수 합계로 보관하고, 전체 순위를 바꾸는 write에서 함께 고친다
+const (
+	statsCountKey      = "score_stats_count"
+	statsSumKey        = "score_stats_sum"
+	statsSumSquaresKey = "score_stats_sum_squares"
+)
+
+func (lb *LeaderBoard) scoreStats(ctx context.Context) (ScoreStats, error) {
+	var sums [3]int64
+	for i, key := range []string{statsCountKey, statsSumKey, statsSumSquaresKey} {
+		data, err := lb.Storage.GetValue(ctx, key)
+		if err != nil || data == nil {
+			return ScoreStats{}, err
+		}
+
+		if sums[i], err = strconv.ParseInt(string(data), 10, 64); err != nil {
+			return ScoreStats{}, err
+		}
+	}
+
+	count := float64(sums[0])
+	if count <= 0 {
+		return ScoreStats{}, nil
+	}
+
+	mean := float64(sums[1]) / count
+	stats := ScoreStats{Count: int(sums[0]), Mean: mean}
+	if count > 1 {
+		variance := (float64(sums[2]) - mean*float64(sums[1])) / (count - 1)
+		stats.StdDev = math.Sqrt(math.Max(variance, 0))
+	}
+
+	return stats, nil
+}
+
+// isRanked는 사용자가 전체 순위에 있는지 반환한다
+func isRanked(user User, exists bool) bool {
+	return exists && isPublic(user)
+}
+
+// statsIncrs는 전체 순위의 oldUser가 newUser로 바뀔 때 통계를 고치는 변경들.
+// oldRanked, newRanked는 바뀌기 전과 후에 전체 순위에 있는지이다.
+func statsIncrs(oldUser User, oldRanked bool, newUser User, newRanked bool) []storage.Incr {
+	var count, sum, sumSquares int64
+	if oldRanked {
+		score := int64(oldUser.Score)
+		count, sum, sumSquares = count-1, sum-score, sumSquares-score*score
+	}
+	if newRanked {
+		score := int64(newUser.Score)
+		count, sum, sumSquares = count+1, sum+score, sumSquares+score*score
+	}
+
+	if count == 0 && sum == 0 && sumSquares == 0 {
+		return nil
+	}
+
+	return []storage.Incr{
+		{Key: statsCountKey, By: count},
+		{Key: statsSumKey, By: sum},
+		{Key: statsSumSquaresKey, By: sumSquares},
+	}
+}
+
+// screen은 oldUser를 newUser로 바꾸는 제출을 Screener로 검사한다
+func (lb *LeaderBoard) screen(ctx context.Context, oldUser User, exists bool, newUser User) (Submission, api.ReviewAction, []string, error) {
+	submission := Submission{
+		UserId:   newUser.Id,
+		Score:    newUser.Score,
+		Previous: oldUser,
+		Exists:   exists,
+		Now:      newUser.UpdatedAt,
+	}
+
+	if exists {
+		rank, err := lb.userRank(ctx, oldUser)
+		if err != nil {
+			return Submission{}, "", nil, err
+		}
+		submission.Previous.Rank = rank
+	}
+
+	rank, err := lb.virtualRank(ctx, "", newUser.Score)
+	if err != nil {
+		return Submission{}, "", nil, err
+	}
+	submission.Rank = rank
+
+	stats, err := lb.scoreStats(ctx)
+	if err != nil {
+		return Submission{}, "", nil, err
+	}
+
+	action, reasons := lb.Screener.Screen(submission, stats)
+	return submission, action, reasons, nil
+}
+
+// quarantine은 보류한 제출을 반영하지 않고 검토 대기열에 넣는다.
+// 제출한 쪽에서 보류된 것을 알 수 없도록 반영된 것처럼 보이는 사용자를 반환한다.
+func (lb *LeaderBoard) quarantine(ctx context.Context, submission Submission, newUser User, reasons []string) (*User, error) {
+	_, err := lb.AddReview(ctx, Review{
+		UserId:        submission.UserId,
+		Score:         submission.Score,
+		PreviousScore: submission.Previous.Score,
+		Action:        api.ReviewActionQuarantine,
+		Reasons:       reasons,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := []User{newUser}
+	users[0].Rank = submission.Rank
+	if err := lb.setPreviousRanks(ctx, users); err != nil {
+		return nil, err
+	}
+
+	return &users[0], nil
+}
@@ -0,0 +1,84 @@
+package leaderboard_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+// statsScreener는 마지막으로 받은 통계를 기억하고 모든 제출을 그대로 반영한다
+type statsScreener struct {
+	stats ScoreStats
+}
+
+func (s *statsScreener) Screen(submission Submission, stats ScoreStats) (api.ReviewAction, []string) {
+	s.stats = stats
+	return "", nil
+}
+
+func TestLeaderBoard_ScoreStats(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testScoreStats(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testScoreStats(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testScoreStats(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	screener := &statsScreener{}
+	lb := LeaderBoard{
+		Storage:  s,
+		Screener: screener,
+	}
+
+	// 제출을 검사할 때 받는 통계는 그 전의 전체 순위의 통계이다
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(screener.stats).To(Equal(ScoreStats{}))
+
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+	g.Expect(screener.stats).To(Equal(ScoreStats{Count: 1, Mean: 10}))
+
+	// 덮어쓴 점수는 통계에서 빠진다
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(screener.stats.Count).To(Equal(2))
+	g.Expect(screener.stats.Mean).To(Equal(15.0))
+
+	g.Expect(lb.SetUser(ctx, "c", 1)).To(Succeed())
+	g.Expect(screener.stats.Count).To(Equal(2))
+	g.Expect(screener.stats.Mean).To(Equal(25.0))
+
+	// 숨긴 사용자와 지운 사용자는 빠진다
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateHidden)).To(Succeed())
+	_, err := lb.EraseUser(ctx, "a", api.Audit{Actor: "admin"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(lb.SetUser(ctx, "d", 5)).To(Succeed())
+	g.Expect(screener.stats).To(Equal(ScoreStats{Count: 1, Mean: 1}))
+
+	// 다시 보이면 더한다
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateActive)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "e", 5)).To(Succeed())
+	g.Expect(screener.stats.Count).To(Equal(3))
+	g.Expect(screener.stats.Mean).To(BeNumerically("~", 26.0/3))
+	g.Expect(screener.stats.StdDev).To(BeNumerically("~", 10.017, 0.001))
+}
@@ -0,0 +1,190 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+type Segment = api.Segment
+
+func segmentIndex(attribute, value string) string {
+	return "segment:" + attribute + "=" + value
+}
+
+func (lb *LeaderBoard) isSegmentAttribute(attribute string) bool {
+	for _, a := range lb.SegmentAttributes {
+		if a == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+func (lb *LeaderBoard) checkAttributes(attributes map[string]string) error {
+	for attribute := range attributes {
+		if !lb.isSegmentAttribute(attribute) {
+			return api.ErrorWithStatusCode(errors.New("unknown attribute: "+attribute), http.StatusBadRequest)
+		}
+	}
+	return nil
+}
+
+// checkQueryOptions는 조회 조건과 스냅샷 id를 검사하고 viewer의 사용자 id를 정규화한다
+func (lb *LeaderBoard) checkQueryOptions(options *api.Options) error {
+	if options.Viewer != "" {
+		viewer, err := api.NormalizeUserId(options.Viewer)
+		if err != nil {
+			return err
+		}
+		options.Viewer = viewer
+	}
+
+	if options.SnapshotId != "" {
+		if err := checkSnapshotId(options.SnapshotId); err != nil {
+			return err
+		}
+	}
+
+	if options.Segment.Attribute == "" {
+		return nil
+	}
+
+	if options.SnapshotId != "" {
+		return api.ErrorWithStatusCode(errors.New("segment is not supported for snapshots"), http.StatusBadRequest)
+	}
+
+	if !lb.isSegmentAttribute(options.Segment.Attribute) {
+		return api.ErrorWithStatusCode(errors.New("unknown attribute: "+options.Segment.Attribute), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// mergeAttributes는 기존 속성에 변경할 속성을 덮어쓴 결과를 반환한다. 값이 ""인 속성은 제거한다.
+func mergeAttributes(attributes, changes map[string]string) map[string]string {
+	var merged map[string]string
+
+	for k, v := range attributes {
+		if merged == nil {
+			merged = map[string]string{}
+		}
+		merged[k] = v
+	}
+
+	for k, v := range changes {
+		if v == "" {
+			delete(merged, k)
+			continue
+		}
+		if merged == nil {
+			merged = map[string]string{}
+		}
+		merged[k] = v
+	}
+
+	return merged
+}
+
+func equalAttributes(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentIndexChanges는 사용자가 속해야 할 세그먼트 인덱스들과 빠져야 할 이전 세그먼트 인덱스들을 반환한다
+func (lb *LeaderBoard) segmentIndexChanges(oldAttributes, attributes map[string]string) (addIndexes, removeIndexes []string) {
+	for _, attribute := range lb.SegmentAttributes {
+		value, ok := attributes[attribute]
+		if ok {
+			addIndexes = append(addIndexes, segmentIndex(attribute, value))
+		}
+
+		if oldValue, oldOk := oldAttributes[attribute]; oldOk && (!ok || oldValue != value) {
+			removeIndexes = append(removeIndexes, segmentIndex(attribute, oldValue))
+		}
+	}
+
+	return addIndexes, removeIndexes
+}
+
+func (lb *LeaderBoard) segmentUserCount(ctx context.Context, segment Segment) (int, error) {
+	if segment.Value == "" {
+		return 0, api.ErrorWithStatusCode(errors.New("segment value is empty"), http.StatusBadRequest)
+	}
+
+	return lb.Storage.IndexCount(ctx, segmentIndex(segment.Attribute, segment.Value))
+}
+
+// setSegmentRank는 user의 세그먼트 순위를 채운다. user가 세그먼트에 속하지 않으면 not found.
+func (lb *LeaderBoard) setSegmentRank(ctx context.Context, user *User, segment Segment) error {
+	value, ok := user.Attributes[segment.Attribute]
+	if !ok || (segment.Value != "" && segment.Value != value) {
+		return api.ErrorWithStatusCode(errors.New("not found"), http.StatusNotFound)
+	}
+
+	index := segmentIndex(segment.Attribute, value)
+
+	if !isPublic(*user) {
+		rank, err := lb.virtualRank(ctx, index, user.Score)
+		if err != nil {
+			return err
+		}
+
+		user.SegmentRank = rank
+		return nil
+	}
+
+	ranks, err := lb.Storage.GetIndexRanks(ctx, index, user.Id)
+	if err != nil {
+		return err
+	}
+
+	user.SegmentRank = ranks[0]
+	return nil
+}
+
+func (lb *LeaderBoard) getSegmentRanks(ctx context.Context, segment Segment, rank, count int) ([]User, error) {
+	if segment.Value == "" {
+		return nil, api.ErrorWithStatusCode(errors.New("segment value is empty"), http.StatusBadRequest)
+	}
+
+	userIds, err := lb.Storage.GetIndexSortedRange(ctx, segmentIndex(segment.Attribute, segment.Value), rank, count)
+	if err != nil {
+		return nil, err
+	}
+
+	userDataList, err := lb.Storage.GetData(ctx, userIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks, err := lb.Storage.GetRanks(ctx, userIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	returnUsers := make([]User, len(userDataList))
+	for i, u := range userDataList {
+		if err := json.Unmarshal(u, &returnUsers[i]); err != nil {
+			return nil, err
+		}
+		returnUsers[i].Rank = ranks[i]
+		returnUsers[i].SegmentRank = rank + i
+	}
+
+	if err := lb.setPreviousRanks(ctx, returnUsers); err != nil {
+		return nil, err
+	}
+
+	return returnUsers, nil
+}
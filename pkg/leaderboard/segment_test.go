@@ -0,0 +1,101 @@
+package leaderboard_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Segment(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testSegment(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testSegment(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testSegment(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{
+		Storage:           s,
+		SegmentAttributes: []string{"country", "platform"},
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 50, api.WithAttribute("country", "US"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 40, api.WithAttribute("country", "KR"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 30, api.WithAttribute("country", "KR"), api.WithAttribute("platform", "ios"))).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "d", 20)).To(Succeed())
+
+	err := lb.SetUser(ctx, "e", 10, api.WithAttribute("color", "red"))
+	g.Expect(err).To(MatchError("unknown attribute: color"))
+
+	user, err := lb.GetUser(ctx, "c", api.WithSegment("country", ""))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Rank).To(Equal(3))
+	g.Expect(user.SegmentRank).To(Equal(2))
+	g.Expect(user.Attributes).To(Equal(map[string]string{"country": "KR", "platform": "ios"}))
+
+	_, err = lb.GetUser(ctx, "c", api.WithSegment("country", "US"))
+	g.Expect(err).To(MatchError("not found"))
+
+	count, err := lb.UserCount(ctx, api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(2))
+
+	users, err := lb.GetRanks(ctx, 1, 10, api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(2))
+	g.Expect([]int{users[0].Rank, users[0].SegmentRank}).To(Equal([]int{2, 1}))
+	g.Expect([]int{users[1].Rank, users[1].SegmentRank}).To(Equal([]int{3, 2}))
+
+	// 점수만 바꾸면 속성은 유지되고 세그먼트 순위도 갱신된다
+	g.Expect(lb.SetUser(ctx, "c", 45)).To(Succeed())
+
+	user, err = lb.GetUser(ctx, "c", api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Rank).To(Equal(2))
+	g.Expect(user.SegmentRank).To(Equal(1))
+
+	// 속성이 바뀌면 세그먼트를 옮긴다
+	g.Expect(lb.SetUser(ctx, "c", 45, api.WithAttribute("country", "US"))).To(Succeed())
+
+	count, err = lb.UserCount(ctx, api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(1))
+
+	users, err = lb.GetRanks(ctx, 1, 10, api.WithSegment("country", "US"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(2))
+	g.Expect(users[0].Id).To(Equal("a"))
+	g.Expect(users[1].Id).To(Equal("c"))
+	g.Expect(users[1].SegmentRank).To(Equal(2))
+
+	// 값이 ""인 속성은 제거된다
+	g.Expect(lb.SetUser(ctx, "c", 45, api.WithAttribute("platform", ""))).To(Succeed())
+
+	count, err = lb.UserCount(ctx, api.WithSegment("platform", "ios"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(0))
+
+	_, err = lb.GetRanks(ctx, 1, 10, api.WithSegment("color", "red"))
+	g.Expect(err).To(MatchError("unknown attribute: color"))
+}
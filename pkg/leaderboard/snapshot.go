@@ -0,0 +1,344 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+type Snapshot = api.Snapshot
+
+const (
+	snapshotListKey = "snapshots"
+	// id 없이 생성한 스냅샷은 생성 시각(UTC)을 id로 사용한다
+	snapshotIdLayout = "20060102T150405.000Z"
+	// 생성 시각이 같은 스냅샷에는 id 뒤에 번호를 붙인다
+	maxSnapshotIdSuffix = 100
+	// 스냅샷 id의 최대 글자 수
+	maxSnapshotIdLength = 64
+)
+
+// checkSnapshotId는 스냅샷 id로 인덱스 이름을 만들 수 있는지 검사한다.
+// 스냅샷 id는 utf-8이고, 출력할 수 있는 글자만 쓸 수 있으며 URL path에 쓰이므로 '/'는 쓸 수 없다.
+func checkSnapshotId(snapshotId string) error {
+	if !utf8.ValidString(snapshotId) {
+		return invalidSnapshotId("snapshot id is not valid utf-8")
+	}
+
+	if snapshotId == "" || utf8.RuneCountInString(snapshotId) > maxSnapshotIdLength {
+		return invalidSnapshotId("snapshot id is empty or too long")
+	}
+
+	for _, r := range snapshotId {
+		if !unicode.IsPrint(r) || r == '/' {
+			return invalidSnapshotId(fmt.Sprintf("snapshot id has invalid character: %q", r))
+		}
+	}
+
+	return nil
+}
+
+func invalidSnapshotId(msg string) error {
+	return api.ErrorWithStatusCode(errors.New(msg), http.StatusBadRequest)
+}
+
+func snapshotIndex(snapshotId string) string {
+	return "snapshot_" + snapshotId
+}
+
+// snapshotRecordKey는 스냅샷 id를 예약하는 값의 key
+func snapshotRecordKey(snapshotId string) string {
+	return "snapshot_record_" + snapshotId
+}
+
+func (lb *LeaderBoard) CreateSnapshot(ctx context.Context, snapshotId string) (Snapshot, error) {
+	now := lb.now()
+
+	snapshotId, err := lb.reserveSnapshotId(ctx, snapshotId, now)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot, err := lb.createSnapshot(ctx, snapshotId, now)
+	if err != nil {
+		// 만들지 못한 id는 다시 사용할 수 있게 한다. 요청이 취소되었어도 지워야 한다.
+		if deleteErr := lb.Storage.DeleteValue(context.Background(), snapshotRecordKey(snapshotId)); deleteErr != nil {
+			return Snapshot{}, deleteErr
+		}
+		return Snapshot{}, err
+	}
+
+	if snapshot.UserCount, err = lb.Storage.IndexCount(ctx, snapshotIndex(snapshotId)); err != nil {
+		return Snapshot{}, err
+	}
+
+	if err := lb.pruneSnapshots(ctx); err != nil {
+		return Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// reserveSnapshotId는 다른 요청이 같은 id로 스냅샷을 만들지 못하게 id를 예약한다.
+// snapshotId가 ""이면 생성 시각으로 id를 만들고, 이미 있으면 번호를 붙인다.
+func (lb *LeaderBoard) reserveSnapshotId(ctx context.Context, snapshotId string, now time.Time) (string, error) {
+	if snapshotId != "" {
+		if err := checkSnapshotId(snapshotId); err != nil {
+			return "", err
+		}
+
+		exists, err := lb.snapshotExists(ctx, snapshotId)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			reserved, err := lb.Storage.SetValueIfAbsent(ctx, snapshotRecordKey(snapshotId), []byte(snapshotId), 0)
+			if err != nil {
+				return "", err
+			}
+			exists = !reserved
+		}
+
+		if exists {
+			return "", api.ErrorWithStatusCode(errors.New("snapshot already exists"), http.StatusConflict)
+		}
+
+		return snapshotId, nil
+	}
+
+	baseId := now.UTC().Format(snapshotIdLayout)
+	for i := 1; i <= maxSnapshotIdSuffix; i++ {
+		snapshotId = baseId
+		if i > 1 {
+			snapshotId = fmt.Sprintf("%s-%d", baseId, i)
+		}
+
+		reserved, err := lb.Storage.SetValueIfAbsent(ctx, snapshotRecordKey(snapshotId), []byte(snapshotId), 0)
+		if err != nil {
+			return "", err
+		}
+
+		if reserved {
+			return snapshotId, nil
+		}
+	}
+
+	return "", api.ErrorWithStatusCode(errors.New("snapshot already exists"), http.StatusConflict)
+}
+
+// snapshotExists는 id를 예약하기 전에 만들어진 스냅샷도 찾기 위해 스냅샷 목록을 확인한다
+func (lb *LeaderBoard) snapshotExists(ctx context.Context, snapshotId string) (bool, error) {
+	err := lb.checkSnapshot(ctx, snapshotId)
+	if err == nil {
+		return true, nil
+	}
+
+	if apiErr, ok := err.(api.Error); ok && apiErr.StatusCode() == http.StatusNotFound {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// createSnapshot은 순위를 스냅샷 인덱스로 복사하고 목록에 추가한다.
+// 하나의 write로 저장하므로 목록이 가리키지 않는 인덱스가 남지 않는다.
+func (lb *LeaderBoard) createSnapshot(ctx context.Context, snapshotId string, now time.Time) (Snapshot, error) {
+	snapshot := Snapshot{
+		Id:        snapshotId,
+		CreatedAt: now,
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	w := storage.Write{
+		CopyIndexes: []string{snapshotIndex(snapshotId)},
+		Lists:       []storage.Append{{Key: snapshotListKey, Data: data}},
+	}
+	if _, err := lb.Storage.WriteData(ctx, w); err != nil {
+		return Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// GetSnapshots는 스냅샷 목록을 반환한다. UserCount는 스냅샷 인덱스에 남아있는 사용자 수이다.
+func (lb *LeaderBoard) GetSnapshots(ctx context.Context) ([]Snapshot, error) {
+	snapshots, _, err := lb.getSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range snapshots {
+		if snapshots[i].UserCount, err = lb.Storage.IndexCount(ctx, snapshotIndex(snapshots[i].Id)); err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (lb *LeaderBoard) DeleteSnapshot(ctx context.Context, snapshotId string) error {
+	snapshots, rawList, err := lb.getSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, s := range snapshots {
+		if s.Id == snapshotId {
+			return lb.deleteSnapshot(ctx, s, rawList[i])
+		}
+	}
+
+	return api.ErrorWithStatusCode(errors.New("snapshot not found"), http.StatusNotFound)
+}
+
+// getSnapshots는 생성된 순서대로 스냅샷 목록과 저장된 원본 데이터를 반환한다
+func (lb *LeaderBoard) getSnapshots(ctx context.Context) ([]Snapshot, [][]byte, error) {
+	rawList, err := lb.Storage.GetList(ctx, snapshotListKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshots := make([]Snapshot, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &snapshots[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return snapshots, rawList, nil
+}
+
+func (lb *LeaderBoard) deleteSnapshot(ctx context.Context, snapshot Snapshot, data []byte) error {
+	w := storage.Write{
+		RemoveListItems: []storage.ListItem{{Key: snapshotListKey, Data: data}},
+		DeleteIndexes:   []string{snapshotIndex(snapshot.Id)},
+		DeleteValues:    []string{snapshotRecordKey(snapshot.Id)},
+	}
+	lb.bumpVersion(&w)
+
+	_, err := lb.Storage.WriteData(ctx, w)
+	return err
+}
+
+// pruneSnapshots는 MaxSnapshots, SnapshotRetention을 넘어선 오래된 스냅샷을 삭제한다
+func (lb *LeaderBoard) pruneSnapshots(ctx context.Context) error {
+	if lb.MaxSnapshots <= 0 && lb.SnapshotRetention <= 0 {
+		return nil
+	}
+
+	snapshots, rawList, err := lb.getSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := lb.now()
+
+	for i, s := range snapshots {
+		remaining := len(snapshots) - i
+		tooMany := lb.MaxSnapshots > 0 && remaining > lb.MaxSnapshots
+		tooOld := lb.SnapshotRetention > 0 && now.Sub(s.CreatedAt) > lb.SnapshotRetention
+
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		if err := lb.deleteSnapshot(ctx, s, rawList[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lb *LeaderBoard) checkSnapshot(ctx context.Context, snapshotId string) error {
+	snapshots, _, err := lb.getSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range snapshots {
+		if s.Id == snapshotId {
+			return nil
+		}
+	}
+
+	return api.ErrorWithStatusCode(errors.New("snapshot not found"), http.StatusNotFound)
+}
+
+func (lb *LeaderBoard) snapshotUserCount(ctx context.Context, snapshotId string) (int, error) {
+	if err := lb.checkSnapshot(ctx, snapshotId); err != nil {
+		return 0, err
+	}
+
+	return lb.Storage.IndexCount(ctx, snapshotIndex(snapshotId))
+}
+
+func (lb *LeaderBoard) getSnapshotUser(ctx context.Context, snapshotId, userId string) (User, error) {
+	if err := lb.checkSnapshot(ctx, snapshotId); err != nil {
+		return User{}, err
+	}
+
+	index := snapshotIndex(snapshotId)
+
+	ranks, err := lb.Storage.GetIndexRanks(ctx, index, userId)
+	if err != nil {
+		return User{}, err
+	}
+
+	if ranks[0] == 0 {
+		return User{}, api.ErrorWithStatusCode(errors.New("not found"), http.StatusNotFound)
+	}
+
+	scores, err := lb.Storage.GetIndexScores(ctx, index, userId)
+	if err != nil {
+		return User{}, err
+	}
+
+	return User{
+		Id:    userId,
+		Score: scores[0],
+		Rank:  ranks[0],
+	}, nil
+}
+
+func (lb *LeaderBoard) getSnapshotRanks(ctx context.Context, snapshotId string, rank, count int) ([]User, error) {
+	if err := lb.checkSnapshot(ctx, snapshotId); err != nil {
+		return nil, err
+	}
+
+	index := snapshotIndex(snapshotId)
+
+	userIds, err := lb.Storage.GetIndexSortedRange(ctx, index, rank, count)
+	if err != nil {
+		return nil, err
+	}
+
+	scores, err := lb.Storage.GetIndexScores(ctx, index, userIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	returnUsers := make([]User, len(userIds))
+	for i, userId := range userIds {
+		returnUsers[i] = User{
+			Id:    userId,
+			Score: scores[i],
+			Rank:  rank + i,
+		}
+	}
+
+	return returnUsers, nil
+}
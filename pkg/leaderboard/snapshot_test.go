@@ -0,0 +1,236 @@
+package leaderboard_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/benbjohnson/clock"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Snapshot(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testSnapshot(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testSnapshot(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testSnapshot(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	timeMock := clock.NewMock()
+	timeMock.Set(time.Date(2021, 5, 7, 18, 0, 0, 0, time.UTC))
+
+	lb := LeaderBoard{
+		NowFunc:      timeMock.Now,
+		Storage:      s,
+		MaxSnapshots: 2,
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	snapshot, err := lb.CreateSnapshot(ctx, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshot).To(Equal(api.Snapshot{
+		Id:        "20210507T180000.000Z",
+		UserCount: 2,
+		CreatedAt: timeMock.Now(),
+	}))
+
+	// 같은 id로는 다시 만들 수 없다
+	_, err = lb.CreateSnapshot(ctx, snapshot.Id)
+	g.Expect(err).To(MatchError("snapshot already exists"))
+
+	// 같은 시각에 id 없이 만든 스냅샷에는 번호가 붙는다
+	other, err := lb.CreateSnapshot(ctx, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(other.Id).To(Equal("20210507T180000.000Z-2"))
+	g.Expect(lb.DeleteSnapshot(ctx, other.Id)).To(Succeed())
+
+	// 스냅샷 이후의 변경은 스냅샷에 반영되지 않는다
+	g.Expect(lb.SetUser(ctx, "b", 40)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 10)).To(Succeed())
+
+	count, err := lb.UserCount(ctx, api.WithSnapshot(snapshot.Id))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(2))
+
+	user, err := lb.GetUser(ctx, "b", api.WithSnapshot(snapshot.Id))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user).To(Equal(User{Id: "b", Score: 20, Rank: 2}))
+
+	_, err = lb.GetUser(ctx, "c", api.WithSnapshot(snapshot.Id))
+	g.Expect(err).To(MatchError("not found"))
+
+	users, err := lb.GetRanks(ctx, 1, 10, api.WithSnapshot(snapshot.Id))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(Equal([]User{
+		{Id: "a", Score: 30, Rank: 1},
+		{Id: "b", Score: 20, Rank: 2},
+	}))
+
+	users, err = lb.GetRanks(ctx, 2, 1, api.WithSnapshot(snapshot.Id))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(Equal([]User{
+		{Id: "b", Score: 20, Rank: 2},
+	}))
+
+	_, err = lb.GetRanks(ctx, 1, 10, api.WithSnapshot("unknown"))
+	g.Expect(err).To(MatchError("snapshot not found"))
+
+	// MaxSnapshots를 넘으면 오래된 스냅샷부터 삭제한다
+	timeMock.Add(time.Hour)
+	_, err = lb.CreateSnapshot(ctx, "s2")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	timeMock.Add(time.Hour)
+	_, err = lb.CreateSnapshot(ctx, "s3")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	snapshots, err := lb.GetSnapshots(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(2))
+	g.Expect(snapshots[0].Id).To(Equal("s2"))
+	g.Expect(snapshots[1].Id).To(Equal("s3"))
+
+	_, err = lb.UserCount(ctx, api.WithSnapshot(snapshot.Id))
+	g.Expect(err).To(MatchError("snapshot not found"))
+
+	users, err = lb.GetRanks(ctx, 1, 10, api.WithSnapshot("s3"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(Equal([]User{
+		{Id: "b", Score: 40, Rank: 1},
+		{Id: "a", Score: 30, Rank: 2},
+		{Id: "c", Score: 10, Rank: 3},
+	}))
+
+	g.Expect(lb.DeleteSnapshot(ctx, "s2")).To(Succeed())
+	g.Expect(lb.DeleteSnapshot(ctx, "s2")).To(MatchError("snapshot not found"))
+
+	snapshots, err = lb.GetSnapshots(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+
+	// 동시에 같은 id로 만들면 하나만 성공한다
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = lb.CreateSnapshot(ctx, "s2")
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+		} else {
+			g.Expect(err).To(MatchError("snapshot already exists"))
+		}
+	}
+	g.Expect(created).To(Equal(1))
+}
+
+func TestLeaderBoard_SnapshotRetention(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	timeMock := clock.NewMock()
+
+	lb := LeaderBoard{
+		NowFunc:           timeMock.Now,
+		Storage:           &storage.MemStorage{},
+		SnapshotRetention: 24 * time.Hour,
+	}
+
+	_, err := lb.CreateSnapshot(ctx, "old")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	timeMock.Add(25 * time.Hour)
+
+	_, err = lb.CreateSnapshot(ctx, "new")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	snapshots, err := lb.GetSnapshots(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].Id).To(Equal("new"))
+}
+
+func TestLeaderBoard_SnapshotId(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	lb := LeaderBoard{Storage: &storage.MemStorage{}}
+
+	for _, snapshotId := range []string{"a/b", "line\nbreak", string(make([]byte, 65))} {
+		_, err := lb.CreateSnapshot(ctx, snapshotId)
+		g.Expect(err).To(HaveOccurred(), snapshotId)
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(400), snapshotId)
+
+		_, err = lb.UserCount(ctx, api.WithSnapshot(snapshotId))
+		g.Expect(err).To(HaveOccurred(), snapshotId)
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(400), snapshotId)
+	}
+
+	snapshots, err := lb.GetSnapshots(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(BeEmpty())
+}
+
+// failingWriteStorage는 WriteData가 항상 실패한다
+type failingWriteStorage struct {
+	Storage
+}
+
+func (s *failingWriteStorage) WriteData(ctx context.Context, w storage.Write) (bool, error) {
+	return false, errors.New("write failed")
+}
+
+func TestLeaderBoard_SnapshotWriteFailed(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	s := &storage.MemStorage{}
+	g.Expect((&LeaderBoard{Storage: s}).SetUser(ctx, "a", 10)).To(Succeed())
+
+	lb := LeaderBoard{Storage: &failingWriteStorage{Storage: s}}
+
+	_, err := lb.CreateSnapshot(ctx, "s1")
+	g.Expect(err).To(MatchError("write failed"))
+
+	// 목록에 없는 스냅샷 인덱스가 남지 않고, 같은 id를 다시 사용할 수 있다
+	names, err := s.IndexNames(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(names).To(Equal([]string{""}))
+
+	lb.Storage = s
+	snapshot, err := lb.CreateSnapshot(ctx, "s1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshot.UserCount).To(Equal(1))
+}
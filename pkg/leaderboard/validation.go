@@ -0,0 +1,160 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+type Rejection = api.Rejection
+
+const rejectionListKey = "rejections"
+
+// DefaultMaxRejections는 MaxRejections가 0일 때 보관하는 거부 기록 수
+const DefaultMaxRejections = 10000
+
+func (lb *LeaderBoard) maxRejections() int {
+	switch {
+	case lb.MaxRejections > 0:
+		return lb.MaxRejections
+	case lb.MaxRejections < 0:
+		return 0
+	}
+	return DefaultMaxRejections
+}
+
+// ScoreRules는 SetUser로 제출하는 점수의 검증 규칙. 관리자의 OverrideScore에는 적용하지 않는다.
+type ScoreRules struct {
+	// nil이면 제한 없음
+	MinScore *int
+	MaxScore *int
+	// 한 번에 올릴 수 있는 최대 점수 (0이면 제한 없음). 이미 점수가 있는 사용자에게만 적용한다.
+	MaxIncrease int
+	// true이면 점수를 낮출 수 없다
+	Monotonic bool
+
+	// SubmissionWindow 동안 사용자별로 받을 최대 제출 수 (0이면 제한 없음)
+	MaxSubmissions   int
+	SubmissionWindow time.Duration
+}
+
+func submissionCountKey(userId string, window int64) string {
+	return submissionCountPrefix(userId) + strconv.FormatInt(window, 10)
+}
+
+func submissionCountPrefix(userId string) string {
+	return "submissions_" + userId + ":"
+}
+
+// recordRejection은 err가 제출을 거부한 것이면 거부 기록을 남긴다. 반환값은 err이거나 기록하지 못한 오류이다.
+// 제출마다 한 번만 남기도록 다시 시도가 모두 끝난 다음에 호출한다.
+func (lb *LeaderBoard) recordRejection(ctx context.Context, userId string, score int, err error) error {
+	var rejectionErr api.RejectionError
+	if !errors.As(err, &rejectionErr) {
+		return err
+	}
+
+	oldUser, _, loadErr := lb.loadUser(ctx, userId)
+	if loadErr != nil {
+		return loadErr
+	}
+
+	data, jsonErr := json.Marshal(Rejection{
+		UserId:        userId,
+		Score:         score,
+		PreviousScore: oldUser.Score,
+		Reason:        rejectionErr.Reason,
+		Message:       rejectionErr.Message,
+		CreatedAt:     lb.now(),
+	})
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	if err := lb.Storage.AppendList(ctx, rejectionListKey, data, lb.maxRejections()); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// countSubmission은 userId의 제출 수를 세고, SubmissionWindow 동안 MaxSubmissions를 넘으면 거부한다
+func (lb *LeaderBoard) countSubmission(ctx context.Context, userId string) error {
+	rules := lb.Rules
+	if rules.MaxSubmissions <= 0 || rules.SubmissionWindow <= 0 {
+		return nil
+	}
+
+	window := lb.now().UnixNano() / int64(rules.SubmissionWindow)
+
+	count, err := lb.Storage.IncrValue(ctx, submissionCountKey(userId, window), rules.SubmissionWindow)
+	if err != nil {
+		return err
+	}
+
+	if count > rules.MaxSubmissions {
+		return api.NewRejectionError(api.RejectionTooManySubmissions,
+			fmt.Sprintf("too many submissions: at most %d per %v", rules.MaxSubmissions, rules.SubmissionWindow))
+	}
+
+	return nil
+}
+
+// checkScoreRange는 score가 MinScore, MaxScore 사이인지 검사한다
+func (lb *LeaderBoard) checkScoreRange(score int) error {
+	rules := lb.Rules
+
+	if rules.MinScore != nil && score < *rules.MinScore {
+		return api.NewRejectionError(api.RejectionScoreTooLow, fmt.Sprintf("score is less than %d", *rules.MinScore))
+	}
+
+	if rules.MaxScore != nil && score > *rules.MaxScore {
+		return api.NewRejectionError(api.RejectionScoreTooHigh, fmt.Sprintf("score is greater than %d", *rules.MaxScore))
+	}
+
+	return nil
+}
+
+// validateScore는 oldUser가 score로 바뀔 수 있는지 검사한다. 제출 수는 세지 않는다.
+func (lb *LeaderBoard) validateScore(oldUser User, exists bool, score int) error {
+	rules := lb.Rules
+
+	if err := lb.checkScoreRange(score); err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	if rules.Monotonic && score < oldUser.Score {
+		return api.NewRejectionError(api.RejectionScoreDecreased, "score can not be decreased")
+	}
+
+	if rules.MaxIncrease > 0 && score-oldUser.Score > rules.MaxIncrease {
+		return api.NewRejectionError(api.RejectionIncreaseTooLarge, fmt.Sprintf("score can be increased by at most %d", rules.MaxIncrease))
+	}
+
+	return nil
+}
+
+func (lb *LeaderBoard) GetRejections(ctx context.Context) ([]Rejection, error) {
+	rawList, err := lb.Storage.GetList(ctx, rejectionListKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rejections := make([]Rejection, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &rejections[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return rejections, nil
+}
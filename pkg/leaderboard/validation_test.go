@@ -0,0 +1,170 @@
+package leaderboard_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Validation(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testValidation(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testValidation(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testValidation(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	minScore, maxScore := 0, 1000
+	lb := LeaderBoard{
+		Storage: s,
+		NowFunc: func() time.Time { return now },
+		Rules: ScoreRules{
+			MinScore:         &minScore,
+			MaxScore:         &maxScore,
+			MaxIncrease:      100,
+			Monotonic:        true,
+			MaxSubmissions:   2,
+			SubmissionWindow: time.Minute,
+		},
+	}
+
+	expectRejection := func(err error, reason api.RejectionReason) {
+		var rejectionErr api.RejectionError
+		g.Expect(errors.As(err, &rejectionErr)).To(BeTrue(), "err=%v", err)
+		g.Expect(rejectionErr.Reason).To(Equal(reason))
+
+		var apiErr api.Error
+		g.Expect(errors.As(err, &apiErr)).To(BeTrue())
+		g.Expect(apiErr.StatusCode()).To(Equal(http.StatusUnprocessableEntity))
+	}
+
+	expectRejection(lb.SetUser(ctx, "a", -1), api.RejectionScoreTooLow)
+	expectRejection(lb.SetUser(ctx, "b", 1001), api.RejectionScoreTooHigh)
+
+	// 처음 제출하는 점수에는 MaxIncrease를 적용하지 않는다
+	g.Expect(lb.SetUser(ctx, "a", 500)).To(Succeed())
+
+	now = now.Add(time.Minute)
+	expectRejection(lb.SetUser(ctx, "a", 601), api.RejectionIncreaseTooLarge)
+	expectRejection(lb.SetUser(ctx, "a", 499), api.RejectionScoreDecreased)
+	expectRejection(lb.SetUser(ctx, "a", 550), api.RejectionTooManySubmissions)
+
+	now = now.Add(time.Minute)
+	g.Expect(lb.SetUser(ctx, "a", 600)).To(Succeed())
+
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(600))
+
+	// 관리자의 점수 수정에는 규칙을 적용하지 않는다
+	g.Expect(lb.OverrideScore(ctx, "a", 0, api.Audit{Actor: "admin"})).To(Succeed())
+
+	rejections, err := lb.GetRejections(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rejections).To(HaveLen(5))
+	g.Expect(rejections[0]).To(Equal(api.Rejection{
+		UserId:    "a",
+		Score:     -1,
+		Reason:    api.RejectionScoreTooLow,
+		Message:   "score is less than 0",
+		CreatedAt: now.Add(-2 * time.Minute),
+	}))
+	g.Expect(rejections[2].PreviousScore).To(Equal(500))
+	g.Expect(rejections[4].Reason).To(Equal(api.RejectionTooManySubmissions))
+
+	// MaxRejections를 넘으면 오래된 기록부터 지운다
+	lb.MaxRejections = 2
+	expectRejection(lb.SetUser(ctx, "c", -2), api.RejectionScoreTooLow)
+
+	rejections, err = lb.GetRejections(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rejections).To(HaveLen(2))
+	g.Expect(rejections[1].UserId).To(Equal("c"))
+}
+
+func TestLeaderBoard_DefaultMaxRejections(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	maxScore := 0
+	lb := LeaderBoard{
+		Storage: &storage.MemStorage{},
+		Rules:   ScoreRules{MaxScore: &maxScore},
+	}
+
+	for i := 0; i < DefaultMaxRejections+1; i++ {
+		g.Expect(lb.SetUser(ctx, "a", i+1)).NotTo(Succeed())
+	}
+
+	rejections, err := lb.GetRejections(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rejections).To(HaveLen(DefaultMaxRejections))
+	g.Expect(rejections[0].Score).To(Equal(2))
+}
+
+func TestLeaderBoard_ValidationQuota(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	maxScore := 1000
+	s := &hookStorage{Storage: &storage.MemStorage{}}
+	lb := LeaderBoard{
+		Storage: s,
+		Rules: ScoreRules{
+			MaxScore:         &maxScore,
+			Monotonic:        true,
+			MaxSubmissions:   2,
+			SubmissionWindow: time.Hour,
+		},
+	}
+
+	// 범위를 벗어난 점수는 제출 수에 넣지 않는다
+	for i := 0; i < 3; i++ {
+		g.Expect(lb.SetUser(ctx, "a", 1001)).NotTo(Succeed())
+	}
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+
+	// 읽은 직후에 점수가 올라가서 다시 시도한 제출도 한 번만 세고 한 번만 기록한다
+	s.afterGetData = func() {
+		g.Expect(lb.OverrideScore(ctx, "a", 100, api.Audit{Actor: "admin"})).To(Succeed())
+	}
+	err := lb.SetUser(ctx, "a", 50)
+	var rejectionErr api.RejectionError
+	g.Expect(errors.As(err, &rejectionErr)).To(BeTrue(), "err=%v", err)
+	g.Expect(rejectionErr.Reason).To(Equal(api.RejectionScoreDecreased))
+
+	rejections, err := lb.GetRejections(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rejections).To(HaveLen(4))
+	g.Expect(rejections[3].Score).To(Equal(50))
+	g.Expect(rejections[3].PreviousScore).To(Equal(100))
+
+	err = lb.SetUser(ctx, "a", 200)
+	g.Expect(errors.As(err, &rejectionErr)).To(BeTrue(), "err=%v", err)
+	g.Expect(rejectionErr.Reason).To(Equal(api.RejectionTooManySubmissions))
+}
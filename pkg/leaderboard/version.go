@@ -0,0 +1,77 @@
+package leaderboard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+const (
+	versionKey          = "board_version"
+	versionUpdatedAtKey = "board_version_updated_at"
+	// 저장소가 비워지면 버전이 0부터 다시 시작하므로, 이전 버전과 구분하기 위해 저장소마다 만드는 값
+	versionEpochKey = "board_version_epoch"
+)
+
+// bumpVersion은 순위표의 버전을 늘리는 변경을 w에 더한다. 조회 결과가 바뀌는 변경과 같은 write로 저장한다.
+// 조회하는 쪽은 버전을 먼저 읽으므로, 새 버전으로 이전 데이터를 캐시하는 일은 없다.
+func (lb *LeaderBoard) bumpVersion(w *storage.Write) {
+	updatedAt := lb.now().UTC().Format(time.RFC3339Nano)
+
+	w.Incrs = append(w.Incrs, storage.Incr{Key: versionKey, By: 1})
+	w.Values = append(w.Values, storage.Value{Key: versionUpdatedAtKey, Data: []byte(updatedAt)})
+}
+
+func (lb *LeaderBoard) GetVersion(ctx context.Context) (api.BoardVersion, error) {
+	epoch, err := lb.versionEpoch(ctx)
+	if err != nil {
+		return api.BoardVersion{}, err
+	}
+	version := api.BoardVersion{Epoch: epoch}
+
+	data, err := lb.Storage.GetValue(ctx, versionKey)
+	if err != nil || data == nil {
+		return version, err
+	}
+
+	if version.Version, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+		return api.BoardVersion{}, err
+	}
+
+	data, err = lb.Storage.GetValue(ctx, versionUpdatedAtKey)
+	if err != nil || data == nil {
+		return version, err
+	}
+
+	if version.UpdatedAt, err = time.Parse(time.RFC3339Nano, string(data)); err != nil {
+		return api.BoardVersion{}, err
+	}
+
+	return version, nil
+}
+
+// versionEpoch는 저장소의 epoch를 반환한다. 없으면 새로 만든다.
+func (lb *LeaderBoard) versionEpoch(ctx context.Context) (string, error) {
+	data, err := lb.Storage.GetValue(ctx, versionEpochKey)
+	if err != nil || data != nil {
+		return string(data), err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	// 동시에 만들었으면 먼저 저장된 것을 사용한다
+	if _, err := lb.Storage.SetValueIfAbsent(ctx, versionEpochKey, []byte(hex.EncodeToString(id)), 0); err != nil {
+		return "", err
+	}
+
+	data, err = lb.Storage.GetValue(ctx, versionEpochKey)
+	return string(data), err
+}
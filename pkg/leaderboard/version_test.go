@@ -0,0 +1,99 @@
+package leaderboard_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Version(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testVersion(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testVersion(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testVersion(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage: s,
+		NowFunc: func() time.Time { return now },
+	}
+
+	version := func() api.BoardVersion {
+		v, err := lb.GetVersion(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		return v
+	}
+
+	// 한 번도 바뀌지 않았다
+	epoch := version().Epoch
+	g.Expect(epoch).NotTo(BeEmpty())
+	g.Expect(version()).To(Equal(api.BoardVersion{Epoch: epoch}))
+
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(version().Version).To(Equal(int64(1)))
+	g.Expect(version().UpdatedAt).To(BeTemporally("==", now))
+
+	// 점수가 같으면 바뀐 것이 없다
+	now = now.Add(time.Minute)
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(version().Version).To(Equal(int64(1)))
+	g.Expect(version().UpdatedAt).To(BeTemporally("==", now.Add(-time.Minute)))
+
+	g.Expect(lb.SetUser(ctx, "a", 20)).To(Succeed())
+	g.Expect(version().Version).To(Equal(int64(2)))
+	g.Expect(version().UpdatedAt).To(BeTemporally("==", now))
+
+	g.Expect(lb.SetUserState(ctx, "a", api.UserStateHidden)).To(Succeed())
+	g.Expect(version().Version).To(Equal(int64(3)))
+
+	// 조회는 버전을 바꾸지 않는다
+	_, err := lb.GetRanks(ctx, 1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(version().Version).To(Equal(int64(3)))
+
+	g.Expect(lb.Checkpoint(ctx)).To(Succeed())
+	g.Expect(version().Version).To(Equal(int64(4)))
+
+	snapshot, err := lb.CreateSnapshot(ctx, "s1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(version().Version).To(Equal(int64(4)))
+
+	g.Expect(lb.DeleteSnapshot(ctx, snapshot.Id)).To(Succeed())
+	g.Expect(version().Version).To(Equal(int64(5)))
+
+	_, err = lb.EraseUser(ctx, "a", api.Audit{Actor: "admin"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(version().Version).To(Equal(int64(6)))
+	g.Expect(version().Epoch).To(Equal(epoch))
+
+	// 다른 저장소는 epoch가 다르다
+	other := LeaderBoard{Storage: &storage.MemStorage{}}
+	otherVersion, err := other.GetVersion(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(otherVersion.Epoch).NotTo(BeEmpty())
+	g.Expect(otherVersion.Epoch).NotTo(Equal(epoch))
+}
@@ -0,0 +1,628 @@
+package leaderboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/signature"
+)
+
+type Webhook = api.Webhook
+type WebhookDelivery = api.WebhookDelivery
+
+const (
+	webhookListKey = "webhooks"
+
+	DefaultMaxWebhookDeliveries = 100
+	DefaultWebhookMaxAttempts   = 5
+	DefaultWebhookBackoff       = time.Second
+	DefaultWebhookTimeout       = 10 * time.Second
+	DefaultWebhookLeaseTTL      = 30 * time.Second
+)
+
+func webhookDeliveryListKey(webhookId string) string {
+	return "webhook_deliveries_" + webhookId
+}
+
+func webhookDeadLetterListKey(webhookId string) string {
+	return "webhook_deadletters_" + webhookId
+}
+
+// webhookCursorKey는 webhook으로 보냈거나 dead letter 목록으로 옮긴 마지막 이벤트 순번을 저장하는 값
+func webhookCursorKey(webhookId string) string {
+	return "webhook_cursor_" + webhookId
+}
+
+func (lb *LeaderBoard) webhookCursor(ctx context.Context, webhookId string) (int64, error) {
+	data, err := lb.Storage.GetValue(ctx, webhookCursorKey(webhookId))
+	if err != nil || data == nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(data), 10, 64)
+}
+
+func (lb *LeaderBoard) setWebhookCursor(ctx context.Context, webhookId string, seq int64) error {
+	return lb.Storage.SetValue(ctx, webhookCursorKey(webhookId), []byte(strconv.FormatInt(seq, 10)), 0)
+}
+
+func (lb *LeaderBoard) maxWebhookDeliveries() int {
+	if lb.MaxWebhookDeliveries > 0 {
+		return lb.MaxWebhookDeliveries
+	}
+	return DefaultMaxWebhookDeliveries
+}
+
+func checkWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return api.ErrorWithStatusCode(errors.New("url is empty or invalid format"), http.StatusBadRequest)
+	}
+	return nil
+}
+
+func (lb *LeaderBoard) AddWebhook(ctx context.Context, webhook Webhook) (Webhook, error) {
+	if lb.MaxEvents <= 0 {
+		return Webhook{}, api.ErrorWithStatusCode(errors.New("change feed is disabled"), http.StatusConflict)
+	}
+
+	if err := checkWebhookURL(webhook.URL); err != nil {
+		return Webhook{}, err
+	}
+
+	for _, eventType := range webhook.Events {
+		if !eventType.IsValid() {
+			return Webhook{}, api.ErrorWithStatusCode(fmt.Errorf("invalid event type: %s", eventType), http.StatusBadRequest)
+		}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Webhook{}, err
+	}
+
+	webhook.Id = hex.EncodeToString(id)
+	webhook.CreatedAt = lb.now()
+
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return Webhook{}, err
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	if err := lb.Storage.AppendList(ctx, webhookListKey, data, 0); err != nil {
+		return Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (lb *LeaderBoard) GetWebhooks(ctx context.Context) ([]Webhook, error) {
+	webhooks, _, err := lb.getWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// getWebhooks는 Secret을 포함한 webhook 목록과 저장된 원본 데이터를 반환한다
+func (lb *LeaderBoard) getWebhooks(ctx context.Context) ([]Webhook, [][]byte, error) {
+	rawList, err := lb.Storage.GetList(ctx, webhookListKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	webhooks := make([]Webhook, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &webhooks[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return webhooks, rawList, nil
+}
+
+func (lb *LeaderBoard) DeleteWebhook(ctx context.Context, webhookId string) error {
+	webhooks, rawList, err := lb.getWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, webhook := range webhooks {
+		if webhook.Id != webhookId {
+			continue
+		}
+
+		if err := lb.Storage.RemoveListItem(ctx, webhookListKey, rawList[i]); err != nil {
+			return err
+		}
+
+		if err := lb.Storage.DeleteValue(ctx, webhookCursorKey(webhookId)); err != nil {
+			return err
+		}
+
+		if err := lb.Storage.DeleteList(ctx, webhookDeliveryListKey(webhookId)); err != nil {
+			return err
+		}
+
+		return lb.Storage.DeleteList(ctx, webhookDeadLetterListKey(webhookId))
+	}
+
+	return api.ErrorWithStatusCode(errors.New("webhook not found"), http.StatusNotFound)
+}
+
+func (lb *LeaderBoard) GetWebhookDeliveries(ctx context.Context, webhookId string) ([]WebhookDelivery, error) {
+	return lb.getWebhookDeliveries(ctx, webhookId, webhookDeliveryListKey(webhookId))
+}
+
+func (lb *LeaderBoard) GetDeadLetters(ctx context.Context, webhookId string) ([]WebhookDelivery, error) {
+	return lb.getWebhookDeliveries(ctx, webhookId, webhookDeadLetterListKey(webhookId))
+}
+
+func (lb *LeaderBoard) getWebhookDeliveries(ctx context.Context, webhookId, key string) ([]WebhookDelivery, error) {
+	webhooks, _, err := lb.getWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, webhook := range webhooks {
+		if webhook.Id == webhookId {
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, api.ErrorWithStatusCode(errors.New("webhook not found"), http.StatusNotFound)
+	}
+
+	rawList, err := lb.Storage.GetList(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]WebhookDelivery, len(rawList))
+	for i, data := range rawList {
+		if err := json.Unmarshal(data, &deliveries[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return deliveries, nil
+}
+
+// recordWebhookDelivery는 전송 결과를 기록한다. 실패한 전송은 dead letter 목록에도 남긴다.
+func (lb *LeaderBoard) recordWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	if err := lb.Storage.AppendList(ctx, webhookDeliveryListKey(delivery.WebhookId), data, lb.maxWebhookDeliveries()); err != nil {
+		return err
+	}
+
+	if delivery.Status != api.DeliveryStatusFailed {
+		return nil
+	}
+
+	return lb.Storage.AppendList(ctx, webhookDeadLetterListKey(delivery.WebhookId), data, lb.maxWebhookDeliveries())
+}
+
+func webhookMatches(webhook Webhook, event Event) bool {
+	// 등록하기 전의 이벤트는 보내지 않는다
+	if event.CreatedAt.Before(webhook.CreatedAt) {
+		return false
+	}
+
+	if len(webhook.Events) == 0 {
+		return true
+	}
+
+	for _, eventType := range webhook.Events {
+		if eventType == event.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookDispatcher는 변경 이벤트를 읽어서 등록된 webhook들로 보낸다.
+// webhook마다 따로 goroutine과 cursor를 두므로 느린 webhook이 다른 webhook이나 SetUser를 막지 않는다.
+// cursor는 이벤트를 보냈거나 dead letter 목록으로 옮긴 다음에만 저장하므로,
+// 다시 시작하면 끝내지 못한 이벤트부터 다시 보낸다 (at-least-once).
+type WebhookDispatcher struct {
+	LeaderBoard *LeaderBoard
+
+	// nil이면 DefaultWebhookTimeout이 설정되고 내부 주소로는 연결하지 않는 client를 사용한다
+	Client *http.Client
+	// 이벤트 하나를 보내는 최대 시도 횟수 (0이면 DefaultWebhookMaxAttempts)
+	MaxAttempts int
+	// 첫 재시도까지 기다리는 시간. 재시도할 때마다 두 배로 늘어난다. (0이면 DefaultWebhookBackoff)
+	Backoff time.Duration
+
+	// 설정하면 lease를 잡은 서버 하나만 보낸다. lease를 잃으면 보내기를 멈추고 다시 잡을 때까지 기다린다.
+	Lease *Lease
+	// lease를 잡아두는 시간. 그 1/3마다 연장한다. (0이면 DefaultWebhookLeaseTTL)
+	LeaseTTL time.Duration
+
+	// nil이면 실제 시계를 사용한다
+	Clock  clock.Clock
+	Logger *log.Logger
+}
+
+func (d *WebhookDispatcher) clock() clock.Clock {
+	if d.Clock != nil {
+		return d.Clock
+	}
+	return clock.New()
+}
+
+// deniedWebhookNetworks는 webhook이 연결할 수 없는 loopback, private, link-local 주소 대역.
+// 169.254.169.254 같은 cloud metadata 주소도 여기에 들어간다.
+var deniedWebhookNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// denyInternalAddress는 이름을 해석한 다음 실제로 연결할 주소가 내부 주소이면 연결하지 않는다
+func denyInternalAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsMulticast() {
+		return fmt.Errorf("webhook address is not allowed: %s", host)
+	}
+
+	for _, network := range deniedWebhookNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("webhook address is not allowed: %s", host)
+		}
+	}
+
+	return nil
+}
+
+var defaultWebhookClient = &http.Client{
+	Timeout: DefaultWebhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: DefaultWebhookTimeout,
+			Control: denyInternalAddress,
+		}).DialContext,
+		TLSHandshakeTimeout: DefaultWebhookTimeout,
+	},
+	// redirect를 따라가면 내부 주소로 보낼 수 있으므로 3xx 응답을 그대로 실패로 처리한다
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func (d *WebhookDispatcher) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return defaultWebhookClient
+}
+
+func (d *WebhookDispatcher) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return DefaultWebhookMaxAttempts
+}
+
+func (d *WebhookDispatcher) backoff() time.Duration {
+	if d.Backoff > 0 {
+		return d.Backoff
+	}
+	return DefaultWebhookBackoff
+}
+
+func (d *WebhookDispatcher) leaseTTL() time.Duration {
+	if d.LeaseTTL > 0 {
+		return d.LeaseTTL
+	}
+	return DefaultWebhookLeaseTTL
+}
+
+func (d *WebhookDispatcher) logf(format string, v ...interface{}) {
+	if d.Logger != nil {
+		d.Logger.Printf(format, v...)
+	}
+}
+
+// wait는 ctx가 끝나면 false를 반환한다
+func (d *WebhookDispatcher) wait(ctx context.Context, duration time.Duration) bool {
+	timer := d.clock().Timer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Run은 ctx가 끝날 때까지 이벤트를 보낸다
+func (d *WebhookDispatcher) Run(ctx context.Context) error {
+	if d.Lease == nil {
+		return d.dispatch(ctx)
+	}
+
+	for {
+		acquired, err := d.Lease.TryAcquire(ctx, d.leaseTTL())
+		if err != nil {
+			d.logf("webhook dispatcher: lease: %v\n", err)
+		}
+
+		if acquired {
+			d.dispatchWhileLeased(ctx)
+		}
+
+		if !d.wait(ctx, d.leaseTTL()/3) {
+			return ctx.Err()
+		}
+	}
+}
+
+// dispatchWhileLeased는 lease를 연장하는 동안 이벤트를 보내고, lease를 잃으면 보내기를 멈춘다
+func (d *WebhookDispatcher) dispatchWhileLeased(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		defer cancel()
+
+		for d.wait(ctx, d.leaseTTL()/3) {
+			acquired, err := d.Lease.TryAcquire(ctx, d.leaseTTL())
+			if err != nil || !acquired {
+				d.logf("webhook dispatcher: lease is lost: %v\n", err)
+				return
+			}
+		}
+	}()
+
+	d.dispatch(ctx)
+}
+
+// dispatch는 등록된 webhook마다 이벤트를 보내는 goroutine을 실행하고, ctx가 끝나면 모두 멈출 때까지 기다린다
+func (d *WebhookDispatcher) dispatch(ctx context.Context) error {
+	lb := d.LeaderBoard
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	workers := map[string]context.CancelFunc{}
+	defer func() {
+		for _, cancel := range workers {
+			cancel()
+		}
+	}()
+
+	for {
+		webhooks, _, err := lb.getWebhooks(ctx)
+		if err != nil {
+			d.logf("webhook dispatcher: %v\n", err)
+		}
+
+		if err == nil {
+			registered := map[string]bool{}
+			for _, webhook := range webhooks {
+				registered[webhook.Id] = true
+				if workers[webhook.Id] != nil {
+					continue
+				}
+
+				workerCtx, cancel := context.WithCancel(ctx)
+				workers[webhook.Id] = cancel
+
+				wg.Add(1)
+				go func(webhook Webhook) {
+					defer wg.Done()
+					d.work(workerCtx, webhook)
+				}(webhook)
+			}
+
+			// 삭제된 webhook으로는 더 보내지 않는다
+			for id, cancel := range workers {
+				if !registered[id] {
+					cancel()
+					delete(workers, id)
+				}
+			}
+		}
+
+		if !d.wait(ctx, lb.eventPollInterval()) {
+			return ctx.Err()
+		}
+	}
+}
+
+// work는 webhook의 cursor 다음 이벤트부터 차례로 보낸다
+func (d *WebhookDispatcher) work(ctx context.Context, webhook Webhook) {
+	lb := d.LeaderBoard
+
+	cursor, err := lb.webhookCursor(ctx, webhook.Id)
+	for err != nil {
+		d.logf("webhook %v: %v\n", webhook.Id, err)
+		if !d.wait(ctx, lb.eventPollInterval()) {
+			return
+		}
+		cursor, err = lb.webhookCursor(ctx, webhook.Id)
+	}
+
+	for {
+		events, err := lb.GetEvents(ctx, cursor, eventBatchSize)
+		if err != nil {
+			d.logf("webhook %v: %v\n", webhook.Id, err)
+		}
+
+		saved := true
+		for _, event := range events {
+			if webhookMatches(webhook, event) {
+				delivery, ok := d.deliver(ctx, webhook, event)
+				if !ok {
+					return
+				}
+				d.record(ctx, delivery)
+				saved = false
+			}
+
+			cursor, saved = event.Seq, false
+		}
+
+		if !saved {
+			if err := lb.setWebhookCursor(ctx, webhook.Id, cursor); err != nil {
+				d.logf("webhook %v: %v\n", webhook.Id, err)
+			}
+		}
+
+		// 아직 읽지 않은 이벤트가 남아있을 수 있다
+		if len(events) == eventBatchSize {
+			continue
+		}
+
+		if !d.wait(ctx, lb.eventPollInterval()) {
+			return
+		}
+	}
+}
+
+// deliver는 성공하거나 MaxAttempts번 실패할 때까지 지수적으로 늘어나는 간격으로 다시 보낸다.
+// 끝내기 전에 ctx가 끝나면 false를 반환한다.
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook Webhook, event Event) (WebhookDelivery, bool) {
+	delivery := WebhookDelivery{
+		WebhookId: webhook.Id,
+		Event:     event,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Status = api.DeliveryStatusFailed
+		delivery.Error = err.Error()
+		delivery.CompletedAt = d.clock().Now()
+		return delivery, true
+	}
+
+	backoff := d.backoff()
+
+	for {
+		delivery.Attempts++
+
+		delivery.StatusCode, err = d.post(ctx, webhook, body)
+		if ctx.Err() != nil {
+			return WebhookDelivery{}, false
+		}
+
+		if err == nil {
+			delivery.Status = api.DeliveryStatusDelivered
+			delivery.Error = ""
+			break
+		}
+
+		delivery.Error = err.Error()
+
+		if delivery.Attempts >= d.maxAttempts() {
+			delivery.Status = api.DeliveryStatusFailed
+			break
+		}
+
+		if !d.wait(ctx, backoff) {
+			return WebhookDelivery{}, false
+		}
+
+		backoff *= 2
+	}
+
+	delivery.CompletedAt = d.clock().Now()
+	return delivery, true
+}
+
+func (d *WebhookDispatcher) record(ctx context.Context, delivery WebhookDelivery) {
+	if delivery.Status == api.DeliveryStatusFailed {
+		d.logf("webhook %v: event %v is moved to dead letters after %v attempts: %v\n",
+			delivery.WebhookId, delivery.Event.Seq, delivery.Attempts, delivery.Error)
+	}
+
+	if err := d.LeaderBoard.recordWebhookDelivery(ctx, delivery); err != nil {
+		d.logf("webhook %v: %v\n", delivery.WebhookId, err)
+	}
+}
+
+// post는 서명한 payload를 보내고 응답 status code를 반환한다. 2xx가 아니면 오류.
+func (d *WebhookDispatcher) post(ctx context.Context, webhook Webhook, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := d.clock().Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signature.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(signature.WebhookSignatureHeader, signature.SignWebhook([]byte(webhook.Secret), timestamp, body))
+
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
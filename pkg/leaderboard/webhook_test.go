@@ -0,0 +1,380 @@
+package leaderboard_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/signature"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderBoard_Webhooks(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testWebhooks(t, &storage.MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testWebhooks(t, &storage.RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testWebhooks(t *testing.T, s Storage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	lb := LeaderBoard{
+		Storage: s,
+		NowFunc: func() time.Time { return now },
+	}
+
+	_, err := lb.AddWebhook(ctx, api.Webhook{URL: "http://partner.xx/hook"})
+	g.Expect(err).To(MatchError("change feed is disabled"))
+
+	lb.MaxEvents = 100
+
+	_, err = lb.AddWebhook(ctx, api.Webhook{URL: "partner.xx/hook"})
+	g.Expect(err).To(MatchError("url is empty or invalid format"))
+
+	_, err = lb.AddWebhook(ctx, api.Webhook{URL: "http://partner.xx/hook", Events: []api.EventType{"unknown"}})
+	g.Expect(err).To(MatchError("invalid event type: unknown"))
+
+	webhook, err := lb.AddWebhook(ctx, api.Webhook{URL: "http://partner.xx/hook", Events: []api.EventType{api.EventEnteredTop}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(webhook.Id).NotTo(BeEmpty())
+	g.Expect(webhook.Secret).NotTo(BeEmpty())
+	g.Expect(webhook.CreatedAt).To(Equal(now))
+
+	webhooks, err := lb.GetWebhooks(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(webhooks).To(HaveLen(1))
+	g.Expect(webhooks[0].Id).To(Equal(webhook.Id))
+	g.Expect(webhooks[0].Secret).To(BeEmpty())
+
+	deliveries, err := lb.GetWebhookDeliveries(ctx, webhook.Id)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deliveries).To(BeEmpty())
+
+	_, err = lb.GetDeadLetters(ctx, "unknown")
+	g.Expect(err).To(MatchError("webhook not found"))
+
+	g.Expect(lb.DeleteWebhook(ctx, "unknown")).To(MatchError("webhook not found"))
+	g.Expect(lb.DeleteWebhook(ctx, webhook.Id)).To(Succeed())
+
+	webhooks, err = lb.GetWebhooks(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(webhooks).To(BeEmpty())
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		MaxEvents:         100,
+		EventTopN:         10,
+		EventPollInterval: time.Millisecond,
+	}
+
+	received := make(chan api.Event, 10)
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(signature.WebhookTimestampHeader), 10, 64)
+		if r.Header.Get(signature.WebhookSignatureHeader) != signature.SignWebhook([]byte(secret), timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		event := api.Event{}
+		json.Unmarshal(body, &event)
+		received <- event
+	}))
+	defer receiver.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	// 느린 receiver는 다른 webhook이나 SetUser를 막지 않는다
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer slow.Close()
+	defer close(unblock)
+
+	webhook, err := lb.AddWebhook(ctx, api.Webhook{URL: receiver.URL, Events: []api.EventType{api.EventEnteredTop, api.EventOvertaken}})
+	g.Expect(err).NotTo(HaveOccurred())
+	secret = webhook.Secret
+
+	failingWebhook, err := lb.AddWebhook(ctx, api.Webhook{URL: failing.URL, Events: []api.EventType{api.EventUserAdded}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = lb.AddWebhook(ctx, api.Webhook{URL: slow.URL})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// 테스트 서버는 loopback 주소이므로 기본 client 대신 제한 없는 client를 사용한다
+	dispatcher := &WebhookDispatcher{
+		LeaderBoard: lb,
+		Client:      &http.Client{},
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- dispatcher.Run(ctx)
+	}()
+
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	var event api.Event
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.Type).To(Equal(api.EventEnteredTop))
+	g.Expect(event.UserId).To(Equal("a"))
+
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.Type).To(Equal(api.EventEnteredTop))
+	g.Expect(event.UserId).To(Equal("b"))
+
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.Type).To(Equal(api.EventOvertaken))
+	g.Expect(event.UserId).To(Equal("a"))
+	g.Expect(event.OvertakenBy).To(Equal("b"))
+
+	g.Eventually(func() ([]api.WebhookDelivery, error) {
+		return lb.GetWebhookDeliveries(ctx, webhook.Id)
+	}).Should(HaveLen(3))
+
+	deadLetters := []api.WebhookDelivery{}
+	g.Eventually(func() ([]api.WebhookDelivery, error) {
+		var err error
+		deadLetters, err = lb.GetDeadLetters(ctx, failingWebhook.Id)
+		return deadLetters, err
+	}).Should(HaveLen(2))
+	g.Expect(deadLetters[0].Status).To(Equal(api.DeliveryStatusFailed))
+	g.Expect(deadLetters[0].Attempts).To(Equal(3))
+	g.Expect(deadLetters[0].StatusCode).To(Equal(http.StatusInternalServerError))
+	g.Expect(deadLetters[0].Event.UserId).To(Equal("a"))
+
+	cancel()
+	g.Eventually(done).Should(Receive(Equal(context.Canceled)))
+}
+
+func TestWebhookDispatcher_Resume(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	lb := &LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		MaxEvents:         100,
+		EventTopN:         10,
+		EventPollInterval: time.Millisecond,
+	}
+
+	// 첫 요청은 dispatcher가 멈출 때까지 응답하지 않는다
+	received := make(chan api.Event, 10)
+	var requests int32
+	unblock := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			<-unblock
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		event := api.Event{}
+		json.Unmarshal(body, &event)
+		received <- event
+	}))
+	defer receiver.Close()
+	defer close(unblock)
+
+	_, err := lb.AddWebhook(ctx, api.Webhook{URL: receiver.URL, Events: []api.EventType{api.EventUserAdded}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	run := func() (context.CancelFunc, chan error) {
+		ctx, cancel := context.WithCancel(ctx)
+		dispatcher := &WebhookDispatcher{
+			LeaderBoard: lb,
+			Client:      &http.Client{},
+			Backoff:     time.Millisecond,
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- dispatcher.Run(ctx)
+		}()
+		return cancel, done
+	}
+
+	cancel, done := run()
+
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Eventually(func() int32 { return atomic.LoadInt32(&requests) }).Should(Equal(int32(1)))
+
+	cancel()
+	g.Eventually(done).Should(Receive())
+
+	// 멈춘 동안의 이벤트와 보내는 중이던 이벤트를 다시 시작한 다음 보낸다
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	cancel, done = run()
+
+	var event api.Event
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.UserId).To(Equal("a"))
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.UserId).To(Equal("b"))
+
+	cancel()
+	g.Eventually(done).Should(Receive())
+
+	// 보낸 이벤트는 다시 보내지 않는다
+	g.Expect(lb.SetUser(ctx, "c", 30)).To(Succeed())
+
+	cancel, done = run()
+	defer cancel()
+
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.UserId).To(Equal("c"))
+	g.Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+}
+
+func TestWebhookDispatcher_Lease(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	s := &storage.MemStorage{}
+	lb := &LeaderBoard{
+		Storage:           s,
+		MaxEvents:         100,
+		EventTopN:         10,
+		EventPollInterval: time.Millisecond,
+	}
+
+	received := make(chan api.Event, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		event := api.Event{}
+		json.Unmarshal(body, &event)
+		received <- event
+	}))
+	defer receiver.Close()
+
+	_, err := lb.AddWebhook(ctx, api.Webhook{URL: receiver.URL, Events: []api.EventType{api.EventUserAdded}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// 서버마다 dispatcher를 실행해도 lease를 잡은 하나만 보낸다
+	leases := map[*Lease]context.CancelFunc{}
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		lease := &Lease{Storage: s, Name: "webhooks", Owner: strconv.Itoa(i)}
+		leases[lease] = cancel
+
+		dispatcher := &WebhookDispatcher{
+			LeaderBoard: lb,
+			Client:      &http.Client{},
+			Lease:       lease,
+			LeaseTTL:    30 * time.Millisecond,
+		}
+		go dispatcher.Run(ctx)
+	}
+
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	var event api.Event
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.UserId).To(Equal("a"))
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.UserId).To(Equal("b"))
+	g.Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+
+	// lease를 잡은 서버가 멈추면 다른 서버가 이어서 보낸다
+	owner, err := s.GetValue(ctx, "lease_webhooks")
+	g.Expect(err).NotTo(HaveOccurred())
+	for lease, cancel := range leases {
+		if lease.Owner == string(owner) {
+			cancel()
+		}
+	}
+
+	g.Expect(lb.SetUser(ctx, "c", 30)).To(Succeed())
+
+	g.Eventually(received).Should(Receive(&event))
+	g.Expect(event.UserId).To(Equal("c"))
+	g.Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+}
+
+func TestWebhookDispatcher_InternalAddress(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		MaxEvents:         100,
+		EventTopN:         10,
+		EventPollInterval: time.Millisecond,
+	}
+
+	var requests int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer receiver.Close()
+
+	webhook, err := lb.AddWebhook(ctx, api.Webhook{URL: receiver.URL, Events: []api.EventType{api.EventUserAdded}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// 기본 client는 loopback 주소로 연결하지 않는다
+	dispatcher := &WebhookDispatcher{
+		LeaderBoard: lb,
+		MaxAttempts: 1,
+	}
+	go dispatcher.Run(ctx)
+
+	g.Expect(lb.SetUser(ctx, "a", 10)).To(Succeed())
+
+	deadLetters := []api.WebhookDelivery{}
+	g.Eventually(func() ([]api.WebhookDelivery, error) {
+		var err error
+		deadLetters, err = lb.GetDeadLetters(ctx, webhook.Id)
+		return deadLetters, err
+	}).Should(HaveLen(1))
+	g.Expect(deadLetters[0].Error).To(ContainSubstring("not allowed"))
+	g.Expect(atomic.LoadInt32(&requests)).To(BeZero())
+}
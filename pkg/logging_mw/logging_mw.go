@@ -14,26 +14,148 @@ type LoggingMiddleware struct {
 
 var _ api.LeaderBoard = (*LoggingMiddleware)(nil)
 
-func (mw *LoggingMiddleware) UserCount(ctx context.Context) (int, error) {
-	count, err := mw.Receiver.UserCount(ctx)
-	mw.Logger.Printf("LeaderBoard.UserCount() -> %v, err=%v\n", count, err)
+func (mw *LoggingMiddleware) UserCount(ctx context.Context, opts ...api.Option) (int, error) {
+	count, err := mw.Receiver.UserCount(ctx, opts...)
+	mw.Logger.Printf("LeaderBoard.UserCount(opts=%+v) -> %v, err=%v\n", api.NewOptions(opts...), count, err)
 	return count, err
 }
 
-func (mw *LoggingMiddleware) GetUser(ctx context.Context, userId string) (api.User, error) {
-	user, err := mw.Receiver.GetUser(ctx, userId)
-	mw.Logger.Printf("LeaderBoard.GetUser(userId=%v) -> %+v, err=%v\n", userId, user, err)
+func (mw *LoggingMiddleware) GetUser(ctx context.Context, userId string, opts ...api.Option) (api.User, error) {
+	user, err := mw.Receiver.GetUser(ctx, userId, opts...)
+	mw.Logger.Printf("LeaderBoard.GetUser(userId=%v, opts=%+v) -> %+v, err=%v\n", userId, api.NewOptions(opts...), user, err)
 	return user, err
 }
 
-func (mw *LoggingMiddleware) SetUser(ctx context.Context, userId string, score int) error {
-	err := mw.Receiver.SetUser(ctx, userId, score)
-	mw.Logger.Printf("LeaderBoard.SetUser(userId=%v, score=%v) -> err=%v\n", userId, score, err)
+func (mw *LoggingMiddleware) SetUser(ctx context.Context, userId string, score int, opts ...api.Option) error {
+	err := mw.Receiver.SetUser(ctx, userId, score, opts...)
+	mw.Logger.Printf("LeaderBoard.SetUser(userId=%v, score=%v, opts=%+v) -> err=%v\n", userId, score, api.NewOptions(opts...), err)
 	return err
 }
 
-func (mw *LoggingMiddleware) GetRanks(ctx context.Context, rank, count int) ([]api.User, error) {
-	users, err := mw.Receiver.GetRanks(ctx, rank, count)
-	mw.Logger.Printf("LeaderBoard.GetRanks(rank=%v, count=%v) -> %+v, err=%v\n", rank, count, users, err)
+func (mw *LoggingMiddleware) GetRanks(ctx context.Context, rank, count int, opts ...api.Option) ([]api.User, error) {
+	users, err := mw.Receiver.GetRanks(ctx, rank, count, opts...)
+	mw.Logger.Printf("LeaderBoard.GetRanks(rank=%v, count=%v, opts=%+v) -> %+v, err=%v\n", rank, count, api.NewOptions(opts...), users, err)
 	return users, err
 }
+
+func (mw *LoggingMiddleware) CreateSnapshot(ctx context.Context, snapshotId string) (api.Snapshot, error) {
+	snapshot, err := mw.Receiver.CreateSnapshot(ctx, snapshotId)
+	mw.Logger.Printf("LeaderBoard.CreateSnapshot(snapshotId=%v) -> %+v, err=%v\n", snapshotId, snapshot, err)
+	return snapshot, err
+}
+
+func (mw *LoggingMiddleware) GetSnapshots(ctx context.Context) ([]api.Snapshot, error) {
+	snapshots, err := mw.Receiver.GetSnapshots(ctx)
+	mw.Logger.Printf("LeaderBoard.GetSnapshots() -> %+v, err=%v\n", snapshots, err)
+	return snapshots, err
+}
+
+func (mw *LoggingMiddleware) DeleteSnapshot(ctx context.Context, snapshotId string) error {
+	err := mw.Receiver.DeleteSnapshot(ctx, snapshotId)
+	mw.Logger.Printf("LeaderBoard.DeleteSnapshot(snapshotId=%v) -> err=%v\n", snapshotId, err)
+	return err
+}
+
+func (mw *LoggingMiddleware) SetUserState(ctx context.Context, userId string, state api.UserState) error {
+	err := mw.Receiver.SetUserState(ctx, userId, state)
+	mw.Logger.Printf("LeaderBoard.SetUserState(userId=%v, state=%v) -> err=%v\n", userId, state, err)
+	return err
+}
+
+func (mw *LoggingMiddleware) OverrideScore(ctx context.Context, userId string, score int, audit api.Audit) error {
+	err := mw.Receiver.OverrideScore(ctx, userId, score, audit)
+	mw.Logger.Printf("LeaderBoard.OverrideScore(userId=%v, score=%v, audit=%+v) -> err=%v\n", userId, score, audit, err)
+	return err
+}
+
+func (mw *LoggingMiddleware) RevertScore(ctx context.Context, userId string, audit api.Audit) error {
+	err := mw.Receiver.RevertScore(ctx, userId, audit)
+	mw.Logger.Printf("LeaderBoard.RevertScore(userId=%v, audit=%+v) -> err=%v\n", userId, audit, err)
+	return err
+}
+
+func (mw *LoggingMiddleware) GetHistory(ctx context.Context, userId string) ([]api.HistoryEntry, error) {
+	entries, err := mw.Receiver.GetHistory(ctx, userId)
+	mw.Logger.Printf("LeaderBoard.GetHistory(userId=%v) -> %+v, err=%v\n", userId, entries, err)
+	return entries, err
+}
+
+func (mw *LoggingMiddleware) GetRejections(ctx context.Context) ([]api.Rejection, error) {
+	rejections, err := mw.Receiver.GetRejections(ctx)
+	mw.Logger.Printf("LeaderBoard.GetRejections() -> %+v, err=%v\n", rejections, err)
+	return rejections, err
+}
+
+func (mw *LoggingMiddleware) AddReview(ctx context.Context, review api.Review) (api.Review, error) {
+	review, err := mw.Receiver.AddReview(ctx, review)
+	mw.Logger.Printf("LeaderBoard.AddReview() -> %+v, err=%v\n", review, err)
+	return review, err
+}
+
+func (mw *LoggingMiddleware) GetReviews(ctx context.Context) ([]api.Review, error) {
+	reviews, err := mw.Receiver.GetReviews(ctx)
+	mw.Logger.Printf("LeaderBoard.GetReviews() -> %+v, err=%v\n", reviews, err)
+	return reviews, err
+}
+
+func (mw *LoggingMiddleware) ResolveReview(ctx context.Context, reviewId string, approve bool, audit api.Audit) error {
+	err := mw.Receiver.ResolveReview(ctx, reviewId, approve, audit)
+	mw.Logger.Printf("LeaderBoard.ResolveReview(reviewId=%v, approve=%v, audit=%+v) -> err=%v\n", reviewId, approve, audit, err)
+	return err
+}
+
+func (mw *LoggingMiddleware) ExportUser(ctx context.Context, userId string) (api.UserExport, error) {
+	export, err := mw.Receiver.ExportUser(ctx, userId)
+	// 개인 데이터이므로 내용은 남기지 않는다
+	mw.Logger.Printf("LeaderBoard.ExportUser(userId=%v) -> err=%v\n", userId, err)
+	return export, err
+}
+
+func (mw *LoggingMiddleware) EraseUser(ctx context.Context, userId string, audit api.Audit) (api.ErasureReport, error) {
+	report, err := mw.Receiver.EraseUser(ctx, userId, audit)
+	mw.Logger.Printf("LeaderBoard.EraseUser(userId=%v, audit=%+v) -> %+v, err=%v\n", userId, audit, report, err)
+	return report, err
+}
+
+func (mw *LoggingMiddleware) ExpireInactive(ctx context.Context) (int, error) {
+	expired, err := mw.Receiver.ExpireInactive(ctx)
+	mw.Logger.Printf("LeaderBoard.ExpireInactive() -> %v, err=%v\n", expired, err)
+	return expired, err
+}
+
+func (mw *LoggingMiddleware) AddWebhook(ctx context.Context, webhook api.Webhook) (api.Webhook, error) {
+	added, err := mw.Receiver.AddWebhook(ctx, webhook)
+	// secret은 남기지 않는다
+	mw.Logger.Printf("LeaderBoard.AddWebhook(url=%v, events=%v) -> id=%v, err=%v\n", webhook.URL, webhook.Events, added.Id, err)
+	return added, err
+}
+
+func (mw *LoggingMiddleware) GetWebhooks(ctx context.Context) ([]api.Webhook, error) {
+	webhooks, err := mw.Receiver.GetWebhooks(ctx)
+	mw.Logger.Printf("LeaderBoard.GetWebhooks() -> %+v, err=%v\n", webhooks, err)
+	return webhooks, err
+}
+
+func (mw *LoggingMiddleware) DeleteWebhook(ctx context.Context, webhookId string) error {
+	err := mw.Receiver.DeleteWebhook(ctx, webhookId)
+	mw.Logger.Printf("LeaderBoard.DeleteWebhook(webhookId=%v) -> err=%v\n", webhookId, err)
+	return err
+}
+
+func (mw *LoggingMiddleware) GetWebhookDeliveries(ctx context.Context, webhookId string) ([]api.WebhookDelivery, error) {
+	deliveries, err := mw.Receiver.GetWebhookDeliveries(ctx, webhookId)
+	mw.Logger.Printf("LeaderBoard.GetWebhookDeliveries(webhookId=%v) -> %+v, err=%v\n", webhookId, deliveries, err)
+	return deliveries, err
+}
+
+func (mw *LoggingMiddleware) GetDeadLetters(ctx context.Context, webhookId string) ([]api.WebhookDelivery, error) {
+	deliveries, err := mw.Receiver.GetDeadLetters(ctx, webhookId)
+	mw.Logger.Printf("LeaderBoard.GetDeadLetters(webhookId=%v) -> %+v, err=%v\n", webhookId, deliveries, err)
+	return deliveries, err
+}
+
+func (mw *LoggingMiddleware) GetVersion(ctx context.Context) (api.BoardVersion, error) {
+	version, err := mw.Receiver.GetVersion(ctx)
+	mw.Logger.Printf("LeaderBoard.GetVersion() -> %+v, err=%v\n", version, err)
+	return version, err
+}
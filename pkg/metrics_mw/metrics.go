@@ -0,0 +1,118 @@
+package metrics_mw
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultCollectTimeout은 BoardCollector가 사용자 수 등을 조회하는 시간 제한
+const DefaultCollectTimeout = 5 * time.Second
+
+// Metrics는 LeaderBoard와 Storage 호출의 prometheus 지표들.
+// label에는 메소드 이름과 결과만 넣고 사용자 id 같은 값은 넣지 않는다.
+type Metrics struct {
+	calls           *prometheus.CounterVec
+	callDuration    *prometheus.HistogramVec
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+}
+
+// NewMetrics는 지표들을 만들어서 registerer에 등록한다
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "leaderboard_calls_total",
+			Help: "Number of LeaderBoard method calls by method and result (ok, error or HTTP status code).",
+		}, []string{"method", "result"}),
+		callDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "leaderboard_call_duration_seconds",
+			Help:    "Latency of LeaderBoard method calls.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "leaderboard_storage_duration_seconds",
+			Help:    "Latency of storage operations.",
+			Buckets: []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "leaderboard_storage_errors_total",
+			Help: "Number of failed storage operations.",
+		}, []string{"operation"}),
+	}
+
+	registerer.MustRegister(m.calls, m.callDuration, m.storageDuration, m.storageErrors)
+	return m
+}
+
+func (m *Metrics) observeCall(method string, start time.Time, err error) {
+	m.callDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	m.calls.WithLabelValues(method, result(err)).Inc()
+}
+
+func (m *Metrics) observeStorage(operation string, start time.Time, err error) {
+	m.storageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.storageErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// result는 err을 label 값으로 바꾼다. status code가 있는 오류는 status code로 구분한다.
+func result(err error) string {
+	if err == nil {
+		return "ok"
+	}
+
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		return strconv.Itoa(statusErr.StatusCode())
+	}
+	return "error"
+}
+
+var (
+	usersDesc = prometheus.NewDesc("leaderboard_users",
+		"Number of users in the public ranking.", nil, nil)
+	boardsDesc = prometheus.NewDesc("leaderboard_boards",
+		"Number of rank boards: the current board and its snapshots.", nil, nil)
+)
+
+// BoardCollector는 수집할 때마다 사용자 수와 순위표 수를 조회하는 prometheus.Collector
+type BoardCollector struct {
+	LeaderBoard api.LeaderBoard
+	// 0이면 DefaultCollectTimeout
+	Timeout time.Duration
+}
+
+var _ prometheus.Collector = (*BoardCollector)(nil)
+
+func (collector *BoardCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- usersDesc
+	ch <- boardsDesc
+}
+
+func (collector *BoardCollector) Collect(ch chan<- prometheus.Metric) {
+	timeout := collector.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCollectTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if count, err := collector.LeaderBoard.UserCount(ctx); err != nil {
+		ch <- prometheus.NewInvalidMetric(usersDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(usersDesc, prometheus.GaugeValue, float64(count))
+	}
+
+	if snapshots, err := collector.LeaderBoard.GetSnapshots(ctx); err != nil {
+		ch <- prometheus.NewInvalidMetric(boardsDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(boardsDesc, prometheus.GaugeValue, float64(1+len(snapshots)))
+	}
+}
@@ -0,0 +1,184 @@
+package metrics_mw
+
+import (
+	"context"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+// MetricsMiddleware는 api.LeaderBoard 메소드별 호출 수와 처리 시간을 Metrics에 기록한다
+type MetricsMiddleware struct {
+	Metrics  *Metrics
+	Receiver api.LeaderBoard
+}
+
+var _ api.LeaderBoard = (*MetricsMiddleware)(nil)
+
+func (mw *MetricsMiddleware) UserCount(ctx context.Context, opts ...api.Option) (int, error) {
+	start := time.Now()
+	count, err := mw.Receiver.UserCount(ctx, opts...)
+	mw.Metrics.observeCall("UserCount", start, err)
+	return count, err
+}
+
+func (mw *MetricsMiddleware) GetUser(ctx context.Context, userId string, opts ...api.Option) (api.User, error) {
+	start := time.Now()
+	user, err := mw.Receiver.GetUser(ctx, userId, opts...)
+	mw.Metrics.observeCall("GetUser", start, err)
+	return user, err
+}
+
+func (mw *MetricsMiddleware) SetUser(ctx context.Context, userId string, score int, opts ...api.Option) error {
+	start := time.Now()
+	err := mw.Receiver.SetUser(ctx, userId, score, opts...)
+	mw.Metrics.observeCall("SetUser", start, err)
+	return err
+}
+
+func (mw *MetricsMiddleware) GetRanks(ctx context.Context, rank, count int, opts ...api.Option) ([]api.User, error) {
+	start := time.Now()
+	users, err := mw.Receiver.GetRanks(ctx, rank, count, opts...)
+	mw.Metrics.observeCall("GetRanks", start, err)
+	return users, err
+}
+
+func (mw *MetricsMiddleware) CreateSnapshot(ctx context.Context, snapshotId string) (api.Snapshot, error) {
+	start := time.Now()
+	snapshot, err := mw.Receiver.CreateSnapshot(ctx, snapshotId)
+	mw.Metrics.observeCall("CreateSnapshot", start, err)
+	return snapshot, err
+}
+
+func (mw *MetricsMiddleware) GetSnapshots(ctx context.Context) ([]api.Snapshot, error) {
+	start := time.Now()
+	snapshots, err := mw.Receiver.GetSnapshots(ctx)
+	mw.Metrics.observeCall("GetSnapshots", start, err)
+	return snapshots, err
+}
+
+func (mw *MetricsMiddleware) DeleteSnapshot(ctx context.Context, snapshotId string) error {
+	start := time.Now()
+	err := mw.Receiver.DeleteSnapshot(ctx, snapshotId)
+	mw.Metrics.observeCall("DeleteSnapshot", start, err)
+	return err
+}
+
+func (mw *MetricsMiddleware) SetUserState(ctx context.Context, userId string, state api.UserState) error {
+	start := time.Now()
+	err := mw.Receiver.SetUserState(ctx, userId, state)
+	mw.Metrics.observeCall("SetUserState", start, err)
+	return err
+}
+
+func (mw *MetricsMiddleware) OverrideScore(ctx context.Context, userId string, score int, audit api.Audit) error {
+	start := time.Now()
+	err := mw.Receiver.OverrideScore(ctx, userId, score, audit)
+	mw.Metrics.observeCall("OverrideScore", start, err)
+	return err
+}
+
+func (mw *MetricsMiddleware) RevertScore(ctx context.Context, userId string, audit api.Audit) error {
+	start := time.Now()
+	err := mw.Receiver.RevertScore(ctx, userId, audit)
+	mw.Metrics.observeCall("RevertScore", start, err)
+	return err
+}
+
+func (mw *MetricsMiddleware) GetHistory(ctx context.Context, userId string) ([]api.HistoryEntry, error) {
+	start := time.Now()
+	entries, err := mw.Receiver.GetHistory(ctx, userId)
+	mw.Metrics.observeCall("GetHistory", start, err)
+	return entries, err
+}
+
+func (mw *MetricsMiddleware) GetRejections(ctx context.Context) ([]api.Rejection, error) {
+	start := time.Now()
+	rejections, err := mw.Receiver.GetRejections(ctx)
+	mw.Metrics.observeCall("GetRejections", start, err)
+	return rejections, err
+}
+
+func (mw *MetricsMiddleware) AddReview(ctx context.Context, review api.Review) (api.Review, error) {
+	start := time.Now()
+	review, err := mw.Receiver.AddReview(ctx, review)
+	mw.Metrics.observeCall("AddReview", start, err)
+	return review, err
+}
+
+func (mw *MetricsMiddleware) GetReviews(ctx context.Context) ([]api.Review, error) {
+	start := time.Now()
+	reviews, err := mw.Receiver.GetReviews(ctx)
+	mw.Metrics.observeCall("GetReviews", start, err)
+	return reviews, err
+}
+
+func (mw *MetricsMiddleware) ResolveReview(ctx context.Context, reviewId string, approve bool, audit api.Audit) error {
+	start := time.Now()
+	err := mw.Receiver.ResolveReview(ctx, reviewId, approve, audit)
+	mw.Metrics.observeCall("ResolveReview", start, err)
+	return err
+}
+
+func (mw *MetricsMiddleware) ExportUser(ctx context.Context, userId string) (api.UserExport, error) {
+	start := time.Now()
+	export, err := mw.Receiver.ExportUser(ctx, userId)
+	mw.Metrics.observeCall("ExportUser", start, err)
+	return export, err
+}
+
+func (mw *MetricsMiddleware) EraseUser(ctx context.Context, userId string, audit api.Audit) (api.ErasureReport, error) {
+	start := time.Now()
+	report, err := mw.Receiver.EraseUser(ctx, userId, audit)
+	mw.Metrics.observeCall("EraseUser", start, err)
+	return report, err
+}
+
+func (mw *MetricsMiddleware) ExpireInactive(ctx context.Context) (int, error) {
+	start := time.Now()
+	expired, err := mw.Receiver.ExpireInactive(ctx)
+	mw.Metrics.observeCall("ExpireInactive", start, err)
+	return expired, err
+}
+
+func (mw *MetricsMiddleware) AddWebhook(ctx context.Context, webhook api.Webhook) (api.Webhook, error) {
+	start := time.Now()
+	webhook, err := mw.Receiver.AddWebhook(ctx, webhook)
+	mw.Metrics.observeCall("AddWebhook", start, err)
+	return webhook, err
+}
+
+func (mw *MetricsMiddleware) GetWebhooks(ctx context.Context) ([]api.Webhook, error) {
+	start := time.Now()
+	webhooks, err := mw.Receiver.GetWebhooks(ctx)
+	mw.Metrics.observeCall("GetWebhooks", start, err)
+	return webhooks, err
+}
+
+func (mw *MetricsMiddleware) DeleteWebhook(ctx context.Context, webhookId string) error {
+	start := time.Now()
+	err := mw.Receiver.DeleteWebhook(ctx, webhookId)
+	mw.Metrics.observeCall("DeleteWebhook", start, err)
+	return err
+}
+
+func (mw *MetricsMiddleware) GetWebhookDeliveries(ctx context.Context, webhookId string) ([]api.WebhookDelivery, error) {
+	start := time.Now()
+	deliveries, err := mw.Receiver.GetWebhookDeliveries(ctx, webhookId)
+	mw.Metrics.observeCall("GetWebhookDeliveries", start, err)
+	return deliveries, err
+}
+
+func (mw *MetricsMiddleware) GetDeadLetters(ctx context.Context, webhookId string) ([]api.WebhookDelivery, error) {
+	start := time.Now()
+	deliveries, err := mw.Receiver.GetDeadLetters(ctx, webhookId)
+	mw.Metrics.observeCall("GetDeadLetters", start, err)
+	return deliveries, err
+}
+
+func (mw *MetricsMiddleware) GetVersion(ctx context.Context) (api.BoardVersion, error) {
+	start := time.Now()
+	version, err := mw.Receiver.GetVersion(ctx)
+	mw.Metrics.observeCall("GetVersion", start, err)
+	return version, err
+}
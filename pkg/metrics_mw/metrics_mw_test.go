@@ -0,0 +1,137 @@
+package metrics_mw_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/api/apifakes"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/metrics_mw"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+
+	fake := &apifakes.FakeLeaderBoard{}
+	fake.GetUserReturns(api.User{}, api.ErrorWithStatusCode(errors.New("user1 not found"), http.StatusNotFound))
+	fake.SetUserReturns(errors.New("connection refused"))
+
+	mw := &metrics_mw.MetricsMiddleware{
+		Metrics:  metrics_mw.NewMetrics(registry),
+		Receiver: fake,
+	}
+
+	_, err := mw.UserCount(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = mw.UserCount(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = mw.GetUser(ctx, "user1")
+	g.Expect(err).To(MatchError("user1 not found"))
+
+	g.Expect(mw.SetUser(ctx, "user1", 100)).To(MatchError("connection refused"))
+
+	g.Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP leaderboard_calls_total Number of LeaderBoard method calls by method and result (ok, error or HTTP status code).
+# TYPE leaderboard_calls_total counter
+leaderboard_calls_total{method="GetUser",result="404"} 1
+leaderboard_calls_total{method="SetUser",result="error"} 1
+leaderboard_calls_total{method="UserCount",result="ok"} 2
+`), "leaderboard_calls_total")).To(Succeed())
+
+	// 메소드마다 처리 시간 histogram이 하나씩 있다
+	g.Expect(testutil.GatherAndCount(registry, "leaderboard_call_duration_seconds")).To(Equal(3))
+
+	expectNoUserIds(g, registry, "user1")
+}
+
+func TestStorageMiddleware(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+
+	s := &metrics_mw.StorageMiddleware{
+		Metrics:  metrics_mw.NewMetrics(registry),
+		Receiver: &storage.MemStorage{},
+	}
+
+	_, err := s.WriteData(ctx, storage.Write{Key: "user1", Data: []byte("data1"), Score: 100, AddIndexes: []string{""}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	data, err := s.GetData(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(data).To(Equal([][]byte{[]byte("data1")}))
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Count(canceledCtx)
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	g.Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP leaderboard_storage_errors_total Number of failed storage operations.
+# TYPE leaderboard_storage_errors_total counter
+leaderboard_storage_errors_total{operation="Count"} 1
+`), "leaderboard_storage_errors_total")).To(Succeed())
+
+	g.Expect(testutil.GatherAndCount(registry, "leaderboard_storage_duration_seconds")).To(Equal(3))
+
+	expectNoUserIds(g, registry, "user1")
+}
+
+func TestBoardCollector(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	lb := &leaderboard.LeaderBoard{Storage: &storage.MemStorage{}}
+	g.Expect(lb.SetUser(ctx, "user1", 100)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "user2", 200)).To(Succeed())
+	_, err := lb.CreateSnapshot(ctx, "s1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&metrics_mw.BoardCollector{LeaderBoard: lb})
+
+	g.Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP leaderboard_boards Number of rank boards: the current board and its snapshots.
+# TYPE leaderboard_boards gauge
+leaderboard_boards 2
+# HELP leaderboard_users Number of users in the public ranking.
+# TYPE leaderboard_users gauge
+leaderboard_users 2
+`))).To(Succeed())
+
+	// 수집할 때마다 다시 조회한다
+	g.Expect(lb.SetUser(ctx, "user3", 300)).To(Succeed())
+	g.Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP leaderboard_users Number of users in the public ranking.
+# TYPE leaderboard_users gauge
+leaderboard_users 3
+`), "leaderboard_users")).To(Succeed())
+}
+
+// expectNoUserIds는 label 값에 사용자 id가 없는지 확인한다
+func expectNoUserIds(g *WithT, registry *prometheus.Registry, userIds ...string) {
+	families, err := registry.Gather()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				g.Expect(userIds).NotTo(ContainElement(label.GetValue()), family.GetName())
+			}
+		}
+	}
+}
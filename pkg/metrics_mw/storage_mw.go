@@ -0,0 +1,227 @@
+package metrics_mw
+
+import (
+	"context"
+	"time"
+
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+)
+
+// StorageMiddleware는 leaderboard.Storage 작업별 처리 시간과 오류 수를 Metrics에 기록한다
+type StorageMiddleware struct {
+	Metrics  *Metrics
+	Receiver leaderboard.Storage
+}
+
+var _ leaderboard.Storage = (*StorageMiddleware)(nil)
+
+func (mw *StorageMiddleware) Count(ctx context.Context) (int, error) {
+	start := time.Now()
+	result, err := mw.Receiver.Count(ctx)
+	mw.Metrics.observeStorage("Count", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) GetData(ctx context.Context, keys ...string) ([][]byte, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetData(ctx, keys...)
+	mw.Metrics.observeStorage("GetData", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) WriteData(ctx context.Context, w storage.Write) (bool, error) {
+	start := time.Now()
+	result, err := mw.Receiver.WriteData(ctx, w)
+	mw.Metrics.observeStorage("WriteData", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) ScanDataKeys(ctx context.Context, batchSize int, handler func(keys []string) error) error {
+	start := time.Now()
+	err := mw.Receiver.ScanDataKeys(ctx, batchSize, handler)
+	mw.Metrics.observeStorage("ScanDataKeys", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) GetRanks(ctx context.Context, keys ...string) ([]int, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetRanks(ctx, keys...)
+	mw.Metrics.observeStorage("GetRanks", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) GetSortedRange(ctx context.Context, rank, count int) ([]string, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetSortedRange(ctx, rank, count)
+	mw.Metrics.observeStorage("GetSortedRange", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) CopyIndex(ctx context.Context, name string) error {
+	start := time.Now()
+	err := mw.Receiver.CopyIndex(ctx, name)
+	mw.Metrics.observeStorage("CopyIndex", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) GetIndexRanks(ctx context.Context, name string, keys ...string) ([]int, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetIndexRanks(ctx, name, keys...)
+	mw.Metrics.observeStorage("GetIndexRanks", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) GetIndexScores(ctx context.Context, name string, keys ...string) ([]int, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetIndexScores(ctx, name, keys...)
+	mw.Metrics.observeStorage("GetIndexScores", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) IndexCount(ctx context.Context, name string) (int, error) {
+	start := time.Now()
+	result, err := mw.Receiver.IndexCount(ctx, name)
+	mw.Metrics.observeStorage("IndexCount", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) IndexCountAbove(ctx context.Context, name string, score int) (int, error) {
+	start := time.Now()
+	result, err := mw.Receiver.IndexCountAbove(ctx, name, score)
+	mw.Metrics.observeStorage("IndexCountAbove", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) GetIndexSortedRange(ctx context.Context, name string, rank, count int) ([]string, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetIndexSortedRange(ctx, name, rank, count)
+	mw.Metrics.observeStorage("GetIndexSortedRange", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) DeleteIndex(ctx context.Context, name string) error {
+	start := time.Now()
+	err := mw.Receiver.DeleteIndex(ctx, name)
+	mw.Metrics.observeStorage("DeleteIndex", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) IndexNames(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	result, err := mw.Receiver.IndexNames(ctx)
+	mw.Metrics.observeStorage("IndexNames", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) AppendList(ctx context.Context, key string, data []byte, maxLen int) error {
+	start := time.Now()
+	err := mw.Receiver.AppendList(ctx, key, data, maxLen)
+	mw.Metrics.observeStorage("AppendList", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) GetList(ctx context.Context, key string) ([][]byte, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetList(ctx, key)
+	mw.Metrics.observeStorage("GetList", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) RemoveListItem(ctx context.Context, key string, data []byte) error {
+	start := time.Now()
+	err := mw.Receiver.RemoveListItem(ctx, key, data)
+	mw.Metrics.observeStorage("RemoveListItem", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) DeleteList(ctx context.Context, key string) error {
+	start := time.Now()
+	err := mw.Receiver.DeleteList(ctx, key)
+	mw.Metrics.observeStorage("DeleteList", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) GetValue(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	result, err := mw.Receiver.GetValue(ctx, key)
+	mw.Metrics.observeStorage("GetValue", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) SetValue(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := mw.Receiver.SetValue(ctx, key, data, ttl)
+	mw.Metrics.observeStorage("SetValue", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) SetValueIfAbsent(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	result, err := mw.Receiver.SetValueIfAbsent(ctx, key, data, ttl)
+	mw.Metrics.observeStorage("SetValueIfAbsent", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) ExtendValue(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	result, err := mw.Receiver.ExtendValue(ctx, key, data, ttl)
+	mw.Metrics.observeStorage("ExtendValue", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) DeleteValue(ctx context.Context, key string) error {
+	start := time.Now()
+	err := mw.Receiver.DeleteValue(ctx, key)
+	mw.Metrics.observeStorage("DeleteValue", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) ValueKeys(ctx context.Context, prefix string) ([]string, error) {
+	start := time.Now()
+	result, err := mw.Receiver.ValueKeys(ctx, prefix)
+	mw.Metrics.observeStorage("ValueKeys", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) IncrValue(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	start := time.Now()
+	result, err := mw.Receiver.IncrValue(ctx, key, ttl)
+	mw.Metrics.observeStorage("IncrValue", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) AppendStream(ctx context.Context, key string, data []byte, maxLen int) (int64, error) {
+	start := time.Now()
+	result, err := mw.Receiver.AppendStream(ctx, key, data, maxLen)
+	mw.Metrics.observeStorage("AppendStream", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) ReadStream(ctx context.Context, key string, afterSeq int64, count int) (seqs []int64, data [][]byte, err error) {
+	start := time.Now()
+	seqs, data, err = mw.Receiver.ReadStream(ctx, key, afterSeq, count)
+	mw.Metrics.observeStorage("ReadStream", start, err)
+	return seqs, data, err
+}
+
+func (mw *StorageMiddleware) DeleteStreamItems(ctx context.Context, key string, seqs ...int64) error {
+	start := time.Now()
+	err := mw.Receiver.DeleteStreamItems(ctx, key, seqs...)
+	mw.Metrics.observeStorage("DeleteStreamItems", start, err)
+	return err
+}
+
+func (mw *StorageMiddleware) LastStreamSeq(ctx context.Context, key string) (int64, error) {
+	start := time.Now()
+	result, err := mw.Receiver.LastStreamSeq(ctx, key)
+	mw.Metrics.observeStorage("LastStreamSeq", start, err)
+	return result, err
+}
+
+func (mw *StorageMiddleware) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := mw.Receiver.Ping(ctx)
+	mw.Metrics.observeStorage("Ping", start, err)
+	return err
+}
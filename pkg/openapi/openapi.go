@@ -0,0 +1,662 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+// Document는 OpenAPI 3 문서 중 요청 검증과 문서화에 사용하는 부분
+type Document struct {
+	OpenAPI string `json:"openapi"`
+	Info    Info   `json:"info"`
+	// 모든 operation에 적용하는 인증 방식. operation의 Security가 있으면 그것을 사용한다.
+	Security   []SecurityRequirement            `json:"security,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// SecurityRequirement는 security scheme 이름별 scope 목록
+type SecurityRequirement map[string][]string
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationId string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// nil이면 Document의 Security를 사용하고, 비어있으면 인증 없이 사용할 수 있다
+	Security []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Schema struct {
+	Ref         string        `json:"$ref,omitempty"`
+	Type        string        `json:"type,omitempty"`
+	Format      string        `json:"format,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Nullable    bool          `json:"nullable,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Minimum     *float64      `json:"minimum,omitempty"`
+	Maximum     *float64      `json:"maximum,omitempty"`
+	Items       *Schema       `json:"items,omitempty"`
+	// $ref에 nullable 등을 더할 때 사용한다
+	AllOf      []*Schema          `json:"allOf,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// false이면 Properties에 없는 필드를 허용하지 않는다. 값 schema가 있으면 모든 필드가 그 schema를 따른다.
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties,omitempty"`
+}
+
+// AdditionalProperties는 boolean이나 schema인 additionalProperties
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *AdditionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+func (a AdditionalProperties) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// Load는 JSON으로 된 OpenAPI 문서를 읽고, 모든 $ref가 있는지 확인한다
+func Load(data []byte) (*Document, error) {
+	doc := &Document{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+
+	if err := doc.checkSecurity(doc.Security); err != nil {
+		return nil, err
+	}
+
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			if err := doc.checkOperation(operation); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+
+	for name, schema := range doc.Components.Schemas {
+		if err := doc.checkSchema(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// checkSecurity는 requirements의 security scheme들이 있는지 확인한다
+func (doc *Document) checkSecurity(requirements []SecurityRequirement) error {
+	for _, requirement := range requirements {
+		for name := range requirement {
+			if _, ok := doc.Components.SecuritySchemes[name]; !ok {
+				return fmt.Errorf("unknown security scheme: %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+func (doc *Document) checkOperation(operation *Operation) error {
+	if err := doc.checkSecurity(operation.Security); err != nil {
+		return err
+	}
+
+	for _, param := range operation.Parameters {
+		param, err := doc.parameter(param)
+		if err != nil {
+			return err
+		}
+		if err := doc.checkSchema(param.Schema); err != nil {
+			return err
+		}
+	}
+
+	if operation.RequestBody != nil {
+		for _, mediaType := range operation.RequestBody.Content {
+			if err := doc.checkSchema(mediaType.Schema); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, response := range operation.Responses {
+		response, err := doc.response(response)
+		if err != nil {
+			return err
+		}
+		for _, mediaType := range response.Content {
+			if err := doc.checkSchema(mediaType.Schema); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (doc *Document) checkSchema(schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+
+	if schema.Ref != "" {
+		_, err := doc.schema(schema)
+		return err
+	}
+
+	if err := doc.checkSchema(schema.Items); err != nil {
+		return err
+	}
+	for _, item := range schema.AllOf {
+		if err := doc.checkSchema(item); err != nil {
+			return err
+		}
+	}
+	for _, property := range schema.Properties {
+		if err := doc.checkSchema(property); err != nil {
+			return err
+		}
+	}
+	if schema.AdditionalProperties != nil {
+		return doc.checkSchema(schema.AdditionalProperties.Schema)
+	}
+	return nil
+}
+
+func refName(ref, prefix string) (string, error) {
+	if !strings.HasPrefix(ref, prefix) {
+		return "", errors.New("unsupported $ref: " + ref)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// schema는 $ref이면 참조하는 schema를 반환한다
+func (doc *Document) schema(schema *Schema) (*Schema, error) {
+	if schema.Ref == "" {
+		return schema, nil
+	}
+
+	name, err := refName(schema.Ref, "#/components/schemas/")
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := doc.Components.Schemas[name]
+	if !ok {
+		return nil, errors.New("unknown $ref: " + schema.Ref)
+	}
+	return target, nil
+}
+
+func (doc *Document) parameter(param *Parameter) (*Parameter, error) {
+	if param.Ref == "" {
+		return param, nil
+	}
+
+	name, err := refName(param.Ref, "#/components/parameters/")
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := doc.Components.Parameters[name]
+	if !ok {
+		return nil, errors.New("unknown $ref: " + param.Ref)
+	}
+	return target, nil
+}
+
+func (doc *Document) response(response *Response) (*Response, error) {
+	if response.Ref == "" {
+		return response, nil
+	}
+
+	name, err := refName(response.Ref, "#/components/responses/")
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := doc.Components.Responses[name]
+	if !ok {
+		return nil, errors.New("unknown $ref: " + response.Ref)
+	}
+	return target, nil
+}
+
+// Schema는 components의 이름이 name인 schema를 반환한다
+func (doc *Document) Schema(name string) (*Schema, bool) {
+	schema, ok := doc.Components.Schemas[name]
+	return schema, ok
+}
+
+// Operation은 path와 method에 해당하는 operation을 찾는다. path는 "/v1/users/{id}" 형식이다.
+func (doc *Document) Operation(method, path string) (*Operation, bool) {
+	operation, ok := doc.Paths[path][strings.ToLower(method)]
+	return operation, ok
+}
+
+// ResponseTypes는 operation이 성공했을 때 응답하는 media type들을 반환한다
+func (doc *Document) ResponseTypes(operation *Operation) []string {
+	var mediaTypes []string
+	for status, response := range operation.Responses {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		if response, err := doc.response(response); err == nil {
+			for mediaType := range response.Content {
+				mediaTypes = append(mediaTypes, mediaType)
+			}
+		}
+	}
+
+	sort.Strings(mediaTypes)
+	return mediaTypes
+}
+
+func badRequest(message string) error {
+	return api.ErrorWithStatusCode(errors.New(message), http.StatusBadRequest)
+}
+
+// ValidateRequest는 요청의 parameter와 body가 operation에 맞는지 확인한다.
+// pathParams는 path parameter 이름과 값이고, body는 이미 읽은 요청 body이다.
+func (doc *Document) ValidateRequest(operation *Operation, req *http.Request, pathParams map[string]string, body []byte) error {
+	query := req.URL.Query()
+	known := map[string]bool{}
+
+	for _, param := range operation.Parameters {
+		param, err := doc.parameter(param)
+		if err != nil {
+			return err
+		}
+
+		var values []string
+		switch param.In {
+		case "path":
+			values = []string{pathParams[param.Name]}
+		case "query":
+			known[param.Name] = true
+			values = query[param.Name]
+		case "header":
+			values = req.Header.Values(param.Name)
+		default:
+			continue
+		}
+
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			if param.Required {
+				return badRequest(param.Name + " is required")
+			}
+			continue
+		}
+
+		if err := doc.validateParameter(param, values); err != nil {
+			return err
+		}
+	}
+
+	for name := range query {
+		if !known[name] {
+			return badRequest("unknown query parameter: " + name)
+		}
+	}
+
+	return doc.validateBody(operation.RequestBody, req.Header.Get("Content-Type"), body)
+}
+
+func (doc *Document) validateParameter(param *Parameter, values []string) error {
+	schema, err := doc.schema(param.Schema)
+	if err != nil {
+		return err
+	}
+
+	if schema.Type != "array" && len(values) > 1 {
+		return badRequest(param.Name + " must not be repeated")
+	}
+
+	var value interface{}
+	if schema.Type == "array" {
+		items := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			item, err := parseParameter(schema.Items, v)
+			if err != nil {
+				return badRequest(param.Name + " is invalid format")
+			}
+			items = append(items, item)
+		}
+		value = items
+	} else if value, err = parseParameter(schema, values[0]); err != nil {
+		return badRequest(param.Name + " is invalid format")
+	}
+
+	if err := doc.Validate(schema, value); err != nil {
+		return badRequest(param.Name + " " + err.Error())
+	}
+	return nil
+}
+
+// parseParameter는 문자열인 parameter 값을 schema의 type으로 변환한다
+func parseParameter(schema *Schema, value string) (interface{}, error) {
+	if schema == nil {
+		return value, nil
+	}
+
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, err
+		}
+		return json.Number(value), nil
+	case "boolean":
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+func (doc *Document) validateBody(requestBody *RequestBody, contentType string, body []byte) error {
+	if len(body) == 0 {
+		if requestBody != nil && requestBody.Required {
+			return badRequest("request body is required")
+		}
+		return nil
+	}
+
+	if requestBody == nil {
+		return badRequest("request body is not allowed")
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || requestBody.Content[mediaType] == nil {
+		return api.ErrorWithStatusCode(errors.New("request body must be application/json"), http.StatusUnsupportedMediaType)
+	}
+
+	value, err := decodeJson(body)
+	if err != nil {
+		return badRequest("invalid request body: " + err.Error())
+	}
+
+	if err := doc.Validate(requestBody.Content[mediaType].Schema, value); err != nil {
+		return badRequest("invalid request body: " + err.Error())
+	}
+	return nil
+}
+
+func decodeJson(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// ValidateResponse는 응답 body가 operation의 status code에 해당하는 응답 schema에 맞는지 확인한다
+func (doc *Document) ValidateResponse(operation *Operation, statusCode int, contentType string, body []byte) error {
+	response, ok := operation.Responses[strconv.Itoa(statusCode)]
+	if !ok {
+		if response, ok = operation.Responses["default"]; !ok {
+			return fmt.Errorf("status code %d is not documented", statusCode)
+		}
+	}
+
+	response, err := doc.response(response)
+	if err != nil {
+		return err
+	}
+
+	// 304처럼 body가 없는 응답
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status code %d must not have a body", statusCode)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || response.Content[mediaType] == nil {
+		return fmt.Errorf("content type %q is not documented", contentType)
+	}
+
+	if mediaType != "application/json" {
+		return nil
+	}
+
+	value, err := decodeJson(body)
+	if err != nil {
+		return err
+	}
+	return doc.Validate(response.Content[mediaType].Schema, value)
+}
+
+// Validate는 JSON에서 읽은 value가 schema에 맞는지 확인한다. 숫자는 json.Number여야 한다.
+func (doc *Document) Validate(schema *Schema, value interface{}) error {
+	return doc.validate(schema, value, "")
+}
+
+func (doc *Document) validate(schema *Schema, value interface{}, field string) error {
+	if schema == nil {
+		return nil
+	}
+
+	schema, err := doc.schema(schema)
+	if err != nil {
+		return err
+	}
+
+	fail := func(format string, args ...interface{}) error {
+		message := fmt.Sprintf(format, args...)
+		if field == "" {
+			return errors.New(message)
+		}
+		return errors.New(field + ": " + message)
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fail("must not be null")
+	}
+
+	for _, item := range schema.AllOf {
+		if err := doc.validate(item, value, field); err != nil {
+			return err
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fail("must be one of %v", schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fail("must be an object")
+		}
+		return doc.validateObject(schema, object, field)
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fail("must be an array")
+		}
+		for i, item := range items {
+			if err := doc.validate(schema.Items, item, field+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fail("must be a date-time")
+			}
+		}
+
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fail("must be a number")
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return fail("must be a number")
+		}
+		if schema.Type == "integer" {
+			if _, err := n.Int64(); err != nil {
+				return fail("must be an integer")
+			}
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return fail("must be greater than or equal to %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			return fail("must be less than or equal to %v", *schema.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+	}
+
+	return nil
+}
+
+func (doc *Document) validateObject(schema *Schema, object map[string]interface{}, field string) error {
+	prefix := ""
+	if field != "" {
+		prefix = field + "."
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return errors.New(prefix + name + " is required")
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertySchema, ok := schema.Properties[name]
+		if !ok {
+			additional := schema.AdditionalProperties
+			if additional != nil && !additional.Allowed {
+				return errors.New(prefix + name + " is unknown field")
+			}
+			if additional == nil || additional.Schema == nil {
+				continue
+			}
+			propertySchema = additional.Schema
+		}
+
+		if err := doc.validate(propertySchema, object[name], prefix+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, item := range enum {
+		if item == value {
+			return true
+		}
+		if n, ok := value.(json.Number); ok {
+			if f, ok := item.(float64); ok && n.String() == strconv.FormatFloat(f, 'f', -1, 64) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/openapi"
+	. "github.com/onsi/gomega"
+)
+
+const testSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/items/{id}": {
+      "put": {
+        "operationId": "putItem",
+        "parameters": [
+          {"$ref": "#/components/parameters/ItemId"},
+          {"name": "dry_run", "in": "query", "schema": {"type": "boolean"}},
+          {"name": "tag", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}},
+          {"name": "X-Version", "in": "header", "required": true, "schema": {"type": "integer", "minimum": 1}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}
+        },
+        "responses": {
+          "200": {"description": "ok", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+          "304": {"description": "not modified"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Kind": {"type": "string", "enum": ["a", "b"]},
+      "Item": {
+        "type": "object",
+        "properties": {
+          "count": {"type": "integer", "minimum": 0, "maximum": 10},
+          "kind": {"$ref": "#/components/schemas/Kind"},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+          "parent": {"nullable": true, "allOf": [{"$ref": "#/components/schemas/Item"}]},
+          "created_at": {"type": "string", "format": "date-time"}
+        },
+        "required": ["count"],
+        "additionalProperties": false
+      }
+    },
+    "parameters": {
+      "ItemId": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+    },
+    "responses": {
+      "Error": {"description": "error", "content": {"application/json": {"schema": {"type": "object"}}}}
+    }
+  }
+}`
+
+func TestLoad(t *testing.T) {
+	g := NewWithT(t)
+
+	doc, err := Load([]byte(testSpec))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	operation, ok := doc.Operation(http.MethodPut, "/items/{id}")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(operation.OperationId).To(Equal("putItem"))
+	g.Expect(doc.ResponseTypes(operation)).To(Equal([]string{"application/json"}))
+
+	_, ok = doc.Operation(http.MethodGet, "/items/{id}")
+	g.Expect(ok).To(BeFalse())
+
+	_, err = Load([]byte(`{"paths": {"/a": {"get": {"responses": {"200": {"$ref": "#/components/responses/Unknown"}}}}}}`))
+	g.Expect(err).To(MatchError(ContainSubstring("unknown $ref")))
+
+	_, err = Load([]byte(`{"security": [{"ApiKey": []}], "paths": {}}`))
+	g.Expect(err).To(MatchError("unknown security scheme: ApiKey"))
+}
+
+func TestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	doc, err := Load([]byte(testSpec))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	item, _ := doc.Schema("Item")
+
+	testDataList := []struct {
+		value       string
+		expectedErr string
+	}{
+		{value: `{"count": 1}`},
+		{value: `{"count": 1, "kind": "a", "labels": {"x": "y"}, "parent": null, "created_at": "2021-05-01T00:00:00Z"}`},
+		{value: `{"count": 1, "parent": {"count": 2}}`},
+		{value: `{}`, expectedErr: "count is required"},
+		{value: `[]`, expectedErr: "must be an object"},
+		{value: `{"count": 1.5}`, expectedErr: "count: must be an integer"},
+		{value: `{"count": "1"}`, expectedErr: "count: must be a number"},
+		{value: `{"count": 11}`, expectedErr: "count: must be less than or equal to 10"},
+		{value: `{"count": -1}`, expectedErr: "count: must be greater than or equal to 0"},
+		{value: `{"count": null}`, expectedErr: "count: must not be null"},
+		{value: `{"count": 1, "kind": "c"}`, expectedErr: "kind: must be one of [a b]"},
+		{value: `{"count": 1, "labels": {"x": 1}}`, expectedErr: "labels.x: must be a string"},
+		{value: `{"count": 1, "parent": {}}`, expectedErr: "parent.count is required"},
+		{value: `{"count": 1, "created_at": "yesterday"}`, expectedErr: "created_at: must be a date-time"},
+		{value: `{"count": 1, "size": 3}`, expectedErr: "size is unknown field"},
+	}
+
+	for _, testData := range testDataList {
+		var value interface{}
+		g.Expect(unmarshal(testData.value, &value)).To(Succeed())
+
+		err := doc.Validate(item, value)
+		if testData.expectedErr == "" {
+			g.Expect(err).NotTo(HaveOccurred(), testData.value)
+		} else {
+			g.Expect(err).To(MatchError(testData.expectedErr), testData.value)
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	g := NewWithT(t)
+
+	doc, err := Load([]byte(testSpec))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	operation, _ := doc.Operation(http.MethodPut, "/items/{id}")
+
+	testDataList := []struct {
+		description        string
+		query              string
+		header             http.Header
+		body               string
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			description: "valid",
+			query:       "?dry_run=true&tag=x&tag=y",
+			header:      http.Header{"Content-Type": {"application/json"}, "X-Version": {"1"}},
+			body:        `{"count": 1}`,
+		},
+		{
+			description:        "missing header",
+			header:             http.Header{"Content-Type": {"application/json"}},
+			body:               `{"count": 1}`,
+			expectedErr:        "X-Version is required",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "invalid header",
+			header:             http.Header{"Content-Type": {"application/json"}, "X-Version": {"0"}},
+			body:               `{"count": 1}`,
+			expectedErr:        "X-Version must be greater than or equal to 1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "invalid query",
+			query:              "?dry_run=maybe",
+			header:             http.Header{"Content-Type": {"application/json"}, "X-Version": {"1"}},
+			body:               `{"count": 1}`,
+			expectedErr:        "dry_run is invalid format",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "repeated query",
+			query:              "?dry_run=true&dry_run=false",
+			header:             http.Header{"Content-Type": {"application/json"}, "X-Version": {"1"}},
+			body:               `{"count": 1}`,
+			expectedErr:        "dry_run must not be repeated",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "unknown query",
+			query:              "?count=1",
+			header:             http.Header{"Content-Type": {"application/json"}, "X-Version": {"1"}},
+			body:               `{"count": 1}`,
+			expectedErr:        "unknown query parameter: count",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "empty body",
+			header:             http.Header{"X-Version": {"1"}},
+			expectedErr:        "request body is required",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "not json",
+			header:             http.Header{"Content-Type": {"text/plain"}, "X-Version": {"1"}},
+			body:               `count=1`,
+			expectedErr:        "request body must be application/json",
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			description:        "invalid json",
+			header:             http.Header{"Content-Type": {"application/json"}, "X-Version": {"1"}},
+			body:               `{"count": 1} {}`,
+			expectedErr:        "invalid request body: unexpected data after JSON value",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			description:        "invalid body",
+			header:             http.Header{"Content-Type": {"application/json; charset=utf-8"}, "X-Version": {"1"}},
+			body:               `{"count": 1, "kind": "c"}`,
+			expectedErr:        "invalid request body: kind: must be one of [a b]",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testData := range testDataList {
+		req := httptest.NewRequest(http.MethodPut, "/items/item1"+testData.query, nil)
+		for k, v := range testData.header {
+			req.Header[k] = v
+		}
+
+		err := doc.ValidateRequest(operation, req, map[string]string{"id": "item1"}, []byte(testData.body))
+		if testData.expectedErr == "" {
+			g.Expect(err).NotTo(HaveOccurred(), testData.description)
+			continue
+		}
+
+		g.Expect(err).To(MatchError(testData.expectedErr), testData.description)
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(testData.expectedStatusCode), testData.description)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	g := NewWithT(t)
+
+	doc, err := Load([]byte(testSpec))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	operation, _ := doc.Operation(http.MethodPut, "/items/{id}")
+
+	g.Expect(doc.ValidateResponse(operation, http.StatusOK, "application/json", []byte(`{"count": 1}`))).To(Succeed())
+	g.Expect(doc.ValidateResponse(operation, http.StatusOK, "application/json", []byte(`{"count": 1, "size": 1}`))).
+		To(MatchError("size is unknown field"))
+	g.Expect(doc.ValidateResponse(operation, http.StatusOK, "text/html", []byte(`<html>`))).
+		To(MatchError(ContainSubstring("is not documented")))
+	g.Expect(doc.ValidateResponse(operation, http.StatusNotFound, "application/json", []byte(`{"message": "not found"}`))).
+		To(Succeed())
+
+	// content가 없는 응답은 body가 없어야 한다
+	g.Expect(doc.ValidateResponse(operation, http.StatusNotModified, "", nil)).To(Succeed())
+	g.Expect(doc.ValidateResponse(operation, http.StatusNotModified, "application/json", []byte(`{}`))).
+		To(MatchError("status code 304 must not have a body"))
+}
+
+func unmarshal(data string, v *interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
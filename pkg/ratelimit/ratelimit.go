@@ -0,0 +1,269 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Limit은 Period마다 Count개의 요청을 허용하는 token bucket.
+// bucket에는 최대 Count개의 token이 있고, Period/Count마다 하나씩 채워진다.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// Unlimited는 제한이 없는지 반환한다
+func (limit Limit) Unlimited() bool {
+	return limit.Count <= 0 || limit.Period <= 0
+}
+
+// String은 ParseLimit이 읽는 "100/1m0s" 형식으로 반환한다
+func (limit Limit) String() string {
+	return fmt.Sprintf("%d/%s", limit.Count, limit.Period)
+}
+
+// Period의 ms 단위 값
+func (limit Limit) periodMillis() float64 {
+	return float64(limit.Period) / float64(time.Millisecond)
+}
+
+// tokensFor는 elapsed 동안 채워지는 token 수를 반환한다.
+// 나눗셈을 나중에 해서 Period가 지나면 정확히 Count개가 채워지게 한다.
+func (limit Limit) tokensFor(elapsed time.Duration) float64 {
+	return float64(elapsed.Milliseconds()) * float64(limit.Count) / limit.periodMillis()
+}
+
+// durationFor는 tokens개가 채워지는 데 걸리는 시간을 ms 단위로 올림해서 반환한다
+func (limit Limit) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	// 부동소수점 오차로 1ms가 더해지지 않게 1µs보다 작은 값은 버린다
+	millis := tokens*limit.periodMillis()/float64(limit.Count) - 0.001
+	return time.Duration(math.Ceil(millis)) * time.Millisecond
+}
+
+// ParseLimit은 "100/1m"처럼 요청 수와 기간으로 된 제한을 읽는다. ""이면 제한하지 않는다.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	countStr, periodStr, ok := cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q is invalid format", s)
+	}
+
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has invalid count", s)
+	}
+
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period < time.Millisecond {
+		return Limit{}, fmt.Errorf("rate limit %q has invalid period", s)
+	}
+
+	return Limit{Count: count, Period: period}, nil
+}
+
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Result는 요청 하나를 bucket에서 꺼낸 결과
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// bucket에 남은 token 수
+	Remaining int
+	// 요청이 거부되었을 때, 다음 token이 채워질 때까지 기다려야 하는 시간
+	RetryAfter time.Duration
+	// bucket이 가득 찰 때까지 남은 시간
+	Reset time.Duration
+}
+
+func newResult(limit Limit, allowed bool, tokens float64) Result {
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     limit.durationFor(float64(limit.Count) - tokens),
+	}
+	if !allowed {
+		result.RetryAfter = limit.durationFor(1 - tokens)
+		if result.RetryAfter < time.Millisecond {
+			result.RetryAfter = time.Millisecond
+		}
+	}
+	return result
+}
+
+// Limiter는 key마다 token bucket을 두고 요청을 허용할지 정한다
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// bucket이 가득 차서 지워도 되는 시각
+	full time.Time
+}
+
+// MemLimiter는 bucket들을 메모리에 두는 Limiter. 서버가 하나일 때 사용한다.
+type MemLimiter struct {
+	NowFunc func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	// 가득 찬 bucket들을 마지막으로 지운 시각
+	lastPrune time.Time
+}
+
+func (limiter *MemLimiter) now() time.Time {
+	if limiter.NowFunc != nil {
+		return limiter.NowFunc()
+	}
+	return time.Now()
+}
+
+func (limiter *MemLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true, Limit: limit}, nil
+	}
+
+	now := limiter.now()
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if limiter.buckets == nil {
+		limiter.buckets = map[string]*bucket{}
+	}
+	limiter.prune(now)
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Count), last: now}
+		limiter.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Count), b.tokens+limit.tokensFor(elapsed))
+		b.last = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(limit.durationFor(float64(limit.Count) - b.tokens))
+
+	return newResult(limit, allowed, b.tokens), nil
+}
+
+// prune은 1분마다 가득 찬 bucket들을 지운다. 다음 요청에서 가득 찬 bucket을 새로 만들면 되기 때문이다.
+func (limiter *MemLimiter) prune(now time.Time) {
+	if now.Sub(limiter.lastPrune) < time.Minute {
+		return
+	}
+	limiter.lastPrune = now
+
+	for key, b := range limiter.buckets {
+		if !now.Before(b.full) {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+// allowScript는 Count, Period(ms), 현재 시각(ms)을 받아 token bucket에서 token 하나를 꺼낸다.
+// bucket은 tokens, last(ms) field를 가진 hash이다.
+// 가득 찰 시간이 지나면 bucket이 지워지도록 expire를 건다.
+var allowScript = redis.NewScript(`
+local count = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+	tokens = count
+	last = now
+end
+
+if now > last then
+	tokens = math.min(count, tokens + (now - last) * count / period)
+	last = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+-- 지수 표기 없이 저장한다
+local tokensStr = string.format("%.12f", tokens)
+redis.call("HMSET", KEYS[1], "tokens", tokensStr, "last", last)
+redis.call("PEXPIRE", KEYS[1], math.max(1, math.ceil((count - tokens) * period / count)))
+return {allowed, tokensStr}
+`)
+
+// RedisLimiter는 bucket들을 redis에 두는 Limiter. 여러 서버가 같은 bucket을 공유한다.
+type RedisLimiter struct {
+	KeyPrefix string
+	Client    *redis.Client
+	// 서버들의 시각 차이는 bucket이 채워지는 양에만 영향을 준다
+	NowFunc func() time.Time
+}
+
+func (limiter *RedisLimiter) now() time.Time {
+	if limiter.NowFunc != nil {
+		return limiter.NowFunc()
+	}
+	return time.Now()
+}
+
+func (limiter *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true, Limit: limit}, nil
+	}
+
+	args := []interface{}{
+		limit.Count,
+		strconv.FormatFloat(limit.periodMillis(), 'f', -1, 64),
+		limiter.now().UnixNano() / int64(time.Millisecond),
+	}
+
+	reply, err := allowScript.Run(ctx, limiter.Client, []string{limiter.KeyPrefix + "_ratelimit_" + key}, args...).Result()
+	if err != nil {
+		return Result{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, errors.New("unexpected rate limit script result")
+	}
+
+	allowed, _ := values[0].(int64)
+	tokensStr, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(limit, allowed == 1, tokens), nil
+}
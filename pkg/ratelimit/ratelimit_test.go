@@ -0,0 +1,144 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/bigflood/leaderboard/pkg/ratelimit"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("MemLimiter", func(t *testing.T) {
+		now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+		testLimiter(t, &MemLimiter{NowFunc: func() time.Time { return now }}, &now)
+	})
+
+	t.Run("RedisLimiter", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+		client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+		testLimiter(t, &RedisLimiter{KeyPrefix: "test", Client: client, NowFunc: func() time.Time { return now }}, &now)
+
+		// 다른 서버의 limiter도 같은 bucket을 사용한다
+		g := NewWithT(t)
+		other := &RedisLimiter{KeyPrefix: "test", Client: client, NowFunc: func() time.Time { return now }}
+		limit := Limit{Count: 1, Period: time.Minute}
+
+		result, err := other.Allow(context.Background(), "shared", limit)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Allowed).To(BeTrue())
+
+		result, err = (&RedisLimiter{KeyPrefix: "test", Client: client, NowFunc: func() time.Time { return now }}).
+			Allow(context.Background(), "shared", limit)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Allowed).To(BeFalse())
+
+		// 가득 찬 bucket은 expire된다
+		g.Expect(s.TTL("test_ratelimit_shared")).To(Equal(time.Minute))
+	})
+}
+
+func testLimiter(t *testing.T, limiter Limiter, now *time.Time) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	limit := Limit{Count: 3, Period: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, "client1", limit)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Allowed).To(BeTrue())
+		g.Expect(result.Remaining).To(Equal(i))
+		g.Expect(result.Limit).To(Equal(limit))
+	}
+
+	result, err := limiter.Allow(ctx, "client1", limit)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeFalse())
+	g.Expect(result.Remaining).To(Equal(0))
+	g.Expect(result.RetryAfter).To(Equal(time.Second))
+	g.Expect(result.Reset).To(Equal(3 * time.Second))
+
+	// 다른 key는 bucket이 따로 있다
+	result, err = limiter.Allow(ctx, "client2", limit)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeTrue())
+
+	// 0.5초가 지나도 token이 하나가 되지 않는다
+	*now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "client1", limit)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeFalse())
+	g.Expect(result.RetryAfter).To(Equal(500 * time.Millisecond))
+
+	*now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "client1", limit)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeTrue())
+	g.Expect(result.Remaining).To(Equal(0))
+
+	// 오래 지나도 Count개까지만 채워진다
+	*now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		result, err = limiter.Allow(ctx, "client1", limit)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Allowed).To(BeTrue())
+	}
+	result, err = limiter.Allow(ctx, "client1", limit)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeFalse())
+
+	// 천천히 채워지는 bucket
+	slow := Limit{Count: 1, Period: 7 * time.Minute}
+	result, err = limiter.Allow(ctx, "client3", slow)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeTrue())
+
+	*now = now.Add(time.Second)
+	result, err = limiter.Allow(ctx, "client3", slow)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeFalse())
+	g.Expect(result.RetryAfter).To(Equal(7*time.Minute - time.Second))
+
+	*now = now.Add(7*time.Minute - time.Second)
+	result, err = limiter.Allow(ctx, "client3", slow)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeTrue())
+
+	// 제한이 없으면 항상 허용한다
+	result, err = limiter.Allow(ctx, "client1", Limit{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Allowed).To(BeTrue())
+}
+
+func TestParseLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	limit, err := ParseLimit("100/1m")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(limit).To(Equal(Limit{Count: 100, Period: time.Minute}))
+
+	limit, err = ParseLimit(limit.String())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(limit).To(Equal(Limit{Count: 100, Period: time.Minute}))
+
+	limit, err = ParseLimit("")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(limit.Unlimited()).To(BeTrue())
+
+	_, err = ParseLimit("100")
+	g.Expect(err).To(MatchError(`rate limit "100" is invalid format`))
+	_, err = ParseLimit("0/1s")
+	g.Expect(err).To(MatchError(`rate limit "0/1s" has invalid count`))
+	_, err = ParseLimit("10/soon")
+	g.Expect(err).To(MatchError(`rate limit "10/soon" has invalid period`))
+}
@@ -0,0 +1,216 @@
+package signature
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+)
+
+const (
+	GameIdHeader    = "X-Game-Id"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+	SignatureHeader = "X-Signature"
+
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+
+	DefaultMaxSkew = 5 * time.Minute
+)
+
+// Submission은 서명하는 점수 제출 내용. 요청 결과에 영향을 주는 값은 모두 서명에 포함한다.
+type Submission struct {
+	Board  string
+	UserId string
+	Score  int
+	// unix time (초)
+	Timestamp int64
+	Nonce     string
+	// Idempotency-Key header의 값
+	IdempotencyKey string
+	// 함께 변경할 사용자 속성
+	Attributes map[string]string
+}
+
+// message는 서명할 내용을 "<board>\n<user id>\n<score>\n<timestamp>\n<nonce>\n<idempotency key>\n<attributes>" 로 만든다.
+// attributes는 이름 순으로 정렬해서 url query처럼 "name=value&..." 로 escape한다.
+func (s Submission) message() []byte {
+	names := make([]string, 0, len(s.Attributes))
+	for name := range s.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attributes := make([]string, len(names))
+	for i, name := range names {
+		attributes[i] = url.QueryEscape(name) + "=" + url.QueryEscape(s.Attributes[name])
+	}
+
+	return []byte(fmt.Sprintf("%s\n%s\n%d\n%d\n%s\n%s\n%s",
+		s.Board, s.UserId, s.Score, s.Timestamp, s.Nonce, s.IdempotencyKey, strings.Join(attributes, "&")))
+}
+
+// Sign은 submission의 HMAC-SHA256 서명을 hex 문자열로 반환한다
+func Sign(secret []byte, submission Submission) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(submission.message())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Signer는 게임 클라이언트의 점수 제출 요청에 서명한다
+type Signer struct {
+	Board  string
+	GameId string
+	Secret []byte
+
+	NowFunc func() time.Time
+}
+
+func (signer *Signer) now() time.Time {
+	if signer.NowFunc != nil {
+		return signer.NowFunc()
+	}
+	return time.Now()
+}
+
+// Header는 새 timestamp, nonce로 서명한 요청 header들을 반환한다. idempotencyKey가 있으면 그 header도 포함한다.
+// nonce는 한 번만 쓸 수 있으므로 요청을 다시 보낼 때마다 새로 만들어야 한다.
+func (signer *Signer) Header(userId string, score int, attributes map[string]string, idempotencyKey string) (http.Header, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	submission := Submission{
+		Board:     signer.Board,
+		UserId:    userId,
+		Score:     score,
+		Timestamp: signer.now().Unix(),
+		Nonce:     hex.EncodeToString(nonce),
+
+		IdempotencyKey: idempotencyKey,
+		Attributes:     attributes,
+	}
+
+	header := http.Header{}
+	if idempotencyKey != "" {
+		header.Set(api.IdempotencyKeyHeader, idempotencyKey)
+	}
+	header.Set(GameIdHeader, signer.GameId)
+	header.Set(TimestampHeader, strconv.FormatInt(submission.Timestamp, 10))
+	header.Set(NonceHeader, submission.Nonce)
+	header.Set(SignatureHeader, Sign(signer.Secret, submission))
+	return header, nil
+}
+
+// NonceStore는 사용한 nonce를 기억한다. leaderboard.Storage 구현들이 이 interface를 만족한다.
+type NonceStore interface {
+	SetValueIfAbsent(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error)
+}
+
+// Verifier는 점수 제출 요청의 서명을 검증한다
+type Verifier struct {
+	Board string
+	// game id별 secret key
+	Secrets map[string][]byte
+	// 서버 시각과 요청 timestamp의 허용 오차 (0이면 DefaultMaxSkew)
+	MaxSkew time.Duration
+	Nonces  NonceStore
+
+	NowFunc func() time.Time
+}
+
+func (verifier *Verifier) now() time.Time {
+	if verifier.NowFunc != nil {
+		return verifier.NowFunc()
+	}
+	return time.Now()
+}
+
+func (verifier *Verifier) maxSkew() time.Duration {
+	if verifier.MaxSkew > 0 {
+		return verifier.MaxSkew
+	}
+	return DefaultMaxSkew
+}
+
+func unauthorized(msg string) error {
+	return api.ErrorWithStatusCode(errors.New(msg), http.StatusUnauthorized)
+}
+
+// Verify는 header의 서명이 userId, score, attributes와 header의 Idempotency-Key로 제출한 요청에 대한 올바른 서명인지 검사한다
+func (verifier *Verifier) Verify(ctx context.Context, header http.Header, userId string, score int, attributes map[string]string) error {
+	gameId := header.Get(GameIdHeader)
+	signature := header.Get(SignatureHeader)
+	if gameId == "" || signature == "" {
+		return unauthorized("signature is required")
+	}
+
+	secret, ok := verifier.Secrets[gameId]
+	if !ok {
+		return unauthorized("unknown game id")
+	}
+
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return unauthorized("timestamp is empty or invalid format")
+	}
+
+	nonce := header.Get(NonceHeader)
+	if nonce == "" {
+		return unauthorized("nonce is empty")
+	}
+
+	submission := Submission{
+		Board:     verifier.Board,
+		UserId:    userId,
+		Score:     score,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+
+		IdempotencyKey: header.Get(api.IdempotencyKeyHeader),
+		Attributes:     attributes,
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, submission))) {
+		return unauthorized("invalid signature")
+	}
+
+	skew := verifier.now().Sub(time.Unix(timestamp, 0))
+	if skew > verifier.maxSkew() || skew < -verifier.maxSkew() {
+		return unauthorized("timestamp is too old or in the future")
+	}
+
+	// 허용 오차를 벗어난 timestamp는 위에서 거부되므로 그 동안만 nonce를 기억하면 된다
+	ok, err = verifier.Nonces.SetValueIfAbsent(ctx, "nonce_"+gameId+":"+nonce, []byte{1}, 2*verifier.maxSkew())
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return unauthorized("nonce is already used")
+	}
+
+	return nil
+}
+
+// SignWebhook은 webhook으로 보내는 payload의 HMAC-SHA256 서명을 hex 문자열로 반환한다.
+// 서명하는 내용은 "<timestamp>.<body>" 이고, 받는 쪽은 WebhookTimestampHeader의 값으로 다시 계산해서 비교한다.
+func SignWebhook(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package signature_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	. "github.com/bigflood/leaderboard/pkg/signature"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+)
+
+func TestVerifier(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	nowFunc := func() time.Time { return now }
+
+	signer := &Signer{
+		Board:   "main",
+		GameId:  "game1",
+		Secret:  []byte("secret1"),
+		NowFunc: nowFunc,
+	}
+
+	verifier := &Verifier{
+		Board:   "main",
+		Secrets: map[string][]byte{"game1": []byte("secret1")},
+		MaxSkew: time.Minute,
+		Nonces:  &storage.MemStorage{NowFunc: nowFunc},
+		NowFunc: nowFunc,
+	}
+
+	expectUnauthorized := func(err error, msg string) {
+		g.Expect(err).To(MatchError(msg))
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusUnauthorized))
+	}
+
+	header, err := signer.Header("user1", 100, nil, "")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(verifier.Verify(ctx, header, "user1", 100, nil)).To(Succeed())
+
+	// 같은 nonce는 다시 쓸 수 없다
+	expectUnauthorized(verifier.Verify(ctx, header, "user1", 100, nil), "nonce is already used")
+
+	header, err = signer.Header("user1", 100, nil, "")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	expectUnauthorized(verifier.Verify(ctx, header, "user1", 101, nil), "invalid signature")
+	expectUnauthorized(verifier.Verify(ctx, header, "user2", 100, nil), "invalid signature")
+	expectUnauthorized(verifier.Verify(ctx, http.Header{}, "user1", 100, nil), "signature is required")
+
+	// 속성과 idempotency key도 서명에 포함한다
+	attributes := map[string]string{"country": "KR", "platform": "ios"}
+	header, err = signer.Header("user1", 100, attributes, "key1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(header.Get(api.IdempotencyKeyHeader)).To(Equal("key1"))
+
+	expectUnauthorized(verifier.Verify(ctx, header, "user1", 100, map[string]string{"country": "US", "platform": "ios"}), "invalid signature")
+	expectUnauthorized(verifier.Verify(ctx, header, "user1", 100, nil), "invalid signature")
+
+	otherKey := header.Clone()
+	otherKey.Set(api.IdempotencyKeyHeader, "key2")
+	expectUnauthorized(verifier.Verify(ctx, otherKey, "user1", 100, attributes), "invalid signature")
+
+	g.Expect(verifier.Verify(ctx, header, "user1", 100, map[string]string{"platform": "ios", "country": "KR"})).To(Succeed())
+
+	// 다른 board의 서명은 받지 않는다
+	otherBoard := *signer
+	otherBoard.Board = "other"
+	header, err = otherBoard.Header("user1", 100, nil, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	expectUnauthorized(verifier.Verify(ctx, header, "user1", 100, nil), "invalid signature")
+
+	unknownGame := *signer
+	unknownGame.GameId = "game2"
+	header, err = unknownGame.Header("user1", 100, nil, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	expectUnauthorized(verifier.Verify(ctx, header, "user1", 100, nil), "unknown game id")
+
+	header, err = signer.Header("user1", 100, nil, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	now = now.Add(2 * time.Minute)
+	expectUnauthorized(verifier.Verify(ctx, header, "user1", 100, nil), "timestamp is too old or in the future")
+}
+
+func TestSignWebhook(t *testing.T) {
+	g := NewWithT(t)
+
+	body := []byte(`{"seq":1}`)
+	sig := SignWebhook([]byte("secret1"), 1619827200, body)
+
+	g.Expect(sig).To(HaveLen(64))
+	g.Expect(SignWebhook([]byte("secret1"), 1619827200, body)).To(Equal(sig))
+	g.Expect(SignWebhook([]byte("secret2"), 1619827200, body)).NotTo(Equal(sig))
+	g.Expect(SignWebhook([]byte("secret1"), 1619827201, body)).NotTo(Equal(sig))
+	g.Expect(SignWebhook([]byte("secret1"), 1619827200, []byte(`{"seq":2}`))).NotTo(Equal(sig))
+}
@@ -1,17 +1,49 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type MemStorage struct {
-	mutex        sync.Mutex
-	values       map[string][]byte
+	// 만료 시각을 계산할 때 사용한다. nil이면 time.Now.
+	NowFunc func() time.Time
+
+	mutex  sync.Mutex
+	values map[string][]byte
+	// 이름이 ""인 인덱스가 전체 순위
+	indexes map[string]*memIndex
+	lists   map[string][][]byte
+	streams map[string]*memStream
+
+	expiringValues map[string]expiringValue
+	// expiringValues가 이 크기가 되면 만료된 값들을 정리한다
+	sweepSize int
+}
+
+type expiringValue struct {
+	data []byte
+	// zero이면 만료되지 않는다
+	expiresAt time.Time
+}
+
+func (v expiringValue) expired(now time.Time) bool {
+	return !v.expiresAt.IsZero() && !now.Before(v.expiresAt)
+}
+
+type memIndex struct {
 	scores       map[string]Score
 	sortedScores []Score
+
+	// scores, sortedScores를 다른 인덱스와 공유하고 있으면 true.
+	// 공유 중에는 수정하기 전에 복사한다 (copy-on-write).
+	shared bool
 }
 
 type Score struct {
@@ -20,15 +52,91 @@ type Score struct {
 	rank  int
 }
 
-func (storage *MemStorage) Count(ctx context.Context) (int, error) {
+func (index *memIndex) set(key string, score int) {
+	index.unshare()
+
+	if index.scores == nil {
+		index.scores = map[string]Score{}
+	}
+	index.scores[key] = Score{key: key, score: score}
+
+	index.sort()
+}
+
+func (index *memIndex) remove(key string) {
+	if _, ok := index.scores[key]; !ok {
+		return
+	}
+
+	index.unshare()
+
+	delete(index.scores, key)
+
+	index.sort()
+}
+
+func (index *memIndex) unshare() {
+	if !index.shared {
+		return
+	}
+
+	scores := make(map[string]Score, len(index.scores)+1)
+	for k, s := range index.scores {
+		scores[k] = s
+	}
+	index.scores = scores
+	index.sortedScores = nil
+	index.shared = false
+}
+
+func (index *memIndex) sort() {
+	index.sortedScores = index.sortedScores[:0]
+
+	for k, s := range index.scores {
+		index.sortedScores = append(index.sortedScores, Score{key: k, score: s.score})
+	}
+
+	sort.Slice(index.sortedScores, func(i, j int) bool {
+		return index.sortedScores[i].score > index.sortedScores[j].score
+	})
+
+	for i := range index.sortedScores {
+		index.sortedScores[i].rank = i + 1
+		s := index.sortedScores[i]
+		index.scores[s.key] = s
+	}
+}
+
+// lock은 mutex를 잠근다. ctx가 이미 끝났으면 잠그지 않고 ctx의 오류를 반환하므로 취소된 요청은 더 진행하지 않는다.
+func (storage *MemStorage) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	storage.mutex.Lock()
-	defer storage.mutex.Unlock()
 
-	return len(storage.sortedScores), nil
+	// 기다리는 동안 끝났을 수도 있다
+	if err := ctx.Err(); err != nil {
+		storage.mutex.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Ping은 메모리 저장소이므로 항상 성공한다
+func (storage *MemStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (storage *MemStorage) Count(ctx context.Context) (int, error) {
+	return storage.IndexCount(ctx, "")
 }
 
 func (storage *MemStorage) GetData(ctx context.Context, keys ...string) ([][]byte, error) {
-	storage.mutex.Lock()
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
 	defer storage.mutex.Unlock()
 
 	returnList := make([][]byte, len(keys))
@@ -40,56 +148,147 @@ func (storage *MemStorage) GetData(ctx context.Context, keys ...string) ([][]byt
 	return returnList, nil
 }
 
-func (storage *MemStorage) SetData(ctx context.Context, key string, data []byte, score int) error {
-	storage.mutex.Lock()
-	defer storage.mutex.Unlock()
-
+func (storage *MemStorage) setData(key string, data []byte, score int, addIndexes, removeIndexes []string) {
 	if storage.values == nil {
 		storage.values = map[string][]byte{}
 	}
 	storage.values[key] = data
 
-	if storage.scores == nil {
-		storage.scores = map[string]Score{}
+	if storage.indexes == nil {
+		storage.indexes = map[string]*memIndex{}
+	}
+
+	for _, name := range addIndexes {
+		index := storage.indexes[name]
+		if index == nil {
+			index = &memIndex{}
+			storage.indexes[name] = index
+		}
+		index.set(key, score)
 	}
-	storage.scores[key] = Score{key: key, score: score}
 
-	storage.sortedScores = storage.sortedScores[:0]
+	for _, name := range removeIndexes {
+		if index := storage.indexes[name]; index != nil {
+			index.remove(key)
+		}
+	}
+}
 
-	for k, s := range storage.scores {
-		storage.sortedScores = append(storage.sortedScores, Score{key: k, score: s.score})
+// WriteData는 w의 변경들을 잠근 채로 모두 적용한다. w.CheckData인데 data가 다르면 적용하지 않고 false를 반환한다.
+func (storage *MemStorage) WriteData(ctx context.Context, w Write) (bool, error) {
+	if err := storage.lock(ctx); err != nil {
+		return false, err
 	}
+	defer storage.mutex.Unlock()
 
-	sort.Slice(storage.sortedScores, func(i, j int) bool {
-		return storage.sortedScores[i].score > storage.sortedScores[j].score
-	})
+	if w.CheckData {
+		data, exists := storage.values[w.Key]
+		if exists != (w.ExpectData != nil) || !bytes.Equal(data, w.ExpectData) {
+			return false, nil
+		}
+	}
+
+	if w.Key != "" {
+		if w.Delete {
+			storage.deleteData(w.Key, w.RemoveIndexes)
+		} else {
+			storage.setData(w.Key, w.Data, w.Score, w.AddIndexes, w.RemoveIndexes)
+		}
 
-	for i := range storage.sortedScores {
-		storage.sortedScores[i].rank = i + 1
-		s := storage.sortedScores[i]
-		storage.scores[s.key] = s
+		for _, item := range w.IndexScores {
+			storage.setIndexScore(item.Name, w.Key, item.Score)
+		}
 	}
 
-	return nil
+	for _, item := range w.Lists {
+		storage.appendList(item.Key, item.Data, item.MaxLen)
+	}
+
+	for _, key := range w.DeleteLists {
+		delete(storage.lists, key)
+	}
+
+	for _, item := range w.RemoveListItems {
+		storage.removeListItem(item.Key, item.Data)
+	}
+
+	for _, item := range w.Streams {
+		storage.appendStream(item.Key, item.Data, item.MaxLen)
+	}
+
+	for _, item := range w.Incrs {
+		if err := storage.incrValue(item.Key, item.By); err != nil {
+			return false, err
+		}
+	}
+
+	for _, item := range w.Values {
+		storage.setValue(item.Key, item.Data, 0)
+	}
+
+	for _, key := range w.DeleteValues {
+		delete(storage.expiringValues, key)
+	}
+
+	for _, name := range w.CopyIndexes {
+		storage.copyIndex(name)
+	}
+
+	for _, name := range w.DeleteIndexes {
+		delete(storage.indexes, name)
+	}
+
+	return true, nil
 }
 
-func (storage *MemStorage) GetRanks(ctx context.Context, keys ...string) ([]int, error) {
-	storage.mutex.Lock()
-	defer storage.mutex.Unlock()
+func (storage *MemStorage) ScanDataKeys(ctx context.Context, batchSize int, handler func(keys []string) error) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
 
-	returnData := make([]int, len(keys))
+	keys := make([]string, 0, len(storage.values))
+	for key := range storage.values {
+		keys = append(keys, key)
+	}
+	// handler가 저장소를 사용할 수 있도록 key들을 복사한 다음에 잠금을 푼다
+	storage.mutex.Unlock()
 
-	for i, key := range keys {
-		s, ok := storage.scores[key]
-		if ok {
-			returnData[i] = s.rank
+	sort.Strings(keys)
+
+	for len(keys) > 0 {
+		n := batchSize
+		if n > len(keys) {
+			n = len(keys)
 		}
+
+		if err := handler(keys[:n]); err != nil {
+			return err
+		}
+		keys = keys[n:]
 	}
 
-	return returnData, nil
+	return nil
+}
+
+func (storage *MemStorage) deleteData(key string, removeIndexes []string) {
+	delete(storage.values, key)
+
+	for _, name := range removeIndexes {
+		if index := storage.indexes[name]; index != nil {
+			index.remove(key)
+		}
+	}
+}
+
+func (storage *MemStorage) GetRanks(ctx context.Context, keys ...string) ([]int, error) {
+	return storage.GetIndexRanks(ctx, "", keys...)
 }
 
 func (storage *MemStorage) GetSortedRange(ctx context.Context, rank, count int) ([]string, error) {
+	return storage.GetIndexSortedRange(ctx, "", rank, count)
+}
+
+func sortedRange(sortedScores []Score, rank, count int) ([]string, error) {
 	if rank < 1 {
 		return nil, errors.New("invalid rank")
 	}
@@ -98,23 +297,559 @@ func (storage *MemStorage) GetSortedRange(ctx context.Context, rank, count int)
 		return nil, errors.New("invalid count")
 	}
 
-	storage.mutex.Lock()
-	defer storage.mutex.Unlock()
-
 	baseIndex := rank - 1
-	if baseIndex >= len(storage.sortedScores) {
+	if baseIndex >= len(sortedScores) {
 		return nil, nil
 	}
 
-	if maxCount := len(storage.sortedScores) - baseIndex; count > maxCount {
+	if maxCount := len(sortedScores) - baseIndex; count > maxCount {
 		count = maxCount
 	}
 
 	returnData := make([]string, count)
 
 	for i := range returnData {
-		returnData[i] = storage.sortedScores[baseIndex+i].key
+		returnData[i] = sortedScores[baseIndex+i].key
+	}
+
+	return returnData, nil
+}
+
+func (storage *MemStorage) CopyIndex(ctx context.Context, name string) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	storage.copyIndex(name)
+
+	return nil
+}
+
+func (storage *MemStorage) copyIndex(name string) {
+	if storage.indexes == nil {
+		storage.indexes = map[string]*memIndex{}
+	}
+
+	src := storage.indexes[""]
+	if src == nil {
+		src = &memIndex{}
+		storage.indexes[""] = src
+	}
+
+	src.shared = true
+	storage.indexes[name] = &memIndex{
+		scores:       src.scores,
+		sortedScores: src.sortedScores,
+		shared:       true,
+	}
+}
+
+func (storage *MemStorage) GetIndexRanks(ctx context.Context, name string, keys ...string) ([]int, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	returnData := make([]int, len(keys))
+
+	index, ok := storage.indexes[name]
+	if !ok {
+		return returnData, nil
+	}
+
+	for i, key := range keys {
+		s, ok := index.scores[key]
+		if ok {
+			returnData[i] = s.rank
+		}
 	}
 
 	return returnData, nil
 }
+
+func (storage *MemStorage) GetIndexScores(ctx context.Context, name string, keys ...string) ([]int, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	returnData := make([]int, len(keys))
+
+	index, ok := storage.indexes[name]
+	if !ok {
+		return returnData, nil
+	}
+
+	for i, key := range keys {
+		returnData[i] = index.scores[key].score
+	}
+
+	return returnData, nil
+}
+
+func (storage *MemStorage) setIndexScore(name string, key string, score int) {
+	if storage.indexes == nil {
+		storage.indexes = map[string]*memIndex{}
+	}
+
+	index := storage.indexes[name]
+	if index == nil {
+		index = &memIndex{}
+		storage.indexes[name] = index
+	}
+	index.set(key, score)
+}
+
+func (storage *MemStorage) IndexCount(ctx context.Context, name string) (int, error) {
+	if err := storage.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer storage.mutex.Unlock()
+
+	index, ok := storage.indexes[name]
+	if !ok {
+		return 0, nil
+	}
+
+	return len(index.sortedScores), nil
+}
+
+func (storage *MemStorage) IndexCountAbove(ctx context.Context, name string, score int) (int, error) {
+	if err := storage.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer storage.mutex.Unlock()
+
+	index, ok := storage.indexes[name]
+	if !ok {
+		return 0, nil
+	}
+
+	// sortedScores는 score 내림차순
+	return sort.Search(len(index.sortedScores), func(i int) bool {
+		return index.sortedScores[i].score <= score
+	}), nil
+}
+
+func (storage *MemStorage) GetIndexSortedRange(ctx context.Context, name string, rank, count int) ([]string, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	var sortedScores []Score
+	if index, ok := storage.indexes[name]; ok {
+		sortedScores = index.sortedScores
+	}
+
+	return sortedRange(sortedScores, rank, count)
+}
+
+func (storage *MemStorage) DeleteIndex(ctx context.Context, name string) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	delete(storage.indexes, name)
+
+	return nil
+}
+
+func (storage *MemStorage) IndexNames(ctx context.Context) ([]string, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	var names []string
+	for name, index := range storage.indexes {
+		if len(index.scores) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (storage *MemStorage) AppendList(ctx context.Context, key string, data []byte, maxLen int) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	storage.appendList(key, data, maxLen)
+
+	return nil
+}
+
+func (storage *MemStorage) appendList(key string, data []byte, maxLen int) {
+	if storage.lists == nil {
+		storage.lists = map[string][][]byte{}
+	}
+
+	list := append(storage.lists[key], data)
+	if maxLen > 0 && len(list) > maxLen {
+		list = append([][]byte(nil), list[len(list)-maxLen:]...)
+	}
+	storage.lists[key] = list
+}
+
+func (storage *MemStorage) GetList(ctx context.Context, key string) ([][]byte, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	list := storage.lists[key]
+
+	returnList := make([][]byte, len(list))
+	copy(returnList, list)
+
+	return returnList, nil
+}
+
+func (storage *MemStorage) RemoveListItem(ctx context.Context, key string, data []byte) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	storage.removeListItem(key, data)
+
+	return nil
+}
+
+func (storage *MemStorage) removeListItem(key string, data []byte) {
+	list, ok := storage.lists[key]
+	if !ok {
+		return
+	}
+
+	newList := make([][]byte, 0, len(list))
+	for _, item := range list {
+		if !bytes.Equal(item, data) {
+			newList = append(newList, item)
+		}
+	}
+	storage.lists[key] = newList
+}
+
+func (storage *MemStorage) DeleteList(ctx context.Context, key string) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	delete(storage.lists, key)
+
+	return nil
+}
+
+func (storage *MemStorage) now() time.Time {
+	if storage.NowFunc != nil {
+		return storage.NowFunc()
+	}
+	return time.Now()
+}
+
+func (storage *MemStorage) GetValue(ctx context.Context, key string) ([]byte, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	return storage.getValue(key), nil
+}
+
+func (storage *MemStorage) getValue(key string) []byte {
+	v, ok := storage.expiringValues[key]
+	if !ok {
+		return nil
+	}
+
+	if v.expired(storage.now()) {
+		delete(storage.expiringValues, key)
+		return nil
+	}
+
+	return v.data
+}
+
+func (storage *MemStorage) SetValue(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	storage.setValue(key, data, ttl)
+
+	return nil
+}
+
+func (storage *MemStorage) SetValueIfAbsent(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	if err := storage.lock(ctx); err != nil {
+		return false, err
+	}
+	defer storage.mutex.Unlock()
+
+	if storage.getValue(key) != nil {
+		return false, nil
+	}
+
+	storage.setValue(key, data, ttl)
+
+	return true, nil
+}
+
+func (storage *MemStorage) ExtendValue(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	if err := storage.lock(ctx); err != nil {
+		return false, err
+	}
+	defer storage.mutex.Unlock()
+
+	if !bytes.Equal(storage.getValue(key), data) {
+		return false, nil
+	}
+
+	storage.setValue(key, data, ttl)
+
+	return true, nil
+}
+
+func (storage *MemStorage) setValue(key string, data []byte, ttl time.Duration) {
+	if storage.expiringValues == nil {
+		storage.expiringValues = map[string]expiringValue{}
+	}
+
+	now := storage.now()
+
+	if len(storage.expiringValues) >= storage.sweepSize {
+		for k, v := range storage.expiringValues {
+			if v.expired(now) {
+				delete(storage.expiringValues, k)
+			}
+		}
+		storage.sweepSize = 2*len(storage.expiringValues) + 64
+	}
+
+	v := expiringValue{data: data}
+	if ttl > 0 {
+		v.expiresAt = now.Add(ttl)
+	}
+	storage.expiringValues[key] = v
+}
+
+func (storage *MemStorage) DeleteValue(ctx context.Context, key string) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	delete(storage.expiringValues, key)
+
+	return nil
+}
+
+func (storage *MemStorage) ValueKeys(ctx context.Context, prefix string) ([]string, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	now := storage.now()
+
+	var keys []string
+	for key, v := range storage.expiringValues {
+		if strings.HasPrefix(key, prefix) && !v.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (storage *MemStorage) IncrValue(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	if err := storage.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer storage.mutex.Unlock()
+
+	data := storage.getValue(key)
+	if data == nil {
+		storage.setValue(key, []byte("1"), ttl)
+		return 1, nil
+	}
+
+	n, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, err
+	}
+	n++
+
+	v := storage.expiringValues[key]
+	v.data = []byte(strconv.Itoa(n))
+	storage.expiringValues[key] = v
+
+	return n, nil
+}
+
+// incrValue는 key의 값을 by만큼 늘린다. 만료 시각은 그대로 둔다.
+func (storage *MemStorage) incrValue(key string, by int64) error {
+	var n int64
+	if data := storage.getValue(key); data != nil {
+		var err error
+		if n, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+			return err
+		}
+	}
+
+	data := []byte(strconv.FormatInt(n+by, 10))
+
+	v, ok := storage.expiringValues[key]
+	if !ok {
+		storage.setValue(key, data, 0)
+		return nil
+	}
+
+	v.data = data
+	storage.expiringValues[key] = v
+	return nil
+}
+
+// memStream은 최근 항목들을 담는 ring buffer.
+// 순번이 seq인 항목은 entries[(seq-1)%len(entries)]에 있다.
+type memStream struct {
+	entries [][]byte
+	// entries에 남아있는 항목 수
+	count   int
+	lastSeq int64
+}
+
+func (stream *memStream) append(data []byte, maxLen int) int64 {
+	if stream.count == len(stream.entries) && (maxLen <= 0 || len(stream.entries) < maxLen) {
+		stream.grow(maxLen)
+	}
+
+	stream.lastSeq++
+	stream.entries[(stream.lastSeq-1)%int64(len(stream.entries))] = data
+
+	if stream.count < len(stream.entries) {
+		stream.count++
+	}
+	if maxLen > 0 && stream.count > maxLen {
+		stream.count = maxLen
+	}
+
+	return stream.lastSeq
+}
+
+func (stream *memStream) grow(maxLen int) {
+	size := 2*len(stream.entries) + 16
+	if maxLen > 0 && size > maxLen {
+		size = maxLen
+	}
+
+	entries := make([][]byte, size)
+	for seq := stream.firstSeq(); seq <= stream.lastSeq; seq++ {
+		entries[(seq-1)%int64(size)] = stream.get(seq)
+	}
+	stream.entries = entries
+}
+
+func (stream *memStream) firstSeq() int64 {
+	return stream.lastSeq - int64(stream.count) + 1
+}
+
+func (stream *memStream) get(seq int64) []byte {
+	return stream.entries[(seq-1)%int64(len(stream.entries))]
+}
+
+func (storage *MemStorage) AppendStream(ctx context.Context, key string, data []byte, maxLen int) (int64, error) {
+	if err := storage.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer storage.mutex.Unlock()
+
+	return storage.appendStream(key, data, maxLen), nil
+}
+
+func (storage *MemStorage) appendStream(key string, data []byte, maxLen int) int64 {
+	if storage.streams == nil {
+		storage.streams = map[string]*memStream{}
+	}
+
+	stream := storage.streams[key]
+	if stream == nil {
+		stream = &memStream{}
+		storage.streams[key] = stream
+	}
+
+	return stream.append(data, maxLen)
+}
+
+func (storage *MemStorage) ReadStream(ctx context.Context, key string, afterSeq int64, count int) ([]int64, [][]byte, error) {
+	if err := storage.lock(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer storage.mutex.Unlock()
+
+	stream := storage.streams[key]
+	if stream == nil {
+		return nil, nil, nil
+	}
+
+	seq := afterSeq + 1
+	if first := stream.firstSeq(); seq < first {
+		seq = first
+	}
+
+	var seqs []int64
+	var data [][]byte
+	for ; seq <= stream.lastSeq && len(seqs) < count; seq++ {
+		// 지운 항목
+		if stream.get(seq) == nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+		data = append(data, stream.get(seq))
+	}
+
+	return seqs, data, nil
+}
+
+func (storage *MemStorage) DeleteStreamItems(ctx context.Context, key string, seqs ...int64) error {
+	if err := storage.lock(ctx); err != nil {
+		return err
+	}
+	defer storage.mutex.Unlock()
+
+	stream := storage.streams[key]
+	if stream == nil {
+		return nil
+	}
+
+	for _, seq := range seqs {
+		if seq >= stream.firstSeq() && seq <= stream.lastSeq {
+			stream.entries[(seq-1)%int64(len(stream.entries))] = nil
+		}
+	}
+
+	return nil
+}
+
+func (storage *MemStorage) LastStreamSeq(ctx context.Context, key string) (int64, error) {
+	if err := storage.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer storage.mutex.Unlock()
+
+	stream := storage.streams[key]
+	if stream == nil {
+		return 0, nil
+	}
+	return stream.lastSeq, nil
+}
@@ -2,13 +2,37 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/go-redis/redis/v8"
 )
 
+// DefaultPingTimeout은 RedisStorage.PingTimeout이 0일 때 PING 응답을 기다리는 시간
+const DefaultPingTimeout = time.Second
+
 type RedisStorage struct {
 	KeyPrefix string
 	Client    *redis.Client
+
+	// Ping이 PING 응답을 기다리는 시간. 0이면 DefaultPingTimeout.
+	PingTimeout time.Duration
+}
+
+func (s *RedisStorage) Ping(ctx context.Context) error {
+	timeout := s.PingTimeout
+	if timeout <= 0 {
+		timeout = DefaultPingTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return s.Client.Ping(ctx).Err()
 }
 
 func (s *RedisStorage) Count(ctx context.Context) (int, error) {
@@ -44,30 +68,183 @@ func (s *RedisStorage) GetData(ctx context.Context, keys ...string) ([][]byte, e
 	return returnArr, nil
 }
 
-func (s *RedisStorage) SetData(ctx context.Context, key string, data []byte, score int) error {
-	dataKey := s.KeyPrefix + "_data_" + key
-	scoresKey := s.KeyPrefix + "_scores"
+// writeDataScript는 KEYS[1]의 data를 확인한 다음 ARGV[3]부터 나열된 명령들을 차례로 실행한다.
+// ARGV[1]은 확인 방법("", "absent", "equal"), ARGV[2]는 "equal"일 때 기대하는 data이다.
+// 명령은 이름과 인자들로 되어있고, 명령마다 KEYS[2]부터 key를 하나씩 사용한다 (xadd, zcopy는 두 개).
+var writeDataScript = redis.NewScript(`
+if ARGV[1] == "absent" then
+	if redis.call("EXISTS", KEYS[1]) == 1 then
+		return 0
+	end
+elseif ARGV[1] == "equal" then
+	if redis.call("GET", KEYS[1]) ~= ARGV[2] then
+		return 0
+	end
+end
 
-	pipe := s.Client.TxPipeline()
-	setCmd := pipe.Set(ctx, dataKey, data, 0)
-	zaddCmd := pipe.ZAdd(ctx, scoresKey, &redis.Z{
-		Score:  float64(score),
-		Member: key,
-	})
+local k = 2
+local i = 3
+while i <= #ARGV do
+	local op = ARGV[i]
+	if op == "set" then
+		redis.call("SET", KEYS[k], ARGV[i+1])
+		i = i + 2
+	elseif op == "del" then
+		redis.call("DEL", KEYS[k])
+		i = i + 1
+	elseif op == "zadd" then
+		redis.call("ZADD", KEYS[k], ARGV[i+1], ARGV[i+2])
+		i = i + 3
+	elseif op == "zrem" then
+		redis.call("ZREM", KEYS[k], ARGV[i+1])
+		i = i + 2
+	elseif op == "rpush" then
+		redis.call("RPUSH", KEYS[k], ARGV[i+1])
+		if tonumber(ARGV[i+2]) > 0 then
+			redis.call("LTRIM", KEYS[k], -tonumber(ARGV[i+2]), -1)
+		end
+		i = i + 3
+	elseif op == "lrem" then
+		redis.call("LREM", KEYS[k], 0, ARGV[i+1])
+		i = i + 2
+	elseif op == "zcopy" then
+		-- KEYS[k+1]의 점수들로 KEYS[k]를 덮어쓴다
+		redis.call("ZUNIONSTORE", KEYS[k], 1, KEYS[k+1])
+		k = k + 1
+		i = i + 1
+	elseif op == "incrby" then
+		redis.call("INCRBY", KEYS[k], ARGV[i+1])
+		i = i + 2
+	elseif op == "xadd" then
+		-- KEYS[k+1]은 스트림의 순번
+		local seq = redis.call("INCR", KEYS[k+1])
+		if tonumber(ARGV[i+2]) > 0 then
+			redis.call("XADD", KEYS[k], "MAXLEN", ARGV[i+2], seq .. "-0", "data", ARGV[i+1])
+		else
+			redis.call("XADD", KEYS[k], seq .. "-0", "data", ARGV[i+1])
+		end
+		k = k + 1
+		i = i + 3
+	else
+		return redis.error_reply("unknown write op " .. op)
+	end
+	k = k + 1
+end
+return 1
+`)
 
-	if _, err := pipe.Exec(ctx); err != nil {
-		return err
+// writeOps는 writeDataScript에 넘길 key와 인자들을 모은다
+type writeOps struct {
+	keys []string
+	args []interface{}
+}
+
+func (ops *writeOps) add(op, key string, args ...interface{}) {
+	ops.keys = append(ops.keys, key)
+	ops.args = append(ops.args, op)
+	ops.args = append(ops.args, args...)
+}
+
+// WriteData는 w의 변경들을 lua script 하나로 적용한다. w.CheckData인데 data가 다르면 적용하지 않고 false를 반환한다.
+func (s *RedisStorage) WriteData(ctx context.Context, w Write) (bool, error) {
+	dataKey := s.KeyPrefix + "_data_" + w.Key
+
+	ops := &writeOps{keys: []string{dataKey}, args: []interface{}{"", ""}}
+	if w.CheckData {
+		if w.ExpectData == nil {
+			ops.args[0] = "absent"
+		} else {
+			ops.args[0], ops.args[1] = "equal", w.ExpectData
+		}
 	}
 
-	if _, err := setCmd.Result(); err != nil {
-		return err
+	if w.Key != "" {
+		if w.Delete {
+			ops.add("del", dataKey)
+		} else {
+			ops.add("set", dataKey, w.Data)
+			for _, name := range w.AddIndexes {
+				ops.add("zadd", s.indexKey(name), w.Score, w.Key)
+			}
+		}
+		for _, name := range w.RemoveIndexes {
+			ops.add("zrem", s.indexKey(name), w.Key)
+		}
+		for _, item := range w.IndexScores {
+			ops.add("zadd", s.indexKey(item.Name), item.Score, w.Key)
+		}
+	}
+
+	for _, item := range w.Lists {
+		ops.add("rpush", s.listKey(item.Key), item.Data, item.MaxLen)
 	}
 
-	if _, err := zaddCmd.Result(); err != nil {
+	for _, item := range w.Incrs {
+		ops.add("incrby", s.valueKey(item.Key), item.By)
+	}
+
+	for _, key := range w.DeleteLists {
+		ops.add("del", s.listKey(key))
+	}
+
+	for _, item := range w.RemoveListItems {
+		ops.add("lrem", s.listKey(item.Key), item.Data)
+	}
+
+	for _, item := range w.Streams {
+		ops.add("xadd", s.streamKey(item.Key), item.Data, item.MaxLen)
+		ops.keys = append(ops.keys, s.streamSeqKey(item.Key))
+	}
+
+	for _, item := range w.Values {
+		ops.add("set", s.valueKey(item.Key), item.Data)
+	}
+
+	for _, key := range w.DeleteValues {
+		ops.add("del", s.valueKey(key))
+	}
+
+	for _, name := range w.CopyIndexes {
+		ops.add("zcopy", s.indexKey(name))
+		ops.keys = append(ops.keys, s.indexKey(""))
+	}
+
+	for _, name := range w.DeleteIndexes {
+		ops.add("del", s.indexKey(name))
+	}
+
+	applied, err := writeDataScript.Run(ctx, s.Client, ops.keys, ops.args...).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return applied == 1, nil
+}
+
+func (s *RedisStorage) ScanDataKeys(ctx context.Context, batchSize int, handler func(keys []string) error) error {
+	prefix := s.KeyPrefix + "_data_"
+
+	var keys []string
+	iter := s.Client.Scan(ctx, 0, globEscape(prefix)+"*", int64(batchSize)).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), prefix))
+		if len(keys) < batchSize {
+			continue
+		}
+
+		if err := handler(keys); err != nil {
+			return err
+		}
+		keys = nil
+	}
+	if err := iter.Err(); err != nil {
 		return err
 	}
 
-	return nil
+	if len(keys) == 0 {
+		return nil
+	}
+	return handler(keys)
 }
 
 func (s *RedisStorage) GetRanks(ctx context.Context, keys ...string) ([]int, error) {
@@ -104,5 +281,327 @@ func (s *RedisStorage) GetRanks(ctx context.Context, keys ...string) ([]int, err
 func (s *RedisStorage) GetSortedRange(ctx context.Context, rank, count int) ([]string, error) {
 	scoresKey := s.KeyPrefix + "_scores"
 
-	return s.Client.ZRevRange(ctx, scoresKey, int64(rank-1), int64(count)).Result()
+	return s.sortedRange(ctx, scoresKey, rank, count)
+}
+
+func (s *RedisStorage) sortedRange(ctx context.Context, key string, rank, count int) ([]string, error) {
+	if rank < 1 {
+		return nil, errors.New("invalid rank")
+	}
+
+	if count <= 0 {
+		return nil, errors.New("invalid count")
+	}
+
+	start := int64(rank - 1)
+	stop := start + int64(count) - 1
+
+	return s.Client.ZRevRange(ctx, key, start, stop).Result()
+}
+
+func (s *RedisStorage) CopyIndex(ctx context.Context, name string) error {
+	scoresKey := s.indexKey("")
+	indexKey := s.indexKey(name)
+
+	return s.Client.ZUnionStore(ctx, indexKey, &redis.ZStore{Keys: []string{scoresKey}}).Err()
+}
+
+func (s *RedisStorage) GetIndexRanks(ctx context.Context, name string, keys ...string) ([]int, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	indexKey := s.indexKey(name)
+
+	cmds := make([]*redis.IntCmd, len(keys))
+
+	pipe := s.Client.TxPipeline()
+
+	for i, k := range keys {
+		cmds[i] = pipe.ZRevRank(ctx, indexKey, k)
+	}
+
+	// 인덱스에 없는 key는 redis.Nil 을 반환한다
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	ranks := make([]int, len(cmds))
+	for i, cmd := range cmds {
+		rank, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ranks[i] = int(rank + 1)
+	}
+
+	return ranks, nil
+}
+
+func (s *RedisStorage) GetIndexScores(ctx context.Context, name string, keys ...string) ([]int, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	indexKey := s.indexKey(name)
+
+	cmds := make([]*redis.FloatCmd, len(keys))
+
+	pipe := s.Client.TxPipeline()
+
+	for i, k := range keys {
+		cmds[i] = pipe.ZScore(ctx, indexKey, k)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	scores := make([]int, len(cmds))
+	for i, cmd := range cmds {
+		score, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = int(score)
+	}
+
+	return scores, nil
+}
+
+func (s *RedisStorage) IndexCount(ctx context.Context, name string) (int, error) {
+	count, err := s.Client.ZCard(ctx, s.indexKey(name)).Result()
+
+	return int(count), err
+}
+
+func (s *RedisStorage) IndexCountAbove(ctx context.Context, name string, score int) (int, error) {
+	count, err := s.Client.ZCount(ctx, s.indexKey(name), fmt.Sprintf("(%d", score), "+inf").Result()
+
+	return int(count), err
+}
+
+func (s *RedisStorage) GetIndexSortedRange(ctx context.Context, name string, rank, count int) ([]string, error) {
+	return s.sortedRange(ctx, s.indexKey(name), rank, count)
+}
+
+func (s *RedisStorage) DeleteIndex(ctx context.Context, name string) error {
+	return s.Client.Del(ctx, s.indexKey(name)).Err()
+}
+
+func (s *RedisStorage) IndexNames(ctx context.Context) ([]string, error) {
+	var names []string
+
+	iter := s.Client.Scan(ctx, 0, globEscape(s.KeyPrefix)+"_scores*", 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		switch {
+		case key == s.indexKey(""):
+			names = append(names, "")
+		case strings.HasPrefix(key, s.indexKey("")+"_"):
+			names = append(names, strings.TrimPrefix(key, s.indexKey("")+"_"))
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *RedisStorage) AppendList(ctx context.Context, key string, data []byte, maxLen int) error {
+	listKey := s.listKey(key)
+
+	pipe := s.Client.TxPipeline()
+	pipe.RPush(ctx, listKey, data)
+	if maxLen > 0 {
+		pipe.LTrim(ctx, listKey, int64(-maxLen), -1)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStorage) GetList(ctx context.Context, key string) ([][]byte, error) {
+	values, err := s.Client.LRange(ctx, s.listKey(key), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	returnList := make([][]byte, len(values))
+	for i, v := range values {
+		returnList[i] = []byte(v)
+	}
+
+	return returnList, nil
+}
+
+func (s *RedisStorage) RemoveListItem(ctx context.Context, key string, data []byte) error {
+	return s.Client.LRem(ctx, s.listKey(key), 0, data).Err()
+}
+
+func (s *RedisStorage) DeleteList(ctx context.Context, key string) error {
+	return s.Client.Del(ctx, s.listKey(key)).Err()
+}
+
+func (s *RedisStorage) GetValue(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.Client.Get(ctx, s.valueKey(key)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *RedisStorage) SetValue(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return s.Client.Set(ctx, s.valueKey(key), data, ttl).Err()
+}
+
+func (s *RedisStorage) SetValueIfAbsent(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	return s.Client.SetNX(ctx, s.valueKey(key), data, ttl).Result()
+}
+
+var extendValueScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+func (s *RedisStorage) ExtendValue(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	n, err := extendValueScript.Run(ctx, s.Client, []string{s.valueKey(key)}, data, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (s *RedisStorage) DeleteValue(ctx context.Context, key string) error {
+	return s.Client.Del(ctx, s.valueKey(key)).Err()
+}
+
+func (s *RedisStorage) ValueKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	iter := s.Client.Scan(ctx, 0, globEscape(s.valueKey(prefix))+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), s.valueKey("")))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+var incrValueScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (s *RedisStorage) IncrValue(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	n, err := incrValueScript.Run(ctx, s.Client, []string{s.valueKey(key)}, ttl.Milliseconds()).Int()
+	return n, err
+}
+
+// appendStreamScript는 순번을 늘리고 그 순번을 id로 스트림에 추가한다
+var appendStreamScript = redis.NewScript(`
+local seq = redis.call("INCR", KEYS[2])
+if tonumber(ARGV[2]) > 0 then
+	redis.call("XADD", KEYS[1], "MAXLEN", ARGV[2], seq .. "-0", "data", ARGV[1])
+else
+	redis.call("XADD", KEYS[1], seq .. "-0", "data", ARGV[1])
+end
+return seq
+`)
+
+func (s *RedisStorage) AppendStream(ctx context.Context, key string, data []byte, maxLen int) (int64, error) {
+	keys := []string{s.streamKey(key), s.streamSeqKey(key)}
+	return appendStreamScript.Run(ctx, s.Client, keys, data, maxLen).Int64()
+}
+
+func (s *RedisStorage) ReadStream(ctx context.Context, key string, afterSeq int64, count int) ([]int64, [][]byte, error) {
+	start := fmt.Sprintf("%d-0", afterSeq+1)
+
+	messages, err := s.Client.XRangeN(ctx, s.streamKey(key), start, "+", int64(count)).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seqs := make([]int64, len(messages))
+	data := make([][]byte, len(messages))
+	for i, message := range messages {
+		id := strings.TrimSuffix(message.ID, "-0")
+		if seqs[i], err = strconv.ParseInt(id, 10, 64); err != nil {
+			return nil, nil, err
+		}
+		data[i] = []byte(fmt.Sprint(message.Values["data"]))
+	}
+
+	return seqs, data, nil
+}
+
+func (s *RedisStorage) DeleteStreamItems(ctx context.Context, key string, seqs ...int64) error {
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(seqs))
+	for i, seq := range seqs {
+		ids[i] = fmt.Sprintf("%d-0", seq)
+	}
+
+	return s.Client.XDel(ctx, s.streamKey(key), ids...).Err()
+}
+
+func (s *RedisStorage) LastStreamSeq(ctx context.Context, key string) (int64, error) {
+	seq, err := s.Client.Get(ctx, s.streamSeqKey(key)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return seq, err
+}
+
+func (s *RedisStorage) streamKey(key string) string {
+	return s.KeyPrefix + "_stream_" + key
+}
+
+func (s *RedisStorage) streamSeqKey(key string) string {
+	return s.KeyPrefix + "_streamseq_" + key
+}
+
+func (s *RedisStorage) valueKey(key string) string {
+	return s.KeyPrefix + "_value_" + key
+}
+
+func (s *RedisStorage) listKey(key string) string {
+	return s.KeyPrefix + "_list_" + key
+}
+
+// globEscape는 s를 SCAN의 MATCH pattern에서 글자 그대로 찾도록 escape한다
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// indexKey는 name 인덱스의 sorted set key를 반환한다. 이름이 ""이면 전체 순위.
+func (s *RedisStorage) indexKey(name string) string {
+	if name == "" {
+		return s.KeyPrefix + "_scores"
+	}
+	return s.KeyPrefix + "_scores_" + name
 }
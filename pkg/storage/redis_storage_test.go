@@ -7,9 +7,10 @@ import (
 	. "github.com/onsi/gomega"
 	"sync"
 	"testing"
+	"time"
 )
 
-func TestRedisStorage_SetData(t *testing.T) {
+func TestRedisStorage_WriteData(t *testing.T) {
 	g := NewWithT(t)
 
 	ctx := context.Background()
@@ -29,13 +30,16 @@ func TestRedisStorage_SetData(t *testing.T) {
 		Client:    client,
 	}
 
-	err = storage.SetData(ctx, "user1", []byte("data1"), 123)
+	applied, err := storage.WriteData(ctx, Write{Key: "user1", Data: []byte("data1"), Score: 123, AddIndexes: []string{""}})
 	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeTrue())
 
-	// SetData 함수에서 multi...exec 를 사용해서 redis 명령들을 드랜잭션으로 처리했는지 확인
-	lastPipeCmds := hook.processPipeCmdsList[0]
-	g.Expect(lastPipeCmds[0]).To(Equal("multi"))
-	g.Expect(lastPipeCmds[len(lastPipeCmds)-1]).To(Equal("exec"))
+	// WriteData 함수에서 lua script 하나로 redis 명령들을 원자적으로 처리했는지 확인
+	g.Expect(hook.processPipeCmdsList).To(BeEmpty())
+	g.Expect(hook.processCmdList).NotTo(BeEmpty())
+	for _, cmd := range hook.processCmdList {
+		g.Expect(cmd).To(BeElementOf("evalsha", "eval"))
+	}
 
 	data, err := storage.GetData(ctx, "user1")
 	g.Expect(err).NotTo(HaveOccurred())
@@ -78,3 +82,28 @@ func (h *redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder
 	h.processPipeCmdsList = append(h.processPipeCmdsList, cmdNames)
 	return nil
 }
+
+func TestRedisStorage_Ping(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	s, err := miniredis.Run()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	defer s.Close()
+
+	storage := &RedisStorage{
+		KeyPrefix:   "test",
+		Client:      redis.NewClient(&redis.Options{Addr: s.Addr(), MaxRetries: -1}),
+		PingTimeout: 100 * time.Millisecond,
+	}
+
+	g.Expect(storage.Ping(ctx)).To(Succeed())
+
+	s.Close()
+	g.Expect(storage.Ping(ctx)).NotTo(Succeed())
+
+	g.Expect(s.Restart()).To(Succeed())
+	g.Expect(storage.Ping(ctx)).To(Succeed())
+}
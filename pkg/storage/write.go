@@ -0,0 +1,73 @@
+package storage
+
+// Write는 WriteData가 한 번에 적용하는 변경들. 모두 적용되거나 하나도 적용되지 않는다.
+type Write struct {
+	// Key의 data를 Data로 저장하고, AddIndexes 인덱스들에 Key를 Score로 추가하고
+	// RemoveIndexes 인덱스들에서는 Key를 제거한다. 이름이 ""인 인덱스가 전체 순위이다.
+	// Key가 ""이면 data와 인덱스의 Key는 건드리지 않고 나머지 변경들만 적용한다.
+	Key                       string
+	Data                      []byte
+	Score                     int
+	AddIndexes, RemoveIndexes []string
+
+	// Delete가 true이면 Data를 저장하는 대신 Key의 data를 지운다
+	Delete bool
+	// 함께 Key를 추가할 인덱스들. AddIndexes와 달리 인덱스마다 점수를 정한다.
+	IndexScores []IndexScore
+
+	// CheckData가 true이면 저장된 Key의 data가 ExpectData와 같을 때만 적용한다. ExpectData가 nil이면 data가 없어야 한다.
+	CheckData  bool
+	ExpectData []byte
+
+	// 함께 목록 끝에 추가할 항목들
+	Lists []Append
+	// 함께 스트림 끝에 추가할 항목들. 순번은 AppendStream과 같이 붙는다.
+	Streams []Append
+	// 함께 늘릴 값들
+	Incrs []Incr
+	// 함께 지울 목록들
+	DeleteLists []string
+	// 함께 목록에서 뺄 항목들
+	RemoveListItems []ListItem
+
+	// 함께 만료 없이 저장할 값들
+	Values []Value
+	// 함께 지울 값들
+	DeleteValues []string
+
+	// 함께 전체 순위를 복사할 인덱스들. CopyIndex와 같다.
+	CopyIndexes []string
+	// 함께 지울 인덱스들
+	DeleteIndexes []string
+}
+
+// IndexScore는 Name 인덱스에서 Write.Key의 점수
+type IndexScore struct {
+	Name  string
+	Score int
+}
+
+// Append는 목록 끝에 추가할 항목. MaxLen이 0보다 크면 최근 MaxLen개만 남긴다.
+type Append struct {
+	Key    string
+	Data   []byte
+	MaxLen int
+}
+
+// Incr는 정수 값 key를 By만큼 늘린다. By가 음수이면 줄인다. 값이 없으면 0에서 시작한다.
+type Incr struct {
+	Key string
+	By  int64
+}
+
+// Value는 값 Key에 저장할 Data
+type Value struct {
+	Key  string
+	Data []byte
+}
+
+// ListItem은 목록 Key에서 Data와 같은 항목들
+type ListItem struct {
+	Key  string
+	Data []byte
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+type writeStorage interface {
+	WriteData(ctx context.Context, w Write) (bool, error)
+	GetData(ctx context.Context, keys ...string) ([][]byte, error)
+	GetRanks(ctx context.Context, keys ...string) ([]int, error)
+	GetIndexScores(ctx context.Context, name string, keys ...string) ([]int, error)
+	GetList(ctx context.Context, key string) ([][]byte, error)
+	GetValue(ctx context.Context, key string) ([]byte, error)
+	IncrValue(ctx context.Context, key string, ttl time.Duration) (int, error)
+	ReadStream(ctx context.Context, key string, afterSeq int64, count int) ([]int64, [][]byte, error)
+}
+
+func TestStorage_WriteData(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		testWriteData(t, &MemStorage{})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		testWriteData(t, &RedisStorage{
+			KeyPrefix: "test",
+			Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		})
+	})
+}
+
+func testWriteData(t *testing.T, storage writeStorage) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	write := Write{
+		Key:         "user1",
+		Data:        []byte("data1"),
+		Score:       10,
+		AddIndexes:  []string{""},
+		IndexScores: []IndexScore{{Name: "updated", Score: -5}},
+		CheckData:   true,
+		Lists:       []Append{{Key: "history", Data: []byte("h1"), MaxLen: 2}},
+		Incrs:       []Incr{{Key: "count", By: 1}, {Key: "sum", By: -3}},
+		Streams:     []Append{{Key: "events", Data: []byte("e"), MaxLen: 10}},
+	}
+
+	applied, err := storage.WriteData(ctx, write)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeTrue())
+
+	// 이미 data가 있으면 적용하지 않는다
+	applied, err = storage.WriteData(ctx, write)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeFalse())
+
+	write.Data, write.Score, write.ExpectData = []byte("data2"), 20, []byte("data1")
+	write.Lists = []Append{{Key: "history", Data: []byte("h2"), MaxLen: 2}, {Key: "history", Data: []byte("h3"), MaxLen: 2}}
+	applied, err = storage.WriteData(ctx, write)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeTrue())
+
+	// 기대한 data와 다르면 아무것도 바꾸지 않는다
+	write.Data, write.ExpectData = []byte("data3"), []byte("data1")
+	write.Lists = []Append{{Key: "history", Data: []byte("h4")}}
+	applied, err = storage.WriteData(ctx, write)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeFalse())
+
+	data, err := storage.GetData(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(data[0])).To(Equal("data2"))
+
+	ranks, err := storage.GetRanks(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ranks).To(Equal([]int{1}))
+
+	list, err := storage.GetList(ctx, "history")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(list).To(Equal([][]byte{[]byte("h2"), []byte("h3")}))
+
+	value, err := storage.GetValue(ctx, "count")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(value)).To(Equal("2"))
+
+	value, err = storage.GetValue(ctx, "sum")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(value)).To(Equal("-6"))
+
+	// IncrValue로도 늘릴 수 있다
+	n, err := storage.IncrValue(ctx, "count", 0)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(3))
+
+	// 적용한 변경의 스트림 항목만 남는다
+	seqs, events, err := storage.ReadStream(ctx, "events", 0, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(seqs).To(Equal([]int64{1, 2}))
+	g.Expect(events).To(Equal([][]byte{[]byte("e"), []byte("e")}))
+
+	scores, err := storage.GetIndexScores(ctx, "updated", "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(scores).To(Equal([]int{-5}))
+
+	// 확인하지 않으면 항상 적용한다
+	write.CheckData = false
+	applied, err = storage.WriteData(ctx, write)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeTrue())
+
+	// 기대한 data일 때만 지운다
+	del := Write{
+		Key:           "user1",
+		Delete:        true,
+		RemoveIndexes: []string{"", "updated"},
+		CheckData:     true,
+		ExpectData:    []byte("data2"),
+		DeleteLists:   []string{"history"},
+	}
+
+	applied, err = storage.WriteData(ctx, del)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeFalse())
+
+	del.ExpectData = []byte("data3")
+	applied, err = storage.WriteData(ctx, del)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeTrue())
+
+	data, err = storage.GetData(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(data[0]).To(BeNil())
+
+	for _, name := range []string{"", "updated"} {
+		scores, err = storage.GetIndexScores(ctx, name, "user1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(scores).To(Equal([]int{0}))
+	}
+
+	list, err = storage.GetList(ctx, "history")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(list).To(BeEmpty())
+
+	// Key 없이 값, 목록, 인덱스만 바꾼다
+	applied, err = storage.WriteData(ctx, Write{
+		Key:        "user2",
+		Data:       []byte("data"),
+		Score:      7,
+		AddIndexes: []string{"", "old"},
+		Lists:      []Append{{Key: "items", Data: []byte("a")}, {Key: "items", Data: []byte("b")}},
+		Values:     []Value{{Key: "gone", Data: []byte("x")}},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeTrue())
+
+	applied, err = storage.WriteData(ctx, Write{
+		Values:          []Value{{Key: "updated_at", Data: []byte("now")}},
+		DeleteValues:    []string{"gone"},
+		CopyIndexes:     []string{"copy"},
+		DeleteIndexes:   []string{"old"},
+		RemoveListItems: []ListItem{{Key: "items", Data: []byte("a")}},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied).To(BeTrue())
+
+	data, err = storage.GetData(ctx, "", "user2")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(data).To(Equal([][]byte{nil, []byte("data")}))
+
+	value, err = storage.GetValue(ctx, "updated_at")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(value).To(Equal([]byte("now")))
+
+	value, err = storage.GetValue(ctx, "gone")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(value).To(BeNil())
+
+	scores, err = storage.GetIndexScores(ctx, "copy", "user2")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(scores).To(Equal([]int{7}))
+
+	scores, err = storage.GetIndexScores(ctx, "old", "user2")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(scores).To(Equal([]int{0}))
+
+	list, err = storage.GetList(ctx, "items")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(list).To(Equal([][]byte{[]byte("b")}))
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+)
+
+func TestClientToServerAPIKeys(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	keys := &apikey.Store{
+		Storage: lb.Storage,
+		Board:   "main",
+		Static:  map[string]apikey.Key{apikey.Hash("admin-secret"): {Id: "admin", Scopes: []apikey.Scope{apikey.ScopeAdmin}}},
+	}
+
+	server := http_server.New(lb, nil, http_server.WithAPIKeys(keys))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+
+	expectStatus := func(err error, statusCode int) {
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(statusCode), err.Error())
+	}
+
+	admin := http_client.New(endpoint)
+	admin.APIKey = "admin-secret"
+
+	writerKey, err := admin.IssueAPIKey(ctx, apikey.Key{Name: "game server", Scopes: []apikey.Scope{apikey.ScopeWrite}, Boards: []string{"main"}})
+	g.Expect(err).NotTo(HaveOccurred())
+	readerKey, err := admin.IssueAPIKey(ctx, apikey.Key{Name: "website", Scopes: []apikey.Scope{apikey.ScopeRead}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	issued, err := admin.GetAPIKeys(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(issued).To(HaveLen(2))
+	g.Expect(issued[0].Secret).To(BeEmpty())
+
+	anonymous := http_client.New(endpoint)
+	writer := http_client.New(endpoint)
+	writer.APIKey = writerKey.Secret
+	reader := http_client.New(endpoint)
+	reader.APIKey = readerKey.Secret
+
+	expectStatus(anonymous.SetUser(ctx, "user1", 100), http.StatusUnauthorized)
+	expectStatus(reader.SetUser(ctx, "user1", 100), http.StatusForbidden)
+	g.Expect(writer.SetUser(ctx, "user1", 100)).To(Succeed())
+
+	_, err = anonymous.GetUser(ctx, "user1")
+	expectStatus(err, http.StatusUnauthorized)
+
+	user, err := reader.GetUser(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(100))
+
+	_, err = writer.GetAPIKeys(ctx)
+	expectStatus(err, http.StatusForbidden)
+
+	// websocket 구독도 API key를 확인한다
+	g.Expect(anonymous.SubscribeRanks(ctx, 1, 1, func(users []api.User) error { return nil })).NotTo(Succeed())
+
+	windows := make(chan []api.User, 10)
+	go reader.SubscribeRanks(ctx, 1, 1, func(users []api.User) error {
+		windows <- users
+		return nil
+	})
+
+	var window []api.User
+	g.Eventually(windows).Should(Receive(&window))
+	g.Expect(window).To(HaveLen(1))
+
+	// 폐기한 key는 바로 사용할 수 없다
+	g.Expect(admin.RevokeAPIKey(ctx, writerKey.Id)).To(Succeed())
+	expectStatus(writer.SetUser(ctx, "user1", 200), http.StatusUnauthorized)
+}
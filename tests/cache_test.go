@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+)
+
+// countingLeaderBoard는 서버가 순위를 실제로 조회한 횟수를 센다
+type countingLeaderBoard struct {
+	api.LeaderBoard
+	getRanks int32
+}
+
+func (lb *countingLeaderBoard) GetRanks(ctx context.Context, rank, count int, opts ...api.Option) ([]api.User, error) {
+	atomic.AddInt32(&lb.getRanks, 1)
+	return lb.LeaderBoard.GetRanks(ctx, rank, count, opts...)
+}
+
+func TestClientToServerConditionalGet(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	lb := &countingLeaderBoard{
+		LeaderBoard: &leaderboard.LeaderBoard{
+			Storage: &storage.MemStorage{},
+		},
+	}
+
+	server := http_server.New(lb, nil, http_server.WithCacheControl(map[string]string{
+		"GET /ranks":     "public, max-age=10",
+		"GET /usercount": "",
+	}))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+	client := http_client.New(endpoint)
+
+	version, err := client.GetVersion(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(version.Version).To(BeZero())
+
+	g.Expect(client.SetUser(ctx, "user1", 100)).To(Succeed())
+	g.Expect(client.SetUser(ctx, "user2", 200)).To(Succeed())
+
+	version, err = client.GetVersion(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(version.Version).To(Equal(int64(2)))
+
+	// route별 Cache-Control
+	for path, expected := range map[string]string{
+		"/v1/ranks?rank=1&count=10": "public, max-age=10",
+		"/v1/usercount":             "",
+		"/v1/users/user1":           "private, no-cache",
+	} {
+		resp, err := http.Get(endpoint + path)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		g.Expect(resp.Header.Get("Cache-Control")).To(Equal(expected), path)
+	}
+	atomic.StoreInt32(&lb.getRanks, 0)
+
+	users, err := client.GetRanks(ctx, 1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(2))
+	g.Expect(atomic.LoadInt32(&lb.getRanks)).To(Equal(int32(1)))
+
+	// 바뀌지 않았으면 서버는 304로 응답하고 클라이언트는 기억한 응답을 사용한다
+	cached, err := client.GetRanks(ctx, 1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cached).To(Equal(users))
+	g.Expect(atomic.LoadInt32(&lb.getRanks)).To(Equal(int32(1)))
+
+	// 다른 구간은 따로 기억한다
+	_, err = client.GetRanks(ctx, 1, 1)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(atomic.LoadInt32(&lb.getRanks)).To(Equal(int32(2)))
+
+	g.Expect(client.SetUser(ctx, "user1", 300)).To(Succeed())
+
+	users, err = client.GetRanks(ctx, 1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users[0].Id).To(Equal("user1"))
+	g.Expect(atomic.LoadInt32(&lb.getRanks)).To(Equal(int32(3)))
+
+	// 기억하지 않는 클라이언트는 매번 조회한다
+	uncached := http_client.New(endpoint)
+	uncached.MaxCachedResponses = 0
+	for i := 0; i < 2; i++ {
+		_, err = uncached.GetRanks(ctx, 1, 10)
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(atomic.LoadInt32(&lb.getRanks)).To(Equal(int32(5)))
+}
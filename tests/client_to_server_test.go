@@ -4,11 +4,17 @@ import (
 	"context"
 	"github.com/bigflood/leaderboard/api"
 	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
 	"github.com/bigflood/leaderboard/pkg/http_server"
 	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/signature"
 	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/gomega"
 	"log"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -38,6 +44,149 @@ func TestClientToServerLeaderBoardWithMultiGoroutines(t *testing.T) {
 	})
 }
 
+func TestClientToServerSnapshots(t *testing.T) {
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	testClientToServer(t, lb, func(client api.LeaderBoard) {
+		testSnapshots(t, client)
+	})
+}
+
+func TestClientToServerSegments(t *testing.T) {
+	lb := &leaderboard.LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		SegmentAttributes: []string{"country"},
+	}
+
+	testClientToServer(t, lb, func(client api.LeaderBoard) {
+		testSegments(t, client)
+	})
+}
+
+func TestClientToServerRetryWithIdempotencyKey(t *testing.T) {
+	g := NewWithT(t)
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	e := echo.New()
+	http_handler.New(lb).Setup(e)
+
+	ctx := context.Background()
+
+	// 첫 요청은 처리한 다음 응답을 잃어버린 것처럼 502를 반환하고,
+	// 다시 시도하기 전에 다른 점수 변경이 먼저 반영되게 한다
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(api.IdempotencyKeyHeader))
+		if len(keys) == 1 {
+			e.ServeHTTP(httptest.NewRecorder(), r)
+			g.Expect(lb.SetUser(ctx, "a", 20)).To(Succeed())
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		e.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := http_client.New(server.URL)
+	client.RetryInterval = time.Millisecond
+
+	g.Expect(client.SetUser(ctx, "a", 10)).To(Succeed())
+
+	g.Expect(keys).To(HaveLen(2))
+	g.Expect(keys[0]).NotTo(BeEmpty())
+	g.Expect(keys[1]).To(Equal(keys[0]))
+
+	// 다시 시도한 요청은 반영되지 않았다
+	user, err := lb.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(20))
+}
+
+func TestClientToServerRetryInProgress(t *testing.T) {
+	g := NewWithT(t)
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	e := echo.New()
+	http_handler.New(lb).Setup(e)
+
+	// 첫 요청이 처리되는 동안 다시 보낸 요청은 409를 받는다
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"request with the same idempotency key is in progress"}`))
+			return
+		}
+		e.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := http_client.New(server.URL)
+	client.RetryInterval = time.Millisecond
+
+	user := api.User{}
+	g.Expect(client.SetUser(context.Background(), "a", 10, api.WithResult(&user))).To(Succeed())
+	g.Expect(requests).To(Equal(2))
+	g.Expect(user.Id).To(Equal("a"))
+	g.Expect(user.Score).To(Equal(10))
+	g.Expect(user.Rank).To(Equal(1))
+}
+
+func TestClientToServerSignedSubmissions(t *testing.T) {
+	g := NewWithT(t)
+
+	s := &storage.MemStorage{}
+	lb := &leaderboard.LeaderBoard{
+		Storage:           s,
+		SegmentAttributes: []string{"country"},
+	}
+
+	server := http_server.New(lb, nil, http_server.WithVerifier(&signature.Verifier{
+		Board:   "main",
+		Secrets: map[string][]byte{"game1": []byte("secret1")},
+		Nonces:  s,
+	}))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	ctx := context.Background()
+	client := http_client.New("http://" + listener.Addr().String())
+
+	err = client.SetUser(ctx, "a", 10)
+	g.Expect(err).To(MatchError("signature is required"))
+
+	client.Signer = &signature.Signer{
+		Board:  "main",
+		GameId: "game1",
+		Secret: []byte("secret1"),
+	}
+	g.Expect(client.SetUser(ctx, "a", 10)).To(Succeed())
+	g.Expect(client.SetUser(ctx, "a", 20, api.WithAttribute("country", "KR"), api.WithIdempotencyKey("key1"))).To(Succeed())
+
+	user, err := client.GetUser(ctx, "a")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(20))
+	g.Expect(user.Attributes).To(Equal(map[string]string{"country": "KR"}))
+
+	client.Signer.Secret = []byte("wrong")
+	g.Expect(client.SetUser(ctx, "a", 30)).To(MatchError("invalid signature"))
+}
+
 func testClientToServer(t *testing.T, logic api.LeaderBoard, f func(client api.LeaderBoard)) {
 	server := http_server.New(logic, nil)
 
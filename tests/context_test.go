@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+)
+
+// blockingStorage는 순위 조회가 ctx가 끝날 때까지 기다리고, 끝난 이유를 stopped로 알린다
+type blockingStorage struct {
+	*storage.MemStorage
+	stopped chan error
+}
+
+func (s *blockingStorage) GetSortedRange(ctx context.Context, rank, count int) ([]string, error) {
+	<-ctx.Done()
+	s.stopped <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func TestClientToServerContext(t *testing.T) {
+	g := NewWithT(t)
+
+	s := &blockingStorage{
+		MemStorage: &storage.MemStorage{},
+		stopped:    make(chan error, 1),
+	}
+	lb := &leaderboard.LeaderBoard{Storage: s}
+
+	server := http_server.New(lb, nil, http_server.WithTimeouts(time.Hour, map[string]time.Duration{
+		"GET /ranks": 50 * time.Millisecond,
+	}))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	client := http_client.New("http://" + listener.Addr().String())
+
+	// 서버의 시간 제한을 넘으면 저장소 작업을 멈추고 504를 반환한다
+	_, err = client.GetRanks(context.Background(), 1, 10)
+	var statusErr interface{ StatusCode() int }
+	g.Expect(errors.As(err, &statusErr)).To(BeTrue())
+	g.Expect(statusErr.StatusCode()).To(Equal(http.StatusGatewayTimeout))
+	g.Eventually(s.stopped).Should(Receive(Equal(context.DeadlineExceeded)))
+
+	// 클라이언트가 요청을 취소하면 서버의 저장소 작업도 멈춘다
+	server2 := http_server.New(lb, nil)
+
+	listener2, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener2.Close()
+
+	go server2.Serve(listener2)
+	defer server2.Shutdown(context.Background())
+
+	client = http_client.New("http://" + listener2.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = client.GetRanks(ctx, 1, 10)
+	g.Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+	g.Eventually(s.stopped).Should(Receive(Equal(context.Canceled)))
+}
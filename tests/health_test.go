@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestClientToServerHealth(t *testing.T) {
+	g := NewWithT(t)
+
+	s, err := miniredis.Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer s.Close()
+
+	redisStorage := &storage.RedisStorage{
+		KeyPrefix:   "test",
+		Client:      redis.NewClient(&redis.Options{Addr: s.Addr(), MaxRetries: -1}),
+		PingTimeout: 100 * time.Millisecond,
+	}
+	lb := &leaderboard.LeaderBoard{Storage: redisStorage}
+
+	server := http_server.New(lb, nil,
+		http_server.WithHealthChecks(map[string]http_handler.HealthCheck{"storage": redisStorage}),
+		http_server.WithShutdownDelay(200*time.Millisecond))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+
+	endpoint := "http://" + listener.Addr().String()
+
+	getStatus := func(path string) int {
+		resp, err := http.Get(endpoint + path)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	g.Expect(getStatus("/healthz")).To(Equal(http.StatusOK))
+	g.Expect(getStatus("/readyz")).To(Equal(http.StatusOK))
+
+	// redis에 연결할 수 없는 동안에는 준비되지 않았다
+	s.Close()
+	g.Expect(getStatus("/readyz")).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(getStatus("/healthz")).To(Equal(http.StatusOK))
+
+	g.Expect(s.Restart()).To(Succeed())
+	g.Expect(getStatus("/readyz")).To(Equal(http.StatusOK))
+
+	// graceful shutdown을 시작하면 연결을 닫기 전까지 readiness가 실패한다
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(context.Background())
+	}()
+
+	g.Eventually(func() int { return getStatus("/readyz") }).Should(Equal(http.StatusServiceUnavailable))
+	g.Expect(getStatus("/healthz")).To(Equal(http.StatusOK))
+
+	g.Eventually(shutdown).Should(Receive(BeNil()))
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+)
+
+func TestClientToServerJWT(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	jwks, err := jwtauth.MarshalJWKS(map[string]*rsa.PublicKey{"key1": &rsaKey.PublicKey})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	g.Expect(os.WriteFile(path, jwks, 0600)).To(Succeed())
+
+	rsaKeys, err := jwtauth.LoadJWKSFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	server := http_server.New(lb, nil, http_server.WithTokens(&jwtauth.Verifier{
+		RSAKeys:  rsaKeys,
+		Issuer:   "game",
+		Audience: "leaderboard",
+	}))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+
+	expectStatus := func(err error, statusCode int) {
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(statusCode), err.Error())
+	}
+
+	newClient := func(claims map[string]interface{}) *http_client.Client {
+		claims["iss"] = "game"
+		claims["aud"] = "leaderboard"
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+
+		token, err := jwtauth.SignRS256(rsaKey, "key1", claims)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		client := http_client.New(endpoint)
+		client.BearerToken = token
+		return client
+	}
+
+	anonymous := http_client.New(endpoint)
+	player := newClient(map[string]interface{}{"sub": "user1"})
+	admin := newClient(map[string]interface{}{"sub": "ops", "leaderboard_admin": true})
+
+	expectStatus(anonymous.SetUser(ctx, "user1", 100), http.StatusUnauthorized)
+
+	// 플레이어는 자신의 점수만 제출할 수 있다
+	g.Expect(player.SetUser(ctx, "user1", 100)).To(Succeed())
+	expectStatus(player.SetUser(ctx, "user2", 100), http.StatusForbidden)
+	g.Expect(admin.SetUser(ctx, "user2", 200)).To(Succeed())
+
+	user, err := player.GetUser(ctx, "user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Score).To(Equal(100))
+
+	_, err = player.GetUser(ctx, "user2")
+	expectStatus(err, http.StatusForbidden)
+
+	users, err := player.GetRanks(ctx, 1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(2))
+
+	// websocket 구독도 토큰을 확인한다
+	g.Expect(anonymous.SubscribeRanks(ctx, 1, 1, func(users []api.User) error { return nil })).NotTo(Succeed())
+
+	windows := make(chan []api.User, 10)
+	go player.SubscribeRanks(ctx, 1, 1, func(users []api.User) error {
+		windows <- users
+		return nil
+	})
+
+	var window []api.User
+	g.Eventually(windows).Should(Receive(&window))
+	g.Expect(window).To(HaveLen(1))
+}
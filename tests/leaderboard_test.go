@@ -136,3 +136,88 @@ func testMultiGoroutines(t *testing.T, lb api.LeaderBoard) {
 		g.Expect(user.Rank).To(Equal(i + 1))
 	}
 }
+
+func TestSnapshots(t *testing.T) {
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+	testSnapshots(t, lb)
+}
+
+func testSnapshots(t *testing.T, lb api.LeaderBoard) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	err := lb.SetUser(ctx, "a", 10)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	snapshot, err := lb.CreateSnapshot(ctx, "friday 18:00")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshot.Id).To(Equal("friday 18:00"))
+	g.Expect(snapshot.UserCount).To(Equal(1))
+
+	err = lb.SetUser(ctx, "a", 20)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = lb.SetUser(ctx, "b", 30)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	count, err := lb.UserCount(ctx, api.WithSnapshot(snapshot.Id))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(1))
+
+	user, err := lb.GetUser(ctx, "a", api.WithSnapshot(snapshot.Id))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user).To(Equal(api.User{Id: "a", Score: 10, Rank: 1}))
+
+	users, err := lb.GetRanks(ctx, 1, 10, api.WithSnapshot(snapshot.Id))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(Equal([]api.User{{Id: "a", Score: 10, Rank: 1}}))
+
+	snapshots, err := lb.GetSnapshots(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].Id).To(Equal(snapshot.Id))
+
+	err = lb.DeleteSnapshot(ctx, snapshot.Id)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = lb.GetUser(ctx, "a", api.WithSnapshot(snapshot.Id))
+	g.Expect(err).To(MatchError("snapshot not found"))
+}
+
+func TestSegments(t *testing.T) {
+	lb := &leaderboard.LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		SegmentAttributes: []string{"country"},
+	}
+	testSegments(t, lb)
+}
+
+func testSegments(t *testing.T, lb api.LeaderBoard) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	err := lb.SetUser(ctx, "a", 30, api.WithAttribute("country", "US"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = lb.SetUser(ctx, "b", 20, api.WithAttribute("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	user, err := lb.GetUser(ctx, "b", api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Rank).To(Equal(2))
+	g.Expect(user.SegmentRank).To(Equal(1))
+	g.Expect(user.Attributes).To(Equal(map[string]string{"country": "KR"}))
+
+	count, err := lb.UserCount(ctx, api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(1))
+
+	users, err := lb.GetRanks(ctx, 1, 10, api.WithSegment("country", "KR"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(1))
+	g.Expect(users[0].Id).To(Equal("b"))
+}
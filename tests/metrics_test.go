@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/metrics_mw"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestClientToServerMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	registry := prometheus.NewRegistry()
+	metrics := metrics_mw.NewMetrics(registry)
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &metrics_mw.StorageMiddleware{Metrics: metrics, Receiver: &storage.MemStorage{}},
+	}
+	registry.MustRegister(&metrics_mw.BoardCollector{LeaderBoard: lb})
+
+	server := http_server.New(&metrics_mw.MetricsMiddleware{Metrics: metrics, Receiver: lb}, nil,
+		http_server.WithMetrics(registry))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+	client := http_client.New(endpoint)
+
+	g.Expect(client.SetUser(ctx, "user1", 100)).To(Succeed())
+	g.Expect(client.SetUser(ctx, "user2", 200)).To(Succeed())
+	_, err = client.GetUser(ctx, "unknown")
+	g.Expect(err).To(HaveOccurred())
+
+	resp, err := http.Get(endpoint + "/no/such/route/user1")
+	g.Expect(err).NotTo(HaveOccurred())
+	resp.Body.Close()
+
+	// route에 없는 method나 표준이 아닌 method도 label 값을 늘리지 않는다
+	for _, method := range []string{http.MethodDelete, "BREW-user1"} {
+		req, err := http.NewRequest(method, endpoint+"/v1/usercount", nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp, err = http.DefaultClient.Do(req)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		g.Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	}
+
+	resp, err = http.Get(endpoint + "/metrics")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer resp.Body.Close()
+
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	body, err := io.ReadAll(resp.Body)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	text := string(body)
+	g.Expect(text).To(ContainSubstring(`http_request_duration_seconds_count{code="200",method="PUT",route="/v1/users/:id"} 2`))
+	g.Expect(text).To(ContainSubstring(`http_request_duration_seconds_count{code="404",method="GET",route="/v1/users/:id"} 1`))
+	g.Expect(text).To(ContainSubstring(`http_request_duration_seconds_count{code="404",method="GET",route="unmatched"} 1`))
+	g.Expect(text).To(ContainSubstring(`http_request_duration_seconds_count{code="405",method="DELETE",route="unmatched"} 1`))
+	g.Expect(text).To(ContainSubstring(`http_request_duration_seconds_count{code="405",method="other",route="unmatched"} 1`))
+	g.Expect(text).To(ContainSubstring(`leaderboard_calls_total{method="SetUser",result="ok"} 2`))
+	g.Expect(text).To(ContainSubstring(`leaderboard_calls_total{method="GetUser",result="404"} 1`))
+	g.Expect(text).To(ContainSubstring(`leaderboard_storage_duration_seconds_count{operation="WriteData"} 2`))
+	g.Expect(text).To(ContainSubstring("leaderboard_users 2"))
+	g.Expect(text).To(ContainSubstring("leaderboard_boards 1"))
+
+	// label에 사용자 id나 요청 path를 넣지 않는다
+	g.Expect(text).NotTo(ContainSubstring("user1"))
+	g.Expect(text).NotTo(ContainSubstring("unknown"))
+}
+
+func TestClientToServerUnauthenticatedRoutes(t *testing.T) {
+	g := NewWithT(t)
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	keys := &apikey.Store{
+		Storage: lb.Storage,
+		Board:   "main",
+	}
+
+	server := http_server.New(lb, nil, http_server.WithAPIKeys(keys), http_server.WithMetrics(prometheus.NewRegistry()))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+
+	// 문서에 인증 없이 사용할 수 있다고 적은 route는 API key 없이 요청할 수 있다
+	spec := http_handler.Spec()
+	for path, operations := range spec.Paths {
+		operation := operations["get"]
+		if operation == nil || operation.Security == nil || len(operation.Security) > 0 {
+			continue
+		}
+
+		resp, err := http.Get(endpoint + path)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK), path)
+	}
+
+	resp, err := http.Get(endpoint + "/v1/usercount")
+	g.Expect(err).NotTo(HaveOccurred())
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+}
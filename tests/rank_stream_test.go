@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/websocket"
+)
+
+func TestClientToServerRankStream(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 10)).To(Succeed())
+
+	server := http_server.New(lb, nil, http_server.WithRankStream(10*time.Millisecond, 1))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	client := http_client.New("http://" + listener.Addr().String())
+
+	windows := make(chan []api.User, 10)
+	done := make(chan error)
+	go func() {
+		done <- client.SubscribeRanks(ctx, 1, 2, func(users []api.User) error {
+			windows <- users
+			return nil
+		})
+	}()
+
+	ids := func(users []api.User) []string {
+		result := []string{}
+		for _, user := range users {
+			result = append(result, user.Id)
+		}
+		return result
+	}
+
+	var window []api.User
+	g.Eventually(windows).Should(Receive(&window))
+	g.Expect(ids(window)).To(Equal([]string{"a", "b"}))
+
+	// 구간 밖의 변경은 보내지 않는다
+	g.Expect(lb.SetUser(ctx, "c", 15)).To(Succeed())
+	g.Consistently(windows, 50*time.Millisecond).ShouldNot(Receive())
+
+	g.Expect(lb.SetUser(ctx, "c", 40)).To(Succeed())
+	g.Eventually(windows).Should(Receive(&window))
+	g.Expect(ids(window)).To(Equal([]string{"c", "a"}))
+	g.Expect(window[0].Score).To(Equal(40))
+	g.Expect(window[1].Rank).To(Equal(2))
+
+	// 연결 수 제한을 넘으면 연결할 수 없다
+	err = client.SubscribeRanks(ctx, 1, 10, func(users []api.User) error {
+		return nil
+	})
+	g.Expect(err).To(HaveOccurred())
+
+	err = client.SubscribeRanks(ctx, 1, 1000, func(users []api.User) error {
+		return nil
+	})
+	g.Expect(err).To(HaveOccurred())
+
+	cancel()
+	g.Eventually(done).Should(Receive(Equal(context.Canceled)))
+}
+
+// slowRanksLeaderBoard는 block이 닫힐 때까지 rank 구간의 조회를 멈춘다
+type slowRanksLeaderBoard struct {
+	*leaderboard.LeaderBoard
+	rank  int
+	block chan struct{}
+}
+
+func (lb *slowRanksLeaderBoard) GetRanks(ctx context.Context, rank, count int, opts ...api.Option) ([]api.User, error) {
+	if rank == lb.rank {
+		<-lb.block
+	}
+	return lb.LeaderBoard.GetRanks(ctx, rank, count, opts...)
+}
+
+func TestClientToServerRankStreamSlowSnapshot(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &slowRanksLeaderBoard{
+		LeaderBoard: &leaderboard.LeaderBoard{Storage: &storage.MemStorage{}},
+		rank:        1,
+		block:       make(chan struct{}),
+	}
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	// 0 이하인 설정은 기본값을 사용한다
+	server := http_server.New(lb, nil, http_server.WithRankStream(0, 0))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	client := http_client.New("http://" + listener.Addr().String())
+
+	subscribe := func(rank int) chan []api.User {
+		windows := make(chan []api.User, 10)
+		go client.SubscribeRanks(ctx, rank, 1, func(users []api.User) error {
+			windows <- users
+			return nil
+		})
+		return windows
+	}
+
+	slow := subscribe(1)
+	g.Consistently(slow, 50*time.Millisecond).ShouldNot(Receive())
+
+	// 한 구간의 느린 조회가 다른 구간의 구독을 막지 않는다
+	var window []api.User
+	g.Eventually(subscribe(2)).Should(Receive(&window))
+	g.Expect(window).To(HaveLen(1))
+	g.Expect(window[0].Id).To(Equal("b"))
+
+	close(lb.block)
+	g.Eventually(slow).Should(Receive(&window))
+	g.Expect(window[0].Id).To(Equal("a"))
+}
+
+func TestClientToServerRankStreamOrigin(t *testing.T) {
+	g := NewWithT(t)
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+	g.Expect(lb.SetUser(context.Background(), "a", 30)).To(Succeed())
+
+	server := http_server.New(lb, nil, http_server.WithAllowedOrigins([]string{"https://Game.example.com/"}))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	location := "ws://" + listener.Addr().String() + "/v1/ranks/stream?rank=1&count=10"
+
+	dial := func(origin string) (*websocket.Conn, error) {
+		config, err := websocket.NewConfig(location, origin)
+		g.Expect(err).NotTo(HaveOccurred())
+		return websocket.DialConfig(config)
+	}
+
+	// 다른 사이트의 페이지에서는 연결할 수 없다
+	_, err = dial("https://evil.example.com")
+	g.Expect(err).To(HaveOccurred())
+
+	for _, origin := range []string{"https://game.example.com", "http://" + listener.Addr().String()} {
+		ws, err := dial(origin)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		update := api.RankUpdate{}
+		g.Expect(websocket.JSON.Receive(ws, &update)).To(Succeed())
+		g.Expect(update.Type).To(Equal(api.RankUpdateSnapshot))
+		g.Expect(update.Users).To(HaveLen(1))
+		ws.Close()
+	}
+}
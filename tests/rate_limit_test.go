@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/apikey"
+	"github.com/bigflood/leaderboard/pkg/http_client"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/ratelimit"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestClientToServerRateLimit(t *testing.T) {
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	nowFunc := func() time.Time { return now }
+
+	t.Run("MemLimiter", func(t *testing.T) {
+		limiter := &ratelimit.MemLimiter{NowFunc: nowFunc}
+		testRateLimit(t, limiter, limiter)
+	})
+
+	t.Run("RedisLimiter", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		// 서버마다 limiter가 따로 있어도 redis의 bucket을 공유한다
+		newLimiter := func() ratelimit.Limiter {
+			return &ratelimit.RedisLimiter{
+				KeyPrefix: "test",
+				Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+				NowFunc:   nowFunc,
+			}
+		}
+		testRateLimit(t, newLimiter(), newLimiter())
+	})
+}
+
+func testRateLimit(t *testing.T, limiter1, limiter2 ratelimit.Limiter) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	keys := &apikey.Store{
+		Storage: lb.Storage,
+		Board:   "main",
+		Static: map[string]apikey.Key{
+			apikey.Hash("server1"): {Id: "server1", Scopes: []apikey.Scope{apikey.ScopeWrite}},
+			apikey.Hash("server2"): {Id: "server2", Scopes: []apikey.Scope{apikey.ScopeWrite}},
+		},
+	}
+
+	limits := http_server.RateLimits{
+		ClientRead:  ratelimit.Limit{Count: 5, Period: time.Minute},
+		ClientWrite: ratelimit.Limit{Count: 3, Period: time.Minute},
+		UserWrite:   ratelimit.Limit{Count: 2, Period: time.Minute},
+	}
+
+	startServer := func(limiter ratelimit.Limiter) string {
+		server := http_server.New(lb, nil, http_server.WithAPIKeys(keys), http_server.WithRateLimit(limiter, limits))
+
+		listener, err := net.Listen("tcp", ":0")
+		g.Expect(err).NotTo(HaveOccurred())
+		t.Cleanup(func() { listener.Close() })
+
+		go server.Serve(listener)
+		t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+		return "http://" + listener.Addr().String()
+	}
+
+	endpoint1, endpoint2 := startServer(limiter1), startServer(limiter2)
+
+	newClient := func(endpoint, key string) *http_client.Client {
+		client := http_client.New(endpoint)
+		client.APIKey = key
+		return client
+	}
+
+	expectTooManyRequests := func(err error) {
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusTooManyRequests), err.Error())
+	}
+
+	// 사용자별 변경 제한은 클라이언트와 서버가 달라도 함께 센다
+	g.Expect(newClient(endpoint1, "server1").SetUser(ctx, "user1", 100)).To(Succeed())
+	g.Expect(newClient(endpoint2, "server2").SetUser(ctx, "user1", 200)).To(Succeed())
+	expectTooManyRequests(newClient(endpoint1, "server2").SetUser(ctx, "user1", 300))
+
+	// 클라이언트별 변경 제한
+	client1 := newClient(endpoint1, "server1")
+	g.Expect(client1.SetUser(ctx, "user2", 100)).To(Succeed())
+	g.Expect(client1.SetUser(ctx, "user3", 100)).To(Succeed())
+	expectTooManyRequests(newClient(endpoint2, "server1").SetUser(ctx, "user4", 100))
+
+	// 조회는 따로 센다
+	for i := 0; i < 5; i++ {
+		_, err := client1.GetUser(ctx, "user1")
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	_, err := client1.GetUser(ctx, "user1")
+	expectTooManyRequests(err)
+
+	req, err := http.NewRequest(http.MethodGet, endpoint2+"/v1/usercount", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	req.Header.Set(apikey.Header, "server2")
+
+	resp, err := http.DefaultClient.Do(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(resp.Header.Get("RateLimit-Limit")).To(Equal("5"))
+	g.Expect(resp.Header.Get("RateLimit-Remaining")).To(Equal("4"))
+	g.Expect(resp.Header.Get("RateLimit-Reset")).To(Equal("12"))
+
+	req.Header.Set(apikey.Header, "server1")
+	resp, err = http.DefaultClient.Do(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+	g.Expect(resp.Header.Get("Retry-After")).To(Equal("12"))
+	g.Expect(resp.Header.Get("RateLimit-Remaining")).To(Equal("0"))
+	g.Expect(resp.Header.Get("RateLimit-Reset")).To(Equal("60"))
+
+	// 인증 없이 사용할 수 있는 route는 제한하지 않는다
+	for i := 0; i < 10; i++ {
+		resp, err = http.Get(endpoint1 + "/healthz")
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+}
+
+func TestClientToServerRateLimitBuckets(t *testing.T) {
+	g := NewWithT(t)
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+
+	keys := &apikey.Store{
+		Storage: lb.Storage,
+		Board:   "main",
+		Static: map[string]apikey.Key{
+			apikey.Hash("server1"): {Id: "server1", Scopes: []apikey.Scope{apikey.ScopeWrite}},
+			apikey.Hash("server2"): {Id: "server2", Scopes: []apikey.Scope{apikey.ScopeWrite}},
+		},
+	}
+
+	limits := http_server.RateLimits{
+		ClientWrite: ratelimit.Limit{Count: 2, Period: time.Minute},
+		UserWrite:   ratelimit.Limit{Count: 2, Period: time.Minute},
+		IP:          ratelimit.Limit{Count: 10, Period: time.Minute},
+	}
+
+	server := http_server.New(lb, nil, http_server.WithAPIKeys(keys), http_server.WithRateLimit(&ratelimit.MemLimiter{}, limits))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+
+	put := func(path, key string) int {
+		req, err := http.NewRequest(http.MethodPut, endpoint+path, strings.NewReader(`{"score": 1}`))
+		g.Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(apikey.Header, key)
+
+		resp, err := http.DefaultClient.Do(req)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	g.Expect(put("/v1/users/user1", "server1")).To(Equal(http.StatusOK))
+	g.Expect(put("/v1/users/user2", "server1")).To(Equal(http.StatusOK))
+
+	// 클라이언트 제한에서 거절한 요청은 사용자 제한의 token을 쓰지 않는다
+	g.Expect(put("/v1/users/user1", "server1")).To(Equal(http.StatusTooManyRequests))
+	g.Expect(put("/v1/users/user1", "server2")).To(Equal(http.StatusOK))
+
+	// escape된 id도 같은 사용자로 센다
+	g.Expect(put("/v1/users/%75ser1", "server2")).To(Equal(http.StatusTooManyRequests))
+
+	// 잘못된 key로 보내는 요청도 인증하기 전에 IP별로 제한한다
+	statusCodes := []int{}
+	for i := 0; i < 6; i++ {
+		statusCodes = append(statusCodes, put("/v1/users/user3", "unknown"))
+	}
+	g.Expect(statusCodes).To(Equal([]int{
+		http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized,
+		http.StatusTooManyRequests,
+	}))
+	g.Expect(put("/v1/users/user3", "server2")).To(Equal(http.StatusTooManyRequests))
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/http_handler"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/gomega"
+)
+
+func TestClientToServerShadowBannedSubmission(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	lb := &leaderboard.LeaderBoard{
+		Storage: &storage.MemStorage{},
+	}
+	g.Expect(lb.SetUser(ctx, "a", 100)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 10)).To(Succeed())
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateShadowBanned)).To(Succeed())
+
+	e := echo.New()
+	http_handler.New(lb).Setup(e)
+
+	testDataList := []struct {
+		path, body, idempotencyKey string
+		score, rank                int
+	}{
+		{"/users/b?score=20", "", "", 20, 2},
+		{"/v1/users/b", `{"score":30}`, "", 30, 2},
+		{"/v1/users/b", `{"score":200}`, "key1", 200, 1},
+		// 다시 보낸 요청은 처음 응답을 그대로 받는다
+		{"/v1/users/b", `{"score":200}`, "key1", 200, 1},
+	}
+
+	// shadow ban된 사용자도 자신의 점수와 순위를 받으므로 shadow ban을 알 수 없다
+	for _, testData := range testDataList {
+		req := httptest.NewRequest(http.MethodPut, testData.path, strings.NewReader(testData.body))
+		if testData.body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		if testData.idempotencyKey != "" {
+			req.Header.Set(api.IdempotencyKeyHeader, testData.idempotencyKey)
+		}
+
+		rw := httptest.NewRecorder()
+		e.ServeHTTP(rw, req)
+		g.Expect(rw.Code).To(Equal(http.StatusOK), "%s %s: %s", testData.path, testData.body, rw.Body.String())
+
+		// /v1 이전의 route는 data로 감싸지 않는다
+		body := rw.Body.Bytes()
+		if strings.HasPrefix(testData.path, "/v1") {
+			resp := struct {
+				Data json.RawMessage `json:"data"`
+			}{}
+			g.Expect(json.Unmarshal(body, &resp)).To(Succeed())
+			body = resp.Data
+		}
+
+		user := api.User{}
+		g.Expect(json.Unmarshal(body, &user)).To(Succeed())
+		g.Expect(user.Id).To(Equal("b"), string(body))
+		g.Expect(user.Score).To(Equal(testData.score), string(body))
+		g.Expect(user.Rank).To(Equal(testData.rank), string(body))
+		g.Expect(user.State).To(BeEmpty(), string(body))
+	}
+
+	user, err := lb.GetUser(ctx, "b", api.WithViewer("b"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.State).To(BeEmpty())
+
+	// 다른 사용자에게는 보이지 않는다
+	users, err := lb.GetRanks(ctx, 1, 10)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(1))
+	g.Expect(users[0].Id).To(Equal("a"))
+}
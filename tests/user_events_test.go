@@ -0,0 +1,316 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/http_server"
+	"github.com/bigflood/leaderboard/pkg/jwtauth"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	. "github.com/onsi/gomega"
+)
+
+type sseMessage struct {
+	Id    string
+	Event string
+	User  api.User
+}
+
+// readSSE는 body에서 읽은 SSE 이벤트와 comment를 messages로 보낸다
+func readSSE(body *bufio.Reader, messages chan<- sseMessage, comments chan<- string) {
+	msg := sseMessage{}
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			close(messages)
+			return
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if msg.Event != "" {
+				messages <- msg
+			}
+			msg = sseMessage{}
+		case strings.HasPrefix(line, ":"):
+			select {
+			case comments <- line:
+			default:
+			}
+		case strings.HasPrefix(line, "id: "):
+			msg.Id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			msg.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg.User)
+		}
+	}
+}
+
+func TestClientToServerUserEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &leaderboard.LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		MaxEvents:         100,
+		EventPollInterval: time.Millisecond,
+	}
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "c", 10)).To(Succeed())
+
+	server := http_server.New(lb, nil, http_server.WithUserEvents(lb, 20*time.Millisecond, 0))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+
+	subscribe := func(userId, lastEventId string) (chan sseMessage, chan string, *http.Response) {
+		req, err := http.NewRequest(http.MethodGet, endpoint+"/v1/users/"+userId+"/events", nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		req = req.WithContext(ctx)
+		if lastEventId != "" {
+			req.Header.Set("Last-Event-ID", lastEventId)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		messages := make(chan sseMessage, 10)
+		comments := make(chan string, 1)
+		if resp.StatusCode == http.StatusOK {
+			go readSSE(bufio.NewReader(resp.Body), messages, comments)
+		}
+		return messages, comments, resp
+	}
+
+	_, _, resp := subscribe("unknown", "")
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	messages, comments, resp := subscribe("b", "")
+	defer resp.Body.Close()
+	g.Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+	var msg sseMessage
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.Event).To(Equal("rank"))
+	g.Expect(msg.Id).To(Equal("3"))
+	g.Expect(msg.User.Rank).To(Equal(2))
+
+	g.Eventually(comments).Should(Receive(Equal(": heartbeat")))
+
+	// b보다 아래의 변경은 보내지 않는다
+	g.Expect(lb.SetUser(ctx, "c", 15)).To(Succeed())
+	g.Consistently(messages, 50*time.Millisecond).ShouldNot(Receive())
+
+	// 다른 사용자에게 추월당해서 순위가 바뀐다
+	g.Expect(lb.SetUser(ctx, "c", 25)).To(Succeed())
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.Id).To(Equal("5"))
+	g.Expect(msg.User.Score).To(Equal(20))
+	g.Expect(msg.User.Rank).To(Equal(3))
+
+	g.Expect(lb.SetUser(ctx, "b", 50)).To(Succeed())
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.User.Score).To(Equal(50))
+	g.Expect(msg.User.Rank).To(Equal(1))
+	lastEventId := msg.Id
+	resp.Body.Close()
+
+	// 연결이 끊긴 동안의 변경을 Last-Event-ID부터 이어서 받는다
+	g.Expect(lb.SetUser(ctx, "b", 5)).To(Succeed())
+
+	messages, _, resp = subscribe("b", lastEventId)
+	defer resp.Body.Close()
+
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.Id).To(Equal(lastEventId))
+	g.Expect(msg.User.Score).To(Equal(5))
+
+	_, err = lb.EraseUser(ctx, "b", api.Audit{Actor: "admin"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.Event).To(Equal("deleted"))
+	g.Expect(msg.User.Id).To(Equal("b"))
+	g.Eventually(messages).Should(BeClosed())
+}
+
+// failingEventSource는 Subscribe 호출 수를 세고, fail이 닫히면 구독을 오류로 끝낸다
+type failingEventSource struct {
+	*leaderboard.LeaderBoard
+	subscribes int32
+	fail       chan struct{}
+}
+
+func (source *failingEventSource) Subscribe(ctx context.Context, afterSeq int64, handler func(api.Event) error) error {
+	atomic.AddInt32(&source.subscribes, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-source.fail:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := source.LeaderBoard.Subscribe(ctx, afterSeq, handler); err != context.Canceled {
+		return err
+	}
+	return errors.New("storage is unavailable")
+}
+
+func TestClientToServerUserEventsSharedFeed(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &leaderboard.LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		MaxEvents:         100,
+		EventPollInterval: time.Millisecond,
+	}
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	source := &failingEventSource{LeaderBoard: lb, fail: make(chan struct{})}
+	server := http_server.New(lb, nil, http_server.WithUserEvents(source, time.Minute, 2))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	endpoint := "http://" + listener.Addr().String()
+
+	subscribe := func(userId string) (chan sseMessage, *http.Response) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/v1/users/"+userId+"/events", nil)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		resp, err := http.DefaultClient.Do(req)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		messages := make(chan sseMessage, 10)
+		if resp.StatusCode == http.StatusOK {
+			go readSSE(bufio.NewReader(resp.Body), messages, make(chan string))
+		}
+		return messages, resp
+	}
+
+	messagesA, resp := subscribe("a")
+	defer resp.Body.Close()
+	messagesB, resp := subscribe("b")
+	defer resp.Body.Close()
+
+	var msg sseMessage
+	g.Eventually(messagesA).Should(Receive(&msg))
+	g.Eventually(messagesB).Should(Receive(&msg))
+
+	// 연결 수 제한을 넘으면 연결할 수 없다
+	_, resp = subscribe("a")
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+	// 연결들이 구독 하나를 함께 사용한다
+	g.Expect(lb.SetUser(ctx, "b", 40)).To(Succeed())
+	g.Eventually(messagesA).Should(Receive(&msg))
+	g.Expect(msg.User.Rank).To(Equal(2))
+	g.Eventually(messagesB).Should(Receive(&msg))
+	g.Expect(msg.User.Rank).To(Equal(1))
+	g.Expect(atomic.LoadInt32(&source.subscribes)).To(Equal(int32(1)))
+
+	// 구독이 실패하면 연결을 끝낸다
+	close(source.fail)
+	g.Eventually(messagesA).Should(BeClosed())
+	g.Eventually(messagesB).Should(BeClosed())
+}
+
+func TestClientToServerUserEventsVisibility(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lb := &leaderboard.LeaderBoard{
+		Storage:           &storage.MemStorage{},
+		MaxEvents:         100,
+		EventPollInterval: time.Millisecond,
+	}
+	g.Expect(lb.SetUser(ctx, "a", 30)).To(Succeed())
+	g.Expect(lb.SetUser(ctx, "b", 20)).To(Succeed())
+
+	secret := []byte("secret")
+	server := http_server.New(lb, nil,
+		http_server.WithUserEvents(lb, time.Minute, 0),
+		http_server.WithTokens(&jwtauth.Verifier{HMACSecret: secret}))
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	token, err := jwtauth.SignHS256(secret, map[string]interface{}{"sub": "b", "exp": time.Now().Add(time.Hour).Unix()})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/v1/users/b/events", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	messages := make(chan sseMessage, 10)
+	go readSSE(bufio.NewReader(resp.Body), messages, make(chan string, 1))
+
+	var msg sseMessage
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.User.Rank).To(Equal(2))
+
+	// 위 순위의 사용자를 숨기면 순위가 오른다
+	g.Expect(lb.SetUserState(ctx, "a", api.UserStateHidden)).To(Succeed())
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.Event).To(Equal("rank"))
+	g.Expect(msg.User.Rank).To(Equal(1))
+
+	// shadow ban된 사용자 자신에게는 계속 자신의 순위를 보낸다
+	g.Expect(lb.SetUserState(ctx, "b", api.UserStateShadowBanned)).To(Succeed())
+	g.Consistently(messages, 50*time.Millisecond).ShouldNot(Receive())
+
+	g.Expect(lb.SetUser(ctx, "b", 40)).To(Succeed())
+	g.Eventually(messages).Should(Receive(&msg))
+	g.Expect(msg.Event).To(Equal("rank"))
+	g.Expect(msg.User.Score).To(Equal(40))
+	g.Expect(msg.User.Rank).To(Equal(1))
+	g.Expect(msg.User.State).To(BeEmpty())
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigflood/leaderboard/api"
+	"github.com/bigflood/leaderboard/pkg/leaderboard"
+	"github.com/bigflood/leaderboard/pkg/storage"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/gomega"
+)
+
+func TestClientToServerUserIds(t *testing.T) {
+	t.Run("MemStorage", func(t *testing.T) {
+		lb := &leaderboard.LeaderBoard{
+			Storage: &storage.MemStorage{},
+		}
+
+		testClientToServer(t, lb, func(client api.LeaderBoard) {
+			testUserIds(t, client)
+		})
+	})
+
+	t.Run("RedisStorage", func(t *testing.T) {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		lb := &leaderboard.LeaderBoard{
+			Storage: &storage.RedisStorage{
+				KeyPrefix: "test",
+				Client:    redis.NewClient(&redis.Options{Addr: s.Addr()}),
+			},
+		}
+
+		testClientToServer(t, lb, func(client api.LeaderBoard) {
+			testUserIds(t, client)
+		})
+	})
+}
+
+func testUserIds(t *testing.T, lb api.LeaderBoard) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+
+	validIds := []string{"a.b@c-d_e", "사용자", "Ελληνικά", "caf\u00e9", "user_1"}
+	for i, userId := range validIds {
+		g.Expect(lb.SetUser(ctx, userId, 100-i)).To(Succeed(), userId)
+
+		user, err := lb.GetUser(ctx, userId)
+		g.Expect(err).NotTo(HaveOccurred(), userId)
+		g.Expect(user.Id).To(Equal(userId))
+		g.Expect(user.Rank).To(Equal(i + 1))
+
+		history, err := lb.GetHistory(ctx, userId)
+		g.Expect(err).NotTo(HaveOccurred(), userId)
+		g.Expect(history).To(HaveLen(1))
+	}
+
+	users, err := lb.GetRanks(ctx, 1, len(validIds))
+	g.Expect(err).NotTo(HaveOccurred())
+	for i, user := range users {
+		g.Expect(user.Id).To(Equal(validIds[i]))
+	}
+
+	// 정규화하면 같은 id
+	user, err := lb.GetUser(ctx, "cafe\u0301")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(user.Id).To(Equal("caf\u00e9"))
+
+	// 다른 route로 가거나 다른 사용자로 처리하지 않고 거부한다
+	for _, userId := range []string{"a/b", "../usercount", "a?score=1", "a#b", "a b", "a%2Fb", "a:b"} {
+		err := lb.SetUser(ctx, userId, 1)
+		g.Expect(err).To(HaveOccurred(), userId)
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusBadRequest), "%s: %v", userId, err)
+
+		_, err = lb.GetUser(ctx, userId)
+		g.Expect(err).To(HaveOccurred(), userId)
+		g.Expect(err.(api.Error).StatusCode()).To(Equal(http.StatusBadRequest), "%s: %v", userId, err)
+	}
+
+	count, err := lb.UserCount(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(count).To(Equal(len(validIds)))
+}